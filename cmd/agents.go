@@ -63,6 +63,9 @@ var agentsShowCmd = &cobra.Command{
 			fmt.Fprintf(w, "%-16s%s\n", "Description:", cfg.Description)
 		}
 		fmt.Fprintf(w, "%-16s%s\n", "Model:", cfg.Model)
+		if len(cfg.FallbackModels) > 0 {
+			fmt.Fprintf(w, "%-16s%s\n", "Fallbacks:", strings.Join(cfg.FallbackModels, ", "))
+		}
 		if cfg.SystemPrompt != "" {
 			fmt.Fprintf(w, "%-16s%s\n", "System Prompt:", cfg.SystemPrompt)
 		}
@@ -75,6 +78,21 @@ var agentsShowCmd = &cobra.Command{
 		if cfg.Workdir != "" {
 			fmt.Fprintf(w, "%-16s%s\n", "Workdir:", cfg.Workdir)
 		}
+		if len(cfg.Tools) > 0 {
+			fmt.Fprintf(w, "%-16s%s\n", "Tools:", strings.Join(cfg.Tools, ", "))
+		}
+		if len(cfg.RunCommand.Allow) > 0 {
+			fmt.Fprintf(w, "%-16s%s\n", "Commands:", strings.Join(cfg.RunCommand.Allow, ", "))
+		}
+		if len(cfg.HTTPGet.Allow) > 0 {
+			fmt.Fprintf(w, "%-16s%s\n", "Hosts:", strings.Join(cfg.HTTPGet.Allow, ", "))
+		}
+		if approval := approvalTools(cfg.ToolsConf); len(approval) > 0 {
+			fmt.Fprintf(w, "%-16s%s\n", "Approval:", strings.Join(approval, ", "))
+		}
+		if len(cfg.MCPServers) > 0 {
+			fmt.Fprintf(w, "%-16s%s\n", "MCP Servers:", strings.Join(mcpServerNames(cfg.MCPServers), ", "))
+		}
 		if len(cfg.SubAgents) > 0 {
 			fmt.Fprintf(w, "%-16s%s\n", "Sub-Agents:", strings.Join(cfg.SubAgents, ", "))
 			fmt.Fprintf(w, "%-16s%d\n", "Max Depth:", cfg.SubAgentsConf.MaxDepth)
@@ -97,6 +115,21 @@ var agentsShowCmd = &cobra.Command{
 		if cfg.Memory.MaxEntries != 0 {
 			fmt.Fprintf(w, "%-16s%d\n", "Memory MaxEntries:", cfg.Memory.MaxEntries)
 		}
+		if cfg.Memory.Strategy != "" {
+			fmt.Fprintf(w, "%-16s%s\n", "Memory Strategy:", cfg.Memory.Strategy)
+		}
+		if cfg.Memory.TopK != 0 {
+			fmt.Fprintf(w, "%-16s%d\n", "Memory TopK:", cfg.Memory.TopK)
+		}
+		if cfg.Memory.EmbeddingModel != "" {
+			fmt.Fprintf(w, "%-16s%s\n", "Memory Embedding:", cfg.Memory.EmbeddingModel)
+		}
+		if cfg.Memory.Consolidate {
+			fmt.Fprintf(w, "%-16s%v\n", "Memory Consolidate:", cfg.Memory.Consolidate)
+		}
+		if len(cfg.Memory.Shared) > 0 {
+			fmt.Fprintf(w, "%-16s%s\n", "Memory Shared:", strings.Join(cfg.Memory.Shared, ", "))
+		}
 		if cfg.Params.Temperature != 0 {
 			fmt.Fprintf(w, "%-16s%g\n", "Temperature:", cfg.Params.Temperature)
 		}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/tool"
+)
+
+// openTTY opens the terminal approval questions are asked on. Stdin cannot
+// be used: it may carry piped input. Tests replace openTTY.
+var openTTY = func() (io.ReadWriteCloser, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+// ttyApprover asks for approval on the terminal, opened the first time a
+// tool call needs approval. Without a terminal every such call is denied,
+// so approval fails closed.
+type ttyApprover struct {
+	once     sync.Once
+	tty      io.ReadWriteCloser
+	prompter *tool.Prompter
+}
+
+// Approve asks on the terminal whether agentName may make call.
+func (a *ttyApprover) Approve(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision {
+	a.once.Do(func() {
+		tty, err := openTTY()
+		if err != nil {
+			return
+		}
+		a.tty = tty
+		a.prompter = tool.NewPrompter(tty, tty)
+	})
+	if a.prompter == nil {
+		return tool.Decision{Reason: "approval is required, but there is no terminal to ask; use --approval-policy to decide non-interactively"}
+	}
+	return a.prompter.Approve(ctx, agentName, call)
+}
+
+// Close closes the terminal if it was opened.
+func (a *ttyApprover) Close() error {
+	if a.tty == nil {
+		return nil
+	}
+	return a.tty.Close()
+}
+
+// newApprover returns the Approver for a run: the policy file at
+// policyPath if one is given, otherwise the terminal. The caller must call
+// the returned close function when the run ends.
+func newApprover(policyPath string) (tool.Approver, func(), error) {
+	if policyPath != "" {
+		policy, err := tool.LoadPolicy(policyPath)
+		if err != nil {
+			return nil, nil, &ExitError{Code: 2, Err: err}
+		}
+		return policy, func() {}, nil
+	}
+	tty := &ttyApprover{}
+	return tty, func() { tty.Close() }, nil
+}
@@ -94,6 +94,49 @@ var configInitCmd = &cobra.Command{
 
 # [providers.ollama]
 # base_url = "http://localhost:11434"
+
+# [providers.gemini]
+# api_key = ""
+# base_url = ""
+
+# Any server that speaks the OpenAI chat completions API (vLLM, LM Studio,
+# llama.cpp server, Groq, Together, OpenRouter) can be added under its own
+# name and used as "<name>/<model>". api_key is optional and falls back to
+# <NAME_UPPER>_API_KEY. base_url is used as given, including its version
+# path: requests go to <base_url>/chat/completions.
+# [providers.groq]
+# type = "openai-compatible"
+# base_url = "https://api.groq.com/openai/v1"
+# [providers.groq.headers]
+# X-Title = "axe"
+
+# Retry rate limits, overloads, server errors, and timeouts.
+# Agents can override these values in their own [retry] section.
+# [retry]
+# max_attempts = 3
+# initial_backoff_ms = 1000
+# max_backoff_ms = 30000
+
+# Cap the size of tool results (including sub-agent answers) before the
+# model sees them. Oversized results keep their head and tail ("truncate")
+# or are summarized by summary_model ("summarize"). Limits can be set per
+# tool here, and agents can override them in [tools_config.<tool>].
+# [tool_results]
+# max_bytes = 20000
+# strategy = "truncate"
+# summary_model = "anthropic/claude-haiku-4-5"
+# [tool_results.tools.call_agent]
+# max_bytes = 8000
+# strategy = "summarize"
+
+# Prices in USD per million tokens, used for cost_usd in "axe run --json".
+# Keys are "provider/model" prefixes; the longest match wins. Common models
+# have built-in prices, so only add entries to override them or to price
+# other models. cache_write and cache_read default to the input price.
+# [pricing."openai/gpt-4o"]
+# input = 2.50
+# output = 10.00
+# cache_read = 1.25
 `
 			if err := os.WriteFile(configTOMLPath, []byte(configTOMLContent), 0600); err != nil {
 				return fmt.Errorf("failed to write config.toml: %w", err)
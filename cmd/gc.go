@@ -3,10 +3,12 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jrswab/axe/internal/agent"
 	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/engine"
 	"github.com/jrswab/axe/internal/memory"
 	"github.com/jrswab/axe/internal/provider"
 	"github.com/spf13/cobra"
@@ -28,6 +30,14 @@ Based on the patterns and repetitions found, suggest concrete actions the user c
 
 Be concise. Reference specific entries by their timestamps when relevant.`
 
+// gcDigestPrompt is the system prompt for folding trimmed entries into the
+// memory digest.
+const gcDigestPrompt = `You maintain the long-term memory of an AI agent. You will receive the agent's current memory digest, if it has one (tagged "digest"), followed by older entries of its run log that are about to be deleted.
+
+Write a new digest that replaces all of them. Keep the knowledge that will still matter in future runs: facts about the environment and codebase, decisions and their reasons, recurring problems and how they were solved, and the user's preferences. Drop one-off details, and prefer recent information where entries conflict.
+
+Use short headings and concise bullet points, and stay under 500 words. Output only the digest.`
+
 var gcCmd = &cobra.Command{
 	Use:   "gc",
 	Short: "Analyze and trim agent memory",
@@ -39,6 +49,7 @@ func init() {
 	gcCmd.Flags().Bool("dry-run", false, "Analyze and print suggestions without trimming the memory file")
 	gcCmd.Flags().Bool("all", false, "Run GC on all agents that have memory.enabled = true")
 	gcCmd.Flags().String("model", "", "Override the model used for pattern detection (provider/model-name format)")
+	gcCmd.Flags().Bool("consolidate", false, "Summarize trimmed entries into a digest entry instead of dropping them")
 	rootCmd.AddCommand(gcCmd)
 }
 
@@ -81,6 +92,12 @@ func runSingleAgentGC(cmd *cobra.Command, agentName string) error {
 		return &ExitError{Code: 2, Err: err}
 	}
 
+	consolidate, _ := cmd.Flags().GetBool("consolidate")
+	consolidate = consolidate || cfg.Memory.Consolidate
+	if consolidate && memory.IsMarkdown(memPath) {
+		return &ExitError{Code: 1, Err: fmt.Errorf("cannot consolidate legacy markdown memory %s: run 'axe memory migrate %s' first", memPath, agentName)}
+	}
+
 	// Step 4: Load all entries (Req 3.5)
 	entries, err := memory.LoadEntries(memPath, 0)
 	if err != nil {
@@ -106,29 +123,20 @@ func runSingleAgentGC(cmd *cobra.Command, agentName string) error {
 		modelStr = modelFlag
 	}
 
-	provName, modelName, err := parseModel(modelStr)
-	if err != nil {
+	if _, _, err := engine.ParseModel(modelStr); err != nil {
 		return &ExitError{Code: 1, Err: err}
 	}
 
-	// Step 7: Load global config and resolve API key (Req 3.8)
+	// Step 7: Load global config (Req 3.8)
 	globalCfg, err := config.Load()
 	if err != nil {
 		return &ExitError{Code: 2, Err: err}
 	}
 
-	apiKey := globalCfg.ResolveAPIKey(provName)
-	baseURL := globalCfg.ResolveBaseURL(provName)
-
-	if provider.Supported(provName) && provName != "ollama" && apiKey == "" {
-		envVar := config.APIKeyEnvVar(provName)
-		return &ExitError{Code: 3, Err: fmt.Errorf("API key for provider %q is not configured (set %s or add to config.toml)", provName, envVar)}
-	}
-
-	// Step 8: Create provider (Req 3.7)
-	prov, err := provider.New(provName, apiKey, baseURL)
+	// Step 8: Resolve the API key and create the provider (Req 3.7)
+	prov, modelName, err := engine.NewProvider(modelStr, cfg.Retry, globalCfg)
 	if err != nil {
-		return &ExitError{Code: 1, Err: err}
+		return runExitError(err)
 	}
 
 	// Step 9: Build and send LLM request (Req 3.7)
@@ -169,7 +177,10 @@ func runSingleAgentGC(cmd *cobra.Command, agentName string) error {
 		return nil
 	}
 
-	// Step 13: Trim entries (Req 3.12, 3.13)
+	// Step 13: Trim entries (Req 3.12, 3.13), or fold them into the digest
+	if consolidate {
+		return consolidateGC(cmd, memPath, count, trimTarget, prov, modelName, modelStr)
+	}
 	removed, err := memory.TrimEntries(memPath, trimTarget)
 	if err != nil {
 		fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
@@ -185,6 +196,49 @@ func runSingleAgentGC(cmd *cobra.Command, agentName string) error {
 	return nil
 }
 
+// consolidateGC replaces all but the last keepN entries of the memory file
+// at memPath, and its current digest, with a new digest written by the
+// model.
+func consolidateGC(cmd *cobra.Command, memPath string, count, keepN int, prov provider.Provider, modelName, modelStr string) error {
+	folded, err := memory.Consolidate(memPath, keepN, func(entries []memory.Entry) (memory.Entry, error) {
+		var log strings.Builder
+		for _, e := range entries {
+			log.WriteString(e.Markdown())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+		defer cancel()
+		resp, err := prov.Send(ctx, &provider.Request{
+			Model:       modelName,
+			System:      gcDigestPrompt,
+			Messages:    []provider.Message{{Role: "user", Content: log.String()}},
+			Temperature: 0.3,
+			MaxTokens:   4096,
+		})
+		if err != nil {
+			return memory.Entry{}, err
+		}
+		return memory.Entry{
+			Task:         "Memory digest",
+			Result:       strings.TrimSpace(resp.Content),
+			Model:        modelStr,
+			InputTokens:  resp.InputTokens,
+			OutputTokens: resp.OutputTokens,
+			Tags:         []string{memory.DigestTag},
+		}, nil
+	})
+	if err != nil {
+		return mapProviderError(err)
+	}
+
+	if folded == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No trimming needed: %d entries within limit (%d).\n", count, keepN)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Consolidated: %d entries folded into the digest, %d entries kept.\n", folded, keepN)
+	}
+	return nil
+}
+
 func runAllAgentsGC(cmd *cobra.Command) error {
 	// Step 1: List all agents (Req 5.1)
 	agents, err := agent.List()
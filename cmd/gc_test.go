@@ -12,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/jrswab/axe/internal/memory"
 )
 
 // resetGCCmd resets all gc command flags between tests.
@@ -20,6 +22,7 @@ func resetGCCmd(t *testing.T) {
 	gcCmd.Flags().Set("dry-run", "false")
 	gcCmd.Flags().Set("all", "false")
 	gcCmd.Flags().Set("model", "")
+	gcCmd.Flags().Set("consolidate", "false")
 }
 
 // --- Phase 2a: Argument Validation ---
@@ -321,7 +324,7 @@ enabled = true
 	}
 
 	// Verify system prompt contains the exact pattern detection prompt text
-	system, ok := reqBody["system"].(string)
+	system, ok := anthropicSystem(reqBody)
 	if !ok {
 		t.Fatal("expected 'system' field in request body")
 	}
@@ -347,9 +350,9 @@ enabled = true
 	if !ok {
 		t.Fatal("expected first message to be an object")
 	}
-	userContent, ok := firstMsg["content"].(string)
+	userContent, ok := anthropicText(firstMsg["content"])
 	if !ok {
-		t.Fatal("expected user message content to be a string")
+		t.Fatal("expected user message content to be text")
 	}
 	if !strings.Contains(userContent, "task1") || !strings.Contains(userContent, "task3") {
 		t.Errorf("expected memory content in user message, got: %s", userContent)
@@ -1027,3 +1030,95 @@ last_n = 3
 		t.Errorf("agent-e memory file should be unchanged after dry run")
 	}
 }
+
+func TestGC_Consolidate(t *testing.T) {
+	resetGCCmd(t)
+	var capturedBody string
+	var mu sync.Mutex
+	server := startGCMockServer(t, &capturedBody, &mu, "- the deploy script needs VPN")
+	defer server.Close()
+
+	tmpDir := setupGCTestAgent(t, "gc-digest", `name = "gc-digest"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[memory]
+enabled = true
+last_n = 3
+consolidate = true
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, "data"))
+
+	memPath := populateGCMemory(t, tmpDir, "gc-digest", 5)
+	if _, _, err := memory.Migrate(memPath); err != nil {
+		t.Fatal(err)
+	}
+	memPath = strings.TrimSuffix(memPath, ".md") + ".jsonl"
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"gc", "gc-digest"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Consolidated: 2 entries folded into the digest, 3 entries kept.") {
+		t.Errorf("unexpected stdout: %q", buf.String())
+	}
+
+	// The digest request carries the entries being folded, not the kept ones
+	mu.Lock()
+	body := capturedBody
+	mu.Unlock()
+	if !strings.Contains(body, "long-term memory") || !strings.Contains(body, "task2") || strings.Contains(body, "task3") {
+		t.Errorf("digest request = %s", body)
+	}
+
+	entries, err := memory.Load(memPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || !entries[0].IsDigest() || entries[0].Result != "- the deploy script needs VPN" || entries[1].Task != "task3" {
+		t.Fatalf("entries = %+v, want the digest followed by task3-task5", entries)
+	}
+
+	// A second run folds the old digest into the new one
+	memory.Append(memPath, memory.Entry{Task: "task6", Result: "result6"})
+	resetGCCmd(t)
+	rootCmd.SetArgs([]string{"gc", "gc-digest"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	mu.Lock()
+	body = capturedBody
+	mu.Unlock()
+	if !strings.Contains(body, "the deploy script needs VPN") || !strings.Contains(body, "task3") {
+		t.Errorf("second digest request = %s", body)
+	}
+	entries, _ = memory.Load(memPath)
+	if digests, rest := memory.Pinned(entries); len(digests) != 1 || len(rest) != 3 || rest[0].Task != "task4" {
+		t.Errorf("entries = %+v, want one digest followed by task4-task6", entries)
+	}
+}
+
+func TestGC_Consolidate_LegacyMarkdown(t *testing.T) {
+	resetGCCmd(t)
+	tmpDir := setupGCTestAgent(t, "gc-legacy", `name = "gc-legacy"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[memory]
+enabled = true
+last_n = 3
+`)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, "data"))
+	populateGCMemory(t, tmpDir, "gc-legacy", 5)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"gc", "gc-legacy", "--consolidate"})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "axe memory migrate gc-legacy") {
+		t.Errorf("err = %v, want a hint to migrate", err)
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/engine"
+	"github.com/jrswab/axe/internal/mcp"
+	"github.com/jrswab/axe/internal/tool"
+	"github.com/spf13/cobra"
+)
+
+// mcpToolNamePattern is the set of names MCP clients accept for tools.
+var mcpToolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol integration",
+	Long: `Subcommands for the Model Context Protocol (MCP). Agents use MCP servers
+through the [mcp_servers] tables of their TOML; 'axe mcp serve' works the
+other way round and offers axe agents to MCP clients.`,
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve agents as MCP tools over stdio",
+	Long: `Speak MCP on stdin and stdout and publish every agent as a tool. Each tool
+takes a task and optional context, like call_agent, and returns the agent's
+final response. Configure an editor or agent host to run 'axe mcp serve' as
+a stdio MCP server.
+
+Logs go to stderr; stdout carries only protocol messages.`,
+	Args: cobra.NoArgs,
+	RunE: runMCPServe,
+}
+
+func init() {
+	mcpServeCmd.Flags().Int("timeout", 0, "Timeout in seconds for each agent call (0 means no limit)")
+	mcpServeCmd.Flags().BoolP("verbose", "v", false, "Log agent calls to stderr")
+	mcpServeCmd.Flags().String("approval-policy", "", "Decide tool approvals from a TOML policy file; without one, calls that require approval are denied")
+	mcpCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCPServe(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetInt("timeout")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	approvalPolicy, _ := cmd.Flags().GetString("approval-policy")
+	stderr := cmd.ErrOrStderr()
+
+	// Stdin carries the protocol, so approvals can only come from a policy
+	// file; without one, calls that require approval are denied.
+	var approver tool.Approver
+	if approvalPolicy != "" {
+		policy, err := tool.LoadPolicy(approvalPolicy)
+		if err != nil {
+			return &ExitError{Code: 2, Err: err}
+		}
+		approver = policy
+	}
+
+	globalCfg, err := config.Load()
+	if err != nil {
+		return &ExitError{Code: 2, Err: err}
+	}
+	agents, err := agent.List()
+	if err != nil {
+		return &ExitError{Code: 2, Err: err}
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].Name < agents[j].Name
+	})
+
+	var tools []mcp.Tool
+	for _, a := range agents {
+		if !mcpToolNamePattern.MatchString(a.Name) {
+			fmt.Fprintf(stderr, "Warning: skipping agent %q: name is not a valid MCP tool name\n", a.Name)
+			continue
+		}
+		tools = append(tools, agentMCPTool(a))
+	}
+
+	server := &mcp.Server{
+		Info:  mcp.Implementation{Name: "axe", Version: Version},
+		Tools: tools,
+		Call: func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallResult, error) {
+			return callServedAgent(ctx, name, args, timeout, engine.RunSpec{
+				GlobalConfig: globalCfg,
+				Approver:     approver,
+				Verbose:      verbose,
+				Stderr:       stderr,
+			})
+		},
+	}
+
+	if verbose {
+		fmt.Fprintf(stderr, "Serving %d agent(s) over MCP stdio\n", len(tools))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Serve(ctx, cmd.InOrStdin(), cmd.OutOrStdout()); err != nil && ctx.Err() == nil {
+		return &ExitError{Code: 1, Err: err}
+	}
+	return nil
+}
+
+// agentMCPTool describes an agent as an MCP tool with the task and context
+// parameters of call_agent.
+func agentMCPTool(a agent.AgentConfig) mcp.Tool {
+	description := a.Description
+	if description == "" {
+		description = fmt.Sprintf("Run the axe agent %q.", a.Name)
+	}
+
+	schema, _ := json.Marshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"task": map[string]string{
+				"type":        "string",
+				"description": "What you need the agent to do",
+			},
+			"context": map[string]string{
+				"type":        "string",
+				"description": "Additional context to pass along",
+			},
+		},
+		"required": []string{"task"},
+	})
+
+	return mcp.Tool{Name: a.Name, Description: description, InputSchema: schema}
+}
+
+// callServedAgent runs the named agent for an MCP tool call as a top-level
+// run, with the task and context framed as for a call_agent sub-agent.
+func callServedAgent(ctx context.Context, name string, args json.RawMessage, timeout int, spec engine.RunSpec) (*mcp.CallResult, error) {
+	var params struct {
+		Task    string `json:"task"`
+		Context string `json:"context"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return &mcp.CallResult{Content: fmt.Sprintf("invalid arguments: %s", err), IsError: true}, nil
+		}
+	}
+
+	if params.Task == "" {
+		return &mcp.CallResult{Content: `"task" argument is required`, IsError: true}, nil
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	spec.Agent = name
+	spec.Message = engine.TaskMessage(params.Task, params.Context)
+	result, err := engine.Run(ctx, spec)
+	if err != nil {
+		return &mcp.CallResult{Content: fmt.Sprintf("Error: agent %q failed - %s", name, err), IsError: true}, nil
+	}
+	return &mcp.CallResult{Content: result.Response.Content}, nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrswab/axe/internal/mcp"
+)
+
+// resetMCPServeCmd resets the mcp serve flags and stdin between tests.
+func resetMCPServeCmd(t *testing.T) {
+	t.Helper()
+	mcpServeCmd.Flags().Set("timeout", "0")
+	mcpServeCmd.Flags().Set("verbose", "false")
+	t.Cleanup(func() { rootCmd.SetIn(os.Stdin) })
+}
+
+func TestMCPServe_ListsAndCallsAgents(t *testing.T) {
+	resetMCPServeCmd(t)
+	server := startMockAnthropicServer(t)
+	defer server.Close()
+
+	tmpDir := setupRunTestAgent(t, "reviewer", `name = "reviewer"
+description = "Reviews code changes"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "axe", "agents", "helper.toml"), []byte(`name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"editor","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"reviewer","arguments":{"task":"review the diff"}}}`,
+	}, "\n") + "\n"
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetIn(strings.NewReader(in))
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"mcp", "serve"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v (stderr: %s)", err, errBuf.String())
+	}
+
+	replies := map[string]mcp.Message{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var msg mcp.Message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("stdout must carry only protocol messages, got %q", line)
+		}
+		replies[string(msg.ID)] = msg
+	}
+
+	var list struct {
+		Tools []mcp.Tool `json:"tools"`
+	}
+	json.Unmarshal(replies["2"].Result, &list)
+	if len(list.Tools) != 2 || list.Tools[0].Name != "helper" || list.Tools[1].Name != "reviewer" {
+		t.Fatalf("tools/list = %s, want helper and reviewer", replies["2"].Result)
+	}
+	if list.Tools[1].Description != "Reviews code changes" {
+		t.Errorf("reviewer description = %q", list.Tools[1].Description)
+	}
+	if !strings.Contains(string(list.Tools[1].InputSchema), `"required":["task"]`) {
+		t.Errorf("reviewer schema = %s, want task required", list.Tools[1].InputSchema)
+	}
+
+	var result struct {
+		Content []mcp.ContentBlock `json:"content"`
+		IsError bool               `json:"isError"`
+	}
+	json.Unmarshal(replies["3"].Result, &result)
+	if result.IsError || mcp.RenderContent(result.Content) != "Hello from mock" {
+		t.Errorf("tools/call = %s, want agent response", replies["3"].Result)
+	}
+}
+
+func TestMCPServe_InvalidConfig(t *testing.T) {
+	resetMCPServeCmd(t)
+	tmpDir := setupRunTestAgent(t, "reviewer", `name = "reviewer"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "axe", "config.toml"), []byte("[providers\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetIn(strings.NewReader(""))
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"mcp", "serve"})
+
+	err := rootCmd.Execute()
+	exitErr, ok := err.(*ExitError)
+	if !ok || exitErr.Code != 2 {
+		t.Errorf("err = %v, want exit code 2", err)
+	}
+}
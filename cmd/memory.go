@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/memory"
+	"github.com/jrswab/axe/internal/resolve"
+	"github.com/spf13/cobra"
+)
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "Inspect and maintain agent memory",
+	Long: `Subcommands for agent memory. Each memory-enabled agent records its runs in
+$XDG_DATA_HOME/axe/memory/<agent>.jsonl (or memory.path in its TOML), one
+JSON entry per line.`,
+}
+
+var memoryShowCmd = &cobra.Command{
+	Use:   "show <agent>",
+	Short: "Print an agent's memory entries, oldest first",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lastN, _ := cmd.Flags().GetInt("last")
+		if lastN < 0 {
+			return &ExitError{Code: 1, Err: fmt.Errorf("--last must be non-negative")}
+		}
+
+		_, path, err := agentMemoryPath(args[0])
+		if err != nil {
+			return err
+		}
+		entries, err := memory.Load(path)
+		if err != nil {
+			return &ExitError{Code: 1, Err: err}
+		}
+		if lastN > 0 && lastN < len(entries) {
+			entries = entries[len(entries)-lastN:]
+		}
+		if len(entries) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "No memory entries for agent %q\n", args[0])
+			return nil
+		}
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		return printMemoryEntries(cmd.OutOrStdout(), entries, jsonOutput)
+	},
+}
+
+var memorySearchCmd = &cobra.Command{
+	Use:   "search <agent> <query>",
+	Short: "Print the memory entries that contain every word of a query",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.Join(args[1:], " ")
+
+		_, path, err := agentMemoryPath(args[0])
+		if err != nil {
+			return err
+		}
+		entries, err := memory.Load(path)
+		if err != nil {
+			return &ExitError{Code: 1, Err: err}
+		}
+		var matches []memory.Entry
+		for _, e := range entries {
+			if e.Matches(query) {
+				matches = append(matches, e)
+			}
+		}
+		if len(matches) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "No memory entries of agent %q match %q\n", args[0], query)
+			return nil
+		}
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		return printMemoryEntries(cmd.OutOrStdout(), matches, jsonOutput)
+	},
+}
+
+var memoryRmCmd = &cobra.Command{
+	Use:   "rm <agent> <timestamp>",
+	Short: "Remove the memory entry with the given timestamp",
+	Long: `Remove the memory entry written at the given time, as shown on its "## "
+line by 'axe memory show' (RFC 3339, e.g. 2026-02-27T03:15:00Z). When several
+entries share that time, as entries written in the same second do, pick one
+with --index: 1 for the first of them in 'axe memory show' order.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ts, err := time.Parse(time.RFC3339, args[1])
+		if err != nil {
+			return &ExitError{Code: 1, Err: fmt.Errorf("invalid timestamp %q: expected RFC 3339, e.g. 2026-02-27T03:15:00Z", args[1])}
+		}
+
+		_, path, err := agentMemoryPath(args[0])
+		if err != nil {
+			return err
+		}
+		index, _ := cmd.Flags().GetInt("index")
+		if index < 0 {
+			return &ExitError{Code: 1, Err: fmt.Errorf("--index must be 1 or more")}
+		}
+		removed, err := memory.Remove(path, ts, index)
+		if errors.Is(err, memory.ErrAmbiguousEntry) {
+			return &ExitError{Code: 1, Err: fmt.Errorf("%w; pick one with --index", err)}
+		}
+		if err != nil {
+			return &ExitError{Code: 1, Err: err}
+		}
+		if removed == 0 && index > 0 {
+			return &ExitError{Code: 1, Err: fmt.Errorf("agent %q has no memory entry %d at %s", args[0], index, args[1])}
+		}
+		if removed == 0 {
+			return &ExitError{Code: 1, Err: fmt.Errorf("agent %q has no memory entry at %s", args[0], args[1])}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %d memory entry(s) from %s\n", removed, path)
+		return nil
+	},
+}
+
+var memoryAddCmd = &cobra.Command{
+	Use:   "add <agent> [note...]",
+	Short: "Add a manual note to an agent's memory",
+	Long: `Add a note to an agent's memory, as if a run had recorded it. The note is
+the remaining arguments, or stdin when none are given. It is loaded into the
+agent's prompt like any other entry.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		note := strings.Join(args[1:], " ")
+		if note == "" {
+			if cmdIn := cmd.InOrStdin(); cmdIn != os.Stdin {
+				data, err := io.ReadAll(cmdIn)
+				if err != nil {
+					return &ExitError{Code: 1, Err: fmt.Errorf("failed to read stdin: %w", err)}
+				}
+				note = string(data)
+			} else {
+				stdin, err := resolve.Stdin()
+				if err != nil {
+					return &ExitError{Code: 1, Err: err}
+				}
+				note = stdin
+			}
+		}
+		note = strings.TrimSpace(note)
+		if note == "" {
+			return &ExitError{Code: 1, Err: fmt.Errorf("note is required (as arguments or on stdin)")}
+		}
+		task, _ := cmd.Flags().GetString("task")
+		tags, _ := cmd.Flags().GetStringArray("tag")
+
+		cfg, path, err := agentMemoryPath(args[0])
+		if err != nil {
+			return err
+		}
+		if err := memory.Append(path, memory.Entry{Task: task, Result: note, Tags: tags}); err != nil {
+			return &ExitError{Code: 1, Err: err}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Added note to %s\n", path)
+		if !cfg.Memory.Enabled {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Note: memory is disabled for agent %q; set memory.enabled = true to load it\n", args[0])
+		}
+		return nil
+	},
+}
+
+var memoryClearCmd = &cobra.Command{
+	Use:   "clear <agent>",
+	Short: "Remove all of an agent's memory entries",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, path, err := agentMemoryPath(args[0])
+		if err != nil {
+			return err
+		}
+		removed, err := memory.Clear(path)
+		if err != nil {
+			return &ExitError{Code: 1, Err: err}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Cleared %d memory entry(s) from %s\n", removed, path)
+		return nil
+	},
+}
+
+var memoryMigrateCmd = &cobra.Command{
+	Use:   "migrate [agent]",
+	Short: "Convert markdown memory files to JSONL",
+	Long: `Convert an agent's legacy markdown memory file (<agent>.md) to the JSONL
+format. The original file is kept as <agent>.md.bak. Agents whose memory.path
+names a .md file must have it updated to the new .jsonl path afterwards.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allFlag, _ := cmd.Flags().GetBool("all")
+		if allFlag && len(args) > 0 {
+			return &ExitError{Code: 1, Err: fmt.Errorf("cannot specify both --all and an agent name")}
+		}
+		if !allFlag && len(args) == 0 {
+			return &ExitError{Code: 1, Err: fmt.Errorf("agent name is required (or use --all)")}
+		}
+
+		if !allFlag {
+			cfg, err := agent.Load(args[0])
+			if err != nil {
+				return &ExitError{Code: 2, Err: err}
+			}
+			return migrateAgentMemory(cmd, args[0], cfg)
+		}
+
+		agents, err := agent.List()
+		if err != nil {
+			return &ExitError{Code: 2, Err: err}
+		}
+		failCount := 0
+		for i := range agents {
+			if err := migrateAgentMemory(cmd, agents[i].Name, &agents[i]); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: migrate failed for agent %q: %v\n", agents[i].Name, err)
+				failCount++
+			}
+		}
+		if failCount > 0 {
+			return &ExitError{Code: 1, Err: fmt.Errorf("migrate completed with errors: %d of %d agents failed", failCount, len(agents))}
+		}
+		return nil
+	},
+}
+
+// agentMemoryPath loads the named agent and returns it with the path of its
+// memory file.
+func agentMemoryPath(name string) (*agent.AgentConfig, string, error) {
+	cfg, err := agent.Load(name)
+	if err != nil {
+		return nil, "", &ExitError{Code: 2, Err: err}
+	}
+	path, err := memory.FilePath(name, cfg.Memory.Path)
+	if err != nil {
+		return nil, "", &ExitError{Code: 1, Err: err}
+	}
+	return cfg, path, nil
+}
+
+// printMemoryEntries writes entries to w as markdown with their full
+// results, or as JSON lines.
+func printMemoryEntries(w io.Writer, entries []memory.Entry, jsonOutput bool) error {
+	for _, e := range entries {
+		if !jsonOutput {
+			fmt.Fprint(w, e.Markdown())
+			continue
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal memory entry: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+	}
+	return nil
+}
+
+// migrateAgentMemory converts the markdown memory file of the named agent,
+// if it has one, and reports the outcome.
+func migrateAgentMemory(cmd *cobra.Command, name string, cfg *agent.AgentConfig) error {
+	path, err := memory.FilePath(name, cfg.Memory.Path)
+	if err != nil {
+		return &ExitError{Code: 1, Err: err}
+	}
+	if !memory.IsMarkdown(path) {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: nothing to migrate\n", name)
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: no memory file at %s\n", name, path)
+		return nil
+	}
+
+	target, n, err := memory.Migrate(path)
+	if err != nil {
+		return &ExitError{Code: 1, Err: err}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s: migrated %d entries to %s (original kept as %s.bak)\n", name, n, target, path)
+	if cfg.Memory.Path != "" && target != path {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Note: set memory.path = %q in the TOML of agent %q\n", target, name)
+	}
+	return nil
+}
+
+func init() {
+	memoryShowCmd.Flags().Int("last", 0, "Only print the last N entries (0 for all)")
+	memoryShowCmd.Flags().Bool("json", false, "Print entries as JSON lines")
+	memorySearchCmd.Flags().Bool("json", false, "Print matching entries as JSON lines")
+	memoryRmCmd.Flags().Int("index", 0, "Remove the Nth entry with the timestamp, counting from 1")
+	memoryAddCmd.Flags().String("task", "Manual note", "Task line recorded with the note")
+	memoryAddCmd.Flags().StringArray("tag", nil, "Tag the note (repeatable)")
+	memoryMigrateCmd.Flags().Bool("all", false, "Migrate the memory of every agent")
+
+	memoryCmd.AddCommand(memoryShowCmd)
+	memoryCmd.AddCommand(memorySearchCmd)
+	memoryCmd.AddCommand(memoryRmCmd)
+	memoryCmd.AddCommand(memoryAddCmd)
+	memoryCmd.AddCommand(memoryClearCmd)
+	memoryCmd.AddCommand(memoryMigrateCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrswab/axe/internal/memory"
+)
+
+// resetMemoryCmd resets all memory subcommand flags to their defaults between tests.
+func resetMemoryCmd(t *testing.T) {
+	t.Helper()
+	memoryShowCmd.Flags().Set("last", "0")
+	memoryShowCmd.Flags().Set("json", "false")
+	memorySearchCmd.Flags().Set("json", "false")
+	memoryRmCmd.Flags().Set("index", "0")
+	memoryAddCmd.Flags().Set("task", "Manual note")
+	memoryAddCmd.Flags().Lookup("tag").Value.(interface{ Replace([]string) error }).Replace(nil)
+	memoryMigrateCmd.Flags().Set("all", "false")
+}
+
+func runMemoryCmd(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	resetMemoryCmd(t)
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	rootCmd.SetOut(out)
+	rootCmd.SetErr(errOut)
+	rootCmd.SetIn(strings.NewReader(""))
+	t.Cleanup(func() { rootCmd.SetIn(os.Stdin) })
+	rootCmd.SetArgs(append([]string{"memory"}, args...))
+	err := rootCmd.Execute()
+	return out.String(), errOut.String(), err
+}
+
+const legacyMemory = "## 2026-01-01T00:00:00Z\n**Task:** t1\n**Result:** r1\n## Not an entry\n\n" +
+	"## 2026-01-02T00:00:00Z\n**Task:** t2\n**Result:** r2\n\n"
+
+func TestMemoryMigrate(t *testing.T) {
+	tmpDir := setupRunTestAgent(t, "scribe", `name = "scribe"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[memory]
+enabled = true
+`)
+	memDir := filepath.Join(tmpDir, "data", "axe", "memory")
+	os.MkdirAll(memDir, 0755)
+	os.WriteFile(filepath.Join(memDir, "scribe.md"), []byte(legacyMemory), 0644)
+
+	out, _, err := runMemoryCmd(t, "migrate", "scribe")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "scribe: migrated 2 entries to "+filepath.Join(memDir, "scribe.jsonl")) {
+		t.Errorf("output = %q", out)
+	}
+	entries, err := memory.Load(filepath.Join(memDir, "scribe.jsonl"))
+	if err != nil || len(entries) != 2 || entries[0].Result != "r1\n## Not an entry" {
+		t.Errorf("entries = %+v, %v", entries, err)
+	}
+
+	// Runs now use the JSONL file, and there is nothing left to migrate
+	if path, _ := memory.FilePath("scribe", ""); path != filepath.Join(memDir, "scribe.jsonl") {
+		t.Errorf("FilePath = %q, want the migrated file", path)
+	}
+	out, _, err = runMemoryCmd(t, "migrate", "scribe")
+	if err != nil || out != "scribe: nothing to migrate\n" {
+		t.Errorf("second migrate = %q, %v", out, err)
+	}
+}
+
+func TestMemoryMigrate_CustomPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	memPath := filepath.Join(tmpDir, "notes.md")
+	os.WriteFile(memPath, []byte(legacyMemory), 0644)
+	setupRunTestAgent(t, "scribe", `name = "scribe"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[memory]
+enabled = true
+path = "`+memPath+`"
+`)
+
+	_, errOut, err := runMemoryCmd(t, "migrate", "--all")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := filepath.Join(tmpDir, "notes.jsonl")
+	if !strings.Contains(errOut, `set memory.path = "`+want+`"`) {
+		t.Errorf("stderr = %q, want a note to update memory.path", errOut)
+	}
+	if _, err := os.Stat(memPath + ".bak"); err != nil {
+		t.Errorf("original not kept: %v", err)
+	}
+}
+
+func TestMemoryMigrate_CustomPathAnyExtension(t *testing.T) {
+	tmpDir := t.TempDir()
+	memPath := filepath.Join(tmpDir, "notes.txt")
+	os.WriteFile(memPath, []byte(legacyMemory), 0644)
+	setupRunTestAgent(t, "scribe", `name = "scribe"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[memory]
+enabled = true
+path = "`+memPath+`"
+`)
+
+	out, _, err := runMemoryCmd(t, "show", "scribe")
+	if err != nil || !strings.Contains(out, "**Task:** t2") {
+		t.Fatalf("show = %q, %v; want the legacy entries", out, err)
+	}
+	out, errOut, err := runMemoryCmd(t, "migrate", "scribe")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := filepath.Join(tmpDir, "notes.jsonl")
+	if !strings.Contains(out, "migrated 2 entries to "+want) || !strings.Contains(errOut, `set memory.path = "`+want+`"`) {
+		t.Errorf("output = %q, stderr = %q", out, errOut)
+	}
+	if entries, err := memory.Load(want); err != nil || len(entries) != 2 {
+		t.Errorf("entries = %+v, %v", entries, err)
+	}
+}
+
+func TestMemoryMigrate_Args(t *testing.T) {
+	setupRunTestAgent(t, "scribe", `name = "scribe"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	if _, _, err := runMemoryCmd(t, "migrate"); err == nil || !strings.Contains(err.Error(), "agent name is required") {
+		t.Errorf("err = %v, want missing agent", err)
+	}
+	if _, _, err := runMemoryCmd(t, "migrate", "scribe", "--all"); err == nil || !strings.Contains(err.Error(), "cannot specify both") {
+		t.Errorf("err = %v, want conflicting arguments", err)
+	}
+	out, _, err := runMemoryCmd(t, "migrate", "scribe")
+	if err != nil || !strings.Contains(out, "scribe: nothing to migrate") {
+		t.Errorf("migrate without memory = %q, %v", out, err)
+	}
+}
+
+// setupMemoryAgent creates a memory-enabled agent whose memory holds one
+// entry per task, a day apart starting on 2026-01-01.
+func setupMemoryAgent(t *testing.T, name string, tasks ...string) string {
+	t.Helper()
+	tmpDir := setupRunTestAgent(t, name, `name = "`+name+`"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[memory]
+enabled = true
+`)
+	path := filepath.Join(tmpDir, "data", "axe", "memory", name+".jsonl")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, task := range tasks {
+		e := memory.Entry{Timestamp: start.AddDate(0, 0, i), Task: task, Result: "result of " + task}
+		if err := memory.Append(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestMemoryShow(t *testing.T) {
+	setupMemoryAgent(t, "scribe", "fix login", "review docs", "fix signup")
+
+	out, _, err := runMemoryCmd(t, "show", "scribe", "--last", "2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "## 2026-01-02T00:00:00Z\n**Task:** review docs\n**Result:** result of review docs\n\n" +
+		"## 2026-01-03T00:00:00Z\n**Task:** fix signup\n**Result:** result of fix signup\n\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	out, _, err = runMemoryCmd(t, "show", "scribe", "--json")
+	if err != nil || strings.Count(out, "\n") != 3 || !strings.HasPrefix(out, `{"timestamp":"2026-01-01T00:00:00Z","task":"fix login"`) {
+		t.Errorf("json output = %q, %v", out, err)
+	}
+}
+
+func TestMemoryShow_Empty(t *testing.T) {
+	setupMemoryAgent(t, "scribe")
+	out, _, err := runMemoryCmd(t, "show", "scribe")
+	if err != nil || out != "No memory entries for agent \"scribe\"\n" {
+		t.Errorf("output = %q, %v", out, err)
+	}
+}
+
+func TestMemorySearch(t *testing.T) {
+	setupMemoryAgent(t, "scribe", "fix login", "review docs", "fix signup")
+
+	out, _, err := runMemoryCmd(t, "search", "scribe", "FIX", "result")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "fix login") || !strings.Contains(out, "fix signup") || strings.Contains(out, "review docs") {
+		t.Errorf("output = %q, want the two fix entries", out)
+	}
+
+	out, _, err = runMemoryCmd(t, "search", "scribe", "deploy")
+	if err != nil || !strings.Contains(out, `No memory entries of agent "scribe" match "deploy"`) {
+		t.Errorf("output = %q, %v", out, err)
+	}
+}
+
+func TestMemoryRm(t *testing.T) {
+	path := setupMemoryAgent(t, "scribe", "fix login", "review docs", "fix signup")
+
+	out, _, err := runMemoryCmd(t, "rm", "scribe", "2026-01-02T00:00:00Z")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Removed 1 memory entry(s)") {
+		t.Errorf("output = %q", out)
+	}
+	entries, _ := memory.Load(path)
+	if len(entries) != 2 || entries[0].Task != "fix login" || entries[1].Task != "fix signup" {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	if _, _, err := runMemoryCmd(t, "rm", "scribe", "2026-01-02T00:00:00Z"); err == nil || !strings.Contains(err.Error(), "no memory entry at") {
+		t.Errorf("err = %v, want no entry", err)
+	}
+	if _, _, err := runMemoryCmd(t, "rm", "scribe", "yesterday"); err == nil || !strings.Contains(err.Error(), "invalid timestamp") {
+		t.Errorf("err = %v, want invalid timestamp", err)
+	}
+}
+
+func TestMemoryRm_SharedTimestamp(t *testing.T) {
+	path := setupMemoryAgent(t, "scribe")
+	ts := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, task := range []string{"mine", "theirs"} {
+		if err := memory.Append(path, memory.Entry{Timestamp: ts, Task: task}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := runMemoryCmd(t, "rm", "scribe", "2026-01-01T00:00:00Z"); err == nil || !strings.Contains(err.Error(), "--index") {
+		t.Errorf("err = %v, want a hint to use --index", err)
+	}
+	if n, _ := memory.CountEntries(path); n != 2 {
+		t.Errorf("CountEntries = %d, want nothing removed", n)
+	}
+
+	if _, _, err := runMemoryCmd(t, "rm", "scribe", "2026-01-01T00:00:00Z", "--index", "2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, _ := memory.Load(path)
+	if len(entries) != 1 || entries[0].Task != "mine" {
+		t.Errorf("entries = %+v, want only the second removed", entries)
+	}
+	if _, _, err := runMemoryCmd(t, "rm", "scribe", "2026-01-01T00:00:00Z", "--index", "2"); err == nil || !strings.Contains(err.Error(), "no memory entry 2 at") {
+		t.Errorf("err = %v, want no entry", err)
+	}
+}
+
+func TestMemoryAdd(t *testing.T) {
+	path := setupMemoryAgent(t, "scribe")
+
+	_, _, err := runMemoryCmd(t, "add", "scribe", "staging", "is", "down", "--tag", "infra", "--tag", "ops")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, _ := memory.Load(path)
+	if len(entries) != 1 || entries[0].Task != "Manual note" || entries[0].Result != "staging is down" ||
+		strings.Join(entries[0].Tags, ",") != "infra,ops" {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	resetMemoryCmd(t)
+	rootCmd.SetIn(strings.NewReader("from stdin\n"))
+	memoryAddCmd.Flags().Set("task", "Deploy rules")
+	rootCmd.SetArgs([]string{"memory", "add", "scribe"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rootCmd.SetIn(os.Stdin)
+	entries, _ = memory.Load(path)
+	if len(entries) != 2 || entries[1].Task != "Deploy rules" || entries[1].Result != "from stdin" || len(entries[1].Tags) != 0 {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	if _, _, err := runMemoryCmd(t, "add", "scribe"); err == nil || !strings.Contains(err.Error(), "note is required") {
+		t.Errorf("err = %v, want missing note", err)
+	}
+}
+
+func TestMemoryAdd_MemoryDisabled(t *testing.T) {
+	setupRunTestAgent(t, "plain", `name = "plain"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	_, errOut, err := runMemoryCmd(t, "add", "plain", "a note")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(errOut, "memory is disabled") {
+		t.Errorf("stderr = %q, want a disabled-memory note", errOut)
+	}
+}
+
+func TestMemoryClear(t *testing.T) {
+	path := setupMemoryAgent(t, "scribe", "fix login", "review docs")
+	os.WriteFile(memory.IndexPath(path), []byte("{}"), 0644)
+
+	out, _, err := runMemoryCmd(t, "clear", "scribe")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Cleared 2 memory entry(s)") {
+		t.Errorf("output = %q", out)
+	}
+	if n, _ := memory.CountEntries(path); n != 0 {
+		t.Errorf("CountEntries = %d, want 0", n)
+	}
+	if _, err := os.Stat(memory.IndexPath(path)); !os.IsNotExist(err) {
+		t.Errorf("index not removed: %v", err)
+	}
+}
+
+func TestMemoryCommands_UnknownAgent(t *testing.T) {
+	setupRunTestAgent(t, "scribe", `name = "scribe"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	_, _, err := runMemoryCmd(t, "show", "nobody")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Errorf("err = %v, want exit code 2", err)
+	}
+}
@@ -7,25 +7,21 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/jrswab/axe/internal/agent"
 	"github.com/jrswab/axe/internal/config"
-	"github.com/jrswab/axe/internal/memory"
+	"github.com/jrswab/axe/internal/engine"
+	"github.com/jrswab/axe/internal/history"
+	"github.com/jrswab/axe/internal/mcp"
 	"github.com/jrswab/axe/internal/provider"
 	"github.com/jrswab/axe/internal/resolve"
-	"github.com/jrswab/axe/internal/tool"
-	"github.com/jrswab/axe/internal/xdg"
+	"github.com/jrswab/axe/internal/usage"
 	"github.com/spf13/cobra"
 )
 
-// defaultUserMessage is sent when no stdin content is piped.
-const defaultUserMessage = "Execute the task described in your instructions."
-
-// maxConversationTurns is the safety limit for the conversation loop.
-const maxConversationTurns = 50
-
 var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Run an agent",
@@ -44,30 +40,15 @@ func init() {
 	runCmd.Flags().Bool("dry-run", false, "Show resolved context without calling the LLM")
 	runCmd.Flags().BoolP("verbose", "v", false, "Print debug info to stderr")
 	runCmd.Flags().Bool("json", false, "Wrap output in JSON with metadata")
+	runCmd.Flags().Bool("stream", false, "Print response text to stdout as it is generated")
+	runCmd.Flags().Bool("approve", false, "Ask before running each tool call")
+	runCmd.Flags().String("approval-policy", "", "Decide tool approvals from a TOML policy file instead of asking")
 	rootCmd.AddCommand(runCmd)
-}
-
-// parseModel splits a "provider/model-name" string into provider and model parts.
-func parseModel(model string) (providerName, modelName string, err error) {
-	idx := strings.Index(model, "/")
-	if idx < 0 {
-		return "", "", fmt.Errorf("invalid model format %q: expected provider/model-name", model)
-	}
-
-	providerName = model[:idx]
-	modelName = model[idx+1:]
-
-	if providerName == "" {
-		return "", "", fmt.Errorf("invalid model format %q: empty provider", model)
-	}
-	if modelName == "" {
-		return "", "", fmt.Errorf("invalid model format %q: empty model name", model)
-	}
 
-	return providerName, modelName, nil
+	mcp.ClientInfo.Version = Version
 }
 
-func runAgent(cmd *cobra.Command, args []string) error {
+func runAgent(cmd *cobra.Command, args []string) (retErr error) {
 	agentName := args[0]
 
 	// Step 1: Load agent config
@@ -87,41 +68,13 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		cfg.Skill = flagSkill
 	}
 
-	// Step 4-5: Parse model and validate provider
-	provName, modelName, err := parseModel(cfg.Model)
-	if err != nil {
-		return &ExitError{Code: 1, Err: err}
-	}
-
-	// Step 5b: Load global config
+	// Step 4: Load global config
 	globalCfg, err := config.Load()
 	if err != nil {
 		return &ExitError{Code: 2, Err: err}
 	}
 
-	// Step 6: Resolve working directory
-	flagWorkdir, _ := cmd.Flags().GetString("workdir")
-	workdir := resolve.Workdir(flagWorkdir, cfg.Workdir)
-
-	// Step 7: Resolve file globs
-	files, err := resolve.Files(cfg.Files, workdir)
-	if err != nil {
-		return &ExitError{Code: 2, Err: err}
-	}
-
-	// Step 8: Load skill
-	configDir, err := xdg.GetConfigDir()
-	if err != nil {
-		return &ExitError{Code: 2, Err: err}
-	}
-
-	skillPath := cfg.Skill
-	skillContent, err := resolve.Skill(skillPath, configDir)
-	if err != nil {
-		return &ExitError{Code: 2, Err: err}
-	}
-
-	// Step 9: Read stdin
+	// Step 5: Read stdin
 	// If cmd.InOrStdin() was overridden (e.g. in tests), read from it directly.
 	// Otherwise, use resolve.Stdin() which checks if os.Stdin is piped.
 	var stdinContent string
@@ -138,286 +91,258 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Step 10: Build system prompt
-	systemPrompt := resolve.BuildSystemPrompt(cfg.SystemPrompt, skillContent, files)
-
-	// Step 10b: Memory — load entries into system prompt
-	var memoryEntries string
-	var memoryPath string
-	var memoryCount int
-	if cfg.Memory.Enabled {
-		var memErr error
-		memoryPath, memErr = memory.FilePath(agentName, cfg.Memory.Path)
-		if memErr != nil {
-			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to load memory for %q: %v\n", agentName, memErr)
-		} else {
-			memoryEntries, memErr = memory.LoadEntries(memoryPath, cfg.Memory.LastN)
-			if memErr != nil {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to load memory for %q: %v\n", agentName, memErr)
-			} else if memoryEntries != "" {
-				systemPrompt += "\n\n---\n\n## Memory\n\n" + memoryEntries
-			}
-
-			memoryCount, memErr = memory.CountEntries(memoryPath)
-			if memErr != nil {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to load memory for %q: %v\n", agentName, memErr)
-			} else if cfg.Memory.MaxEntries > 0 && memoryCount >= cfg.Memory.MaxEntries {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: agent %q memory has %d entries (max_entries: %d). Run 'axe gc %s' to trim.\n", agentName, memoryCount, cfg.Memory.MaxEntries, agentName)
-			}
-		}
-	}
-
 	// Flags
+	flagWorkdir, _ := cmd.Flags().GetString("workdir")
 	timeout, _ := cmd.Flags().GetInt("timeout")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	jsonOutput, _ := cmd.Flags().GetBool("json")
-
-	// Step 11: Dry-run mode
-	if dryRun {
-		return printDryRun(cmd, cfg, provName, modelName, workdir, timeout, systemPrompt, skillContent, files, stdinContent, memoryEntries)
+	stream, _ := cmd.Flags().GetBool("stream")
+	approve, _ := cmd.Flags().GetBool("approve")
+	approvalPolicy, _ := cmd.Flags().GetString("approval-policy")
+
+	// Step 6: Describe the run. The engine resolves the working directory,
+	// files, skill, memory and tools the same way for sub-agents.
+	tracker := usage.NewTracker()
+	run := history.New(agentName)
+	spec := engine.RunSpec{
+		Agent:        agentName,
+		Config:       cfg,
+		GlobalConfig: globalCfg,
+		Workdir:      flagWorkdir,
+		Verbose:      verbose,
+		Stderr:       cmd.ErrOrStderr(),
+		Usage:        tracker,
+		History:      run,
 	}
-
-	// Step 12-13: Resolve API key and validate
-	apiKey := globalCfg.ResolveAPIKey(provName)
-	baseURL := globalCfg.ResolveBaseURL(provName)
-
-	// Check for missing API key only for supported providers that require one.
-	// Unsupported providers fall through to provider.New() which returns a clear error.
-	if provider.Supported(provName) && provName != "ollama" && apiKey == "" {
-		envVar := config.APIKeyEnvVar(provName)
-		return &ExitError{Code: 3, Err: fmt.Errorf("API key for provider %q is not configured (set %s or add to config.toml)", provName, envVar)}
+	if strings.TrimSpace(stdinContent) != "" {
+		spec.Message = stdinContent
 	}
 
-	// Step 14: Create provider
-	prov, err := provider.New(provName, apiKey, baseURL)
+	// Tool calls that require approval (all of them with --approve) are
+	// decided by the policy file, or else asked about on the terminal
+	approver, closeApprover, err := newApprover(approvalPolicy)
 	if err != nil {
-		return &ExitError{Code: 1, Err: err}
-	}
-
-	// Step 15: Build user message
-	userMessage := defaultUserMessage
-	if strings.TrimSpace(stdinContent) != "" {
-		userMessage = stdinContent
+		return err
 	}
+	defer closeApprover()
+	spec.Approve = approve
+	spec.Approver = approver
 
-	// Step 16: Build request
-	req := &provider.Request{
-		Model:       modelName,
-		System:      systemPrompt,
-		Messages:    []provider.Message{{Role: "user", Content: userMessage}},
-		Temperature: cfg.Params.Temperature,
-		MaxTokens:   cfg.Params.MaxTokens,
+	// Step 7: Dry-run mode
+	if dryRun {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+		defer cancel()
+		p, err := engine.Prepare(ctx, spec)
+		if err != nil {
+			return runExitError(err)
+		}
+		return printDryRun(cmd, p, timeout, stdinContent)
 	}
 
-	// Step 16b: Inject tools if agent has sub_agents
-	// Depth starts at 0 for top-level invocation
-	depth := 0
-	effectiveMaxDepth := 3 // system default
-	if cfg.SubAgentsConf.MaxDepth > 0 && cfg.SubAgentsConf.MaxDepth <= 5 {
-		effectiveMaxDepth = cfg.SubAgentsConf.MaxDepth
-	}
-	if len(cfg.SubAgents) > 0 && depth < effectiveMaxDepth {
-		req.Tools = []provider.Tool{tool.CallAgentTool(cfg.SubAgents)}
+	// Streaming writes text deltas straight to stdout. With --json the response
+	// is still streamed from the provider but only the envelope is printed.
+	if stream {
+		spec.Stream = true
+		if !jsonOutput {
+			out := cmd.OutOrStdout()
+			spec.OnDelta = func(text string) { fmt.Fprint(out, text) }
+		}
 	}
 
-	// Verbose: pre-call info
-	if verbose {
-		skillDisplay := skillPath
-		if skillDisplay == "" {
-			skillDisplay = "(none)"
-		}
-		stdinDisplay := "no"
-		if strings.TrimSpace(stdinContent) != "" {
-			stdinDisplay = "yes"
-		}
-		fmt.Fprintf(cmd.ErrOrStderr(), "Model:    %s/%s\n", provName, modelName)
-		fmt.Fprintf(cmd.ErrOrStderr(), "Workdir:  %s\n", workdir)
-		fmt.Fprintf(cmd.ErrOrStderr(), "Skill:    %s\n", skillDisplay)
-		fmt.Fprintf(cmd.ErrOrStderr(), "Files:    %d file(s)\n", len(files))
-		fmt.Fprintf(cmd.ErrOrStderr(), "Stdin:    %s\n", stdinDisplay)
-		fmt.Fprintf(cmd.ErrOrStderr(), "Timeout:  %ds\n", timeout)
-		fmt.Fprintf(cmd.ErrOrStderr(), "Params:   temperature=%g, max_tokens=%d\n", cfg.Params.Temperature, cfg.Params.MaxTokens)
-		if cfg.Memory.Enabled {
-			if memoryCount > 0 {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Memory:   %d entries loaded from %s\n", memoryCount, memoryPath)
-			} else {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Memory:   0 entries (no memory file)\n")
-			}
+	// Verbose: pre-call info, printed once the tools and MCP servers are ready.
+	// The run is recorded in history from then on.
+	started := false
+	spec.OnStart = func(s engine.Start) {
+		started = true
+		if verbose {
+			printRunInfo(cmd.ErrOrStderr(), s, stdinContent, timeout)
 		}
 	}
 
-	// Step 17: Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	// Step 8: Run the agent (conversation loop when tools are present)
+	ctx, cancel := engine.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
+	result, err := engine.Run(ctx, spec)
 
-	// Step 18: Call provider (conversation loop when tools are present)
-	start := time.Now()
-
-	// Determine parallel execution setting.
-	// Default is true (per spec). Only false if explicitly set via TOML.
-	// Using *bool allows distinguishing "not set" (nil) from "set to false".
-	parallel := true
-	if cfg.SubAgentsConf.Parallel != nil {
-		parallel = *cfg.SubAgentsConf.Parallel
-	}
-
-	var resp *provider.Response
-	var totalInputTokens int
-	var totalOutputTokens int
-	var totalToolCalls int
-
-	if len(req.Tools) == 0 {
-		// Single-shot: no tools, no conversation loop (identical to M4)
-		resp, err = prov.Send(ctx, req)
-		if err != nil {
-			durationMs := time.Since(start).Milliseconds()
-			if verbose {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Duration: %dms\n", durationMs)
-			}
-			return mapProviderError(err)
+	// Step 9: Save the run to history with the conversation, sub-agent runs
+	// and exit code when runAgent returns
+	defer func() {
+		if !started {
+			return
 		}
-		totalInputTokens = resp.InputTokens
-		totalOutputTokens = resp.OutputTokens
-
-		if verbose {
-			durationMs := time.Since(start).Milliseconds()
-			fmt.Fprintf(cmd.ErrOrStderr(), "Duration: %dms\n", durationMs)
-			fmt.Fprintf(cmd.ErrOrStderr(), "Tokens:   %d input, %d output\n", resp.InputTokens, resp.OutputTokens)
-			fmt.Fprintf(cmd.ErrOrStderr(), "Stop:     %s\n", resp.StopReason)
+		run.Finish(cfg.Model, result.Request, result.Response, retErr)
+		if retErr != nil {
+			run.ExitCode = exitCodeFromError(retErr)
 		}
-	} else {
-		// Conversation loop: handle tool calls
-		for turn := 0; turn < maxConversationTurns; turn++ {
-			if verbose {
-				pendingToolCalls := 0
-				for _, m := range req.Messages {
-					if m.Role == "tool" {
-						pendingToolCalls += len(m.ToolResults)
-					}
-				}
-				fmt.Fprintf(cmd.ErrOrStderr(), "[turn %d] Sending request (%d messages, %d tool calls pending)\n", turn+1, len(req.Messages), pendingToolCalls)
-			}
-
-			resp, err = prov.Send(ctx, req)
-			if err != nil {
-				durationMs := time.Since(start).Milliseconds()
-				if verbose {
-					fmt.Fprintf(cmd.ErrOrStderr(), "Duration: %dms\n", durationMs)
-				}
-				return mapProviderError(err)
-			}
-
-			totalInputTokens += resp.InputTokens
-			totalOutputTokens += resp.OutputTokens
-
-			if verbose {
-				fmt.Fprintf(cmd.ErrOrStderr(), "[turn %d] Received response: %s (%d tool calls)\n", turn+1, resp.StopReason, len(resp.ToolCalls))
-			}
-
-			// No tool calls: conversation is done
-			if len(resp.ToolCalls) == 0 {
-				break
-			}
-
-			// Append assistant message with tool calls
-			assistantMsg := provider.Message{
-				Role:      "assistant",
-				Content:   resp.Content,
-				ToolCalls: resp.ToolCalls,
-			}
-			req.Messages = append(req.Messages, assistantMsg)
-
-			// Execute tool calls
-			results := executeToolCalls(ctx, resp.ToolCalls, cfg, globalCfg, depth, effectiveMaxDepth, parallel, verbose, cmd.ErrOrStderr())
-			totalToolCalls += len(resp.ToolCalls)
-
-			// Append tool result message
-			toolMsg := provider.Message{
-				Role:        "tool",
-				ToolResults: results,
-			}
-			req.Messages = append(req.Messages, toolMsg)
+		if err := history.Save(run); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to save run history: %v\n", err)
+		} else if verbose {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Run:      %s\n", run.ID)
 		}
+	}()
 
-		// Check if we exhausted turns
-		if resp != nil && len(resp.ToolCalls) > 0 {
-			return &ExitError{Code: 1, Err: fmt.Errorf("agent exceeded maximum conversation turns (%d)", maxConversationTurns)}
+	if err != nil {
+		if verbose && started {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Duration: %dms\n", result.Duration.Milliseconds())
 		}
+		return runExitError(err)
+	}
+	resp := result.Response
+	total := tracker.Total()
+	truncations := run.AllTruncations()
+	if truncations == nil {
+		truncations = []history.Truncation{}
+	}
 
-		if verbose {
-			durationMs := time.Since(start).Milliseconds()
-			fmt.Fprintf(cmd.ErrOrStderr(), "Duration: %dms\n", durationMs)
-			fmt.Fprintf(cmd.ErrOrStderr(), "Tokens:   %d input, %d output (cumulative)\n", totalInputTokens, totalOutputTokens)
-			fmt.Fprintf(cmd.ErrOrStderr(), "Stop:     %s\n", resp.StopReason)
+	if verbose {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Duration: %dms\n", result.Duration.Milliseconds())
+		if len(result.Request.Tools) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Tokens:   %d input, %d output (cumulative)\n", total.InputTokens, total.OutputTokens)
+		} else {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Tokens:   %d input, %d output\n", total.InputTokens, total.OutputTokens)
+		}
+		printCacheTokens(cmd.ErrOrStderr(), total.CacheCreationTokens, total.CacheReadTokens)
+		printCost(cmd.ErrOrStderr(), tracker.Cost(globalCfg))
+		fmt.Fprintf(cmd.ErrOrStderr(), "Stop:     %s\n", resp.StopReason)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Answered: %s\n", resp.ModelRef)
+		if len(truncations) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Trimmed:  %d tool results over their size limit\n", len(truncations))
 		}
 	}
 
-	durationMs := time.Since(start).Milliseconds()
-
-	// Step 19: JSON output
+	// Step 10: JSON output
 	if jsonOutput {
 		envelope := map[string]interface{}{
 			"model":         resp.Model,
+			"model_ref":     resp.ModelRef,
 			"content":       resp.Content,
-			"input_tokens":  totalInputTokens,
-			"output_tokens": totalOutputTokens,
+			"input_tokens":  total.InputTokens,
+			"output_tokens": total.OutputTokens,
 			"stop_reason":   resp.StopReason,
-			"duration_ms":   durationMs,
-			"tool_calls":    totalToolCalls,
+			"duration_ms":   result.Duration.Milliseconds(),
+			"tool_calls":    result.ToolCalls,
+
+			"cache_creation_tokens": total.CacheCreationTokens,
+			"cache_read_tokens":     total.CacheReadTokens,
+			"usage": map[string]interface{}{
+				"by_agent": tracker.ByAgent(),
+				"by_model": tracker.ByModel(),
+			},
+			"cost_usd":          tracker.Cost(globalCfg),
+			"truncated_results": truncations,
 		}
 		data, err := json.Marshal(envelope)
 		if err != nil {
 			return &ExitError{Code: 1, Err: fmt.Errorf("failed to marshal JSON output: %w", err)}
 		}
 		fmt.Fprintln(cmd.OutOrStdout(), string(data))
-	} else {
-		// Step 20: Default output
+	} else if !stream {
+		// Step 11: Default output (already written incrementally when streaming)
 		fmt.Fprint(cmd.OutOrStdout(), resp.Content)
 	}
 
-	// Step 21: Append memory entry after successful response
+	return nil
+}
+
+// printRunInfo writes the verbose summary of a run that is about to start.
+func printRunInfo(w io.Writer, s engine.Start, stdinContent string, timeout int) {
+	cfg := s.Config
+	skillDisplay := cfg.Skill
+	if skillDisplay == "" {
+		skillDisplay = "(none)"
+	}
+	stdinDisplay := "no"
+	if strings.TrimSpace(stdinContent) != "" {
+		stdinDisplay = "yes"
+	}
+	fmt.Fprintf(w, "Model:    %s/%s\n", s.Provider, s.Model)
+	if len(cfg.FallbackModels) > 0 {
+		fmt.Fprintf(w, "Fallback: %s\n", strings.Join(cfg.FallbackModels, ", "))
+	}
+	fmt.Fprintf(w, "Workdir:  %s\n", s.Workdir)
+	fmt.Fprintf(w, "Skill:    %s\n", skillDisplay)
+	fmt.Fprintf(w, "Files:    %d file(s)\n", len(s.Files))
+	if len(cfg.Tools) > 0 {
+		fmt.Fprintf(w, "Tools:    %s\n", strings.Join(cfg.Tools, ", "))
+	}
+	if len(cfg.MCPServers) > 0 {
+		fmt.Fprintf(w, "MCP:      %s (%d tools)\n", strings.Join(mcpServerNames(cfg.MCPServers), ", "), len(s.MCPTools))
+	}
+	fmt.Fprintf(w, "Stdin:    %s\n", stdinDisplay)
+	fmt.Fprintf(w, "Timeout:  %ds\n", timeout)
+	fmt.Fprintf(w, "Params:   temperature=%g, max_tokens=%d\n", cfg.Params.Temperature, cfg.Params.MaxTokens)
 	if cfg.Memory.Enabled {
-		appendPath, appendErr := memory.FilePath(agentName, cfg.Memory.Path)
-		if appendErr != nil {
-			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to save memory for %q: %v\n", agentName, appendErr)
+		if s.MemoryCount > 0 && s.MemoryRanking != "" {
+			fmt.Fprintf(w, "Memory:   %d of %d entries loaded from %s (relevant, ranked by %s)\n", s.MemorySelected, s.MemoryCount, s.MemoryPath, s.MemoryRanking)
+		} else if s.MemoryCount > 0 {
+			fmt.Fprintf(w, "Memory:   %d entries loaded from %s\n", s.MemoryCount, s.MemoryPath)
 		} else {
-			if appendErr = memory.AppendEntry(appendPath, userMessage, resp.Content); appendErr != nil {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to save memory for %q: %v\n", agentName, appendErr)
+			fmt.Fprintf(w, "Memory:   0 entries (no memory file)\n")
+		}
+		for _, pool := range s.SharedMemory {
+			if pool.Count > 0 && s.MemoryRanking != "" {
+				fmt.Fprintf(w, "Shared:   %s: %d of %d entries loaded from %s\n", pool.Name, pool.Selected, pool.Count, pool.Path)
+			} else {
+				fmt.Fprintf(w, "Shared:   %s: %d entries loaded from %s\n", pool.Name, pool.Count, pool.Path)
 			}
 		}
 	}
+}
 
-	return nil
+// printCacheTokens writes the verbose prompt cache line. Nothing is printed
+// when the provider reported no cache activity.
+func printCacheTokens(w io.Writer, creation, read int) {
+	if creation == 0 && read == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Cache:    %d written, %d read\n", creation, read)
 }
 
-func printDryRun(cmd *cobra.Command, cfg *agent.AgentConfig, provName, modelName, workdir string, timeout int, systemPrompt, skillContent string, files []resolve.FileContent, stdinContent string, memoryEntries string) error {
+// printCost writes the verbose cost line, noting any models without a price.
+func printCost(w io.Writer, cost usage.Cost) {
+	fmt.Fprintf(w, "Cost:     $%.6f", cost.Total)
+	if len(cost.Unpriced) > 0 {
+		fmt.Fprintf(w, " (no pricing for %s)", strings.Join(cost.Unpriced, ", "))
+	}
+	fmt.Fprintln(w)
+}
+
+func printDryRun(cmd *cobra.Command, p *engine.Prepared, timeout int, stdinContent string) error {
 	out := cmd.OutOrStdout()
+	cfg := p.Config
 
 	fmt.Fprintln(out, "=== Dry Run ===")
 	fmt.Fprintln(out)
-	fmt.Fprintf(out, "Model:    %s/%s\n", provName, modelName)
-	fmt.Fprintf(out, "Workdir:  %s\n", workdir)
+	fmt.Fprintf(out, "Model:    %s/%s\n", p.Provider, p.Model)
+	if len(cfg.FallbackModels) > 0 {
+		fmt.Fprintf(out, "Fallback: %s\n", strings.Join(cfg.FallbackModels, ", "))
+	}
+	fmt.Fprintf(out, "Workdir:  %s\n", p.Workdir)
 	fmt.Fprintf(out, "Timeout:  %ds\n", timeout)
 	fmt.Fprintf(out, "Params:   temperature=%g, max_tokens=%d\n", cfg.Params.Temperature, cfg.Params.MaxTokens)
 
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "--- System Prompt ---")
-	fmt.Fprintln(out, systemPrompt)
+	fmt.Fprintln(out, p.SystemPrompt)
 
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "--- Skill ---")
-	if skillContent != "" {
-		fmt.Fprintln(out, skillContent)
+	if p.Skill != "" {
+		fmt.Fprintln(out, p.Skill)
 	} else {
 		fmt.Fprintln(out, "(none)")
 	}
 
 	fmt.Fprintln(out)
-	fmt.Fprintf(out, "--- Files (%d) ---\n", len(files))
-	if len(files) > 0 {
-		for _, f := range files {
-			fmt.Fprintln(out, f.Path)
+	fmt.Fprintf(out, "--- Files (%d) ---\n", len(p.Files))
+	if len(p.Files) > 0 {
+		for _, f := range p.Files {
+			if f.IsAttachment() {
+				fmt.Fprintf(out, "%s (attachment: %s, %d bytes)\n", f.Path, f.MediaType, len(f.Data))
+			} else {
+				fmt.Fprintln(out, f.Path)
+			}
 		}
 	} else {
 		fmt.Fprintln(out, "(none)")
@@ -434,27 +359,44 @@ func printDryRun(cmd *cobra.Command, cfg *agent.AgentConfig, provName, modelName
 	if cfg.Memory.Enabled {
 		fmt.Fprintln(out)
 		fmt.Fprintln(out, "--- Memory ---")
-		if memoryEntries != "" {
-			fmt.Fprintln(out, memoryEntries)
+		if p.Memory != "" {
+			fmt.Fprintln(out, p.Memory)
 		} else {
 			fmt.Fprintln(out, "(none)")
 		}
+		for _, pool := range p.SharedMemory {
+			fmt.Fprintln(out)
+			fmt.Fprintf(out, "--- Shared Memory: %s ---\n", pool.Name)
+			if pool.Memory != "" {
+				fmt.Fprintln(out, pool.Memory)
+			} else {
+				fmt.Fprintln(out, "(none)")
+			}
+		}
+	}
+
+	if len(cfg.Tools) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "--- Tools ---")
+		fmt.Fprintln(out, strings.Join(cfg.Tools, ", "))
+	}
+
+	if len(cfg.MCPServers) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "--- MCP Servers ---")
+		fmt.Fprintln(out, strings.Join(mcpServerNames(cfg.MCPServers), ", "))
 	}
 
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "--- Sub-Agents ---")
 	if len(cfg.SubAgents) > 0 {
 		fmt.Fprintln(out, strings.Join(cfg.SubAgents, ", "))
-		effectiveMaxDepth := 3
-		if cfg.SubAgentsConf.MaxDepth > 0 && cfg.SubAgentsConf.MaxDepth <= 5 {
-			effectiveMaxDepth = cfg.SubAgentsConf.MaxDepth
-		}
 		parallelVal := "yes"
 		if cfg.SubAgentsConf.Parallel != nil && !*cfg.SubAgentsConf.Parallel {
 			parallelVal = "no"
 		}
 		timeoutVal := cfg.SubAgentsConf.Timeout
-		fmt.Fprintf(out, "Max Depth: %d\n", effectiveMaxDepth)
+		fmt.Fprintf(out, "Max Depth: %d\n", p.MaxDepth)
 		fmt.Fprintf(out, "Parallel:  %s\n", parallelVal)
 		fmt.Fprintf(out, "Timeout:   %ds\n", timeoutVal)
 	} else {
@@ -464,64 +406,42 @@ func printDryRun(cmd *cobra.Command, cfg *agent.AgentConfig, provName, modelName
 	return nil
 }
 
-// executeToolCalls dispatches tool calls and returns results.
-// When parallel is true and there are multiple calls, they run concurrently.
-func executeToolCalls(ctx context.Context, toolCalls []provider.ToolCall, cfg *agent.AgentConfig, globalCfg *config.GlobalConfig, depth, maxDepth int, parallel, verbose bool, stderr io.Writer) []provider.ToolResult {
-	results := make([]provider.ToolResult, len(toolCalls))
-
-	execOpts := tool.ExecuteOptions{
-		AllowedAgents: cfg.SubAgents,
-		ParentModel:   cfg.Model,
-		Depth:         depth,
-		MaxDepth:      maxDepth,
-		Timeout:       cfg.SubAgentsConf.Timeout,
-		GlobalConfig:  globalCfg,
-		Verbose:       verbose,
-		Stderr:        stderr,
-	}
-
-	if len(toolCalls) == 1 || !parallel {
-		// Sequential execution (also used for single call)
-		for i, tc := range toolCalls {
-			if tc.Name == tool.CallAgentToolName {
-				results[i] = tool.ExecuteCallAgent(ctx, tc, execOpts)
-			} else {
-				results[i] = provider.ToolResult{
-					CallID:  tc.ID,
-					Content: fmt.Sprintf("Unknown tool: %q", tc.Name),
-					IsError: true,
-				}
-			}
-		}
-	} else {
-		// Parallel execution
-		type indexedResult struct {
-			index  int
-			result provider.ToolResult
-		}
-		ch := make(chan indexedResult, len(toolCalls))
-		for i, tc := range toolCalls {
-			go func(idx int, call provider.ToolCall) {
-				var res provider.ToolResult
-				if call.Name == tool.CallAgentToolName {
-					res = tool.ExecuteCallAgent(ctx, call, execOpts)
-				} else {
-					res = provider.ToolResult{
-						CallID:  call.ID,
-						Content: fmt.Sprintf("Unknown tool: %q", call.Name),
-						IsError: true,
-					}
-				}
-				ch <- indexedResult{index: idx, result: res}
-			}(i, tc)
-		}
-		for range toolCalls {
-			ir := <-ch
-			results[ir.index] = ir.result
+// mcpServerNames returns the names of the agent's MCP servers, sorted.
+func mcpServerNames(servers map[string]agent.MCPServerConfig) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runExitError converts an error from engine.Run or engine.Prepare to an
+// ExitError with the correct exit code.
+func runExitError(err error) error {
+	var runErr *engine.RunError
+	if errors.As(err, &runErr) {
+		switch runErr.Category {
+		case engine.ErrCategoryConfig:
+			return &ExitError{Code: 2, Err: runErr}
+		case engine.ErrCategoryAPI:
+			return &ExitError{Code: 3, Err: runErr}
 		}
+		return &ExitError{Code: 1, Err: runErr}
 	}
+	return mapProviderError(err)
+}
 
-	return results
+// approvalTools returns the names of the tools that require approval, sorted.
+func approvalTools(tools map[string]agent.ToolConfig) []string {
+	var names []string
+	for name, tc := range tools {
+		if tc.RequireApproval {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
 // mapProviderError converts a provider error to an ExitError with the correct exit code.
@@ -5,14 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/jrswab/axe/internal/history"
+	"github.com/jrswab/axe/internal/memory"
 )
 
 // resetRunCmd resets all run command flags and stdin to their defaults between tests.
@@ -25,14 +30,18 @@ func resetRunCmd(t *testing.T) {
 	runCmd.Flags().Set("dry-run", "false")
 	runCmd.Flags().Set("verbose", "false")
 	runCmd.Flags().Set("json", "false")
+	runCmd.Flags().Set("stream", "false")
+	runCmd.Flags().Set("approve", "false")
+	runCmd.Flags().Set("approval-policy", "")
 	rootCmd.SetIn(os.Stdin)
 }
 
-// helper: create a temp XDG config dir with an agent TOML file.
+// helper: create temp XDG config and data dirs with an agent TOML file.
 func setupRunTestAgent(t *testing.T, name, toml string) string {
 	t.Helper()
 	tmpDir := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, "data"))
 	agentsDir := filepath.Join(tmpDir, "axe", "agents")
 	if err := os.MkdirAll(agentsDir, 0755); err != nil {
 		t.Fatalf("failed to create agents dir: %v", err)
@@ -43,6 +52,35 @@ func setupRunTestAgent(t *testing.T, name, toml string) string {
 	return tmpDir
 }
 
+// anthropicSystem returns the system prompt text from a decoded Anthropic
+// request body, where it is sent as an array of text blocks.
+func anthropicSystem(body map[string]interface{}) (string, bool) {
+	if _, ok := body["system"].([]interface{}); !ok {
+		return "", false
+	}
+	return anthropicText(body["system"])
+}
+
+// anthropicText returns the text of a decoded Anthropic message content,
+// which is a string or, for a message marked for caching, an array of
+// blocks.
+func anthropicText(content interface{}) (string, bool) {
+	if s, ok := content.(string); ok {
+		return s, true
+	}
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return "", false
+	}
+	var text string
+	for _, b := range blocks {
+		block, _ := b.(map[string]interface{})
+		s, _ := block["text"].(string)
+		text += s
+	}
+	return text, true
+}
+
 // helper: start a mock Anthropic API server returning a successful response.
 func startMockAnthropicServer(t *testing.T) *httptest.Server {
 	t.Helper()
@@ -461,6 +499,76 @@ files = ["*.txt"]
 	}
 }
 
+func TestRun_ImageFilesSentAsAttachments(t *testing.T) {
+	resetRunCmd(t)
+
+	var receivedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "text", "text": "a chart"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	setupRunTestAgent(t, "image-agent", `name = "image-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+files = ["*"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	workdir := t.TempDir()
+	os.WriteFile(filepath.Join(workdir, "chart.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644)
+	os.WriteFile(filepath.Join(workdir, "notes.txt"), []byte("quarterly numbers"), 0644)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "image-agent", "--workdir", workdir})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if system, _ := anthropicSystem(receivedBody); !strings.Contains(system, "quarterly numbers") || strings.Contains(system, "chart.png") {
+		t.Errorf("expected only the text file in the system prompt, got %q", system)
+	}
+
+	msg := receivedBody["messages"].([]interface{})[0].(map[string]interface{})
+	blocks, ok := msg["content"].([]interface{})
+	if !ok || len(blocks) != 2 {
+		t.Fatalf("expected text and image blocks, got %v", msg["content"])
+	}
+	if image := blocks[1].(map[string]interface{}); image["type"] != "image" {
+		t.Errorf("blocks[1] = %v, want image block", image)
+	}
+}
+
+func TestRun_DryRunListsAttachments(t *testing.T) {
+	resetRunCmd(t)
+	setupRunTestAgent(t, "pdf-agent", `name = "pdf-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+files = ["*.pdf"]
+`)
+
+	workdir := t.TempDir()
+	os.WriteFile(filepath.Join(workdir, "spec.pdf"), []byte("%PDF-1.7\nbody"), 0644)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "pdf-agent", "--dry-run", "--workdir", workdir})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "spec.pdf (attachment: application/pdf, 13 bytes)"; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q in dry-run output, got %q", want, buf.String())
+	}
+}
+
 // --- Phase 11f: JSON Output Mode ---
 
 func TestRun_JSONOutput(t *testing.T) {
@@ -539,6 +647,47 @@ model = "anthropic/claude-sonnet-4-20250514"
 	}
 }
 
+func TestRun_CacheTokensReported(t *testing.T) {
+	resetRunCmd(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"content": [{"type": "text", "text": "cached"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 10, "output_tokens": 5, "cache_creation_input_tokens": 1200, "cache_read_input_tokens": 800}
+		}`))
+	}))
+	defer server.Close()
+
+	setupRunTestAgent(t, "cache-agent", `name = "cache-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "cache-agent", "--json", "--verbose"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\noutput: %q", err, buf.String())
+	}
+	if result["cache_creation_tokens"] != float64(1200) || result["cache_read_tokens"] != float64(800) {
+		t.Errorf("cache tokens = %v created, %v read; want 1200, 800", result["cache_creation_tokens"], result["cache_read_tokens"])
+	}
+
+	if !strings.Contains(errBuf.String(), "Cache:    1200 written, 800 read") {
+		t.Errorf("verbose stderr missing cache line\nfull stderr:\n%s", errBuf.String())
+	}
+}
+
 // --- Phase 11h: Error Exit Code Mapping ---
 
 func TestRun_TimeoutExceeded(t *testing.T) {
@@ -720,6 +869,85 @@ model = "openai/gpt-4o"
 	}
 }
 
+func TestRun_GeminiProviderSuccess(t *testing.T) {
+	resetRunCmd(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "Hello from Gemini mock"}]}, "finishReason": "STOP"}], "usageMetadata": {"promptTokenCount": 4, "candidatesTokenCount": 4}}`))
+	}))
+	defer server.Close()
+
+	setupRunTestAgent(t, "gemini-agent", `name = "gemini-agent"
+model = "gemini/gemini-2.0-flash"
+`)
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	t.Setenv("AXE_GEMINI_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "gemini-agent"})
+
+	err := rootCmd.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Hello from Gemini mock") {
+		t.Errorf("expected 'Hello from Gemini mock', got %q", buf.String())
+	}
+}
+
+func TestRun_OpenAICompatibleProvider(t *testing.T) {
+	resetRunCmd(t)
+	var gotAuth, gotTitle, gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotTitle = r.Header.Get("X-Title")
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotModel, _ = body["model"].(string)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"llama-3-70b","choices":[{"message":{"content":"Hello from Groq mock"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	tmpDir := setupRunTestAgent(t, "groq-agent", `name = "groq-agent"
+model = "groq/llama-3-70b"
+`)
+	os.WriteFile(filepath.Join(tmpDir, "axe", "config.toml"), []byte(`
+[providers.groq]
+type = "openai-compatible"
+base_url = "`+server.URL+`/v1"
+
+[providers.groq.headers]
+X-Title = "axe"
+`), 0644)
+	t.Setenv("GROQ_API_KEY", "gsk-test")
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "groq-agent"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "Hello from Groq mock" {
+		t.Errorf("stdout = %q, want %q", buf.String(), "Hello from Groq mock")
+	}
+	if gotAuth != "Bearer gsk-test" {
+		t.Errorf("Authorization = %q, want key from GROQ_API_KEY", gotAuth)
+	}
+	if gotTitle != "axe" {
+		t.Errorf("X-Title = %q, want axe", gotTitle)
+	}
+	if gotModel != "llama-3-70b" {
+		t.Errorf("model = %q, want llama-3-70b", gotModel)
+	}
+}
+
 func TestRun_OllamaNoAPIKeyRequired(t *testing.T) {
 	resetRunCmd(t)
 	server := startMockOllamaServer(t)
@@ -1498,22 +1726,18 @@ model = "anthropic/claude-sonnet-4-20250514"
 	}
 }
 
-func TestRun_Verbose_ConversationTurns(t *testing.T) {
+func TestRun_JSON_CostIncludesSubAgents(t *testing.T) {
 	resetRunCmd(t)
 
 	callCount := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		body := new(bytes.Buffer)
-		body.ReadFrom(r.Body)
-		bodyStr := body.String()
+		body, _ := io.ReadAll(r.Body)
 		w.Header().Set("Content-Type", "application/json")
 
-		if strings.Contains(bodyStr, "Task: verbose task") {
+		if strings.Contains(string(body), "Task: price this") {
 			w.Write([]byte(`{
-				"id": "msg_h", "type": "message", "role": "assistant",
-				"content": [{"type": "text", "text": "verbose sub-result"}],
-				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
-				"usage": {"input_tokens": 5, "output_tokens": 3}
+				"content": [{"type": "text", "text": "sub-result"}], "stop_reason": "end_turn",
+				"usage": {"input_tokens": 1000000, "output_tokens": 0}
 			}`))
 			return
 		}
@@ -1521,172 +1745,844 @@ func TestRun_Verbose_ConversationTurns(t *testing.T) {
 		callCount++
 		if callCount == 1 {
 			w.Write([]byte(`{
-				"id": "msg_1", "type": "message", "role": "assistant",
-				"content": [
-					{"type": "tool_use", "id": "toolu_v", "name": "call_agent", "input": {"agent": "verbose-helper", "task": "verbose task"}}
-				],
-				"model": "claude-sonnet-4-20250514", "stop_reason": "tool_use",
-				"usage": {"input_tokens": 10, "output_tokens": 15}
+				"content": [{"type": "tool_use", "id": "toolu_1", "name": "call_agent", "input": {"agent": "cost-helper", "task": "price this"}}],
+				"stop_reason": "tool_use",
+				"usage": {"input_tokens": 100000, "output_tokens": 10000}
 			}`))
 		} else {
 			w.Write([]byte(`{
-				"id": "msg_2", "type": "message", "role": "assistant",
-				"content": [{"type": "text", "text": "Verbose final."}],
-				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
-				"usage": {"input_tokens": 20, "output_tokens": 10}
+				"content": [{"type": "text", "text": "done"}], "stop_reason": "end_turn",
+				"usage": {"input_tokens": 100000, "output_tokens": 0}
 			}`))
 		}
 	}))
 	defer server.Close()
 
-	tmpDir := setupRunTestAgent(t, "verbose-parent", `name = "verbose-parent"
+	tmpDir := setupRunTestAgent(t, "cost-parent", `name = "cost-parent"
 model = "anthropic/claude-sonnet-4-20250514"
-sub_agents = ["verbose-helper"]
+sub_agents = ["cost-helper"]
 `)
-	agentsDir := filepath.Join(tmpDir, "axe", "agents")
-	os.WriteFile(filepath.Join(agentsDir, "verbose-helper.toml"), []byte(`name = "verbose-helper"
-model = "anthropic/claude-sonnet-4-20250514"
+	os.WriteFile(filepath.Join(tmpDir, "axe", "agents", "cost-helper.toml"), []byte(`name = "cost-helper"
+model = "anthropic/claude-3-5-haiku-20241022"
 `), 0644)
 
 	t.Setenv("ANTHROPIC_API_KEY", "test-key")
 	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
 
 	buf := new(bytes.Buffer)
-	errBuf := new(bytes.Buffer)
 	rootCmd.SetOut(buf)
-	rootCmd.SetErr(errBuf)
-	rootCmd.SetArgs([]string{"run", "verbose-parent", "--verbose"})
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "cost-parent", "--json"})
 
-	err := rootCmd.Execute()
-	if err != nil {
+	if err := rootCmd.Execute(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	stderr := errBuf.String()
-	// Should have turn-by-turn logs
-	if !strings.Contains(stderr, "[turn 1]") {
-		t.Errorf("expected '[turn 1]' in stderr, got %q", stderr)
+	var result struct {
+		InputTokens int `json:"input_tokens"`
+		Usage       struct {
+			ByAgent map[string]struct {
+				InputTokens int `json:"input_tokens"`
+			} `json:"by_agent"`
+		} `json:"usage"`
+		CostUSD struct {
+			Total   float64            `json:"total"`
+			ByAgent map[string]float64 `json:"by_agent"`
+			ByModel map[string]float64 `json:"by_model"`
+		} `json:"cost_usd"`
 	}
-	if !strings.Contains(stderr, "[turn 2]") {
-		t.Errorf("expected '[turn 2]' in stderr, got %q", stderr)
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\noutput: %q", err, buf.String())
 	}
-	if !strings.Contains(stderr, "Sending request") {
-		t.Errorf("expected 'Sending request' in stderr, got %q", stderr)
+
+	// Sub-agent tokens are added to the run totals
+	if result.InputTokens != 1200000 {
+		t.Errorf("input_tokens = %d, want 1200000", result.InputTokens)
 	}
-	if !strings.Contains(stderr, "Received response") {
-		t.Errorf("expected 'Received response' in stderr, got %q", stderr)
+	if result.Usage.ByAgent["cost-helper"].InputTokens != 1000000 {
+		t.Errorf("usage.by_agent = %+v, want cost-helper with 1000000 input tokens", result.Usage.ByAgent)
 	}
-}
 
-// --- Review: Additional coverage tests ---
+	// Parent: 200k input at $3/M + 10k output at $15/M; helper: 1M input at $0.80/M
+	if result.CostUSD.Total != 1.55 {
+		t.Errorf("cost_usd.total = %v, want 1.55", result.CostUSD.Total)
+	}
+	if result.CostUSD.ByAgent["cost-parent"] != 0.75 || result.CostUSD.ByAgent["cost-helper"] != 0.8 {
+		t.Errorf("cost_usd.by_agent = %v", result.CostUSD.ByAgent)
+	}
+	if result.CostUSD.ByModel["anthropic/claude-3-5-haiku-20241022"] != 0.8 {
+		t.Errorf("cost_usd.by_model = %v", result.CostUSD.ByModel)
+	}
+}
 
-func TestRun_ParallelDefault_MultipleToolCalls(t *testing.T) {
-	// Verify that when [sub_agents_config] is absent (Parallel is nil),
-	// multiple tool calls still execute (parallel is the default).
+func TestRun_JSONReportsTruncatedResults(t *testing.T) {
 	resetRunCmd(t)
 
-	var mu sync.Mutex
-	subAgentCalls := make(map[string]bool)
 	callCount := 0
-
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		body := new(bytes.Buffer)
-		body.ReadFrom(r.Body)
-		bodyStr := body.String()
+		body, _ := io.ReadAll(r.Body)
 		w.Header().Set("Content-Type", "application/json")
 
-		if strings.Contains(bodyStr, "Task: default-task-a") {
-			mu.Lock()
-			subAgentCalls["a"] = true
-			mu.Unlock()
-			w.Write([]byte(`{
-				"id": "msg_a", "type": "message", "role": "assistant",
-				"content": [{"type": "text", "text": "result a"}],
-				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
-				"usage": {"input_tokens": 5, "output_tokens": 3}
-			}`))
-			return
-		}
-		if strings.Contains(bodyStr, "Task: default-task-b") {
-			mu.Lock()
-			subAgentCalls["b"] = true
-			mu.Unlock()
-			w.Write([]byte(`{
-				"id": "msg_b", "type": "message", "role": "assistant",
-				"content": [{"type": "text", "text": "result b"}],
-				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
-				"usage": {"input_tokens": 5, "output_tokens": 3}
-			}`))
+		if strings.Contains(string(body), "Task: explain") {
+			fmt.Fprintf(w, `{"content": [{"type": "text", "text": %q}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`, strings.Repeat("chatty ", 200))
 			return
 		}
 
-		mu.Lock()
 		callCount++
-		cc := callCount
-		mu.Unlock()
-
-		if cc == 1 {
-			w.Write([]byte(`{
-				"id": "msg_p1", "type": "message", "role": "assistant",
-				"content": [
-					{"type": "tool_use", "id": "t_a", "name": "call_agent", "input": {"agent": "def-a", "task": "default-task-a"}},
-					{"type": "tool_use", "id": "t_b", "name": "call_agent", "input": {"agent": "def-b", "task": "default-task-b"}}
-				],
-				"model": "claude-sonnet-4-20250514", "stop_reason": "tool_use",
-				"usage": {"input_tokens": 10, "output_tokens": 20}
-			}`))
-		} else {
+		if callCount == 1 {
 			w.Write([]byte(`{
-				"id": "msg_p2", "type": "message", "role": "assistant",
-				"content": [{"type": "text", "text": "Default parallel done."}],
-				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
-				"usage": {"input_tokens": 20, "output_tokens": 10}
+				"content": [{"type": "tool_use", "id": "toolu_t1", "name": "call_agent", "input": {"agent": "chatty-helper", "task": "explain"}}],
+				"stop_reason": "tool_use",
+				"usage": {"input_tokens": 1, "output_tokens": 1}
 			}`))
+			return
 		}
+		w.Write([]byte(`{"content": [{"type": "text", "text": "done"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
 	}))
 	defer server.Close()
 
-	// No [sub_agents_config] section - Parallel should default to true
-	tmpDir := setupRunTestAgent(t, "def-parent", `name = "def-parent"
+	tmpDir := setupRunTestAgent(t, "trunc-parent", `name = "trunc-parent"
 model = "anthropic/claude-sonnet-4-20250514"
-sub_agents = ["def-a", "def-b"]
+sub_agents = ["chatty-helper"]
 `)
-	agentsDir := filepath.Join(tmpDir, "axe", "agents")
-	os.WriteFile(filepath.Join(agentsDir, "def-a.toml"), []byte(`name = "def-a"
-model = "anthropic/claude-sonnet-4-20250514"
-`), 0644)
-	os.WriteFile(filepath.Join(agentsDir, "def-b.toml"), []byte(`name = "def-b"
+	os.WriteFile(filepath.Join(tmpDir, "axe", "agents", "chatty-helper.toml"), []byte(`name = "chatty-helper"
 model = "anthropic/claude-sonnet-4-20250514"
 `), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "axe", "config.toml"), []byte(`[tool_results.tools.call_agent]
+max_bytes = 100
+`), 0600)
 
 	t.Setenv("ANTHROPIC_API_KEY", "test-key")
 	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
 
 	buf := new(bytes.Buffer)
-	errBuf := new(bytes.Buffer)
 	rootCmd.SetOut(buf)
-	rootCmd.SetErr(errBuf)
-	rootCmd.SetArgs([]string{"run", "def-parent"})
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "trunc-parent", "--json"})
 
-	err := rootCmd.Execute()
-	if err != nil {
+	if err := rootCmd.Execute(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	if !subAgentCalls["a"] {
-		t.Error("expected sub-agent 'a' to be called with default parallel=true")
+	var result struct {
+		TruncatedResults []history.Truncation `json:"truncated_results"`
 	}
-	if !subAgentCalls["b"] {
-		t.Error("expected sub-agent 'b' to be called with default parallel=true")
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\noutput: %q", err, buf.String())
+	}
+	want := history.Truncation{Agent: "trunc-parent", CallID: "toolu_t1", Tool: "call_agent", Strategy: "truncate", OriginalBytes: 1400}
+	if len(result.TruncatedResults) != 1 {
+		t.Fatalf("truncated_results = %+v, want one", result.TruncatedResults)
+	}
+	got := result.TruncatedResults[0]
+	if got.Bytes > 100 {
+		t.Errorf("truncated to %d bytes, want at most 100", got.Bytes)
+	}
+	got.Bytes = 0
+	if got != want {
+		t.Errorf("truncated_results[0] = %+v, want %+v", got, want)
 	}
 }
 
-// --- Phase M6-4a: Memory Load into System Prompt ---
+func TestRun_RecordsHistoryWithSubAgents(t *testing.T) {
+	resetRunCmd(t)
 
-// startMockAnthropicServerCapture starts a mock Anthropic server that captures
-// the request body and returns a configurable response.
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(string(body), "Task: look it up") {
+			w.Write([]byte(`{
+				"content": [{"type": "text", "text": "found it"}], "stop_reason": "end_turn",
+				"usage": {"input_tokens": 5, "output_tokens": 3}
+			}`))
+			return
+		}
+
+		callCount++
+		if callCount == 1 {
+			w.Write([]byte(`{
+				"content": [{"type": "tool_use", "id": "toolu_h1", "name": "call_agent", "input": {"agent": "hist-helper", "task": "look it up"}}],
+				"stop_reason": "tool_use",
+				"usage": {"input_tokens": 10, "output_tokens": 15}
+			}`))
+		} else {
+			w.Write([]byte(`{
+				"content": [{"type": "text", "text": "all done"}], "stop_reason": "end_turn",
+				"usage": {"input_tokens": 20, "output_tokens": 10}
+			}`))
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := setupRunTestAgent(t, "hist-parent", `name = "hist-parent"
+model = "anthropic/claude-sonnet-4-20250514"
+sub_agents = ["hist-helper"]
+`)
+	os.WriteFile(filepath.Join(tmpDir, "axe", "agents", "hist-helper.toml"), []byte(`name = "hist-helper"
+model = "anthropic/claude-sonnet-4-20250514"
+`), 0644)
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetIn(strings.NewReader("find the thing"))
+	rootCmd.SetArgs([]string{"run", "hist-parent"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runs, _, err := history.List()
+	if err != nil {
+		t.Fatalf("history.List: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("recorded %d runs, want 1", len(runs))
+	}
+	run := runs[0]
+
+	if run.Agent != "hist-parent" || run.ExitCode != 0 || run.Output != "all done" {
+		t.Errorf("run = agent %q, exit %d, output %q", run.Agent, run.ExitCode, run.Output)
+	}
+	// user, assistant tool call, tool result, final assistant
+	if len(run.Messages) != 4 {
+		t.Fatalf("recorded %d messages, want 4: %+v", len(run.Messages), run.Messages)
+	}
+	if run.Messages[0].Content != "find the thing" {
+		t.Errorf("first message = %q, want the user request", run.Messages[0].Content)
+	}
+	if tc := run.Messages[1].ToolCalls; len(tc) != 1 || tc[0].Name != "call_agent" {
+		t.Errorf("tool calls = %+v", tc)
+	}
+	if tr := run.Messages[2].ToolResults; len(tr) != 1 || tr[0].Content != "found it" {
+		t.Errorf("tool results = %+v", tr)
+	}
+	if run.Usage.InputTokens != 30 {
+		t.Errorf("parent input tokens = %d, want 30", run.Usage.InputTokens)
+	}
+
+	if len(run.SubAgents) != 1 {
+		t.Fatalf("recorded %d sub-agent runs, want 1", len(run.SubAgents))
+	}
+	sub := run.SubAgents[0]
+	if sub.Agent != "hist-helper" || sub.CallID != "toolu_h1" || sub.Output != "found it" {
+		t.Errorf("sub-agent run = agent %q, call %q, output %q", sub.Agent, sub.CallID, sub.Output)
+	}
+	if sub.Usage.InputTokens != 5 || run.TotalUsage().InputTokens != 35 {
+		t.Errorf("sub-agent input tokens = %d, total = %d; want 5 and 35", sub.Usage.InputTokens, run.TotalUsage().InputTokens)
+	}
+}
+
+func TestRun_RecordsFailedRunHistory(t *testing.T) {
+	resetRunCmd(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(500)
+		w.Write([]byte(`{"type": "error", "error": {"type": "server_error", "message": "Internal server error"}}`))
+	}))
+	defer server.Close()
+
+	setupRunTestAgent(t, "hist-fail", `name = "hist-fail"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "hist-fail"})
+
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	runs, _, err := history.List()
+	if err != nil {
+		t.Fatalf("history.List: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("recorded %d runs, want 1", len(runs))
+	}
+	if runs[0].ExitCode != 3 || runs[0].Error == "" {
+		t.Errorf("exit code = %d, error = %q; want 3 and the provider error", runs[0].ExitCode, runs[0].Error)
+	}
+}
+
+func TestRun_DryRunNotRecorded(t *testing.T) {
+	resetRunCmd(t)
+	setupRunTestAgent(t, "hist-dry", `name = "hist-dry"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "hist-dry", "--dry-run"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runs, _, _ := history.List(); len(runs) != 0 {
+		t.Errorf("dry run recorded %d runs, want 0", len(runs))
+	}
+}
+
+func TestRun_BuiltinReadFileTool(t *testing.T) {
+	resetRunCmd(t)
+
+	workdir := t.TempDir()
+	os.WriteFile(filepath.Join(workdir, "notes.txt"), []byte("the secret word is axe"), 0644)
+
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.Header().Set("Content-Type", "application/json")
+
+		if len(requests) == 1 {
+			w.Write([]byte(`{
+				"content": [{"type": "tool_use", "id": "toolu_r1", "name": "read_file", "input": {"path": "notes.txt"}}],
+				"stop_reason": "tool_use", "usage": {"input_tokens": 10, "output_tokens": 5}
+			}`))
+			return
+		}
+		w.Write([]byte(`{
+			"content": [{"type": "text", "text": "axe"}], "stop_reason": "end_turn",
+			"usage": {"input_tokens": 20, "output_tokens": 1}
+		}`))
+	}))
+	defer server.Close()
+
+	setupRunTestAgent(t, "fs-agent", `name = "fs-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["read_file", "list_dir"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "fs-agent", "--workdir", workdir})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "axe" {
+		t.Errorf("stdout = %q, want %q", buf.String(), "axe")
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	tools, _ := requests[0]["tools"].([]interface{})
+	var names []string
+	for _, tl := range tools {
+		names = append(names, tl.(map[string]interface{})["name"].(string))
+	}
+	if strings.Join(names, ",") != "read_file,list_dir" {
+		t.Errorf("tools sent = %v, want [read_file list_dir]", names)
+	}
+
+	// The second request carries the file content as the tool result
+	msgs := requests[1]["messages"].([]interface{})
+	last, _ := json.Marshal(msgs[len(msgs)-1])
+	if !strings.Contains(string(last), "the secret word is axe") {
+		t.Errorf("tool result message = %s, want file content", last)
+	}
+}
+
+func TestRun_BuiltinRunCommandTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	resetRunCmd(t)
+
+	var toolResult string
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+		if callCount == 1 {
+			w.Write([]byte(`{
+				"content": [{"type": "tool_use", "id": "toolu_c1", "name": "run_command", "input": {"command": "sh", "args": ["-c", "echo tests passed"]}}],
+				"stop_reason": "tool_use", "usage": {"input_tokens": 10, "output_tokens": 5}
+			}`))
+			return
+		}
+		toolResult = string(body)
+		w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+	}))
+	defer server.Close()
+
+	setupRunTestAgent(t, "cmd-agent", `name = "cmd-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["run_command"]
+
+[run_command]
+allow = ["sh"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "cmd-agent", "--workdir", t.TempDir()})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(toolResult, `exit code: 0`) || !strings.Contains(toolResult, `tests passed`) {
+		t.Errorf("tool result not sent back to the model: %s", toolResult)
+	}
+}
+
+// fakeTTY is a terminal whose answers are fixed up front.
+type fakeTTY struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (f *fakeTTY) Read(p []byte) (int, error) { return f.Reader.Read(p) }
+func (f *fakeTTY) Close() error               { return nil }
+
+func TestRun_ToolApproval(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		tty      string // answers typed on the terminal; empty means no terminal
+		policy   string
+		wantSent string
+	}{
+		{"approve on terminal", []string{"--approve"}, "y\n", "", "Wrote 1 bytes to out.txt"},
+		{"deny on terminal", []string{"--approve"}, "n\nwrong file\n", "", "Tool call denied: wrong file"},
+		{"no terminal fails closed", []string{"--approve"}, "", "", "there is no terminal to ask"},
+		{"policy allows", []string{"--approve"}, "", "[[rules]]\ntool = \"write_file\"\ndecision = \"allow\"\n", "Wrote 1 bytes to out.txt"},
+		{"policy denies", []string{"--approve"}, "", "[[rules]]\ntool = \"*\"\ndecision = \"deny\"\nreason = \"read only\"\n", "Tool call denied: read only"},
+		{"not required", nil, "", "", "Wrote 1 bytes to out.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRunCmd(t)
+
+			var toolResult string
+			callCount := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				callCount++
+				w.Header().Set("Content-Type", "application/json")
+				if callCount == 1 {
+					w.Write([]byte(`{
+						"content": [{"type": "tool_use", "id": "toolu_w1", "name": "write_file", "input": {"path": "out.txt", "content": "x"}}],
+						"stop_reason": "tool_use", "usage": {"input_tokens": 10, "output_tokens": 5}
+					}`))
+					return
+				}
+				toolResult = string(body)
+				w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+			}))
+			defer server.Close()
+
+			tmpDir := setupRunTestAgent(t, "writer", `name = "writer"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["write_file"]
+`)
+			t.Setenv("ANTHROPIC_API_KEY", "test-key")
+			t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+			tty := &fakeTTY{Reader: strings.NewReader(tt.tty)}
+			origOpenTTY := openTTY
+			openTTY = func() (io.ReadWriteCloser, error) {
+				if tt.tty == "" {
+					return nil, errors.New("no terminal")
+				}
+				return tty, nil
+			}
+			t.Cleanup(func() { openTTY = origOpenTTY })
+
+			args := append([]string{"run", "writer", "--workdir", t.TempDir()}, tt.args...)
+			if tt.policy != "" {
+				policyPath := filepath.Join(tmpDir, "policy.toml")
+				os.WriteFile(policyPath, []byte(tt.policy), 0644)
+				args = append(args, "--approval-policy", policyPath)
+			}
+			rootCmd.SetOut(new(bytes.Buffer))
+			rootCmd.SetErr(new(bytes.Buffer))
+			rootCmd.SetArgs(args)
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(toolResult, tt.wantSent) {
+				t.Errorf("tool result sent to the model = %s, want %q", toolResult, tt.wantSent)
+			}
+			if tt.tty != "" && !strings.Contains(tty.String(), "wants to call write_file") {
+				t.Errorf("terminal output = %q, want the approval question", tty.String())
+			}
+		})
+	}
+}
+
+func TestRun_PluginTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	resetRunCmd(t)
+
+	var firstReq, toolResult string
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+		if callCount == 1 {
+			firstReq = string(body)
+			w.Write([]byte(`{
+				"content": [{"type": "tool_use", "id": "toolu_p1", "name": "shout", "input": {"text": "hello"}}],
+				"stop_reason": "tool_use", "usage": {"input_tokens": 10, "output_tokens": 5}
+			}`))
+			return
+		}
+		toolResult = string(body)
+		w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+	}))
+	defer server.Close()
+
+	tmpDir := setupRunTestAgent(t, "plugin-agent", `name = "plugin-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["shout"]
+`)
+	toolsDir := filepath.Join(tmpDir, "axe", "tools")
+	os.MkdirAll(toolsDir, 0755)
+	os.WriteFile(filepath.Join(toolsDir, "shout.toml"), []byte(`description = "Upper-case text"
+command = "shout.sh"
+`), 0644)
+	os.WriteFile(filepath.Join(toolsDir, "shout.sh"), []byte("#!/bin/sh\ntr a-z A-Z\n"), 0755)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "plugin-agent", "--workdir", t.TempDir()})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(firstReq, `"name":"shout"`) || !strings.Contains(firstReq, "Upper-case text") {
+		t.Errorf("plugin definition not sent to the model: %s", firstReq)
+	}
+	if !strings.Contains(toolResult, `\"TEXT\": \"HELLO\"`) {
+		t.Errorf("plugin output not sent back to the model: %s", toolResult)
+	}
+}
+
+func TestRun_MCPServerTool(t *testing.T) {
+	resetRunCmd(t)
+
+	mcpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		if len(msg.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		results := map[string]string{
+			"initialize": `{"protocolVersion": "2025-06-18", "capabilities": {}, "serverInfo": {"name": "docs", "version": "1"}}`,
+			"tools/list": `{"tools": [{"name": "search_docs", "description": "Search the docs", "inputSchema": {"type": "object", "properties": {"query": {"type": "string"}}}}]}`,
+			"tools/call": `{"content": [{"type": "text", "text": "found 3 pages"}]}`,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, "result": %s}`, msg.ID, results[msg.Method])
+	}))
+	defer mcpServer.Close()
+
+	var firstReq, toolResult string
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callCount++
+		w.Header().Set("Content-Type", "application/json")
+		if callCount == 1 {
+			firstReq = string(body)
+			w.Write([]byte(`{
+				"content": [{"type": "tool_use", "id": "toolu_m1", "name": "search_docs", "input": {"query": "mcp"}}],
+				"stop_reason": "tool_use", "usage": {"input_tokens": 10, "output_tokens": 5}
+			}`))
+			return
+		}
+		toolResult = string(body)
+		w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+	}))
+	defer server.Close()
+
+	tmpDir := setupRunTestAgent(t, "mcp-agent", `name = "mcp-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[mcp_servers.docs]
+`)
+	// The agent's empty table picks up the server from config.toml
+	os.WriteFile(filepath.Join(tmpDir, "axe", "config.toml"), []byte(fmt.Sprintf("[mcp_servers.docs]\nurl = %q\n", mcpServer.URL)), 0644)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "mcp-agent"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(firstReq, `"name":"search_docs"`) {
+		t.Errorf("MCP tool not offered to the model: %s", firstReq)
+	}
+	if !strings.Contains(toolResult, "found 3 pages") {
+		t.Errorf("MCP tool result not sent back to the model: %s", toolResult)
+	}
+}
+
+func TestRun_MCPServerUnavailable(t *testing.T) {
+	resetRunCmd(t)
+	setupRunTestAgent(t, "mcp-down-agent", `name = "mcp-down-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[mcp_servers.local]
+command = "axe-no-such-mcp-server"
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "mcp-down-agent"})
+
+	err := rootCmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("err = %v, want exit code 3", err)
+	}
+	if !strings.Contains(err.Error(), `mcp server "local": failed to start`) {
+		t.Errorf("err = %q, want start failure", err.Error())
+	}
+}
+
+func TestRun_UnknownBuiltinTool(t *testing.T) {
+	resetRunCmd(t)
+	setupRunTestAgent(t, "badtool-agent", `name = "badtool-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["read_file", "teleport"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "badtool-agent"})
+
+	err := rootCmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Fatalf("expected exit code 2, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `unknown tool "teleport"`) {
+		t.Errorf("error = %q, want unknown tool", err.Error())
+	}
+}
+
+func TestRun_Verbose_ConversationTurns(t *testing.T) {
+	resetRunCmd(t)
+
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+		bodyStr := body.String()
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(bodyStr, "Task: verbose task") {
+			w.Write([]byte(`{
+				"id": "msg_h", "type": "message", "role": "assistant",
+				"content": [{"type": "text", "text": "verbose sub-result"}],
+				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
+				"usage": {"input_tokens": 5, "output_tokens": 3}
+			}`))
+			return
+		}
+
+		callCount++
+		if callCount == 1 {
+			w.Write([]byte(`{
+				"id": "msg_1", "type": "message", "role": "assistant",
+				"content": [
+					{"type": "tool_use", "id": "toolu_v", "name": "call_agent", "input": {"agent": "verbose-helper", "task": "verbose task"}}
+				],
+				"model": "claude-sonnet-4-20250514", "stop_reason": "tool_use",
+				"usage": {"input_tokens": 10, "output_tokens": 15}
+			}`))
+		} else {
+			w.Write([]byte(`{
+				"id": "msg_2", "type": "message", "role": "assistant",
+				"content": [{"type": "text", "text": "Verbose final."}],
+				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
+				"usage": {"input_tokens": 20, "output_tokens": 10}
+			}`))
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := setupRunTestAgent(t, "verbose-parent", `name = "verbose-parent"
+model = "anthropic/claude-sonnet-4-20250514"
+sub_agents = ["verbose-helper"]
+`)
+	agentsDir := filepath.Join(tmpDir, "axe", "agents")
+	os.WriteFile(filepath.Join(agentsDir, "verbose-helper.toml"), []byte(`name = "verbose-helper"
+model = "anthropic/claude-sonnet-4-20250514"
+`), 0644)
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "verbose-parent", "--verbose"})
+
+	err := rootCmd.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stderr := errBuf.String()
+	// Should have turn-by-turn logs
+	if !strings.Contains(stderr, "[turn 1]") {
+		t.Errorf("expected '[turn 1]' in stderr, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "[turn 2]") {
+		t.Errorf("expected '[turn 2]' in stderr, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "Sending request") {
+		t.Errorf("expected 'Sending request' in stderr, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "Received response") {
+		t.Errorf("expected 'Received response' in stderr, got %q", stderr)
+	}
+}
+
+// --- Review: Additional coverage tests ---
+
+func TestRun_ParallelDefault_MultipleToolCalls(t *testing.T) {
+	// Verify that when [sub_agents_config] is absent (Parallel is nil),
+	// multiple tool calls still execute (parallel is the default).
+	resetRunCmd(t)
+
+	var mu sync.Mutex
+	subAgentCalls := make(map[string]bool)
+	callCount := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := new(bytes.Buffer)
+		body.ReadFrom(r.Body)
+		bodyStr := body.String()
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(bodyStr, "Task: default-task-a") {
+			mu.Lock()
+			subAgentCalls["a"] = true
+			mu.Unlock()
+			w.Write([]byte(`{
+				"id": "msg_a", "type": "message", "role": "assistant",
+				"content": [{"type": "text", "text": "result a"}],
+				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
+				"usage": {"input_tokens": 5, "output_tokens": 3}
+			}`))
+			return
+		}
+		if strings.Contains(bodyStr, "Task: default-task-b") {
+			mu.Lock()
+			subAgentCalls["b"] = true
+			mu.Unlock()
+			w.Write([]byte(`{
+				"id": "msg_b", "type": "message", "role": "assistant",
+				"content": [{"type": "text", "text": "result b"}],
+				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
+				"usage": {"input_tokens": 5, "output_tokens": 3}
+			}`))
+			return
+		}
+
+		mu.Lock()
+		callCount++
+		cc := callCount
+		mu.Unlock()
+
+		if cc == 1 {
+			w.Write([]byte(`{
+				"id": "msg_p1", "type": "message", "role": "assistant",
+				"content": [
+					{"type": "tool_use", "id": "t_a", "name": "call_agent", "input": {"agent": "def-a", "task": "default-task-a"}},
+					{"type": "tool_use", "id": "t_b", "name": "call_agent", "input": {"agent": "def-b", "task": "default-task-b"}}
+				],
+				"model": "claude-sonnet-4-20250514", "stop_reason": "tool_use",
+				"usage": {"input_tokens": 10, "output_tokens": 20}
+			}`))
+		} else {
+			w.Write([]byte(`{
+				"id": "msg_p2", "type": "message", "role": "assistant",
+				"content": [{"type": "text", "text": "Default parallel done."}],
+				"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
+				"usage": {"input_tokens": 20, "output_tokens": 10}
+			}`))
+		}
+	}))
+	defer server.Close()
+
+	// No [sub_agents_config] section - Parallel should default to true
+	tmpDir := setupRunTestAgent(t, "def-parent", `name = "def-parent"
+model = "anthropic/claude-sonnet-4-20250514"
+sub_agents = ["def-a", "def-b"]
+`)
+	agentsDir := filepath.Join(tmpDir, "axe", "agents")
+	os.WriteFile(filepath.Join(agentsDir, "def-a.toml"), []byte(`name = "def-a"
+model = "anthropic/claude-sonnet-4-20250514"
+`), 0644)
+	os.WriteFile(filepath.Join(agentsDir, "def-b.toml"), []byte(`name = "def-b"
+model = "anthropic/claude-sonnet-4-20250514"
+`), 0644)
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "def-parent"})
+
+	err := rootCmd.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !subAgentCalls["a"] {
+		t.Error("expected sub-agent 'a' to be called with default parallel=true")
+	}
+	if !subAgentCalls["b"] {
+		t.Error("expected sub-agent 'b' to be called with default parallel=true")
+	}
+}
+
+// --- Phase M6-4a: Memory Load into System Prompt ---
+
+// startMockAnthropicServerCapture starts a mock Anthropic server that captures
+// the request body and returns a configurable response.
 func startMockAnthropicServerCapture(t *testing.T, capturedBody *string, mu *sync.Mutex, responseText string) *httptest.Server {
 	t.Helper()
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1977,13 +2873,16 @@ enabled = true
 	}
 
 	// Verify the memory file was created with an entry
-	memoryFile := filepath.Join(tmpDir, "data", "axe", "memory", "mem-append.md")
-	data, err := os.ReadFile(memoryFile)
-	if err != nil {
-		t.Fatalf("expected memory file to exist: %v", err)
+	memoryFile := filepath.Join(tmpDir, "data", "axe", "memory", "mem-append.jsonl")
+	entries, err := memory.Load(memoryFile)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected memory file with one entry: %v, %d entries", err, len(entries))
+	}
+	if entries[0].Model != "anthropic/claude-sonnet-4-20250514" || entries[0].RunID == "" || entries[0].InputTokens == 0 {
+		t.Errorf("entry = %+v, want model, run ID and tokens recorded", entries[0])
 	}
 
-	content := string(data)
+	content := memory.Render(entries)
 	if !strings.Contains(content, "## ") {
 		t.Errorf("expected entry header in memory file, got %q", content)
 	}
@@ -2227,3 +3126,270 @@ model = "openai/gpt-4o"
 		t.Errorf("expected 'openai/gpt-4o' in dry-run output, got %q", output)
 	}
 }
+
+// --- Streaming Output ---
+
+// helper: start a mock Anthropic API server that streams a text response as SSE.
+func startMockAnthropicStreamServer(t *testing.T, gotStream *bool) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		*gotStream = body["stream"] == true
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`event: message_start
+data: {"type":"message_start","message":{"model":"claude-sonnet-4-20250514","usage":{"input_tokens":11,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Streamed "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"reply"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":6}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`))
+	}))
+}
+
+func TestRun_Stream(t *testing.T) {
+	resetRunCmd(t)
+	var gotStream bool
+	server := startMockAnthropicStreamServer(t, &gotStream)
+	defer server.Close()
+
+	setupRunTestAgent(t, "stream-agent", `name = "stream-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "stream-agent", "--stream"})
+
+	err := rootCmd.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !gotStream {
+		t.Error("expected request body to set stream=true")
+	}
+	// Content must be written exactly once (not re-printed after streaming).
+	if buf.String() != "Streamed reply" {
+		t.Errorf("stdout = %q, want %q", buf.String(), "Streamed reply")
+	}
+}
+
+func TestRun_StreamJSON(t *testing.T) {
+	resetRunCmd(t)
+	var gotStream bool
+	server := startMockAnthropicStreamServer(t, &gotStream)
+	defer server.Close()
+
+	setupRunTestAgent(t, "stream-json-agent", `name = "stream-json-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "stream-json-agent", "--stream", "--json"})
+
+	err := rootCmd.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("output is not valid JSON: %v\noutput: %q", err, buf.String())
+	}
+	if result["content"] != "Streamed reply" {
+		t.Errorf("content = %v, want %q", result["content"], "Streamed reply")
+	}
+	if result["input_tokens"] != float64(11) {
+		t.Errorf("input_tokens = %v, want 11", result["input_tokens"])
+	}
+	if result["output_tokens"] != float64(6) {
+		t.Errorf("output_tokens = %v, want 6", result["output_tokens"])
+	}
+}
+
+// --- Retry ---
+
+func TestRun_RetryRecoversFromOverload(t *testing.T) {
+	resetRunCmd(t)
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if n == 1 {
+			w.WriteHeader(529)
+			w.Write([]byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+			return
+		}
+		w.Write([]byte(`{
+			"content": [{"type": "text", "text": "recovered"}],
+			"model": "claude-sonnet-4-20250514", "stop_reason": "end_turn",
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`))
+	}))
+	defer server.Close()
+
+	tmpDir := setupRunTestAgent(t, "retry-agent", `name = "retry-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[retry]
+initial_backoff_ms = 1
+`)
+	os.WriteFile(filepath.Join(tmpDir, "axe", "config.toml"), []byte(`
+[retry]
+max_attempts = 3
+`), 0644)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "retry-agent", "--verbose"})
+
+	err := rootCmd.Execute()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "recovered" {
+		t.Errorf("stdout = %q, want %q", buf.String(), "recovered")
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if !strings.Contains(errBuf.String(), "[retry] attempt 2/3") {
+		t.Errorf("expected retry attempt in verbose output, got:\n%s", errBuf.String())
+	}
+}
+
+func TestRun_RetryDisabledByDefault(t *testing.T) {
+	resetRunCmd(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(529)
+		w.Write([]byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+	}))
+	defer server.Close()
+
+	setupRunTestAgent(t, "noretry-agent", `name = "noretry-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	rootCmd.SetOut(new(bytes.Buffer))
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{"run", "noretry-agent"})
+
+	err := rootCmd.Execute()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRun_FallbackModelAnswers(t *testing.T) {
+	resetRunCmd(t)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(529)
+		w.Write([]byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+	}))
+	defer primary.Close()
+	backup := startMockOpenAIServer(t)
+	defer backup.Close()
+
+	setupRunTestAgent(t, "fallback-agent", `name = "fallback-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+fallback_models = ["openai/gpt-4o"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", primary.URL)
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("AXE_OPENAI_BASE_URL", backup.URL)
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "fallback-agent", "--json", "--verbose"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to parse JSON output: %v\n%s", err, buf.String())
+	}
+	if envelope["content"] != "Hello from OpenAI mock" {
+		t.Errorf("content = %v, want fallback response", envelope["content"])
+	}
+	if envelope["model_ref"] != "openai/gpt-4o" {
+		t.Errorf("model_ref = %v, want openai/gpt-4o", envelope["model_ref"])
+	}
+	if !strings.Contains(errBuf.String(), "[fallback] anthropic/claude-sonnet-4-20250514 failed") {
+		t.Errorf("expected fallback notice in verbose output, got:\n%s", errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "Answered: openai/gpt-4o") {
+		t.Errorf("expected answering model in verbose output, got:\n%s", errBuf.String())
+	}
+}
+
+func TestRun_FallbackMissingAPIKeySkipped(t *testing.T) {
+	resetRunCmd(t)
+	server := startMockAnthropicServer(t)
+	defer server.Close()
+
+	setupRunTestAgent(t, "fallback-agent", `name = "fallback-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+fallback_models = ["openai/gpt-4o"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+	t.Setenv("OPENAI_API_KEY", "")
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(errBuf)
+	rootCmd.SetArgs([]string{"run", "fallback-agent"})
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(errBuf.String(), `Warning: skipping fallback model "openai/gpt-4o"`) {
+		t.Errorf("expected skip warning, got:\n%s", errBuf.String())
+	}
+}
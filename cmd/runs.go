@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jrswab/axe/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "Browse and prune run history",
+	Long: `Subcommands for the run history. Every 'axe run' is recorded under
+$XDG_DATA_HOME/axe/runs/ with its conversation, tool calls, sub-agent runs,
+token usage and exit code.`,
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded runs, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agentFilter, _ := cmd.Flags().GetString("agent")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		runs, err := listRuns(cmd)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTARTED\tAGENT\tMODEL\tEXIT\tTOKENS\tDURATION")
+		shown := 0
+		for _, r := range runs {
+			if agentFilter != "" && r.Agent != agentFilter {
+				continue
+			}
+			if limit > 0 && shown >= limit {
+				break
+			}
+			total := r.TotalUsage()
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%dms\n",
+				r.ID, r.StartedAt.Local().Format("2006-01-02 15:04:05"), r.Agent, r.Model,
+				r.ExitCode, total.InputTokens+total.OutputTokens, r.DurationMs)
+			shown++
+		}
+		return tw.Flush()
+	},
+}
+
+var runsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the full record of a run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := history.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+			data, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal run: %w", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		}
+
+		printRun(cmd.OutOrStdout(), r, "")
+		return nil
+	},
+}
+
+var runsRmCmd = &cobra.Command{
+	Use:   "rm [id...]",
+	Short: "Remove recorded runs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		olderThan, _ := cmd.Flags().GetString("older-than")
+
+		if len(args) > 0 && (all || olderThan != "") {
+			return fmt.Errorf("cannot combine run IDs with --all or --older-than")
+		}
+		if all && olderThan != "" {
+			return fmt.Errorf("cannot combine --all and --older-than")
+		}
+		if len(args) == 0 && !all && olderThan == "" {
+			return fmt.Errorf("run ID is required (or use --all or --older-than)")
+		}
+
+		ids := args
+		if len(ids) == 0 {
+			var cutoff time.Time
+			if olderThan != "" {
+				age, err := parseAge(olderThan)
+				if err != nil {
+					return err
+				}
+				cutoff = time.Now().Add(-age)
+			}
+
+			runs, err := listRuns(cmd)
+			if err != nil {
+				return err
+			}
+			for _, r := range runs {
+				if all || r.StartedAt.Before(cutoff) {
+					ids = append(ids, r.ID)
+				}
+			}
+		}
+
+		for _, id := range ids {
+			if err := history.Remove(id); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %d run(s)\n", len(ids))
+		return nil
+	},
+}
+
+func init() {
+	runsListCmd.Flags().String("agent", "", "Only list runs of this agent")
+	runsListCmd.Flags().Int("limit", 20, "Maximum number of runs to list (0 for all)")
+	runsShowCmd.Flags().Bool("json", false, "Print the raw run record as JSON")
+	runsRmCmd.Flags().Bool("all", false, "Remove all recorded runs")
+	runsRmCmd.Flags().String("older-than", "", "Remove runs started longer ago than this age (e.g. 72h, 30d)")
+
+	runsCmd.AddCommand(runsListCmd)
+	runsCmd.AddCommand(runsShowCmd)
+	runsCmd.AddCommand(runsRmCmd)
+	rootCmd.AddCommand(runsCmd)
+}
+
+// listRuns returns the recorded runs, newest first, warning on stderr about
+// each record that could not be read.
+func listRuns(cmd *cobra.Command) ([]*history.Run, error) {
+	runs, skipped, err := history.List()
+	for _, err := range skipped {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: skipping run record: %v\n", err)
+	}
+	return runs, err
+}
+
+// parseAge parses a duration such as "90m" or "72h", additionally accepting
+// a whole number of days such as "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid age %q: expected a duration such as 72h or 30d", s)
+}
+
+// printRun writes a human-readable transcript of r, followed by its sub-agent
+// runs indented below it.
+func printRun(w io.Writer, r *history.Run, indent string) {
+	if r.ID != "" {
+		fmt.Fprintf(w, "%sID:       %s\n", indent, r.ID)
+	}
+	if r.CallID != "" {
+		fmt.Fprintf(w, "%sCall:     %s\n", indent, r.CallID)
+	}
+	fmt.Fprintf(w, "%sAgent:    %s\n", indent, r.Agent)
+	fmt.Fprintf(w, "%sModel:    %s\n", indent, r.Model)
+	fmt.Fprintf(w, "%sStarted:  %s\n", indent, r.StartedAt.Local().Format(time.RFC3339))
+	fmt.Fprintf(w, "%sDuration: %dms\n", indent, r.DurationMs)
+	if r.ID != "" {
+		fmt.Fprintf(w, "%sExit:     %d\n", indent, r.ExitCode)
+	}
+	fmt.Fprintf(w, "%sTokens:   %d input, %d output\n", indent, r.Usage.InputTokens, r.Usage.OutputTokens)
+	if r.Error != "" {
+		fmt.Fprintf(w, "%sError:    %s\n", indent, r.Error)
+	}
+	for _, tr := range r.Truncations {
+		fmt.Fprintf(w, "%sTrimmed:  %s [%s] %d -> %d bytes (%s)\n", indent, tr.Tool, tr.CallID, tr.OriginalBytes, tr.Bytes, tr.Strategy)
+	}
+
+	if r.System != "" {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s--- System Prompt ---\n", indent)
+		fmt.Fprintln(w, indentLines(r.System, indent))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s--- Messages (%d) ---\n", indent, len(r.Messages))
+	for _, m := range r.Messages {
+		fmt.Fprintf(w, "%s[%s]\n", indent, m.Role)
+		if m.Content != "" {
+			fmt.Fprintln(w, indentLines(m.Content, indent))
+		}
+		for _, a := range m.Attachments {
+			fmt.Fprintf(w, "%s(attachment: %s, %s, %d bytes)\n", indent, a.Name, a.MediaType, a.Size)
+		}
+		for _, tc := range m.ToolCalls {
+			fmt.Fprintf(w, "%s-> %s [%s] %s\n", indent, tc.Name, tc.ID, tc.Arguments)
+		}
+		for _, tr := range m.ToolResults {
+			label := "result"
+			if tr.IsError {
+				label = "error"
+			}
+			fmt.Fprintf(w, "%s<- %s [%s]\n", indent, label, tr.CallID)
+			fmt.Fprintln(w, indentLines(tr.Content, indent))
+		}
+	}
+
+	if len(r.SubAgents) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s--- Sub-Agents (%d) ---\n", indent, len(r.SubAgents))
+		for i, sub := range r.SubAgents {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			printRun(w, sub, indent+"    ")
+		}
+	}
+}
+
+// indentLines prefixes every line of s with indent.
+func indentLines(s, indent string) string {
+	if indent == "" {
+		return s
+	}
+	return indent + strings.ReplaceAll(s, "\n", "\n"+indent)
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrswab/axe/internal/history"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// resetRunsCmd resets all runs subcommand flags to their defaults between tests.
+func resetRunsCmd(t *testing.T) {
+	t.Helper()
+	runsListCmd.Flags().Set("agent", "")
+	runsListCmd.Flags().Set("limit", "20")
+	runsShowCmd.Flags().Set("json", "false")
+	runsRmCmd.Flags().Set("all", "false")
+	runsRmCmd.Flags().Set("older-than", "")
+}
+
+// helper: point XDG_DATA_HOME at a temp dir and save a run for each agent,
+// started one hour apart in the given order. Returns the saved runs.
+func seedRuns(t *testing.T, agents ...string) []*history.Run {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	orig := history.Now
+	t.Cleanup(func() { history.Now = orig })
+
+	base := time.Now().Add(-time.Duration(len(agents)) * time.Hour)
+	var runs []*history.Run
+	for i, name := range agents {
+		history.Now = func() time.Time { return base.Add(time.Duration(i) * time.Hour) }
+		r := history.New(name)
+		req := &provider.Request{System: "sys", Messages: []provider.Message{{Role: "user", Content: "task for " + name}}}
+		r.Record(&provider.Response{InputTokens: 10, OutputTokens: 5})
+		r.Finish("anthropic/claude-sonnet-4", req, &provider.Response{Content: "answer from " + name}, nil)
+		if err := history.Save(r); err != nil {
+			t.Fatalf("failed to save run: %v", err)
+		}
+		runs = append(runs, r)
+	}
+	return runs
+}
+
+func runRunsCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	resetRunsCmd(t)
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs(append([]string{"runs"}, args...))
+	err := rootCmd.Execute()
+	return buf.String(), err
+}
+
+func TestRunsList_Empty(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	out, err := runRunsCmd(t, "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], "ID") {
+		t.Errorf("expected header only, got:\n%s", out)
+	}
+}
+
+func TestRunsList_SkipsBrokenRecords(t *testing.T) {
+	seedRuns(t, "alpha")
+	dir, _ := history.Dir()
+	os.WriteFile(filepath.Join(dir, "20260101T000000-broken.json"), []byte("not json"), 0644)
+
+	resetRunsCmd(t)
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	rootCmd.SetOut(out)
+	rootCmd.SetErr(errOut)
+	rootCmd.SetArgs([]string{"runs", "list"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "alpha") {
+		t.Errorf("output = %q, want the readable run", out.String())
+	}
+	if !strings.Contains(errOut.String(), "Warning: skipping run record: failed to parse run 20260101T000000-broken") {
+		t.Errorf("stderr = %q, want a warning naming the broken record", errOut.String())
+	}
+}
+
+func TestRunsList_NewestFirstAndFilters(t *testing.T) {
+	runs := seedRuns(t, "alpha", "beta", "alpha")
+
+	out, err := runRunsCmd(t, "list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 runs, got:\n%s", out)
+	}
+	if !strings.HasPrefix(lines[1], runs[2].ID) || !strings.HasPrefix(lines[3], runs[0].ID) {
+		t.Errorf("runs not listed newest first:\n%s", out)
+	}
+	if !strings.Contains(lines[1], "15") {
+		t.Errorf("expected token total in listing, got %q", lines[1])
+	}
+
+	out, err = runRunsCmd(t, "list", "--agent", "beta")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out, runs[0].ID) || !strings.Contains(out, runs[1].ID) {
+		t.Errorf("--agent beta listed wrong runs:\n%s", out)
+	}
+
+	out, err = runRunsCmd(t, "list", "--limit", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(strings.Split(strings.TrimSpace(out), "\n")); n != 2 {
+		t.Errorf("--limit 1 listed %d lines, want header and 1 run:\n%s", n, out)
+	}
+}
+
+func TestRunsShow_Transcript(t *testing.T) {
+	runs := seedRuns(t, "alpha")
+
+	out, err := runRunsCmd(t, "show", runs[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"ID:       " + runs[0].ID, "Agent:    alpha", "Exit:     0", "--- System Prompt ---", "task for alpha", "[assistant]", "answer from alpha"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("show output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunsShow_JSON(t *testing.T) {
+	runs := seedRuns(t, "alpha")
+
+	out, err := runRunsCmd(t, "show", runs[0].ID, "--json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got history.Run
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+	if got.ID != runs[0].ID || got.Output != "answer from alpha" {
+		t.Errorf("decoded run: ID %q, output %q", got.ID, got.Output)
+	}
+}
+
+func TestRunsShow_NotFound(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	_, err := runRunsCmd(t, "show", "20260101T000000-abcdef")
+	if !errors.Is(err, history.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRunsRm_ByID(t *testing.T) {
+	runs := seedRuns(t, "alpha", "beta")
+
+	out, err := runRunsCmd(t, "rm", runs[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Removed 1 run(s)") {
+		t.Errorf("unexpected output: %q", out)
+	}
+	remaining, _, _ := history.List()
+	if len(remaining) != 1 || remaining[0].ID != runs[1].ID {
+		t.Errorf("remaining runs = %v", remaining)
+	}
+}
+
+func TestRunsRm_OlderThan(t *testing.T) {
+	runs := seedRuns(t, "alpha", "beta", "gamma")
+
+	// Runs started 3h, 2h and 1h ago
+	if _, err := runRunsCmd(t, "rm", "--older-than", "90m"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	remaining, _, _ := history.List()
+	if len(remaining) != 1 || remaining[0].ID != runs[2].ID {
+		t.Errorf("remaining runs = %d, want only the newest", len(remaining))
+	}
+}
+
+func TestRunsRm_All(t *testing.T) {
+	seedRuns(t, "alpha", "beta")
+
+	if _, err := runRunsCmd(t, "rm", "--all"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining, _, _ := history.List(); len(remaining) != 0 {
+		t.Errorf("remaining runs = %d, want 0", len(remaining))
+	}
+}
+
+func TestRunsRm_ArgumentErrors(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"nothing to remove", []string{"rm"}},
+		{"ID with --all", []string{"rm", "abc", "--all"}},
+		{"--all with --older-than", []string{"rm", "--all", "--older-than", "1d"}},
+		{"invalid age", []string{"rm", "--older-than", "soon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := runRunsCmd(t, tt.args...); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"72h", 72 * time.Hour, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"-1h", 0, true},
+		{"xd", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -17,8 +18,27 @@ type MemoryConfig struct {
 	Path       string `toml:"path"`
 	LastN      int    `toml:"last_n"`
 	MaxEntries int    `toml:"max_entries"`
+	// Strategy picks the entries added to the system prompt: "recent" (the
+	// default) loads the last LastN, "relevant" the TopK most relevant to the
+	// run's message.
+	Strategy string `toml:"strategy"`
+	TopK     int    `toml:"top_k"`
+	// EmbeddingModel is the "provider/model" that ranks entries for the
+	// "relevant" strategy (OpenAI-style and Ollama providers). Empty ranks
+	// them lexically with BM25.
+	EmbeddingModel string `toml:"embedding_model"`
+	// Shared names memory pools the agent reads and appends to alongside its
+	// own memory, e.g. a pool common to a parent and its sub-agents.
+	Shared []string `toml:"shared"`
+	// Consolidate makes "axe gc" fold trimmed entries into a digest entry
+	// instead of dropping them.
+	Consolidate bool `toml:"consolidate"`
 }
 
+// sharedMemoryNamePattern restricts shared memory pool names, which become
+// file names.
+var sharedMemoryNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
 // ParamsConfig holds model parameter overrides for an agent.
 type ParamsConfig struct {
 	Temperature float64 `toml:"temperature"`
@@ -32,19 +52,97 @@ type SubAgentsConfig struct {
 	Timeout  int   `toml:"timeout"`
 }
 
+// RetryConfig holds per-agent overrides for retrying transient provider errors.
+// Zero values inherit from the [retry] section of config.toml.
+type RetryConfig struct {
+	MaxAttempts      int `toml:"max_attempts"`
+	InitialBackoffMs int `toml:"initial_backoff_ms"`
+	MaxBackoffMs     int `toml:"max_backoff_ms"`
+}
+
+// RunCommandConfig configures the run_command built-in tool.
+type RunCommandConfig struct {
+	// Allow lists the commands the agent may run. An entry without spaces or
+	// "*" is an executable name that must equal the command exactly; any
+	// other entry is a pattern matched against the full command line, where
+	// "*" matches any run of characters. No entries means nothing may run.
+	Allow []string `toml:"allow"`
+	// Timeout is the per-command limit in seconds. Zero means 60.
+	Timeout int `toml:"timeout"`
+	// MaxOutputBytes caps the stdout and stderr returned to the model, each.
+	// Zero means 65536.
+	MaxOutputBytes int `toml:"max_output_bytes"`
+	// Env names environment variables passed through to commands in addition
+	// to a minimal base set. Everything else, including API keys, is removed.
+	Env []string `toml:"env"`
+}
+
+// HTTPGetConfig configures the http_get built-in tool.
+type HTTPGetConfig struct {
+	// Allow lists the hosts the agent may fetch from. An entry is a host name
+	// ("docs.example.com"), a host and port ("localhost:8080"), or a
+	// "*." pattern matching any subdomain ("*.example.com"). No entries means
+	// nothing may be fetched. Redirects are followed only to allowed hosts.
+	Allow []string `toml:"allow"`
+	// Timeout is the per-request limit in seconds. Zero means 30.
+	Timeout int `toml:"timeout"`
+	// MaxBytes caps the response body read. Zero means 1048576.
+	MaxBytes int `toml:"max_bytes"`
+	// ContentTypes lists the media types the agent may receive, where "*"
+	// matches any run of characters ("text/*"). Empty means text, JSON and
+	// XML types.
+	ContentTypes []string `toml:"content_types"`
+}
+
+// ToolConfig holds the settings of one tool, from a [tools_config.<name>]
+// table.
+type ToolConfig struct {
+	// RequireApproval pauses the run before each call of the tool until the
+	// call is approved on the terminal or by an approval policy file.
+	RequireApproval bool `toml:"require_approval"`
+	// MaxResultBytes caps the size of the tool's results before they reach
+	// the model, overriding [tool_results] in config.toml. Zero means the
+	// config.toml setting applies.
+	MaxResultBytes int `toml:"max_result_bytes"`
+	// ResultStrategy shortens oversized results: "truncate" keeps their head
+	// and tail, "summarize" has SummaryModel summarize them.
+	ResultStrategy string `toml:"result_strategy"`
+	SummaryModel   string `toml:"summary_model"`
+}
+
+// MCPServerConfig declares an MCP server whose tools the agent may call.
+// It mirrors the [mcp_servers.<name>] tables of config.toml: an entry that
+// sets neither Command nor URL uses the server of the same name from
+// config.toml, and any fields it does set override those.
+type MCPServerConfig struct {
+	Command string            `toml:"command"`
+	Args    []string          `toml:"args"`
+	Env     map[string]string `toml:"env"`
+	URL     string            `toml:"url"`
+	Headers map[string]string `toml:"headers"`
+	Timeout int               `toml:"timeout"`
+}
+
 // AgentConfig represents a parsed agent TOML configuration file.
 type AgentConfig struct {
-	Name          string          `toml:"name"`
-	Description   string          `toml:"description"`
-	Model         string          `toml:"model"`
-	SystemPrompt  string          `toml:"system_prompt"`
-	Skill         string          `toml:"skill"`
-	Files         []string        `toml:"files"`
-	Workdir       string          `toml:"workdir"`
-	SubAgents     []string        `toml:"sub_agents"`
-	SubAgentsConf SubAgentsConfig `toml:"sub_agents_config"`
-	Memory        MemoryConfig    `toml:"memory"`
-	Params        ParamsConfig    `toml:"params"`
+	Name           string                     `toml:"name"`
+	Description    string                     `toml:"description"`
+	Model          string                     `toml:"model"`
+	FallbackModels []string                   `toml:"fallback_models"`
+	SystemPrompt   string                     `toml:"system_prompt"`
+	Skill          string                     `toml:"skill"`
+	Files          []string                   `toml:"files"`
+	Workdir        string                     `toml:"workdir"`
+	Tools          []string                   `toml:"tools"`
+	SubAgents      []string                   `toml:"sub_agents"`
+	SubAgentsConf  SubAgentsConfig            `toml:"sub_agents_config"`
+	Memory         MemoryConfig               `toml:"memory"`
+	Params         ParamsConfig               `toml:"params"`
+	Retry          RetryConfig                `toml:"retry"`
+	RunCommand     RunCommandConfig           `toml:"run_command"`
+	HTTPGet        HTTPGetConfig              `toml:"http_get"`
+	ToolsConf      map[string]ToolConfig      `toml:"tools_config"`
+	MCPServers     map[string]MCPServerConfig `toml:"mcp_servers"`
 }
 
 // Validate checks that required fields are present in the agent configuration.
@@ -57,6 +155,69 @@ func Validate(cfg *AgentConfig) error {
 	if strings.TrimSpace(cfg.Model) == "" {
 		return errors.New("agent config missing required field: model")
 	}
+	for _, ref := range cfg.FallbackModels {
+		if strings.TrimSpace(ref) == "" {
+			return errors.New("fallback_models entries must not be empty")
+		}
+	}
+	for _, name := range cfg.Tools {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("tools entries must not be empty")
+		}
+		if name == "run_command" && len(cfg.RunCommand.Allow) == 0 {
+			return errors.New("run_command.allow must list at least one command when the run_command tool is enabled")
+		}
+		if name == "http_get" && len(cfg.HTTPGet.Allow) == 0 {
+			return errors.New("http_get.allow must list at least one host when the http_get tool is enabled")
+		}
+	}
+	for _, entry := range cfg.RunCommand.Allow {
+		if strings.TrimSpace(entry) == "" {
+			return errors.New("run_command.allow entries must not be empty")
+		}
+	}
+	if cfg.RunCommand.Timeout < 0 {
+		return errors.New("run_command.timeout must be non-negative")
+	}
+	if cfg.RunCommand.MaxOutputBytes < 0 {
+		return errors.New("run_command.max_output_bytes must be non-negative")
+	}
+	for _, entry := range cfg.HTTPGet.Allow {
+		if strings.TrimSpace(entry) == "" {
+			return errors.New("http_get.allow entries must not be empty")
+		}
+	}
+	for _, entry := range cfg.HTTPGet.ContentTypes {
+		if strings.TrimSpace(entry) == "" {
+			return errors.New("http_get.content_types entries must not be empty")
+		}
+	}
+	if cfg.HTTPGet.Timeout < 0 {
+		return errors.New("http_get.timeout must be non-negative")
+	}
+	if cfg.HTTPGet.MaxBytes < 0 {
+		return errors.New("http_get.max_bytes must be non-negative")
+	}
+	for name := range cfg.ToolsConf {
+		if !configuresTool(cfg, name) {
+			return fmt.Errorf("tools_config.%s: %q is not one of the agent's tools", name, name)
+		}
+		tc := cfg.ToolsConf[name]
+		if tc.MaxResultBytes < 0 {
+			return fmt.Errorf("tools_config.%s.max_result_bytes must be non-negative", name)
+		}
+		if tc.ResultStrategy != "" && tc.ResultStrategy != "truncate" && tc.ResultStrategy != "summarize" {
+			return fmt.Errorf("tools_config.%s.result_strategy must be \"truncate\" or \"summarize\", got %q", name, tc.ResultStrategy)
+		}
+	}
+	for name, server := range cfg.MCPServers {
+		if server.Command != "" && server.URL != "" {
+			return fmt.Errorf("mcp_servers.%s: set either command or url, not both", name)
+		}
+		if server.Timeout < 0 {
+			return fmt.Errorf("mcp_servers.%s.timeout must be non-negative", name)
+		}
+	}
 	if cfg.SubAgentsConf.MaxDepth < 0 {
 		return errors.New("sub_agents_config.max_depth must be non-negative")
 	}
@@ -72,9 +233,44 @@ func Validate(cfg *AgentConfig) error {
 	if cfg.Memory.MaxEntries < 0 {
 		return errors.New("memory.max_entries must be non-negative")
 	}
+	if cfg.Memory.Strategy != "" && cfg.Memory.Strategy != "recent" && cfg.Memory.Strategy != "relevant" {
+		return fmt.Errorf("memory.strategy must be \"recent\" or \"relevant\", got %q", cfg.Memory.Strategy)
+	}
+	if cfg.Memory.TopK < 0 {
+		return errors.New("memory.top_k must be non-negative")
+	}
+	for _, name := range cfg.Memory.Shared {
+		if !sharedMemoryNamePattern.MatchString(name) {
+			return fmt.Errorf("memory.shared: invalid pool name %q (use 1-64 letters, digits, '_' or '-')", name)
+		}
+	}
+	if cfg.Retry.MaxAttempts < 0 {
+		return errors.New("retry.max_attempts must be non-negative")
+	}
+	if cfg.Retry.InitialBackoffMs < 0 {
+		return errors.New("retry.initial_backoff_ms must be non-negative")
+	}
+	if cfg.Retry.MaxBackoffMs < 0 {
+		return errors.New("retry.max_backoff_ms must be non-negative")
+	}
 	return nil
 }
 
+// configuresTool reports whether name may be given a [tools_config] table:
+// it is enabled in tools, it is call_agent and the agent has sub-agents, or
+// the agent has MCP servers, whose tool names are not known until they run.
+func configuresTool(cfg *AgentConfig, name string) bool {
+	if name == "call_agent" && len(cfg.SubAgents) > 0 {
+		return true
+	}
+	for _, t := range cfg.Tools {
+		if t == name {
+			return true
+		}
+	}
+	return len(cfg.MCPServers) > 0
+}
+
 // Load reads and parses an agent TOML configuration file by name.
 // The name parameter is the agent name without the .toml extension.
 func Load(name string) (*AgentConfig, error) {
@@ -153,6 +349,10 @@ description = ""
 # Full provider/model per models.dev
 model = "provider/model-name"
 
+# Models to try in order if the primary model fails with an auth,
+# overloaded, or server error (optional)
+# fallback_models = []
+
 # Agent persona (optional)
 # system_prompt = ""
 
@@ -165,6 +365,12 @@ model = "provider/model-name"
 # Working directory (optional)
 # workdir = ""
 
+# Tools the agent may call (optional): read_file, list_dir, write_file and
+# run_command are built in and sandboxed to the working directory, http_get
+# is built in and limited to [http_get] allow; any other name is a plugin
+# from $XDG_CONFIG_HOME/axe/tools/
+# tools = []
+
 # Sub-agents this agent can invoke (optional)
 # sub_agents = []
 
@@ -178,10 +384,60 @@ model = "provider/model-name"
 # path = ""
 # last_n = 10
 # max_entries = 100
+# Load the entries most relevant to the task instead of the last ones,
+# ranked by embeddings, or by keywords (BM25) without an embedding model
+# strategy = "relevant"
+# top_k = 5
+# embedding_model = "ollama/nomic-embed-text"
+# Shared memory pools read and written together with other agents
+# shared = ["project-x"]
+# Have 'axe gc' summarize trimmed entries into a digest kept in the prompt
+# consolidate = true
 
 # [params]
 # temperature = 0.3
 # max_tokens = 4096
+
+# Commands the run_command tool may run: executable names, or patterns
+# matched against the whole command line where * matches anything
+# [run_command]
+# allow = ["go", "make test*"]
+# timeout = 60
+# max_output_bytes = 65536
+# env = []  # extra environment variables to pass through
+
+# Hosts the http_get tool may fetch from; *.example.com matches subdomains
+# [http_get]
+# allow = ["docs.example.com", "api.github.com"]
+# timeout = 30
+# max_bytes = 1048576
+# content_types = ["text/*", "application/json"]
+
+# Ask before each call of a tool (on the terminal, or by --approval-policy)
+# [tools_config.write_file]
+# require_approval = true
+
+# Shorten a tool's results before the model sees them (see [tool_results]
+# in config.toml): keep head and tail, or have a cheap model summarize them
+# [tools_config.call_agent]
+# max_result_bytes = 8000
+# result_strategy = "summarize"
+# summary_model = "anthropic/claude-haiku-4-5"
+
+# MCP servers whose tools the agent may call: a stdio command or an HTTP url.
+# An empty table uses the server of the same name from config.toml
+# [mcp_servers.github]
+# command = "github-mcp-server"
+# args = ["stdio"]
+# env = { GITHUB_TOKEN = "..." }
+# [mcp_servers.docs]
+# url = "http://localhost:8080/mcp"
+
+# Retry transient provider errors (overrides [retry] in config.toml)
+# [retry]
+# max_attempts = 3
+# initial_backoff_ms = 1000
+# max_backoff_ms = 30000
 `
 	return tmpl, nil
 }
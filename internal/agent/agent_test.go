@@ -626,6 +626,33 @@ func TestValidate_MemoryMaxEntries_Negative(t *testing.T) {
 	}
 }
 
+func TestValidate_MemoryStrategy(t *testing.T) {
+	tests := []struct {
+		memory MemoryConfig
+		want   string
+	}{
+		{MemoryConfig{Strategy: "relevant", TopK: 3, EmbeddingModel: "ollama/nomic-embed-text"}, ""},
+		{MemoryConfig{Strategy: "recent"}, ""},
+		{MemoryConfig{Strategy: "similar"}, `memory.strategy must be "recent" or "relevant", got "similar"`},
+		{MemoryConfig{Strategy: "relevant", TopK: -1}, "memory.top_k must be non-negative"},
+		{MemoryConfig{Shared: []string{"project-x", "team_2"}}, ""},
+		{MemoryConfig{Shared: []string{"../x"}}, `memory.shared: invalid pool name "../x" (use 1-64 letters, digits, '_' or '-')`},
+		{MemoryConfig{Shared: []string{""}}, `memory.shared: invalid pool name "" (use 1-64 letters, digits, '_' or '-')`},
+	}
+	for _, tt := range tests {
+		err := Validate(&AgentConfig{Name: "test", Model: "openai/gpt-4o", Memory: tt.memory})
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%+v): unexpected error: %v", tt.memory, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.memory, err, tt.want)
+		}
+	}
+}
+
 func TestValidate_MemoryLastN_Zero(t *testing.T) {
 	cfg := &AgentConfig{
 		Name:   "test",
@@ -735,3 +762,299 @@ func TestScaffold_IsValidTOML(t *testing.T) {
 		t.Errorf("scaffold output (cleaned) is not valid TOML: %v\ncleaned:\n%s", decodeErr, tomlStr)
 	}
 }
+
+func TestLoad_RetryConfig(t *testing.T) {
+	agentsDir := setupAgentsDir(t)
+
+	tomlContent := `
+name = "retry-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[retry]
+max_attempts = 5
+initial_backoff_ms = 200
+max_backoff_ms = 4000
+`
+	writeAgentFile(t, agentsDir, "retry-agent", tomlContent)
+
+	cfg, err := Load("retry-agent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := RetryConfig{MaxAttempts: 5, InitialBackoffMs: 200, MaxBackoffMs: 4000}
+	if cfg.Retry != want {
+		t.Errorf("Retry = %+v, want %+v", cfg.Retry, want)
+	}
+}
+
+func TestValidate_RetryNegative(t *testing.T) {
+	tests := []struct {
+		retry RetryConfig
+		want  string
+	}{
+		{RetryConfig{MaxAttempts: -1}, "retry.max_attempts must be non-negative"},
+		{RetryConfig{InitialBackoffMs: -1}, "retry.initial_backoff_ms must be non-negative"},
+		{RetryConfig{MaxBackoffMs: -1}, "retry.max_backoff_ms must be non-negative"},
+	}
+	for _, tt := range tests {
+		cfg := &AgentConfig{Name: "test", Model: "openai/gpt-4o", Retry: tt.retry}
+		err := Validate(cfg)
+		if err == nil {
+			t.Fatalf("expected error for %+v, got nil", tt.retry)
+		}
+		if err.Error() != tt.want {
+			t.Errorf("got %q, want %q", err.Error(), tt.want)
+		}
+	}
+}
+
+func TestLoad_FallbackModels(t *testing.T) {
+	agentsDir := setupAgentsDir(t)
+
+	tomlContent := `
+name = "fallback-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+fallback_models = ["openai/gpt-4o", "ollama/llama3"]
+`
+	writeAgentFile(t, agentsDir, "fallback-agent", tomlContent)
+
+	cfg, err := Load("fallback-agent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.FallbackModels) != 2 || cfg.FallbackModels[0] != "openai/gpt-4o" || cfg.FallbackModels[1] != "ollama/llama3" {
+		t.Errorf("FallbackModels = %v, want [openai/gpt-4o ollama/llama3]", cfg.FallbackModels)
+	}
+}
+
+func TestValidate_FallbackModelsEmptyEntry(t *testing.T) {
+	cfg := &AgentConfig{Name: "test", Model: "openai/gpt-4o", FallbackModels: []string{"ollama/llama3", " "}}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if want := "fallback_models entries must not be empty"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestLoad_Tools(t *testing.T) {
+	agentsDir := setupAgentsDir(t)
+
+	writeAgentFile(t, agentsDir, "tools-agent", `
+name = "tools-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["read_file", "list_dir"]
+`)
+
+	cfg, err := Load("tools-agent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.Tools) != 2 || cfg.Tools[0] != "read_file" || cfg.Tools[1] != "list_dir" {
+		t.Errorf("Tools = %v, want [read_file list_dir]", cfg.Tools)
+	}
+}
+
+func TestValidate_ToolsEmptyEntry(t *testing.T) {
+	cfg := &AgentConfig{Name: "test", Model: "openai/gpt-4o", Tools: []string{"read_file", ""}}
+	err := Validate(cfg)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if want := "tools entries must not be empty"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestLoad_RunCommandConfig(t *testing.T) {
+	agentsDir := setupAgentsDir(t)
+
+	writeAgentFile(t, agentsDir, "cmd-agent", `
+name = "cmd-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["run_command"]
+
+[run_command]
+allow = ["go", "make test*"]
+timeout = 30
+max_output_bytes = 4096
+env = ["GOPATH"]
+`)
+
+	cfg, err := Load("cmd-agent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rc := cfg.RunCommand
+	if len(rc.Allow) != 2 || rc.Allow[1] != "make test*" || rc.Timeout != 30 || rc.MaxOutputBytes != 4096 || len(rc.Env) != 1 {
+		t.Errorf("RunCommand = %+v", rc)
+	}
+}
+
+func TestValidate_RunCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		tools []string
+		rc    RunCommandConfig
+		want  string
+	}{
+		{"enabled without allowlist", []string{"run_command"}, RunCommandConfig{}, "run_command.allow must list at least one command when the run_command tool is enabled"},
+		{"empty allow entry", nil, RunCommandConfig{Allow: []string{"go", " "}}, "run_command.allow entries must not be empty"},
+		{"negative timeout", nil, RunCommandConfig{Timeout: -1}, "run_command.timeout must be non-negative"},
+		{"negative output cap", nil, RunCommandConfig{MaxOutputBytes: -1}, "run_command.max_output_bytes must be non-negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentConfig{Name: "test", Model: "openai/gpt-4o", Tools: tt.tools, RunCommand: tt.rc}
+			err := Validate(cfg)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_HTTPGetConfig(t *testing.T) {
+	agentsDir := setupAgentsDir(t)
+
+	writeAgentFile(t, agentsDir, "web-agent", `
+name = "web-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["http_get"]
+
+[http_get]
+allow = ["docs.example.com", "*.github.com"]
+timeout = 10
+max_bytes = 2048
+content_types = ["text/html"]
+`)
+
+	cfg, err := Load("web-agent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hg := cfg.HTTPGet
+	if len(hg.Allow) != 2 || hg.Allow[1] != "*.github.com" || hg.Timeout != 10 || hg.MaxBytes != 2048 || len(hg.ContentTypes) != 1 {
+		t.Errorf("HTTPGet = %+v", hg)
+	}
+}
+
+func TestValidate_HTTPGet(t *testing.T) {
+	tests := []struct {
+		name  string
+		tools []string
+		hg    HTTPGetConfig
+		want  string
+	}{
+		{"enabled without allowlist", []string{"http_get"}, HTTPGetConfig{}, "http_get.allow must list at least one host when the http_get tool is enabled"},
+		{"empty allow entry", nil, HTTPGetConfig{Allow: []string{"example.com", ""}}, "http_get.allow entries must not be empty"},
+		{"empty content type", nil, HTTPGetConfig{ContentTypes: []string{" "}}, "http_get.content_types entries must not be empty"},
+		{"negative timeout", nil, HTTPGetConfig{Timeout: -1}, "http_get.timeout must be non-negative"},
+		{"negative size cap", nil, HTTPGetConfig{MaxBytes: -1}, "http_get.max_bytes must be non-negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentConfig{Name: "test", Model: "openai/gpt-4o", Tools: tt.tools, HTTPGet: tt.hg}
+			err := Validate(cfg)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_ToolsConfig(t *testing.T) {
+	approval := map[string]ToolConfig{"write_file": {RequireApproval: true}}
+	tests := []struct {
+		name string
+		cfg  AgentConfig
+		want string
+	}{
+		{"enabled tool", AgentConfig{Tools: []string{"write_file"}, ToolsConf: approval}, ""},
+		{"tool not enabled", AgentConfig{Tools: []string{"read_file"}, ToolsConf: approval}, `tools_config.write_file: "write_file" is not one of the agent's tools`},
+		{"call_agent with sub-agents", AgentConfig{SubAgents: []string{"helper"}, ToolsConf: map[string]ToolConfig{"call_agent": {RequireApproval: true}}}, ""},
+		{"call_agent without sub-agents", AgentConfig{ToolsConf: map[string]ToolConfig{"call_agent": {}}}, `tools_config.call_agent: "call_agent" is not one of the agent's tools`},
+		{"MCP tool", AgentConfig{MCPServers: map[string]MCPServerConfig{"github": {}}, ToolsConf: map[string]ToolConfig{"create_issue": {RequireApproval: true}}}, ""},
+		{"result limit", AgentConfig{Tools: []string{"read_file"}, ToolsConf: map[string]ToolConfig{"read_file": {MaxResultBytes: 4096, ResultStrategy: "summarize"}}}, ""},
+		{"negative result limit", AgentConfig{Tools: []string{"read_file"}, ToolsConf: map[string]ToolConfig{"read_file": {MaxResultBytes: -1}}}, "tools_config.read_file.max_result_bytes must be non-negative"},
+		{"unknown result strategy", AgentConfig{Tools: []string{"read_file"}, ToolsConf: map[string]ToolConfig{"read_file": {ResultStrategy: "drop"}}}, `tools_config.read_file.result_strategy must be "truncate" or "summarize", got "drop"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Name, cfg.Model = "test", "openai/gpt-4o"
+			err := Validate(&cfg)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_MCPServers(t *testing.T) {
+	agentsDir := setupAgentsDir(t)
+
+	writeAgentFile(t, agentsDir, "mcp-agent", `
+name = "mcp-agent"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[mcp_servers.github]
+
+[mcp_servers.local]
+command = "local-mcp"
+args = ["--stdio"]
+env = { TOKEN = "abc" }
+`)
+
+	cfg, err := Load("mcp-agent")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.MCPServers) != 2 {
+		t.Fatalf("MCPServers = %+v, want github and local", cfg.MCPServers)
+	}
+	if _, ok := cfg.MCPServers["github"]; !ok {
+		t.Error("empty [mcp_servers.github] table was dropped")
+	}
+	if local := cfg.MCPServers["local"]; local.Command != "local-mcp" || local.Env["TOKEN"] != "abc" {
+		t.Errorf("local = %+v", local)
+	}
+}
+
+func TestValidate_MCPServers(t *testing.T) {
+	tests := []struct {
+		name   string
+		server MCPServerConfig
+		want   string
+	}{
+		{"command and url", MCPServerConfig{Command: "x", URL: "http://localhost/mcp"}, "mcp_servers.srv: set either command or url, not both"},
+		{"negative timeout", MCPServerConfig{Command: "x", Timeout: -1}, "mcp_servers.srv.timeout must be non-negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AgentConfig{Name: "test", Model: "openai/gpt-4o", MCPServers: map[string]MCPServerConfig{"srv": tt.server}}
+			err := Validate(cfg)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -11,14 +11,72 @@ import (
 )
 
 // ProviderConfig holds per-provider settings from config.toml.
+// Type is empty for built-in providers; custom providers set it to declare
+// which API they speak (e.g. "openai-compatible").
 type ProviderConfig struct {
-	APIKey  string `toml:"api_key"`
-	BaseURL string `toml:"base_url"`
+	Type    string            `toml:"type"`
+	APIKey  string            `toml:"api_key"`
+	BaseURL string            `toml:"base_url"`
+	Headers map[string]string `toml:"headers"`
+}
+
+// RetryConfig holds retry settings for retryable provider errors
+// (rate limits, overload, server errors, and timeouts).
+// Zero values mean "not set" and fall through to the next level.
+type RetryConfig struct {
+	MaxAttempts      int `toml:"max_attempts"`
+	InitialBackoffMs int `toml:"initial_backoff_ms"`
+	MaxBackoffMs     int `toml:"max_backoff_ms"`
+}
+
+// Default retry settings used when neither the agent nor config.toml sets a value.
+// A single attempt means retries are disabled unless configured.
+const (
+	DefaultRetryMaxAttempts      = 1
+	DefaultRetryInitialBackoffMs = 1000
+	DefaultRetryMaxBackoffMs     = 30000
+)
+
+// ToolResultLimit caps the size of tool results returned to the model.
+// Zero values mean "not set" and fall through to the next level.
+type ToolResultLimit struct {
+	MaxBytes     int    `toml:"max_bytes"`     // Largest result passed on as is; zero means no limit
+	Strategy     string `toml:"strategy"`      // "truncate" (keep head and tail) or "summarize"
+	SummaryModel string `toml:"summary_model"` // "provider/model" that writes summaries
+}
+
+// Tool result strategies.
+const (
+	StrategyTruncate  = "truncate"
+	StrategySummarize = "summarize"
+)
+
+// ToolResultsConfig holds the [tool_results] section of config.toml: a limit
+// for every tool, and overrides per tool in [tool_results.tools.<name>].
+type ToolResultsConfig struct {
+	ToolResultLimit
+	Tools map[string]ToolResultLimit `toml:"tools"`
+}
+
+// MCPServerConfig describes how to reach an MCP (Model Context Protocol)
+// server. Exactly one of Command (a stdio subprocess) or URL (streamable
+// HTTP) must be set once agent and global settings are combined.
+type MCPServerConfig struct {
+	Command string            `toml:"command"`
+	Args    []string          `toml:"args"`
+	Env     map[string]string `toml:"env"`
+	URL     string            `toml:"url"`
+	Headers map[string]string `toml:"headers"`
+	Timeout int               `toml:"timeout"` // seconds per request; zero means the default
 }
 
 // GlobalConfig represents the parsed global config file.
 type GlobalConfig struct {
-	Providers map[string]ProviderConfig `toml:"providers"`
+	Providers   map[string]ProviderConfig  `toml:"providers"`
+	Retry       RetryConfig                `toml:"retry"`
+	Pricing     map[string]ModelPricing    `toml:"pricing"`     // keyed by "provider/model" prefix
+	MCPServers  map[string]MCPServerConfig `toml:"mcp_servers"` // keyed by server name
+	ToolResults ToolResultsConfig          `toml:"tool_results"`
 }
 
 // Load reads and parses the global config file at $XDG_CONFIG_HOME/axe/config.toml.
@@ -48,6 +106,15 @@ func Load() (*GlobalConfig, error) {
 		cfg.Providers = map[string]ProviderConfig{}
 	}
 
+	if err := validateToolResultLimit("tool_results", cfg.ToolResults.ToolResultLimit); err != nil {
+		return nil, err
+	}
+	for name, limit := range cfg.ToolResults.Tools {
+		if err := validateToolResultLimit("tool_results.tools."+name, limit); err != nil {
+			return nil, err
+		}
+	}
+
 	return &cfg, nil
 }
 
@@ -55,6 +122,7 @@ func Load() (*GlobalConfig, error) {
 var knownAPIKeyEnvVars = map[string]string{
 	"anthropic": "ANTHROPIC_API_KEY",
 	"openai":    "OPENAI_API_KEY",
+	"gemini":    "GEMINI_API_KEY",
 }
 
 // APIKeyEnvVar returns the environment variable name used to resolve the API key
@@ -102,3 +170,121 @@ func (c *GlobalConfig) ResolveBaseURL(providerName string) string {
 
 	return ""
 }
+
+// ResolveType returns the configured type for the given provider, or an empty
+// string if none is set.
+func (c *GlobalConfig) ResolveType(providerName string) string {
+	if c.Providers != nil {
+		if pc, exists := c.Providers[providerName]; exists {
+			return pc.Type
+		}
+	}
+
+	return ""
+}
+
+// ResolveHeaders returns the extra HTTP headers configured for the given
+// provider, or nil if none are set.
+func (c *GlobalConfig) ResolveHeaders(providerName string) map[string]string {
+	if c.Providers != nil {
+		if pc, exists := c.Providers[providerName]; exists {
+			return pc.Headers
+		}
+	}
+
+	return nil
+}
+
+// ResolveRetry returns the effective retry settings.
+// Resolution order per field: override (e.g. agent TOML) > config file > default.
+// Any value above zero is set, so an agent's max_attempts = 1 turns off
+// retries that config.toml enables.
+func (c *GlobalConfig) ResolveRetry(override RetryConfig) RetryConfig {
+	pick := func(values ...int) int {
+		for _, v := range values {
+			if v > 0 {
+				return v
+			}
+		}
+		return 0
+	}
+
+	return RetryConfig{
+		MaxAttempts:      pick(override.MaxAttempts, c.Retry.MaxAttempts, DefaultRetryMaxAttempts),
+		InitialBackoffMs: pick(override.InitialBackoffMs, c.Retry.InitialBackoffMs, DefaultRetryInitialBackoffMs),
+		MaxBackoffMs:     pick(override.MaxBackoffMs, c.Retry.MaxBackoffMs, DefaultRetryMaxBackoffMs),
+	}
+}
+
+// ResolveToolResult returns the effective result limit for the named tool.
+// Resolution order per field: override (e.g. agent TOML) > the tool's
+// [tool_results.tools.<name>] table > [tool_results] > default (no limit,
+// truncate).
+func (c *GlobalConfig) ResolveToolResult(tool string, override ToolResultLimit) ToolResultLimit {
+	perTool := c.ToolResults.Tools[tool]
+	levels := []ToolResultLimit{override, perTool, c.ToolResults.ToolResultLimit}
+
+	var resolved ToolResultLimit
+	for _, l := range levels {
+		if resolved.MaxBytes == 0 {
+			resolved.MaxBytes = l.MaxBytes
+		}
+		if resolved.Strategy == "" {
+			resolved.Strategy = l.Strategy
+		}
+		if resolved.SummaryModel == "" {
+			resolved.SummaryModel = l.SummaryModel
+		}
+	}
+	if resolved.Strategy == "" {
+		resolved.Strategy = StrategyTruncate
+	}
+	return resolved
+}
+
+// validateToolResultLimit checks a limit from the config section named by
+// section.
+func validateToolResultLimit(section string, l ToolResultLimit) error {
+	if l.MaxBytes < 0 {
+		return fmt.Errorf("%s.max_bytes must be non-negative", section)
+	}
+	if l.Strategy != "" && l.Strategy != StrategyTruncate && l.Strategy != StrategySummarize {
+		return fmt.Errorf("%s.strategy must be %q or %q, got %q", section, StrategyTruncate, StrategySummarize, l.Strategy)
+	}
+	return nil
+}
+
+// ResolveMCPServer returns the effective settings for the named MCP server.
+// An override (e.g. from agent TOML) that sets a command or URL replaces the
+// transport from config.toml; env, headers and timeout are merged per field,
+// with the override winning.
+func (c *GlobalConfig) ResolveMCPServer(name string, override MCPServerConfig) MCPServerConfig {
+	resolved := c.MCPServers[name]
+	if override.Command != "" || override.URL != "" {
+		resolved.Command = override.Command
+		resolved.Args = override.Args
+		resolved.URL = override.URL
+	}
+	resolved.Env = mergeStrings(resolved.Env, override.Env)
+	resolved.Headers = mergeStrings(resolved.Headers, override.Headers)
+	if override.Timeout > 0 {
+		resolved.Timeout = override.Timeout
+	}
+	return resolved
+}
+
+// mergeStrings returns base with the entries of override added, without
+// modifying either map. It returns nil when both are empty.
+func mergeStrings(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -246,6 +246,9 @@ func TestAPIKeyEnvVar_KnownProvider(t *testing.T) {
 	if got := APIKeyEnvVar("openai"); got != "OPENAI_API_KEY" {
 		t.Errorf("expected OPENAI_API_KEY, got %q", got)
 	}
+	if got := APIKeyEnvVar("gemini"); got != "GEMINI_API_KEY" {
+		t.Errorf("expected GEMINI_API_KEY, got %q", got)
+	}
 }
 
 func TestAPIKeyEnvVar_UnknownProvider(t *testing.T) {
@@ -253,3 +256,224 @@ func TestAPIKeyEnvVar_UnknownProvider(t *testing.T) {
 		t.Errorf("expected GROQ_API_KEY, got %q", got)
 	}
 }
+
+func TestLoad_RetryConfig(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+
+	writeConfigTOML(t, tmp, `
+[retry]
+max_attempts = 4
+initial_backoff_ms = 250
+max_backoff_ms = 8000
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := RetryConfig{MaxAttempts: 4, InitialBackoffMs: 250, MaxBackoffMs: 8000}
+	if cfg.Retry != want {
+		t.Errorf("Retry = %+v, want %+v", cfg.Retry, want)
+	}
+}
+
+func TestResolveRetry_Defaults(t *testing.T) {
+	cfg := &GlobalConfig{}
+
+	got := cfg.ResolveRetry(RetryConfig{})
+	want := RetryConfig{
+		MaxAttempts:      DefaultRetryMaxAttempts,
+		InitialBackoffMs: DefaultRetryInitialBackoffMs,
+		MaxBackoffMs:     DefaultRetryMaxBackoffMs,
+	}
+	if got != want {
+		t.Errorf("ResolveRetry = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveRetry_OverrideTakesPrecedence(t *testing.T) {
+	cfg := &GlobalConfig{Retry: RetryConfig{MaxAttempts: 3, InitialBackoffMs: 500}}
+
+	got := cfg.ResolveRetry(RetryConfig{MaxAttempts: 6})
+	if got.MaxAttempts != 6 {
+		t.Errorf("MaxAttempts = %d, want 6 (override)", got.MaxAttempts)
+	}
+	if got.InitialBackoffMs != 500 {
+		t.Errorf("InitialBackoffMs = %d, want 500 (config file)", got.InitialBackoffMs)
+	}
+	if got.MaxBackoffMs != DefaultRetryMaxBackoffMs {
+		t.Errorf("MaxBackoffMs = %d, want default %d", got.MaxBackoffMs, DefaultRetryMaxBackoffMs)
+	}
+}
+
+func TestResolveRetry_SingleAttemptOverrides(t *testing.T) {
+	cfg := &GlobalConfig{Retry: RetryConfig{MaxAttempts: 5}}
+
+	if got := cfg.ResolveRetry(RetryConfig{MaxAttempts: 1}); got.MaxAttempts != 1 {
+		t.Errorf("MaxAttempts = %d, want 1 (override disables retries)", got.MaxAttempts)
+	}
+	if got := cfg.ResolveRetry(RetryConfig{}); got.MaxAttempts != 5 {
+		t.Errorf("MaxAttempts = %d, want 5 (config file)", got.MaxAttempts)
+	}
+}
+
+func TestLoad_OpenAICompatibleProvider(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+
+	writeConfigTOML(t, tmp, `
+[providers.groq]
+type = "openai-compatible"
+base_url = "https://api.groq.com/openai/v1"
+
+[providers.groq.headers]
+X-Title = "axe"
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := cfg.ResolveType("groq"); got != "openai-compatible" {
+		t.Errorf("ResolveType = %q, want openai-compatible", got)
+	}
+	if got := cfg.ResolveBaseURL("groq"); got != "https://api.groq.com/openai/v1" {
+		t.Errorf("ResolveBaseURL = %q", got)
+	}
+	headers := cfg.ResolveHeaders("groq")
+	if len(headers) != 1 || headers["X-Title"] != "axe" {
+		t.Errorf("ResolveHeaders = %v, want map[X-Title:axe]", headers)
+	}
+}
+
+func TestResolveTypeAndHeaders_NotConfigured(t *testing.T) {
+	cfg := &GlobalConfig{}
+	if got := cfg.ResolveType("openai"); got != "" {
+		t.Errorf("ResolveType = %q, want empty", got)
+	}
+	if got := cfg.ResolveHeaders("openai"); got != nil {
+		t.Errorf("ResolveHeaders = %v, want nil", got)
+	}
+}
+
+func TestLoad_MCPServers(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+
+	writeConfigTOML(t, tmp, `
+[mcp_servers.github]
+command = "github-mcp"
+args = ["stdio"]
+timeout = 20
+
+[mcp_servers.github.env]
+GITHUB_TOKEN = "ghp_global"
+
+[mcp_servers.docs]
+url = "https://docs.example.com/mcp"
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	github := cfg.MCPServers["github"]
+	if github.Command != "github-mcp" || len(github.Args) != 1 || github.Timeout != 20 || github.Env["GITHUB_TOKEN"] != "ghp_global" {
+		t.Errorf("github = %+v", github)
+	}
+	if got := cfg.MCPServers["docs"].URL; got != "https://docs.example.com/mcp" {
+		t.Errorf("docs URL = %q", got)
+	}
+}
+
+func TestResolveMCPServer(t *testing.T) {
+	cfg := &GlobalConfig{MCPServers: map[string]MCPServerConfig{
+		"github": {Command: "github-mcp", Args: []string{"stdio"}, Env: map[string]string{"GITHUB_TOKEN": "global", "LOG": "warn"}, Timeout: 20},
+	}}
+
+	// An empty override uses the global definition as is.
+	got := cfg.ResolveMCPServer("github", MCPServerConfig{})
+	if got.Command != "github-mcp" || got.Timeout != 20 || got.Env["GITHUB_TOKEN"] != "global" {
+		t.Errorf("empty override = %+v", got)
+	}
+
+	// Env is merged per key; the transport is kept.
+	got = cfg.ResolveMCPServer("github", MCPServerConfig{Env: map[string]string{"GITHUB_TOKEN": "agent"}})
+	if got.Command != "github-mcp" || got.Env["GITHUB_TOKEN"] != "agent" || got.Env["LOG"] != "warn" {
+		t.Errorf("env override = %+v", got)
+	}
+	if cfg.MCPServers["github"].Env["GITHUB_TOKEN"] != "global" {
+		t.Error("ResolveMCPServer modified the global config")
+	}
+
+	// A URL replaces the global command entirely.
+	got = cfg.ResolveMCPServer("github", MCPServerConfig{URL: "http://localhost:9000/mcp"})
+	if got.Command != "" || got.Args != nil || got.URL != "http://localhost:9000/mcp" || got.Timeout != 20 {
+		t.Errorf("url override = %+v", got)
+	}
+
+	// Servers only the agent declares resolve to the override.
+	got = cfg.ResolveMCPServer("local", MCPServerConfig{Command: "local-mcp"})
+	if got.Command != "local-mcp" {
+		t.Errorf("agent-only server = %+v", got)
+	}
+}
+
+func TestLoad_ToolResults(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+
+	writeConfigTOML(t, tmp, `
+[tool_results]
+max_bytes = 20000
+summary_model = "anthropic/claude-haiku-4-5"
+
+[tool_results.tools.call_agent]
+max_bytes = 8000
+strategy = "summarize"
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.ToolResults.MaxBytes != 20000 || cfg.ToolResults.Tools["call_agent"].Strategy != "summarize" {
+		t.Errorf("ToolResults = %+v", cfg.ToolResults)
+	}
+
+	writeConfigTOML(t, tmp, `
+[tool_results.tools.read_file]
+strategy = "drop"
+`)
+	_, err = Load()
+	if err == nil || err.Error() != `tool_results.tools.read_file.strategy must be "truncate" or "summarize", got "drop"` {
+		t.Errorf("err = %v, want invalid strategy", err)
+	}
+}
+
+func TestResolveToolResult(t *testing.T) {
+	cfg := &GlobalConfig{ToolResults: ToolResultsConfig{
+		ToolResultLimit: ToolResultLimit{MaxBytes: 20000, SummaryModel: "anthropic/claude-haiku-4-5"},
+		Tools:           map[string]ToolResultLimit{"call_agent": {MaxBytes: 8000, Strategy: StrategySummarize}},
+	}}
+
+	tests := []struct {
+		tool     string
+		override ToolResultLimit
+		want     ToolResultLimit
+	}{
+		{"read_file", ToolResultLimit{}, ToolResultLimit{MaxBytes: 20000, Strategy: StrategyTruncate, SummaryModel: "anthropic/claude-haiku-4-5"}},
+		{"call_agent", ToolResultLimit{}, ToolResultLimit{MaxBytes: 8000, Strategy: StrategySummarize, SummaryModel: "anthropic/claude-haiku-4-5"}},
+		{"call_agent", ToolResultLimit{MaxBytes: 500, SummaryModel: "openai/gpt-4o-mini"}, ToolResultLimit{MaxBytes: 500, Strategy: StrategySummarize, SummaryModel: "openai/gpt-4o-mini"}},
+	}
+	for _, tt := range tests {
+		if got := cfg.ResolveToolResult(tt.tool, tt.override); got != tt.want {
+			t.Errorf("ResolveToolResult(%q, %+v) = %+v, want %+v", tt.tool, tt.override, got, tt.want)
+		}
+	}
+
+	if got := (&GlobalConfig{}).ResolveToolResult("read_file", ToolResultLimit{}); got != (ToolResultLimit{Strategy: StrategyTruncate}) {
+		t.Errorf("empty config = %+v, want no limit", got)
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ModelPricing is the price of a model in USD per million tokens.
+// A zero CacheWrite or CacheRead price means "not set"; those tokens are then
+// charged at the Input price.
+type ModelPricing struct {
+	Input      float64 `toml:"input"`
+	Output     float64 `toml:"output"`
+	CacheWrite float64 `toml:"cache_write"`
+	CacheRead  float64 `toml:"cache_read"`
+}
+
+// DefaultPricing holds list prices for common models, keyed by
+// "provider/model" (see ResolvePricing for how keys match). Entries in the
+// [pricing] section of config.toml take precedence.
+var DefaultPricing = map[string]ModelPricing{
+	"anthropic/claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"anthropic/claude-opus-4-1":   {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"anthropic/claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+	"anthropic/claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"anthropic/claude-sonnet-4-5": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"anthropic/claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+	"anthropic/claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"anthropic/claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"anthropic/claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+
+	"openai/gpt-4o":       {Input: 2.50, Output: 10, CacheRead: 1.25},
+	"openai/gpt-4o-mini":  {Input: 0.15, Output: 0.60, CacheRead: 0.075},
+	"openai/gpt-4.1":      {Input: 2, Output: 8, CacheRead: 0.50},
+	"openai/gpt-4.1-mini": {Input: 0.40, Output: 1.60, CacheRead: 0.10},
+	"openai/gpt-4.1-nano": {Input: 0.10, Output: 0.40, CacheRead: 0.025},
+	"openai/o3":           {Input: 2, Output: 8, CacheRead: 0.50},
+	"openai/o3-pro":       {Input: 20, Output: 80},
+	"openai/o3-mini":      {Input: 1.10, Output: 4.40, CacheRead: 0.55},
+	"openai/o4-mini":      {Input: 1.10, Output: 4.40, CacheRead: 0.275},
+
+	"gemini/gemini-2.5-pro":        {Input: 1.25, Output: 10, CacheRead: 0.31},
+	"gemini/gemini-2.5-flash":      {Input: 0.30, Output: 2.50, CacheRead: 0.075},
+	"gemini/gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40, CacheRead: 0.025},
+	"gemini/gemini-2.0-flash":      {Input: 0.10, Output: 0.40, CacheRead: 0.025},
+
+	// Local models cost nothing per token.
+	"ollama/": {},
+}
+
+// datedSuffix matches the snapshot date that follows a model name, as in
+// "claude-sonnet-4-20250514" or "gpt-4o-2024-08-06", and the "latest" alias.
+var datedSuffix = regexp.MustCompile(`^-(\d{8}|\d{4}-\d{2}-\d{2}|latest)$`)
+
+// ResolvePricing returns the price of the model referenced as "provider/model".
+// A key matches the model it names and that model's dated snapshots
+// ("claude-sonnet-4-20250514"), but not other models that merely start with
+// it ("claude-sonnet-4-5"); a key ending in "/" matches every model of the
+// provider. The longest matching key wins. On a tie the config file entry
+// beats the default. The second return value is false if no price is known.
+func (c *GlobalConfig) ResolvePricing(modelRef string) (ModelPricing, bool) {
+	configKey, inConfig := longestMatch(c.Pricing, modelRef)
+	defaultKey, inDefaults := longestMatch(DefaultPricing, modelRef)
+
+	switch {
+	case inConfig && (!inDefaults || len(configKey) >= len(defaultKey)):
+		return c.Pricing[configKey], true
+	case inDefaults:
+		return DefaultPricing[defaultKey], true
+	}
+	return ModelPricing{}, false
+}
+
+// longestMatch returns the longest key in table that matches modelRef.
+func longestMatch(table map[string]ModelPricing, modelRef string) (string, bool) {
+	var best string
+	found := false
+	for key := range table {
+		if pricingKeyMatches(key, modelRef) && (!found || len(key) > len(best)) {
+			best = key
+			found = true
+		}
+	}
+	return best, found
+}
+
+// pricingKeyMatches reports whether the pricing key applies to modelRef.
+func pricingKeyMatches(key, modelRef string) bool {
+	rest, ok := strings.CutPrefix(modelRef, key)
+	if !ok {
+		return false
+	}
+	return rest == "" || strings.HasSuffix(key, "/") || datedSuffix.MatchString(rest)
+}
+
+// Cost returns the price in USD of the given token counts.
+func (p ModelPricing) Cost(input, output, cacheWrite, cacheRead int) float64 {
+	writePrice := p.CacheWrite
+	if writePrice == 0 {
+		writePrice = p.Input
+	}
+	readPrice := p.CacheRead
+	if readPrice == 0 {
+		readPrice = p.Input
+	}
+
+	return (float64(input)*p.Input +
+		float64(output)*p.Output +
+		float64(cacheWrite)*writePrice +
+		float64(cacheRead)*readPrice) / 1e6
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoad_PricingConfig(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+
+	writeConfigTOML(t, tmp, `
+[pricing."groq/llama-3.3-70b"]
+input = 0.59
+output = 0.79
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := ModelPricing{Input: 0.59, Output: 0.79}
+	if got := cfg.Pricing["groq/llama-3.3-70b"]; got != want {
+		t.Errorf("Pricing = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolvePricing(t *testing.T) {
+	cfg := &GlobalConfig{Pricing: map[string]ModelPricing{
+		"openai/gpt-4o":      {Input: 1, Output: 2},
+		"groq/llama-3.3-70b": {Input: 0.59, Output: 0.79},
+	}}
+
+	tests := []struct {
+		name      string
+		modelRef  string
+		wantInput float64
+		wantFound bool
+	}{
+		{"dated snapshot matches family", "anthropic/claude-sonnet-4-20250514", 3, true},
+		{"hyphenated date matches family", "openai/gpt-4o-2024-08-06", 1, true},
+		{"longer model name is a different model", "openai/gpt-4o-mini", 0.15, true},
+		{"newer model is not priced as its prefix", "anthropic/claude-opus-4-5-20251101", 5, true},
+		{"pro model is not priced as base", "openai/o3-pro", 20, true},
+		{"config overrides default", "openai/gpt-4o", 1, true},
+		{"config-only model", "groq/llama-3.3-70b", 0.59, true},
+		{"config key does not match other models", "groq/llama-3.3-70b-versatile", 0, false},
+		{"unpriced model of a known family", "anthropic/claude-opus-4-9", 0, false},
+		{"ollama is free", "ollama/llama3", 0, true},
+		{"unknown model", "mystery/model", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := cfg.ResolvePricing(tt.modelRef)
+			if found != tt.wantFound {
+				t.Fatalf("found = %v, want %v", found, tt.wantFound)
+			}
+			if got.Input != tt.wantInput {
+				t.Errorf("Input = %v, want %v", got.Input, tt.wantInput)
+			}
+		})
+	}
+}
+
+func TestModelPricing_Cost(t *testing.T) {
+	p := ModelPricing{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30}
+
+	got := p.Cost(1_000_000, 100_000, 200_000, 1_000_000)
+	want := 3 + 1.5 + 0.75 + 0.30
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("Cost = %v, want %v", got, want)
+	}
+}
+
+func TestModelPricing_CostCacheDefaultsToInputPrice(t *testing.T) {
+	p := ModelPricing{Input: 2, Output: 8}
+
+	got := p.Cost(0, 0, 500_000, 500_000)
+	if math.Abs(got-2) > 1e-9 {
+		t.Errorf("Cost = %v, want 2", got)
+	}
+}
@@ -0,0 +1,601 @@
+// Package engine runs agents. It resolves an agent's context, sends the
+// conversation to its model and executes the tool calls the model makes,
+// including call_agent sub-agent runs, until the model gives a final answer.
+// Top-level runs and sub-agent runs both go through Run, so they share the
+// same behavior, hooks and telemetry at every depth.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/history"
+	"github.com/jrswab/axe/internal/memory"
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/resolve"
+	"github.com/jrswab/axe/internal/tool"
+	"github.com/jrswab/axe/internal/usage"
+	"github.com/jrswab/axe/internal/xdg"
+)
+
+// DefaultMessage is the user message sent when a run is given none.
+const DefaultMessage = "Execute the task described in your instructions."
+
+// DefaultMaxDepth is the sub-agent nesting limit used when an agent does not
+// set a valid sub_agents_config.max_depth.
+const DefaultMaxDepth = 3
+
+// defaultMemoryTopK is the number of entries the "relevant" memory strategy
+// loads when the agent does not set memory.top_k.
+const defaultMemoryTopK = 5
+
+// maxConversationTurns is the safety limit for the conversation loop.
+const maxConversationTurns = 50
+
+// RunSpec describes one agent run.
+type RunSpec struct {
+	Agent        string               // Name of the agent to run
+	Config       *agent.AgentConfig   // Agent config to use instead of loading Agent's TOML, e.g. after flag overrides; may be nil
+	GlobalConfig *config.GlobalConfig // API keys, base URLs and retry defaults; nil means an empty config
+	Workdir      string               // Working directory override; applies to this run and every sub-agent it starts
+	Message      string               // User message; empty means DefaultMessage
+	Depth        int                  // Sub-agent nesting depth, 0 for a top-level run
+	MaxDepth     int                  // Sub-agent nesting limit; 0 means the agent's sub_agents_config.max_depth
+	Stream       bool                 // Stream responses from the provider
+	OnDelta      provider.StreamFunc  // Receives streamed text deltas when Stream is set; may be nil
+	OnStart      func(Start)          // Called once the run is ready to send its first request; may be nil
+	Approve      bool                 // Require approval for every tool call, at every depth
+	Approver     tool.Approver        // Decides calls that require approval; nil denies them
+	Verbose      bool
+	Stderr       io.Writer
+	Usage        *usage.Tracker // Receives the token usage of this and nested sub-agents; may be nil
+	History      *history.Run   // Run record the conversation and sub-agent runs are recorded in; may be nil
+}
+
+// Prepared is the resolved context of a run: everything that goes into the
+// first request before the provider is called.
+type Prepared struct {
+	Agent        string // Name the agent runs as: RunSpec.Agent, or Config.Name if that is empty
+	Config       *agent.AgentConfig
+	Provider     string // Provider name from Config.Model
+	Model        string // Model name from Config.Model
+	Workdir      string
+	Files        []resolve.FileContent
+	Skill        string // Skill content, empty if the agent has none
+	SystemPrompt string // System prompt including skill, files and memory
+	Memory       string // Memory entries added to the system prompt
+	MemoryPath   string
+	MemoryCount  int
+	// MemoryRanking is how the "relevant" memory strategy ranked entries:
+	// "bm25" or the embedding model. Empty when the last entries were loaded.
+	MemoryRanking  string
+	MemorySelected int // Entries picked by the "relevant" strategy
+	// SharedMemory holds the shared pools of memory.shared, in config order.
+	SharedMemory []SharedMemory
+	MaxDepth     int // Effective sub-agent nesting limit
+}
+
+// SharedMemory is a shared memory pool loaded into a run's system prompt.
+// Entries are picked with the agent's memory strategy, as for its own
+// memory.
+type SharedMemory struct {
+	Name     string // Pool name from memory.shared
+	Path     string
+	Memory   string // Entries added to the system prompt
+	Count    int
+	Selected int // Entries picked by the "relevant" strategy
+}
+
+// Start describes a run that is ready to send its first request.
+type Start struct {
+	*Prepared
+	Request  *provider.Request
+	MCPTools []provider.Tool // Tools offered by the agent's MCP servers
+}
+
+// Result is the outcome of a run. It is returned even when the run fails,
+// with whatever was reached before the failure.
+type Result struct {
+	Model     string             // The agent's configured "provider/model"
+	Request   *provider.Request  // The conversation sent to the model
+	Response  *provider.Response // The last response received
+	ToolCalls int                // Number of tool calls executed
+	Duration  time.Duration      // Time spent in the conversation loop
+}
+
+// Prepare loads the agent described by spec and resolves its working
+// directory, files, skill, system prompt and memory without calling the
+// provider. Skipped context files and memory problems are reported on
+// spec.Stderr as warnings. ctx
+// bounds the embedding requests of the "relevant" memory strategy.
+func Prepare(ctx context.Context, spec RunSpec) (*Prepared, error) {
+	// Step 1: Load agent config
+	cfg := spec.Config
+	if cfg == nil {
+		var err error
+		cfg, err = agent.Load(spec.Agent)
+		if err != nil {
+			return nil, &RunError{Category: ErrCategoryConfig, Err: fmt.Errorf("failed to load agent %q: %w", spec.Agent, err)}
+		}
+	}
+
+	// Step 2: Parse model
+	provName, modelName, err := ParseModel(cfg.Model)
+	if err != nil {
+		return nil, &RunError{Category: ErrCategoryAgent, Err: err}
+	}
+
+	// Step 3: Resolve working directory and file globs
+	workdir := resolve.Workdir(spec.Workdir, cfg.Workdir)
+	files, skipped, err := resolve.Files(cfg.Files, workdir)
+	if err != nil {
+		return nil, &RunError{Category: ErrCategoryConfig, Err: err}
+	}
+	for _, err := range skipped {
+		spec.warnf("context file skipped: %v", err)
+	}
+	files = dropOversizedImages(&spec, provName, files)
+
+	// Step 4: Load skill
+	configDir, err := xdg.GetConfigDir()
+	if err != nil {
+		return nil, &RunError{Category: ErrCategoryConfig, Err: err}
+	}
+	skill, err := resolve.Skill(cfg.Skill, configDir)
+	if err != nil {
+		return nil, &RunError{Category: ErrCategoryConfig, Err: err}
+	}
+
+	p := &Prepared{
+		Agent:        spec.Agent,
+		Config:       cfg,
+		Provider:     provName,
+		Model:        modelName,
+		Workdir:      workdir,
+		Files:        files,
+		Skill:        skill,
+		SystemPrompt: resolve.BuildSystemPrompt(cfg.SystemPrompt, skill, files),
+		MaxDepth:     spec.MaxDepth,
+	}
+	if p.Agent == "" {
+		p.Agent = cfg.Name
+	}
+	if p.MaxDepth <= 0 {
+		p.MaxDepth = DefaultMaxDepth
+		if cfg.SubAgentsConf.MaxDepth > 0 && cfg.SubAgentsConf.MaxDepth <= 5 {
+			p.MaxDepth = cfg.SubAgentsConf.MaxDepth
+		}
+	}
+
+	// Step 5: Memory — load entries into system prompt
+	if cfg.Memory.Enabled {
+		spec.loadMemory(ctx, p)
+	}
+
+	return p, nil
+}
+
+// loadMemory adds the agent's recent or relevant memory entries, then those
+// of its shared pools, to p's system prompt and warns when the memory file
+// has reached max_entries.
+func (s *RunSpec) loadMemory(ctx context.Context, p *Prepared) {
+	cfg := p.Config
+	path, err := memory.FilePath(p.Agent, cfg.Memory.Path)
+	if err != nil {
+		s.warnf("failed to load memory for %q: %v", p.Agent, err)
+		return
+	}
+	p.MemoryPath = path
+
+	p.Memory, p.MemorySelected, err = s.selectMemory(ctx, p, path)
+	if err != nil {
+		s.warnf("failed to load memory for %q: %v", p.Agent, err)
+	} else if p.Memory != "" {
+		p.SystemPrompt += "\n\n---\n\n## Memory\n\n" + p.Memory
+	}
+
+	p.MemoryCount, err = memory.CountEntries(path)
+	if err != nil {
+		s.warnf("failed to load memory for %q: %v", p.Agent, err)
+	} else if cfg.Memory.MaxEntries > 0 && p.MemoryCount >= cfg.Memory.MaxEntries {
+		s.warnf("agent %q memory has %d entries (max_entries: %d). Run 'axe gc %s' to trim.", p.Agent, p.MemoryCount, cfg.Memory.MaxEntries, p.Agent)
+	}
+
+	for _, name := range cfg.Memory.Shared {
+		pool := SharedMemory{Name: name}
+		pool.Path, err = memory.SharedFilePath(name)
+		if err == nil {
+			pool.Memory, pool.Selected, err = s.selectMemory(ctx, p, pool.Path)
+		}
+		if err == nil {
+			pool.Count, err = memory.CountEntries(pool.Path)
+		}
+		if err != nil {
+			s.warnf("failed to load shared memory %q for %q: %v", name, p.Agent, err)
+			continue
+		}
+		if pool.Memory != "" {
+			p.SystemPrompt += "\n\n---\n\n## Shared Memory: " + name + "\n\n" + pool.Memory
+		}
+		p.SharedMemory = append(p.SharedMemory, pool)
+	}
+}
+
+// selectMemory returns the entries of the memory file at path that go into
+// p's system prompt: the last last_n, or, with the "relevant" strategy, the
+// entries most relevant to the run's message along with their number.
+func (s *RunSpec) selectMemory(ctx context.Context, p *Prepared, path string) (string, int, error) {
+	if p.Config.Memory.Strategy != "relevant" || strings.TrimSpace(s.Message) == "" {
+		text, err := memory.LoadEntries(path, p.Config.Memory.LastN)
+		return text, 0, err
+	}
+	entries, err := s.loadRelevantMemory(ctx, p, path)
+	if err != nil {
+		return "", 0, err
+	}
+	return memory.Render(entries), len(entries), nil
+}
+
+// loadRelevantMemory returns the entries of the memory file at path most
+// relevant to the run's message, ranked by the agent's embedding model.
+// Without one, or once it has failed, entries are ranked with BM25
+// instead. p.MemoryRanking records the ranking used.
+func (s *RunSpec) loadRelevantMemory(ctx context.Context, p *Prepared, path string) ([]memory.Entry, error) {
+	cfg := p.Config.Memory
+	topK := cfg.TopK
+	if topK == 0 {
+		topK = defaultMemoryTopK
+	}
+
+	if cfg.EmbeddingModel != "" && p.MemoryRanking != "bm25" {
+		embedder, err := s.newEmbedder(cfg.EmbeddingModel)
+		var entries []memory.Entry
+		if err == nil {
+			entries, err = memory.LoadRelevant(ctx, path, s.Message, topK, embedder)
+		}
+		if err == nil {
+			p.MemoryRanking = cfg.EmbeddingModel
+			return entries, nil
+		}
+		s.warnf("failed to rank memory for %q with %s, using keyword ranking: %v", p.Agent, cfg.EmbeddingModel, err)
+	}
+	p.MemoryRanking = "bm25"
+	return memory.LoadRelevant(ctx, path, s.Message, topK, nil)
+}
+
+// newEmbedder returns a memory.Embedder for the "provider/model" ref.
+func (s *RunSpec) newEmbedder(ref string) (*memory.Embedder, error) {
+	globalCfg := s.GlobalConfig
+	if globalCfg == nil {
+		globalCfg = &config.GlobalConfig{}
+	}
+	prov, modelName, err := newModelProvider(ref, globalCfg)
+	if err != nil {
+		return nil, err
+	}
+	embedder, ok := prov.(provider.Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider of %q does not support embeddings", ref)
+	}
+	return &memory.Embedder{
+		Model: ref,
+		Embed: func(ctx context.Context, texts []string) ([][]float64, error) {
+			return embedder.Embed(ctx, modelName, texts)
+		},
+	}, nil
+}
+
+// Run runs the agent described by spec until its model answers without
+// calling a tool, and returns the final response. Errors setting up the run
+// are *RunError values; errors from the provider are returned unchanged.
+func Run(ctx context.Context, spec RunSpec) (res Result, err error) {
+	p, err := Prepare(ctx, spec)
+	if err != nil {
+		return res, err
+	}
+	cfg := p.Config
+	res.Model = cfg.Model
+
+	globalCfg := spec.GlobalConfig
+	if globalCfg == nil {
+		globalCfg = &config.GlobalConfig{}
+	}
+
+	// Step 6: Resolve API keys and create the provider chain (primary model
+	// plus fallbacks, each with retries for transient errors)
+	prov, err := spec.newProviderChain(cfg, globalCfg)
+	if err != nil {
+		return res, err
+	}
+
+	// Step 7: Build request
+	message := spec.Message
+	if message == "" {
+		message = DefaultMessage
+	}
+	req := &provider.Request{
+		Model:       p.Model,
+		System:      p.SystemPrompt,
+		Messages:    []provider.Message{{Role: "user", Content: message, Parts: resolve.Parts(p.Files)}},
+		Temperature: cfg.Params.Temperature,
+		MaxTokens:   cfg.Params.MaxTokens,
+	}
+
+	// Step 8: Register the agent's tools: call_agent if it has sub_agents and
+	// depth allows, plus the tools it enables
+	var callAgent tool.Executor
+	if spec.Depth < p.MaxDepth {
+		callOpts := CallOptions{
+			AllowedAgents: cfg.SubAgents,
+			Depth:         spec.Depth,
+			MaxDepth:      p.MaxDepth,
+			Timeout:       cfg.SubAgentsConf.Timeout,
+			Workdir:       spec.Workdir,
+			Approve:       spec.Approve,
+			Approver:      spec.Approver,
+			GlobalConfig:  globalCfg,
+			Verbose:       spec.Verbose,
+			Stderr:        spec.Stderr,
+			Usage:         spec.Usage,
+			History:       spec.History,
+		}
+		callAgent = func(ctx context.Context, call provider.ToolCall, _ tool.ExecuteOptions) provider.ToolResult {
+			return CallAgent(ctx, call, callOpts)
+		}
+	}
+	registry, err := tool.ForAgent(cfg, callAgent)
+	if err != nil {
+		return res, &RunError{Category: ErrCategoryConfig, Err: fmt.Errorf("agent %q: %w", p.Agent, err)}
+	}
+
+	// Step 9: Connect to the agent's MCP servers and offer their tools. The
+	// servers stay connected until the run ends.
+	servers, err := tool.ResolveMCPServers(cfg.MCPServers, globalCfg)
+	if err != nil {
+		return res, &RunError{Category: ErrCategoryConfig, Err: fmt.Errorf("agent %q: %w", p.Agent, err)}
+	}
+	mcpTools, err := tool.ConnectMCP(ctx, servers, registry)
+	if err != nil {
+		return res, &RunError{Category: ErrCategoryAPI, Err: fmt.Errorf("agent %q: %w", p.Agent, err)}
+	}
+	defer mcpTools.Close()
+	req.Tools = registry.Definitions()
+	res.Request = req
+
+	if spec.OnStart != nil {
+		spec.OnStart(Start{Prepared: p, Request: req, MCPTools: mcpTools.Definitions()})
+	}
+
+	send := prov.Send
+	if spec.Stream {
+		send = func(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+			return provider.Stream(ctx, prov, req, spec.OnDelta)
+		}
+	}
+
+	execOpts := tool.ExecuteOptions{
+		Workdir:    p.Workdir,
+		RunCommand: cfg.RunCommand,
+		HTTPGet:    cfg.HTTPGet,
+		Verbose:    spec.Verbose,
+		Stderr:     spec.Stderr,
+	}
+
+	// Parallel tool execution defaults to true; *bool distinguishes "not set"
+	// from an explicit false in the TOML.
+	parallel := cfg.SubAgentsConf.Parallel == nil || *cfg.SubAgentsConf.Parallel
+
+	// Step 10: Conversation loop (a single request when there are no tools)
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	// The agent's own token usage, excluding sub-agents, for its memory entry
+	var tokens usage.Counts
+	for turn := 1; turn <= maxConversationTurns; turn++ {
+		if len(req.Tools) > 0 {
+			pending := 0
+			for _, m := range req.Messages {
+				pending += len(m.ToolResults)
+			}
+			spec.logf(fmt.Sprintf("turn %d", turn), "Sending request (%d messages, %d tool calls pending)", len(req.Messages), pending)
+		}
+
+		resp, err := send(ctx, req)
+		if err != nil {
+			return res, err
+		}
+		res.Response = resp
+		spec.Usage.Record(p.Agent, answeredBy(resp, cfg.Model), resp)
+		tokens.Add(usage.FromResponse(resp))
+		spec.History.Record(resp)
+		if len(req.Tools) > 0 {
+			spec.logf(fmt.Sprintf("turn %d", turn), "Received response: %s (%d tool calls)", resp.StopReason, len(resp.ToolCalls))
+		}
+
+		// No tool calls, or no tools to run them with: the conversation is done
+		if len(resp.ToolCalls) == 0 || len(req.Tools) == 0 {
+			// Step 11: Memory — append entry after successful response
+			if cfg.Memory.Enabled {
+				spec.appendMemory(p, memory.Entry{
+					Task:         message,
+					Result:       resp.Content,
+					Model:        answeredBy(resp, cfg.Model),
+					InputTokens:  tokens.InputTokens,
+					OutputTokens: tokens.OutputTokens,
+					RunID:        spec.History.RunID(),
+				})
+			}
+			return res, nil
+		}
+		// Calls that require approval wait for it here, one at a time, before
+		// any of this turn's calls run. Denied calls are answered with the
+		// reason; edited arguments replace the model's.
+		results := make([]provider.ToolResult, len(resp.ToolCalls))
+		var approved []provider.ToolCall
+		var approvedIdx []int
+		for i, tc := range resp.ToolCalls {
+			if registry.Has(tc.Name) && spec.requiresApproval(cfg, tc.Name) {
+				d := spec.approve(ctx, p.Agent, tc)
+				if !d.Approved {
+					results[i] = provider.ToolResult{CallID: tc.ID, Content: "Tool call denied: " + d.Reason, IsError: true}
+					continue
+				}
+				if d.Arguments != nil {
+					resp.ToolCalls[i].Arguments = d.Arguments
+				}
+			}
+			approved = append(approved, resp.ToolCalls[i])
+			approvedIdx = append(approvedIdx, i)
+		}
+
+		req.Messages = append(req.Messages, provider.Message{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for i, r := range executeToolCalls(ctx, registry, approved, execOpts, parallel) {
+			results[approvedIdx[i]] = r
+		}
+		spec.limitResults(ctx, p, globalCfg, resp.ToolCalls, results)
+		res.ToolCalls += len(resp.ToolCalls)
+		req.Messages = append(req.Messages, provider.Message{
+			Role:        "tool",
+			ToolResults: results,
+		})
+	}
+
+	return res, &RunError{Category: ErrCategoryAgent, Err: fmt.Errorf("agent exceeded maximum conversation turns (%d)", maxConversationTurns)}
+}
+
+// requiresApproval reports whether calls of the named tool must be approved
+// before they run.
+func (s *RunSpec) requiresApproval(cfg *agent.AgentConfig, name string) bool {
+	return s.Approve || cfg.ToolsConf[name].RequireApproval
+}
+
+// approve asks spec.Approver whether the agent may make call. Without an
+// Approver the call is denied, so approval fails closed. Timeouts are
+// paused while the Approver waits, so a person's time does not count
+// against the run.
+func (s *RunSpec) approve(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision {
+	if s.Approver == nil {
+		return tool.Decision{Reason: "approval is required, but there is no terminal to ask and no approval policy"}
+	}
+	resume := PauseTimeouts(ctx)
+	d := s.Approver.Approve(ctx, agentName, call)
+	resume()
+	if !d.Approved {
+		s.logf("approve", "%s denied: %s", call.Name, d.Reason)
+	}
+	return d
+}
+
+// appendMemory records the run in the agent's memory file and in each of
+// its shared pools, where the entry is tagged with the agent's name.
+func (s *RunSpec) appendMemory(p *Prepared, entry memory.Entry) {
+	path, err := memory.FilePath(p.Agent, p.Config.Memory.Path)
+	if err == nil {
+		err = memory.Append(path, entry)
+	}
+	if err != nil {
+		s.warnf("failed to save memory for %q: %v", p.Agent, err)
+	}
+
+	shared := entry
+	shared.Tags = append(append([]string(nil), entry.Tags...), memory.AgentTag(p.Agent))
+	for _, name := range p.Config.Memory.Shared {
+		path, err := memory.SharedFilePath(name)
+		if err == nil {
+			err = memory.Append(path, shared)
+		}
+		if err != nil {
+			s.warnf("failed to save shared memory %q for %q: %v", name, p.Agent, err)
+		}
+	}
+}
+
+// executeToolCalls dispatches tool calls and returns results in call order.
+// When parallel is true and there are multiple calls, they run concurrently.
+func executeToolCalls(ctx context.Context, registry *tool.Registry, toolCalls []provider.ToolCall, opts tool.ExecuteOptions, parallel bool) []provider.ToolResult {
+	results := make([]provider.ToolResult, len(toolCalls))
+
+	if len(toolCalls) == 1 || !parallel {
+		for i, tc := range toolCalls {
+			results[i] = registry.Execute(ctx, tc, opts)
+		}
+		return results
+	}
+
+	type indexedResult struct {
+		index  int
+		result provider.ToolResult
+	}
+	ch := make(chan indexedResult, len(toolCalls))
+	for i, tc := range toolCalls {
+		go func(idx int, call provider.ToolCall) {
+			ch <- indexedResult{index: idx, result: registry.Execute(ctx, call, opts)}
+		}(i, tc)
+	}
+	for range toolCalls {
+		ir := <-ch
+		results[ir.index] = ir.result
+	}
+	return results
+}
+
+// logf writes a verbose progress line tagged with kind, e.g. "[retry] ...".
+// Lines from sub-agents are also tagged with the agent's name.
+func (s *RunSpec) logf(kind, format string, args ...any) {
+	if !s.Verbose || s.Stderr == nil {
+		return
+	}
+	fmt.Fprint(s.Stderr, s.tag(kind))
+	fmt.Fprintf(s.Stderr, format+"\n", args...)
+}
+
+// warnf writes a warning to stderr, whether or not the run is verbose.
+func (s *RunSpec) warnf(format string, args ...any) {
+	if s.Stderr == nil {
+		return
+	}
+	prefix := "Warning: "
+	if s.Depth > 0 {
+		prefix = "[sub-agent] " + prefix
+	}
+	fmt.Fprintf(s.Stderr, prefix+format+"\n", args...)
+}
+
+// tag returns the log prefix for kind: "[kind] " for a top-level run and
+// `[sub-agent] [kind] "name" ` for a sub-agent.
+func (s *RunSpec) tag(kind string) string {
+	if s.Depth == 0 {
+		return "[" + kind + "] "
+	}
+	name := s.Agent
+	if s.Config != nil {
+		name = s.Config.Name
+	}
+	return fmt.Sprintf("[sub-agent] [%s] %q ", kind, name)
+}
+
+// dropOversizedImages removes the images in files that the provider would
+// reject for their size, with a warning naming each one.
+func dropOversizedImages(spec *RunSpec, provName string, files []resolve.FileContent) []resolve.FileContent {
+	if provName != "anthropic" {
+		return files
+	}
+	kept := files[:0:0]
+	for _, f := range files {
+		if strings.HasPrefix(f.MediaType, "image/") && len(f.Data) > provider.AnthropicMaxImageSize {
+			spec.warnf("context file skipped: %s is %d bytes; anthropic accepts images up to %d bytes", f.Path, len(f.Data), provider.AnthropicMaxImageSize)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
@@ -0,0 +1,1528 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/history"
+	"github.com/jrswab/axe/internal/memory"
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/tool"
+)
+
+// helper: set up a temp XDG config dir with an agents/ subdirectory
+func setupToolTestAgentsDir(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	agentsDir := filepath.Join(tmpDir, "axe", "agents")
+	if err := os.MkdirAll(agentsDir, 0755); err != nil {
+		t.Fatalf("failed to create agents dir: %v", err)
+	}
+	return agentsDir
+}
+
+// anthropicSystem returns the system prompt text from a decoded Anthropic
+// request body, where it is sent as an array of text blocks.
+func anthropicSystem(body map[string]interface{}) (string, bool) {
+	if _, ok := body["system"].([]interface{}); !ok {
+		return "", false
+	}
+	return anthropicText(body["system"])
+}
+
+// anthropicText returns the text of a decoded Anthropic message content,
+// which is a string or, for a message marked for caching, an array of
+// blocks.
+func anthropicText(content interface{}) (string, bool) {
+	if s, ok := content.(string); ok {
+		return s, true
+	}
+	blocks, ok := content.([]interface{})
+	if !ok {
+		return "", false
+	}
+	var text string
+	for _, b := range blocks {
+		block, _ := b.(map[string]interface{})
+		s, _ := block["text"].(string)
+		text += s
+	}
+	return text, true
+}
+
+func writeToolTestAgent(t *testing.T, agentsDir, name, content string) {
+	t.Helper()
+	path := filepath.Join(agentsDir, name+".toml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write agent file: %v", err)
+	}
+}
+
+// --- Phase 7a: CallAgent argument validation tests ---
+
+func TestCallAgent_EmptyAgentName(t *testing.T) {
+	call := provider.ToolCall{
+		ID:        "test-1",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for empty agent name")
+	}
+	want := `call_agent error: "agent" argument is required`
+	if result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+	if result.CallID != "test-1" {
+		t.Errorf("CallID = %q, want %q", result.CallID, "test-1")
+	}
+}
+
+func TestCallAgent_EmptyTask(t *testing.T) {
+	call := provider.ToolCall{
+		ID:        "test-2",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": ""}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for empty task")
+	}
+	want := `call_agent error: "task" argument is required`
+	if result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+}
+
+func TestCallAgent_InvalidArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{"missing task", `{"agent": "helper"}`, `call_agent error: invalid arguments: $: missing required property "task"`},
+		{"wrong type", `{"agent": 7, "task": "do something"}`, `call_agent error: invalid arguments: $.agent: expected string, got integer`},
+		{"not json", `not json`, `call_agent error: invalid arguments: invalid JSON`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call := provider.ToolCall{
+				ID:        "test-invalid",
+				Name:      tool.CallAgentToolName,
+				Arguments: json.RawMessage(tt.args),
+			}
+			opts := CallOptions{
+				AllowedAgents: []string{"helper"},
+				MaxDepth:      3,
+			}
+			result := CallAgent(context.Background(), call, opts)
+			if !result.IsError {
+				t.Fatal("expected IsError=true for invalid arguments")
+			}
+			if !strings.HasPrefix(result.Content, tt.want) {
+				t.Errorf("Content = %q, want prefix %q", result.Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallAgent_AgentNotAllowed(t *testing.T) {
+	call := provider.ToolCall{
+		ID:        "test-3",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "unknown", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper", "runner"},
+		MaxDepth:      3,
+		Depth:         0,
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for unknown agent")
+	}
+	want := `call_agent error: agent "unknown" is not in this agent's sub_agents list`
+	if result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+}
+
+func TestCallAgent_DepthLimitReached(t *testing.T) {
+	call := provider.ToolCall{
+		ID:        "test-4",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         3,
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for depth limit")
+	}
+	want := fmt.Sprintf("call_agent error: maximum sub-agent depth (%d) reached", 3)
+	if result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+}
+
+// --- Phase 7b: Sub-Agent Loading and Execution tests ---
+
+func TestCallAgent_AgentNotFound(t *testing.T) {
+	_ = setupToolTestAgentsDir(t)
+
+	call := provider.ToolCall{
+		ID:        "test-5",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "nonexistent", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"nonexistent"},
+		MaxDepth:      3,
+		Depth:         0,
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for missing agent")
+	}
+	if !strings.Contains(result.Content, "failed to load agent") {
+		t.Errorf("Content = %q, want to contain 'failed to load agent'", result.Content)
+	}
+	if result.CallID != "test-5" {
+		t.Errorf("CallID = %q, want %q", result.CallID, "test-5")
+	}
+}
+
+func TestCallAgent_Success(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	// Start mock provider server that returns a simple text response
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]interface{}{
+			"id":    "msg_123",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Sub-agent result here"},
+			},
+			"stop_reason": "end_turn",
+			"usage": map[string]int{
+				"input_tokens":  10,
+				"output_tokens": 5,
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	// Write sub-agent TOML pointing at our test server
+	toml := fmt.Sprintf(`name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+system_prompt = "You are a helper."
+`)
+	writeToolTestAgent(t, agentsDir, "helper", toml)
+
+	// Set API key env var for anthropic
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	// Set base URL to our test server
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-6",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "say hello"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected IsError=false, got error: %s", result.Content)
+	}
+	if result.Content != "Sub-agent result here" {
+		t.Errorf("Content = %q, want %q", result.Content, "Sub-agent result here")
+	}
+	if result.CallID != "test-6" {
+		t.Errorf("CallID = %q, want %q", result.CallID, "test-6")
+	}
+}
+
+func TestCallAgent_WithContext(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	var receivedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		resp := map[string]interface{}{
+			"id":    "msg_123",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "done"},
+			},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-7",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "analyze code", "context": "The code is in main.go"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected IsError=false, got error: %s", result.Content)
+	}
+
+	// Verify the user message contains both task and context
+	messages, ok := receivedBody["messages"].([]interface{})
+	if !ok || len(messages) == 0 {
+		t.Fatal("no messages in request body")
+	}
+	firstMsg, ok := messages[0].(map[string]interface{})
+	if !ok {
+		t.Fatal("first message is not a map")
+	}
+	content, _ := anthropicText(firstMsg["content"])
+	if !strings.Contains(content, "Task: analyze code") {
+		t.Errorf("user message missing task: %q", content)
+	}
+	if !strings.Contains(content, "Context:\nThe code is in main.go") {
+		t.Errorf("user message missing context: %q", content)
+	}
+}
+
+func TestCallAgent_WithoutContext(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	var receivedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		resp := map[string]interface{}{
+			"id":    "msg_123",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "done"},
+			},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-8",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "analyze code"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected IsError=false, got error: %s", result.Content)
+	}
+
+	// Verify the user message contains only task (no context section)
+	messages, ok := receivedBody["messages"].([]interface{})
+	if !ok || len(messages) == 0 {
+		t.Fatal("no messages in request body")
+	}
+	firstMsg, ok := messages[0].(map[string]interface{})
+	if !ok {
+		t.Fatal("first message is not a map")
+	}
+	content, _ := anthropicText(firstMsg["content"])
+	if !strings.Contains(content, "Task: analyze code") {
+		t.Errorf("user message missing task: %q", content)
+	}
+	if strings.Contains(content, "Context:") {
+		t.Errorf("user message should not contain Context section: %q", content)
+	}
+}
+
+// --- Phase 7c: Error Handling tests ---
+
+func TestCallAgent_APIError(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"type":"error","error":{"type":"server_error","message":"Internal server error"}}`))
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-9",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for API error")
+	}
+	if !strings.Contains(result.Content, "sub-agent") {
+		t.Errorf("Content = %q, want to contain 'sub-agent'", result.Content)
+	}
+	if !strings.Contains(result.Content, "You may retry or proceed without this result") {
+		t.Errorf("Content missing retry suggestion: %q", result.Content)
+	}
+}
+
+func TestCallAgent_RecordsHistory(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"content": [{"type": "text", "text": "recorded result"}], "stop_reason": "end_turn",
+			"usage": {"input_tokens": 7, "output_tokens": 2}
+		}`))
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	parent := history.New("parent")
+	call := provider.ToolCall{
+		ID:        "test-hist",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "remember this"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		GlobalConfig:  &config.GlobalConfig{},
+		History:       parent,
+	}
+	if result := CallAgent(context.Background(), call, opts); result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+
+	if len(parent.SubAgents) != 1 {
+		t.Fatalf("recorded %d sub-agent runs, want 1", len(parent.SubAgents))
+	}
+	sub := parent.SubAgents[0]
+	if sub.Agent != "helper" || sub.CallID != "test-hist" || sub.Model != "anthropic/claude-sonnet-4-20250514" {
+		t.Errorf("sub-agent run = agent %q, call %q, model %q", sub.Agent, sub.CallID, sub.Model)
+	}
+	if len(sub.Messages) != 2 || sub.Messages[0].Content != "Task: remember this" || sub.Output != "recorded result" {
+		t.Errorf("sub-agent messages = %+v, output %q", sub.Messages, sub.Output)
+	}
+	if sub.Usage.InputTokens != 7 || sub.Error != "" {
+		t.Errorf("sub-agent usage = %+v, error %q", sub.Usage, sub.Error)
+	}
+}
+
+func TestCallAgent_RecordsFailedHistory(t *testing.T) {
+	_ = setupToolTestAgentsDir(t)
+
+	parent := history.New("parent")
+	call := provider.ToolCall{
+		ID:        "test-hist-fail",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "missing", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"missing"},
+		MaxDepth:      3,
+		History:       parent,
+	}
+	CallAgent(context.Background(), call, opts)
+
+	if len(parent.SubAgents) != 1 {
+		t.Fatalf("recorded %d sub-agent runs, want 1", len(parent.SubAgents))
+	}
+	if !strings.Contains(parent.SubAgents[0].Error, "failed to load agent") {
+		t.Errorf("sub-agent error = %q, want the load failure", parent.SubAgents[0].Error)
+	}
+}
+
+// TestCallAgent_DepthLimitNoTools verifies that a sub-agent at the depth limit
+// runs without tools injected, even when the sub-agent has sub_agents configured.
+// This tests Req 10.3: tools are only injected when newDepth < MaxDepth.
+func TestCallAgent_DepthLimitNoTools(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	var receivedBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, _ := io.ReadAll(r.Body)
+		receivedBody = string(bodyBytes)
+		resp := map[string]interface{}{
+			"id":    "msg_depth",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "depth-limited result"},
+			},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	// Sub-agent has sub_agents configured, but depth should prevent tool injection
+	writeToolTestAgent(t, agentsDir, "deep-helper", `name = "deep-helper"
+model = "anthropic/claude-sonnet-4-20250514"
+sub_agents = ["another-agent"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-depth-tools",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "deep-helper", "task": "do something"}`),
+	}
+
+	// Depth=2, MaxDepth=3: newDepth will be 3, which is NOT < 3, so no tools should be injected
+	opts := CallOptions{
+		AllowedAgents: []string{"deep-helper"},
+		MaxDepth:      3,
+		Depth:         2,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected IsError=false, got error: %s", result.Content)
+	}
+	if result.Content != "depth-limited result" {
+		t.Errorf("Content = %q, want %q", result.Content, "depth-limited result")
+	}
+
+	// The request sent to the mock server should NOT contain tools
+	if strings.Contains(receivedBody, `"tools"`) {
+		t.Errorf("expected no 'tools' in request body when at depth limit, but found tools in: %s", receivedBody)
+	}
+}
+
+func TestCallAgent_Timeout(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(3 * time.Second)
+		resp := map[string]interface{}{
+			"id":          "msg_123",
+			"type":        "message",
+			"model":       "claude-sonnet-4-20250514",
+			"role":        "assistant",
+			"content":     []map[string]interface{}{{"type": "text", "text": "done"}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-10",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		Timeout:       1, // 1 second timeout
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for timeout")
+	}
+	if !strings.Contains(result.Content, "sub-agent") {
+		t.Errorf("Content = %q, want to contain 'sub-agent'", result.Content)
+	}
+}
+
+// --- Phase 5a: Sub-Agent Memory Integration tests ---
+
+func TestCallAgent_MemoryEnabled_LoadsIntoPrompt(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	// Set up XDG_DATA_HOME for memory file
+	dataDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataDir)
+
+	// Pre-populate the sub-agent's memory file
+	memDir := filepath.Join(dataDir, "axe", "memory")
+	if err := os.MkdirAll(memDir, 0755); err != nil {
+		t.Fatalf("failed to create memory dir: %v", err)
+	}
+	memContent := "## 2026-02-28T10:00:00Z\n**Task:** previous task\n**Result:** previous result\n\n"
+	memPath := filepath.Join(memDir, "helper.md")
+	if err := os.WriteFile(memPath, []byte(memContent), 0644); err != nil {
+		t.Fatalf("failed to write memory file: %v", err)
+	}
+
+	// Mock provider that captures the system prompt from the request body
+	var receivedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&receivedBody)
+		resp := map[string]interface{}{
+			"id":    "msg_mem",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "done"},
+			},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	// Write sub-agent config with memory enabled
+	toml := `name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+system_prompt = "You are a helper."
+
+[memory]
+enabled = true
+`
+	writeToolTestAgent(t, agentsDir, "helper", toml)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-mem-load",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected IsError=false, got error: %s", result.Content)
+	}
+
+	// Verify system prompt contains the memory section
+	system, ok := anthropicSystem(receivedBody)
+	if !ok {
+		t.Fatal("no 'system' field in request body")
+	}
+	if !strings.Contains(system, "## Memory") {
+		t.Errorf("system prompt missing '## Memory' section: %q", system)
+	}
+	if !strings.Contains(system, "previous task") {
+		t.Errorf("system prompt missing memory entry content: %q", system)
+	}
+	if !strings.Contains(system, "previous result") {
+		t.Errorf("system prompt missing memory entry result: %q", system)
+	}
+}
+
+func TestCallAgent_MemoryEnabled_AppendsEntry(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	// Set up XDG_DATA_HOME for memory file
+	dataDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataDir)
+
+	// Override Now for deterministic timestamps
+	origNow := memory.Now
+	memory.Now = func() time.Time {
+		return time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC)
+	}
+	defer func() { memory.Now = origNow }()
+
+	// Mock provider returning a known response
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]interface{}{
+			"id":    "msg_append",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "Sub-agent completed the task"},
+			},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	// Write sub-agent config with memory enabled
+	toml := `name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+system_prompt = "You are a helper."
+
+[memory]
+enabled = true
+`
+	writeToolTestAgent(t, agentsDir, "helper", toml)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-mem-append",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do the thing"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected IsError=false, got error: %s", result.Content)
+	}
+
+	// Verify memory file was created with the correct entry
+	memPath := filepath.Join(dataDir, "axe", "memory", "helper.jsonl")
+	content, err := memory.LoadEntries(memPath, 0)
+	if err != nil || content == "" {
+		t.Fatalf("failed to read memory file: %v", err)
+	}
+
+	if !strings.Contains(content, "## 2026-02-28T12:00:00Z") {
+		t.Errorf("memory file missing timestamp: %q", content)
+	}
+	if !strings.Contains(content, "**Task:** Task: do the thing") {
+		t.Errorf("memory file missing task: %q", content)
+	}
+	if !strings.Contains(content, "**Result:** Sub-agent completed the task") {
+		t.Errorf("memory file missing result: %q", content)
+	}
+}
+
+func TestCallAgent_SharedMemory(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+	dataDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]interface{}{
+			"id":    "msg_shared",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "found it"},
+			},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	names := []string{"alpha", "beta", "gamma", "delta"}
+	for _, name := range names {
+		writeToolTestAgent(t, agentsDir, name, `name = "`+name+`"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[memory]
+enabled = true
+shared = ["project-x"]
+`)
+	}
+
+	// Sibling sub-agents append to the pool at the same time
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			call := provider.ToolCall{
+				ID:        "call-" + name,
+				Name:      tool.CallAgentToolName,
+				Arguments: json.RawMessage(`{"agent": "` + name + `", "task": "search ` + name + `"}`),
+			}
+			opts := CallOptions{AllowedAgents: names, MaxDepth: 3, GlobalConfig: &config.GlobalConfig{}}
+			if result := CallAgent(context.Background(), call, opts); result.IsError {
+				t.Errorf("%s: unexpected error: %s", name, result.Content)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	poolPath := filepath.Join(dataDir, "axe", "memory", "shared", "project-x.jsonl")
+	entries, err := memory.Load(poolPath)
+	if err != nil {
+		t.Fatalf("failed to load shared pool: %v", err)
+	}
+	authors := map[string]bool{}
+	for _, e := range entries {
+		if len(e.Tags) == 1 && e.Task == "Task: search "+strings.TrimPrefix(e.Tags[0], "agent:") {
+			authors[e.Tags[0]] = true
+		}
+	}
+	if len(entries) != len(names) || len(authors) != len(names) {
+		t.Fatalf("shared pool = %+v, want one tagged entry per agent", entries)
+	}
+	if own, _ := memory.Load(filepath.Join(dataDir, "axe", "memory", "alpha.jsonl")); len(own) != 1 || len(own[0].Tags) != 0 {
+		t.Errorf("own memory = %+v, want one untagged entry", own)
+	}
+
+	// Every agent of the pool sees the others' entries
+	cfg := &agent.AgentConfig{
+		Name:   "epsilon",
+		Model:  "anthropic/claude-sonnet-4-20250514",
+		Memory: agent.MemoryConfig{Enabled: true, Shared: []string{"project-x", "empty"}},
+	}
+	p, err := Prepare(context.Background(), RunSpec{Config: cfg, Message: "next"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.SharedMemory) != 2 || p.SharedMemory[0].Count != len(names) || p.SharedMemory[1].Count != 0 {
+		t.Fatalf("SharedMemory = %+v", p.SharedMemory)
+	}
+	if !strings.Contains(p.SystemPrompt, "## Shared Memory: project-x\n\n") || !strings.Contains(p.SystemPrompt, "**Tags:** agent:beta") {
+		t.Errorf("system prompt missing shared memory:\n%s", p.SystemPrompt)
+	}
+	if strings.Contains(p.SystemPrompt, "## Shared Memory: empty") {
+		t.Error("system prompt has a section for an empty pool")
+	}
+}
+
+func TestCallAgent_MemoryDisabled_NoFileCreated(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	// Set up XDG_DATA_HOME for memory file
+	dataDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataDir)
+
+	// Mock provider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]interface{}{
+			"id":    "msg_nomem",
+			"type":  "message",
+			"model": "claude-sonnet-4-20250514",
+			"role":  "assistant",
+			"content": []map[string]interface{}{
+				{"type": "text", "text": "done"},
+			},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	// Write sub-agent config with memory DISABLED (default)
+	toml := `name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+system_prompt = "You are a helper."
+`
+	writeToolTestAgent(t, agentsDir, "helper", toml)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-mem-disabled",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected IsError=false, got error: %s", result.Content)
+	}
+
+	// Verify no memory file was created
+	memPath := filepath.Join(dataDir, "axe", "memory", "helper.md")
+	if _, err := os.Stat(memPath); err == nil {
+		t.Errorf("expected no memory file at %s, but it exists", memPath)
+	}
+}
+
+func TestCallAgent_MemoryEnabled_Error_NoEntryAppended(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	// Set up XDG_DATA_HOME for memory file
+	dataDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataDir)
+
+	// Mock provider that returns an error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"type":"error","error":{"type":"server_error","message":"Internal server error"}}`))
+	}))
+	defer server.Close()
+
+	// Write sub-agent config with memory enabled
+	toml := `name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+system_prompt = "You are a helper."
+
+[memory]
+enabled = true
+`
+	writeToolTestAgent(t, agentsDir, "helper", toml)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "test-mem-error",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Depth:         0,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if !result.IsError {
+		t.Fatal("expected IsError=true for API error")
+	}
+
+	// Verify no memory file was created (error should prevent memory append)
+	memPath := filepath.Join(dataDir, "axe", "memory", "helper.md")
+	if _, err := os.Stat(memPath); err == nil {
+		t.Errorf("expected no memory file at %s after error, but it exists", memPath)
+	}
+}
+
+func TestCallAgent_RetriesTransientError(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(529)
+			w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":[{"type":"text","text":"second time lucky"}],"model":"claude-sonnet-4-20250514","stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+
+[retry]
+max_attempts = 2
+initial_backoff_ms = 1
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	var stderr strings.Builder
+	call := provider.ToolCall{
+		ID:        "retry-1",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		GlobalConfig:  &config.GlobalConfig{},
+		Verbose:       true,
+		Stderr:        &stderr,
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected success after retry, got error: %s", result.Content)
+	}
+	if result.Content != "second time lucky" {
+		t.Errorf("Content = %q, want %q", result.Content, "second time lucky")
+	}
+	if !strings.Contains(stderr.String(), `[sub-agent] [retry] "helper" attempt 2/2`) {
+		t.Errorf("expected retry notice in verbose output, got:\n%s", stderr.String())
+	}
+}
+
+func TestCallAgent_FallsBackToNextModel(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"gpt-4o","choices":[{"message":{"content":"backup answer"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+	}))
+	defer backup.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+fallback_models = ["openai/gpt-4o"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", primary.URL)
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("AXE_OPENAI_BASE_URL", backup.URL)
+
+	var stderr strings.Builder
+	call := provider.ToolCall{
+		ID:        "fallback-1",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "do something"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		GlobalConfig:  &config.GlobalConfig{},
+		Verbose:       true,
+		Stderr:        &stderr,
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError {
+		t.Fatalf("expected success from fallback, got error: %s", result.Content)
+	}
+	if result.Content != "backup answer" {
+		t.Errorf("Content = %q, want %q", result.Content, "backup answer")
+	}
+	if !strings.Contains(stderr.String(), "answered by openai/gpt-4o") {
+		t.Errorf("expected answering model in verbose output, got:\n%s", stderr.String())
+	}
+}
+
+// --- Shared engine behavior at every depth ---
+
+func TestCallAgent_CarriesWorkdirOverride(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+	workdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workdir, "note.txt"), []byte("from the override"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		if len(requests) == 1 {
+			w.Write([]byte(`{"content": [{"type": "tool_use", "id": "tu1", "name": "read_file", "input": {"path": "note.txt"}}], "stop_reason": "tool_use", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+			return
+		}
+		w.Write([]byte(`{"content": [{"type": "text", "text": "read it"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["read_file"]
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	call := provider.ToolCall{
+		ID:        "workdir-1",
+		Name:      tool.CallAgentToolName,
+		Arguments: json.RawMessage(`{"agent": "helper", "task": "read the note"}`),
+	}
+	opts := CallOptions{
+		AllowedAgents: []string{"helper"},
+		MaxDepth:      3,
+		Workdir:       workdir,
+		GlobalConfig:  &config.GlobalConfig{},
+	}
+	result := CallAgent(context.Background(), call, opts)
+	if result.IsError || result.Content != "read it" {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(requests) != 2 || !strings.Contains(requests[1], "from the override") {
+		t.Errorf("expected the sub-agent to read note.txt from the workdir override, requests:\n%s", strings.Join(requests, "\n"))
+	}
+}
+
+func TestExecuteToolCalls_Parallel(t *testing.T) {
+	// Each call waits for the other to start, so they only both finish when
+	// they run concurrently.
+	var started sync.WaitGroup
+	started.Add(2)
+	bothStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(bothStarted)
+	}()
+	registry := tool.NewRegistry()
+	err := registry.Register(provider.Tool{Name: "wait"}, func(ctx context.Context, call provider.ToolCall, opts tool.ExecuteOptions) provider.ToolResult {
+		started.Done()
+		select {
+		case <-bothStarted:
+		case <-time.After(2 * time.Second):
+			return provider.ToolResult{CallID: call.ID, Content: "timed out", IsError: true}
+		}
+		return provider.ToolResult{CallID: call.ID, Content: "ok"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []provider.ToolCall{{ID: "a", Name: "wait"}, {ID: "b", Name: "wait"}}
+	results := executeToolCalls(context.Background(), registry, calls, tool.ExecuteOptions{}, true)
+	for i, r := range results {
+		if r.IsError || r.CallID != calls[i].ID {
+			t.Errorf("results[%d] = %+v, want ok for %s", i, r, calls[i].ID)
+		}
+	}
+}
+
+func TestExecuteToolCalls_Sequential(t *testing.T) {
+	var order []string
+	registry := tool.NewRegistry()
+	err := registry.Register(provider.Tool{Name: "note"}, func(ctx context.Context, call provider.ToolCall, opts tool.ExecuteOptions) provider.ToolResult {
+		order = append(order, call.ID)
+		return provider.ToolResult{CallID: call.ID}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []provider.ToolCall{{ID: "a", Name: "note"}, {ID: "b", Name: "note"}, {ID: "c", Name: "note"}}
+	executeToolCalls(context.Background(), registry, calls, tool.ExecuteOptions{}, false)
+	if got := strings.Join(order, ","); got != "a,b,c" {
+		t.Errorf("order = %s, want a,b,c", got)
+	}
+}
+
+// approverFunc adapts a function to tool.Approver.
+type approverFunc func(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision
+
+func (f approverFunc) Approve(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision {
+	return f(ctx, agentName, call)
+}
+
+func TestRun_ToolApproval(t *testing.T) {
+	tests := []struct {
+		name     string
+		approver tool.Approver
+		wantFile string // file the write_file call should have created, if any
+		wantSent string // text of the tool result sent back to the model
+	}{
+		{"approved", approverFunc(func(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision {
+			return tool.Decision{Approved: true}
+		}), "out.txt", "Wrote 1 bytes to out.txt"},
+		{"edited", approverFunc(func(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision {
+			return tool.Decision{Approved: true, Arguments: json.RawMessage(`{"path": "edited.txt", "content": "x"}`)}
+		}), "edited.txt", "Wrote 1 bytes to edited.txt"},
+		{"denied", approverFunc(func(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision {
+			return tool.Decision{Reason: "not today"}
+		}), "", "Tool call denied: not today"},
+		{"no approver fails closed", nil, "", "Tool call denied: approval is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentsDir := setupToolTestAgentsDir(t)
+			workdir := t.TempDir()
+
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests = append(requests, string(body))
+				w.Header().Set("Content-Type", "application/json")
+				if len(requests) == 1 {
+					w.Write([]byte(`{"content": [{"type": "tool_use", "id": "tu1", "name": "write_file", "input": {"path": "out.txt", "content": "x"}}], "stop_reason": "tool_use", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+					return
+				}
+				w.Write([]byte(`{"content": [{"type": "text", "text": "done"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+			}))
+			defer server.Close()
+
+			writeToolTestAgent(t, agentsDir, "writer", `name = "writer"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["write_file"]
+
+[tools_config.write_file]
+require_approval = true
+`)
+			t.Setenv("ANTHROPIC_API_KEY", "test-key")
+			t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+			_, err := Run(context.Background(), RunSpec{Agent: "writer", Workdir: workdir, Approver: tt.approver})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(requests) != 2 || !strings.Contains(requests[1], tt.wantSent) {
+				t.Fatalf("expected tool result %q sent back, requests:\n%s", tt.wantSent, strings.Join(requests, "\n"))
+			}
+			entries, _ := os.ReadDir(workdir)
+			var files []string
+			for _, e := range entries {
+				files = append(files, e.Name())
+			}
+			if strings.Join(files, ",") != tt.wantFile {
+				t.Errorf("files in workdir = %v, want %q", files, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	resume := PauseTimeouts(ctx)
+	time.Sleep(100 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatalf("timeout expired while paused: %v", ctx.Err())
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("paused timeout reports a deadline")
+	}
+	resume()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > 50*time.Millisecond {
+		t.Errorf("Deadline() = %v, %v; want the time left after the pause", deadline, ok)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout did not expire after resuming")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("Err() = %v, want context.DeadlineExceeded", ctx.Err())
+	}
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = WithTimeout(parent, time.Hour)
+	defer cancel()
+	cancelParent()
+	<-ctx.Done()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Err() after parent cancel = %v, want context.Canceled", ctx.Err())
+	}
+}
+
+func TestRun_ApprovalPausesTimeout(t *testing.T) {
+	agentsDir := setupToolTestAgentsDir(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.Contains(string(body), "tool_result") {
+			w.Write([]byte(`{"content": [{"type": "tool_use", "id": "tu1", "name": "write_file", "input": {"path": "out.txt", "content": "x"}}], "stop_reason": "tool_use", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+			return
+		}
+		w.Write([]byte(`{"content": [{"type": "text", "text": "done"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+	}))
+	defer server.Close()
+
+	writeToolTestAgent(t, agentsDir, "writer", `name = "writer"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["write_file"]
+
+[tools_config.write_file]
+require_approval = true
+`)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+	// The person takes longer to answer than the whole run may take
+	slow := approverFunc(func(ctx context.Context, agentName string, call provider.ToolCall) tool.Decision {
+		time.Sleep(500 * time.Millisecond)
+		return tool.Decision{Approved: true}
+	})
+	ctx, cancel := WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	res, err := Run(ctx, RunSpec{Agent: "writer", Workdir: t.TempDir(), Approver: slow})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ToolCalls != 1 || res.Response.Content != "done" {
+		t.Errorf("result = %d tool calls, %q, want the approved call and the final answer", res.ToolCalls, res.Response.Content)
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	long := strings.Repeat("a", 100) + strings.Repeat("z", 100)
+	got := truncateMiddle(long, 80)
+	if len(got) > 80 || !strings.HasPrefix(got, "aaa") || !strings.HasSuffix(got, "zzz") || !strings.Contains(got, " of 200 bytes truncated ...]") {
+		t.Errorf("truncateMiddle = %q, want head, marker and tail within 80 bytes", got)
+	}
+
+	if got := truncateMiddle("short", 80); got != "short" {
+		t.Errorf("truncateMiddle(short) = %q, want it unchanged", got)
+	}
+
+	multibyte := strings.Repeat("é", 100)
+	got = truncateMiddle(multibyte, 71)
+	if len(got) > 71 || !utf8.ValidString(got) {
+		t.Errorf("truncateMiddle cut a character: %q", got)
+	}
+
+	if got := truncateMiddle(multibyte, 5); got != "éé" {
+		t.Errorf("truncateMiddle below marker size = %q, want the head only", got)
+	}
+}
+
+func TestRun_LimitsToolResults(t *testing.T) {
+	tests := []struct {
+		name         string
+		toolsConfig  string
+		summary      string // reply of the summary model; empty fails the request
+		wantStrategy string
+		wantSent     string // text of the tool result sent back to the model
+	}{
+		{"truncate", "max_result_bytes = 200", "", "truncate", "bytes truncated ...]"},
+		{"summarize", "max_result_bytes = 200\nresult_strategy = \"summarize\"", "the file is all x", "summarize", "the file is all x"},
+		{"summary failure truncates", "max_result_bytes = 200\nresult_strategy = \"summarize\"", "", "truncate", "bytes truncated ...]"},
+		{"no room for a summary truncates", "max_result_bytes = 60\nresult_strategy = \"summarize\"", "the file is all x", "truncate", "bytes truncated ...]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentsDir := setupToolTestAgentsDir(t)
+			workdir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(workdir, "big.txt"), []byte(strings.Repeat("x", 5000)), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var mu sync.Mutex
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				if strings.Contains(string(body), "You shorten tool results") {
+					if tt.summary == "" {
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(`{"type": "error", "error": {"type": "invalid_request_error", "message": "bad"}}`))
+						return
+					}
+					fmt.Fprintf(w, `{"content": [{"type": "text", "text": %q}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`, tt.summary)
+					return
+				}
+				requests = append(requests, string(body))
+				if len(requests) == 1 {
+					w.Write([]byte(`{"content": [{"type": "tool_use", "id": "tu1", "name": "read_file", "input": {"path": "big.txt"}}], "stop_reason": "tool_use", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+					return
+				}
+				w.Write([]byte(`{"content": [{"type": "text", "text": "done"}], "stop_reason": "end_turn", "usage": {"input_tokens": 1, "output_tokens": 1}}`))
+			}))
+			defer server.Close()
+
+			writeToolTestAgent(t, agentsDir, "reader", `name = "reader"
+model = "anthropic/claude-sonnet-4-20250514"
+tools = ["read_file"]
+
+[tools_config.read_file]
+summary_model = "anthropic/claude-haiku-4-5"
+`+tt.toolsConfig+"\n")
+			t.Setenv("ANTHROPIC_API_KEY", "test-key")
+			t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
+
+			run := history.New("reader")
+			var stderr strings.Builder
+			_, err := Run(context.Background(), RunSpec{Agent: "reader", Workdir: workdir, History: run, Verbose: true, Stderr: &stderr})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(requests) != 2 || !strings.Contains(requests[1], tt.wantSent) || strings.Contains(requests[1], strings.Repeat("x", 500)) {
+				t.Fatalf("expected shortened tool result %q sent back, requests:\n%s", tt.wantSent, strings.Join(requests, "\n"))
+			}
+
+			truncations := run.AllTruncations()
+			if len(truncations) != 1 {
+				t.Fatalf("truncations = %+v, want one", truncations)
+			}
+			tr := truncations[0]
+			if tr.Agent != "reader" || tr.CallID != "tu1" || tr.Tool != "read_file" || tr.Strategy != tt.wantStrategy || tr.OriginalBytes < 5000 || tr.Bytes > 200 {
+				t.Errorf("truncation = %+v", tr)
+			}
+			if !strings.Contains(stderr.String(), "[truncate] read_file result shortened from ") {
+				t.Errorf("stderr = %q, want the truncation logged", stderr.String())
+			}
+		})
+	}
+}
+
+func TestPrepare_RelevantMemory(t *testing.T) {
+	memPath := filepath.Join(t.TempDir(), "mem.md")
+	os.WriteFile(memPath, []byte("## 2026-03-01T00:00:00Z\n**Task:** deploy the billing service\n**Result:** done\n\n"+
+		"## 2026-03-02T00:00:00Z\n**Task:** write release notes\n**Result:** done\n\n"+
+		"## 2026-03-03T00:00:00Z\n**Task:** rotate the TLS certificates\n**Result:** done\n\n"), 0644)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/api/embed" || strings.Contains(body.Input[len(body.Input)-1], "fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// Release notes point the same way as any query; the rest are orthogonal
+		var vectors [][]float64
+		for _, text := range body.Input {
+			if strings.Contains(text, "**Task:**") && !strings.Contains(text, "release notes") {
+				vectors = append(vectors, []float64{0, 1})
+			} else {
+				vectors = append(vectors, []float64{1, 0})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": vectors})
+	}))
+	defer server.Close()
+	t.Setenv("AXE_OLLAMA_BASE_URL", server.URL)
+
+	tests := []struct {
+		name        string
+		model       string
+		message     string
+		wantRanking string
+		wantMemory  string // task of the one entry loaded
+		wantWarning bool
+	}{
+		{"bm25", "", "the billing service is down again", "bm25", "deploy the billing service", false},
+		{"embeddings", "ollama/nomic-embed-text", "what changed this week", "ollama/nomic-embed-text", "write release notes", false},
+		{"embedding failure falls back", "ollama/nomic-embed-text", "fail: renew TLS certificates", "bm25", "rotate the TLS certificates", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(memory.IndexPath(memPath))
+			setupToolTestAgentsDir(t)
+			cfg := &agent.AgentConfig{
+				Name:  "ops",
+				Model: "anthropic/claude-sonnet-4-20250514",
+				Memory: agent.MemoryConfig{
+					Enabled:        true,
+					Path:           memPath,
+					Strategy:       "relevant",
+					TopK:           1,
+					EmbeddingModel: tt.model,
+				},
+			}
+			var stderr strings.Builder
+			p, err := Prepare(context.Background(), RunSpec{Config: cfg, Message: tt.message, Stderr: &stderr})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.MemoryRanking != tt.wantRanking || p.MemorySelected != 1 || p.MemoryCount != 3 {
+				t.Errorf("ranking = %q, selected = %d, count = %d", p.MemoryRanking, p.MemorySelected, p.MemoryCount)
+			}
+			if !strings.Contains(p.Memory, "**Task:** "+tt.wantMemory+"\n") || strings.Count(p.Memory, "## ") != 1 {
+				t.Errorf("Memory = %q, want only %q", p.Memory, tt.wantMemory)
+			}
+			if !strings.Contains(p.SystemPrompt, "## Memory\n\n"+p.Memory) {
+				t.Errorf("SystemPrompt does not include the memory: %q", p.SystemPrompt)
+			}
+			if got := strings.Contains(stderr.String(), "using keyword ranking"); got != tt.wantWarning {
+				t.Errorf("stderr = %q, want warning: %v", stderr.String(), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestPrepare_WarnsAboutSkippedFiles(t *testing.T) {
+	setupToolTestAgentsDir(t)
+	workdir := t.TempDir()
+	png := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, provider.AnthropicMaxImageSize)...)
+	os.WriteFile(filepath.Join(workdir, "big.png"), png, 0644)
+	os.WriteFile(filepath.Join(workdir, "small.png"), png[:64], 0644)
+
+	cfg := &agent.AgentConfig{Name: "viewer", Model: "anthropic/claude-sonnet-4-20250514", Files: []string{"*"}}
+	var stderr strings.Builder
+	p, err := Prepare(context.Background(), RunSpec{Config: cfg, Workdir: workdir, Stderr: &stderr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Files) != 1 || p.Files[0].Path != "small.png" {
+		t.Errorf("%d files, want only small.png", len(p.Files))
+	}
+	if !strings.Contains(stderr.String(), "Warning: context file skipped: big.png is 5242896 bytes; anthropic accepts images up to 5242880 bytes") {
+		t.Errorf("stderr = %q, want a warning naming big.png", stderr.String())
+	}
+
+	// Other providers get the large image
+	cfg.Model = "gemini/gemini-2.5-flash"
+	if p, _ := Prepare(context.Background(), RunSpec{Config: cfg, Workdir: workdir}); len(p.Files) != 2 {
+		t.Errorf("%d files, want both images for gemini", len(p.Files))
+	}
+}
@@ -0,0 +1,33 @@
+package engine
+
+// ErrorCategory classifies run errors for exit code mapping.
+type ErrorCategory string
+
+const (
+	// ErrCategoryConfig indicates an invalid agent or its context (files,
+	// skill, tools, MCP servers).
+	ErrCategoryConfig ErrorCategory = "config"
+	// ErrCategoryAPI indicates a provider or MCP server could not be reached
+	// or is not configured (e.g. a missing API key).
+	ErrCategoryAPI ErrorCategory = "api"
+	// ErrCategoryAgent indicates the agent could not run or finish, e.g. an
+	// invalid model or too many conversation turns.
+	ErrCategoryAgent ErrorCategory = "agent"
+)
+
+// RunError wraps errors from setting up or finishing a run with their
+// category. Errors from the provider itself are not wrapped.
+type RunError struct {
+	Category ErrorCategory
+	Err      error
+}
+
+// Error returns the wrapped error's message.
+func (e *RunError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error, supporting errors.Is and errors.As.
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// ParseModel splits a "provider/model-name" string into provider and model parts.
+func ParseModel(model string) (providerName, modelName string, err error) {
+	idx := strings.Index(model, "/")
+	if idx < 0 {
+		return "", "", fmt.Errorf("invalid model format %q: expected provider/model-name", model)
+	}
+
+	providerName = model[:idx]
+	modelName = model[idx+1:]
+
+	if providerName == "" {
+		return "", "", fmt.Errorf("invalid model format %q: empty provider", model)
+	}
+	if modelName == "" {
+		return "", "", fmt.Errorf("invalid model format %q: empty model name", model)
+	}
+
+	return providerName, modelName, nil
+}
+
+// NewProvider creates the provider for a "provider/model" reference, wrapped
+// with the effective retry policy, and returns it with the bare model name.
+// Errors are *RunError values.
+func NewProvider(ref string, retry agent.RetryConfig, globalCfg *config.GlobalConfig) (provider.Provider, string, error) {
+	prov, modelName, err := newModelProvider(ref, globalCfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return withRetry(prov, retry, globalCfg), modelName, nil
+}
+
+// newModelProvider creates the provider for a "provider/model" reference and
+// returns it with the bare model name. Errors are *RunError values.
+func newModelProvider(ref string, globalCfg *config.GlobalConfig) (provider.Provider, string, error) {
+	provName, modelName, err := ParseModel(ref)
+	if err != nil {
+		return nil, "", &RunError{Category: ErrCategoryAgent, Err: err}
+	}
+
+	apiKey := globalCfg.ResolveAPIKey(provName)
+	baseURL := globalCfg.ResolveBaseURL(provName)
+
+	// Check for missing API key only for supported providers that require one.
+	// Unsupported providers fall through to provider.New() which returns a clear error.
+	if provider.Supported(provName) && provName != "ollama" && apiKey == "" {
+		envVar := config.APIKeyEnvVar(provName)
+		return nil, "", &RunError{Category: ErrCategoryAPI, Err: fmt.Errorf("API key for provider %q is not configured (set %s or add to config.toml)", provName, envVar)}
+	}
+
+	prov, err := provider.New(provName, apiKey, baseURL, providerOptions(globalCfg, provName)...)
+	if err != nil {
+		return nil, "", &RunError{Category: ErrCategoryAgent, Err: err}
+	}
+
+	return prov, modelName, nil
+}
+
+// providerOptions returns the provider.New options configured for
+// providerName in config.toml (custom provider type and extra headers).
+func providerOptions(globalCfg *config.GlobalConfig, providerName string) []provider.Option {
+	return []provider.Option{
+		provider.WithType(globalCfg.ResolveType(providerName)),
+		provider.WithHeaders(globalCfg.ResolveHeaders(providerName)),
+	}
+}
+
+// newProviderChain creates a provider for the agent's model followed by each
+// of its fallback_models, wraps each with the retry policy, and chains them.
+// An error creating the primary provider is returned; a fallback that cannot
+// be created is skipped with a warning so it does not block the primary.
+func (s *RunSpec) newProviderChain(cfg *agent.AgentConfig, globalCfg *config.GlobalConfig) (provider.Provider, error) {
+	refs := append([]string{cfg.Model}, cfg.FallbackModels...)
+
+	var retryOpts []provider.RetryOption
+	var fallbackOpts []provider.FallbackOption
+	if s.Verbose {
+		retryOpts = append(retryOpts, provider.WithRetryNotify(func(attempt, maxAttempts int, err error, wait time.Duration) {
+			s.logf("retry", "attempt %d/%d in %s after error: %v", attempt, maxAttempts, wait.Round(time.Millisecond), err)
+		}))
+		fallbackOpts = append(fallbackOpts, provider.WithFallbackNotify(func(from, to string, err error) {
+			s.logf("fallback", "%s failed (%v); trying %s", from, err, to)
+		}))
+	}
+
+	var models []provider.FallbackModel
+	for i, ref := range refs {
+		prov, modelName, err := newModelProvider(ref, globalCfg)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			s.warnf("skipping fallback model %q: %v", ref, err)
+			continue
+		}
+		models = append(models, provider.FallbackModel{
+			Ref:      ref,
+			Provider: withRetry(prov, cfg.Retry, globalCfg, retryOpts...),
+			Model:    modelName,
+		})
+	}
+
+	return provider.NewFallback(models, fallbackOpts...), nil
+}
+
+// withRetry wraps prov with the effective retry policy for an agent.
+func withRetry(prov provider.Provider, agentRetry agent.RetryConfig, globalCfg *config.GlobalConfig, opts ...provider.RetryOption) provider.Provider {
+	rc := globalCfg.ResolveRetry(config.RetryConfig(agentRetry))
+	return provider.NewRetry(prov, provider.RetryPolicy{
+		MaxAttempts:    rc.MaxAttempts,
+		InitialBackoff: time.Duration(rc.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(rc.MaxBackoffMs) * time.Millisecond,
+	}, opts...)
+}
+
+// answeredBy returns the "provider/model" that produced resp, falling back
+// to the agent's configured model.
+func answeredBy(resp *provider.Response, model string) string {
+	if resp.ModelRef != "" {
+		return resp.ModelRef
+	}
+	return model
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/history"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// summaryPrompt is the system prompt of the model that summarizes oversized
+// tool results.
+const summaryPrompt = "You shorten tool results for another AI agent. Summarize the result you are given, keeping every fact, name, number, path and error message the agent may need. Reply with the summary only."
+
+// truncationMarker replaces the middle of a truncated result.
+const truncationMarker = "\n\n[... %d of %d bytes truncated ...]\n\n"
+
+// limitResults shortens the results of calls that exceed their tool's size
+// limit, in place, so that one oversized result (a chatty sub-agent, a large
+// file) cannot crowd the rest of the conversation out of the model's context
+// window. Each shortened result is logged and recorded in the run history.
+func (s *RunSpec) limitResults(ctx context.Context, p *Prepared, globalCfg *config.GlobalConfig, calls []provider.ToolCall, results []provider.ToolResult) {
+	for i, call := range calls {
+		tc := p.Config.ToolsConf[call.Name]
+		limit := globalCfg.ResolveToolResult(call.Name, config.ToolResultLimit{
+			MaxBytes:     tc.MaxResultBytes,
+			Strategy:     tc.ResultStrategy,
+			SummaryModel: tc.SummaryModel,
+		})
+		original := results[i].Content
+		if limit.MaxBytes == 0 || len(original) <= limit.MaxBytes {
+			continue
+		}
+
+		strategy := limit.Strategy
+		var content string
+		if strategy == config.StrategySummarize {
+			summary, err := s.summarize(ctx, p, globalCfg, limit, call.Name, original)
+			if err != nil {
+				s.warnf("could not summarize %s result, truncating it instead: %v", call.Name, err)
+				strategy = config.StrategyTruncate
+			}
+			content = summary
+		}
+		if strategy == config.StrategyTruncate {
+			content = truncateMiddle(original, limit.MaxBytes)
+		}
+
+		s.logf("truncate", "%s result shortened from %d to %d bytes (%s)", call.Name, len(original), len(content), strategy)
+		s.History.Truncated(history.Truncation{
+			Agent:         p.Agent,
+			CallID:        call.ID,
+			Tool:          call.Name,
+			Strategy:      strategy,
+			OriginalBytes: len(original),
+			Bytes:         len(content),
+		})
+		results[i].Content = content
+	}
+}
+
+// minSummaryBytes is the smallest room worth asking a model to summarize
+// into; tighter limits are met by truncation.
+const minSummaryBytes = 64
+
+// summarize has limit.SummaryModel summarize a tool result in at most
+// limit.MaxBytes bytes. The summary's token usage counts toward the agent.
+// It returns an error if the limit leaves too little room after the
+// summary's header.
+func (s *RunSpec) summarize(ctx context.Context, p *Prepared, globalCfg *config.GlobalConfig, limit config.ToolResultLimit, toolName, content string) (string, error) {
+	if limit.SummaryModel == "" {
+		return "", errors.New("no summary_model is set")
+	}
+	header := fmt.Sprintf("[summary of a %d-byte %s result]\n", len(content), toolName)
+	budget := limit.MaxBytes - len(header)
+	if budget < minSummaryBytes {
+		return "", fmt.Errorf("a %d-byte limit leaves no room for a summary", limit.MaxBytes)
+	}
+	prov, model, err := NewProvider(limit.SummaryModel, p.Config.Retry, globalCfg)
+	if err != nil {
+		return "", err
+	}
+
+	req := &provider.Request{
+		Model:  model,
+		System: summaryPrompt,
+		Messages: []provider.Message{{
+			Role:    "user",
+			Content: fmt.Sprintf("Summarize this %s result in at most %d bytes:\n\n%s", toolName, budget, content),
+		}},
+		// Roughly four bytes per token
+		MaxTokens: max(limit.MaxBytes/4, 64),
+	}
+	resp, err := prov.Send(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	s.Usage.Record(p.Agent, answeredBy(resp, limit.SummaryModel), resp)
+	s.History.Record(resp)
+
+	return truncateMiddle(header+resp.Content, limit.MaxBytes), nil
+}
+
+// truncateMiddle shortens s to at most limit bytes by keeping its head and
+// tail and replacing the middle with a marker saying how much was cut. Cuts
+// fall on UTF-8 character boundaries. When limit is too small for the
+// marker, only the head is kept.
+func truncateMiddle(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	// The marker is sized for the largest count it can show, so the result
+	// never exceeds limit.
+	keep := limit - len(fmt.Sprintf(truncationMarker, len(s), len(s)))
+	if keep <= 0 {
+		end := limit
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		return s[:end]
+	}
+
+	head := keep / 2
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	tail := len(s) - (keep - keep/2)
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+	return s[:head] + fmt.Sprintf(truncationMarker, tail-head, len(s)) + s[tail:]
+}
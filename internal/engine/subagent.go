@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/history"
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/tool"
+	"github.com/jrswab/axe/internal/usage"
+)
+
+// CallOptions holds what a call_agent tool call inherits from the agent
+// making it.
+type CallOptions struct {
+	AllowedAgents []string // The calling agent's sub_agents
+	Depth         int      // Depth of the calling agent
+	MaxDepth      int
+	Timeout       int    // Seconds each sub-agent may run; 0 means no limit
+	Workdir       string // Working directory override carried down from the top-level run
+	Approve       bool   // Require approval for every tool call
+	Approver      tool.Approver
+	GlobalConfig  *config.GlobalConfig
+	Verbose       bool
+	Stderr        io.Writer
+	Usage         *usage.Tracker // Receives the token usage of this and nested sub-agents; may be nil
+	History       *history.Run   // Run record of the calling agent; sub-agent runs are attached to it. May be nil
+}
+
+// callAgentArgs are the decoded arguments of a call_agent tool call.
+type callAgentArgs struct {
+	Agent   string `json:"agent"`
+	Task    string `json:"task"`
+	Context string `json:"context"`
+}
+
+// TaskMessage builds the user message a sub-agent receives for task, with
+// the caller's optional context appended.
+func TaskMessage(task, taskContext string) string {
+	if strings.TrimSpace(taskContext) != "" {
+		return fmt.Sprintf("Task: %s\n\nContext:\n%s", task, taskContext)
+	}
+	return fmt.Sprintf("Task: %s", task)
+}
+
+// CallAgent executes a call_agent tool call by running the sub-agent with Run.
+// It always returns a ToolResult (never an error). Errors are communicated via
+// ToolResult.Content and ToolResult.IsError fields.
+func CallAgent(ctx context.Context, call provider.ToolCall, opts CallOptions) (result provider.ToolResult) {
+	// Step 1: Validate and extract arguments
+	def := tool.CallAgentTool(opts.AllowedAgents)
+	if result, ok := tool.CheckArguments(def, call); !ok {
+		return result
+	}
+	var args callAgentArgs
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return provider.ToolResult{
+			CallID:  call.ID,
+			Content: fmt.Sprintf("call_agent error: invalid arguments: %s", err),
+			IsError: true,
+		}
+	}
+	agentName := args.Agent
+
+	// Step 2: Validate agent name
+	if agentName == "" {
+		return provider.ToolResult{
+			CallID:  call.ID,
+			Content: `call_agent error: "agent" argument is required`,
+			IsError: true,
+		}
+	}
+
+	// Step 3: Validate task
+	if args.Task == "" {
+		return provider.ToolResult{
+			CallID:  call.ID,
+			Content: `call_agent error: "task" argument is required`,
+			IsError: true,
+		}
+	}
+
+	// Step 4: Validate agent is allowed
+	allowed := false
+	for _, a := range opts.AllowedAgents {
+		if a == agentName {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return provider.ToolResult{
+			CallID:  call.ID,
+			Content: fmt.Sprintf("call_agent error: agent %q is not in this agent's sub_agents list", agentName),
+			IsError: true,
+		}
+	}
+
+	// Step 5: Check depth limit
+	if opts.Depth >= opts.MaxDepth {
+		return provider.ToolResult{
+			CallID:  call.ID,
+			Content: fmt.Sprintf("call_agent error: maximum sub-agent depth (%d) reached", opts.MaxDepth),
+			IsError: true,
+		}
+	}
+
+	// Verbose: log before sub-agent call
+	if opts.Verbose && opts.Stderr != nil {
+		taskPreview := args.Task
+		if len(taskPreview) > 80 {
+			taskPreview = taskPreview[:80] + "..."
+		}
+		fmt.Fprintf(opts.Stderr, "[sub-agent] Calling %q (depth %d) with task: %s\n", agentName, opts.Depth+1, taskPreview)
+	}
+
+	start := time.Now()
+
+	// Step 6: Record the sub-agent run under the caller's run; it is completed
+	// with the conversation and outcome when this function returns.
+	run := opts.History.Child(agentName, call.ID)
+	var res Result
+	defer func() {
+		var runErr error
+		if result.IsError {
+			runErr = errors.New(result.Content)
+		}
+		run.Finish(res.Model, res.Request, res.Response, runErr)
+	}()
+
+	// Step 7: Create timeout context
+	var callCtx context.Context
+	var cancel context.CancelFunc
+	if opts.Timeout > 0 {
+		callCtx, cancel = WithTimeout(ctx, time.Duration(opts.Timeout)*time.Second)
+	} else {
+		callCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// Step 8: Run the sub-agent
+	res, err := Run(callCtx, RunSpec{
+		Agent:        agentName,
+		GlobalConfig: opts.GlobalConfig,
+		Workdir:      opts.Workdir,
+		Approve:      opts.Approve,
+		Approver:     opts.Approver,
+		Message:      TaskMessage(args.Task, args.Context),
+		Depth:        opts.Depth + 1,
+		MaxDepth:     opts.MaxDepth,
+		Verbose:      opts.Verbose,
+		Stderr:       opts.Stderr,
+		Usage:        opts.Usage,
+		History:      run,
+	})
+	if err != nil {
+		return errorResult(call.ID, agentName, err.Error(), opts)
+	}
+
+	// Step 9: Return result
+	if opts.Verbose && opts.Stderr != nil {
+		durationMs := time.Since(start).Milliseconds()
+		fmt.Fprintf(opts.Stderr, "[sub-agent] %q completed in %dms (%d chars returned, answered by %s)\n", agentName, durationMs, len(res.Response.Content), res.Response.ModelRef)
+	}
+
+	return provider.ToolResult{
+		CallID:  call.ID,
+		Content: res.Response.Content,
+		IsError: false,
+	}
+}
+
+// errorResult creates an error ToolResult for a sub-agent failure.
+func errorResult(callID, agentName, errMsg string, opts CallOptions) provider.ToolResult {
+	if opts.Verbose && opts.Stderr != nil {
+		fmt.Fprintf(opts.Stderr, "[sub-agent] %q failed: %s\n", agentName, errMsg)
+	}
+	return provider.ToolResult{
+		CallID:  callID,
+		Content: fmt.Sprintf("Error: sub-agent %q failed - %s. You may retry or proceed without this result.", agentName, errMsg),
+		IsError: true,
+	}
+}
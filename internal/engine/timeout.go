@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// timeoutKey finds the innermost pausable timeout in a context chain.
+type timeoutKey struct{}
+
+// timeoutContext is a context with a timeout whose clock can be stopped,
+// so time spent waiting for a person does not count against a run.
+type timeoutContext struct {
+	context.Context // Parent, for values
+	done            chan struct{}
+
+	mu        sync.Mutex
+	err       error
+	timer     *time.Timer
+	remaining time.Duration
+	started   time.Time
+	paused    int
+}
+
+// WithTimeout is like context.WithTimeout, except that the timeout stops
+// counting while PauseTimeouts holds it. When it expires, Err returns
+// context.DeadlineExceeded.
+func WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	c := &timeoutContext{
+		Context:   parent,
+		done:      make(chan struct{}),
+		remaining: d,
+		started:   time.Now(),
+	}
+	c.mu.Lock()
+	c.timer = time.AfterFunc(d, func() { c.finish(context.DeadlineExceeded) })
+	c.mu.Unlock()
+	stop := context.AfterFunc(parent, func() { c.finish(parent.Err()) })
+	return c, func() {
+		stop()
+		c.finish(context.Canceled)
+	}
+}
+
+// Deadline reports when the timeout expires if it keeps running. While it
+// is paused there is no deadline.
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused > 0 {
+		return time.Time{}, false
+	}
+	return c.started.Add(c.remaining), true
+}
+
+func (c *timeoutContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *timeoutContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *timeoutContext) Value(key any) any {
+	if key == (timeoutKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// finish ends the context with err, unless it has already ended.
+func (c *timeoutContext) finish(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.timer.Stop()
+	close(c.done)
+}
+
+// pause stops the clock, keeping the time left.
+func (c *timeoutContext) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused++
+	if c.paused == 1 && c.err == nil && c.timer.Stop() {
+		c.remaining -= time.Since(c.started)
+	}
+}
+
+// resume restarts the clock with the time left when it was paused.
+func (c *timeoutContext) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paused--
+	if c.paused == 0 && c.err == nil {
+		c.started = time.Now()
+		c.timer.Reset(c.remaining)
+	}
+}
+
+// PauseTimeouts stops the clocks of every timeout set with WithTimeout in
+// ctx's chain, e.g. the run's and a sub-agent's, and returns a function
+// that restarts them. Pauses nest.
+func PauseTimeouts(ctx context.Context) (resume func()) {
+	var paused []*timeoutContext
+	for {
+		c, ok := ctx.Value(timeoutKey{}).(*timeoutContext)
+		if !ok {
+			break
+		}
+		c.pause()
+		paused = append(paused, c)
+		ctx = c.Context
+	}
+	return func() {
+		for _, c := range paused {
+			c.resume()
+		}
+	}
+}
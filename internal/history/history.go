@@ -0,0 +1,409 @@
+// Package history records each `axe run` — its conversation, tool calls,
+// nested sub-agent runs, token usage and exit code — as a JSON file under
+// <xdg-data-dir>/runs/ so it can be inspected after the process exits.
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/usage"
+	"github.com/jrswab/axe/internal/xdg"
+)
+
+// Now is the time source used for run IDs, start times and durations.
+// Override in tests for deterministic output.
+var Now func() time.Time = time.Now
+
+// ErrNotFound is returned when no stored run has the requested ID.
+var ErrNotFound = errors.New("run not found")
+
+// Run is the record of one agent run. A top-level run has an ID and an exit
+// code; each sub-agent it called is recorded as a nested Run identified by
+// the tool call that started it.
+//
+// The methods of a nil *Run do nothing, so callers can record unconditionally.
+type Run struct {
+	ID          string       `json:"id,omitempty"`
+	CallID      string       `json:"call_id,omitempty"`
+	Agent       string       `json:"agent"`
+	Model       string       `json:"model"`
+	StartedAt   time.Time    `json:"started_at"`
+	DurationMs  int64        `json:"duration_ms"`
+	ExitCode    int          `json:"exit_code"`
+	Error       string       `json:"error,omitempty"`
+	System      string       `json:"system,omitempty"`
+	Messages    []Message    `json:"messages"`
+	Output      string       `json:"output"`
+	Usage       usage.Counts `json:"usage"` // This agent's own usage, excluding sub-agents
+	Truncations []Truncation `json:"truncations,omitempty"`
+	SubAgents   []*Run       `json:"sub_agents,omitempty"`
+
+	root string // ID of the top-level run a sub-agent run belongs to
+	mu   sync.Mutex
+}
+
+// Message is a conversation message as stored in a run record. Attachment
+// data is not stored, only its name, type and size.
+type Message struct {
+	Role        string       `json:"role"`
+	Content     string       `json:"content,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+}
+
+// Attachment describes a file sent with a message.
+type Attachment struct {
+	Name      string `json:"name"`
+	MediaType string `json:"media_type"`
+	Size      int    `json:"size"`
+}
+
+// ToolCall is a tool invocation requested by the model.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolResult is the result returned to the model for a tool call.
+type ToolResult struct {
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// Truncation records a tool result that exceeded its size limit and was
+// shortened before it was returned to the model.
+type Truncation struct {
+	Agent         string `json:"agent"`
+	CallID        string `json:"call_id"`
+	Tool          string `json:"tool"`
+	Strategy      string `json:"strategy"` // "truncate" or "summarize"
+	OriginalBytes int    `json:"original_bytes"`
+	Bytes         int    `json:"bytes"`
+}
+
+// New starts the record of a top-level run of agent.
+func New(agent string) *Run {
+	now := Now()
+	return &Run{
+		ID:        newID(now),
+		Agent:     agent,
+		StartedAt: now.UTC(),
+	}
+}
+
+// Child starts the record of a sub-agent run started by the tool call
+// callID and attaches it to r. It returns nil if r is nil. It is safe to
+// call from concurrently running tool calls.
+func (r *Run) Child(agent, callID string) *Run {
+	if r == nil {
+		return nil
+	}
+
+	child := &Run{
+		CallID:    callID,
+		Agent:     agent,
+		StartedAt: Now().UTC(),
+		root:      r.RunID(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.SubAgents = append(r.SubAgents, child)
+	return child
+}
+
+// RunID returns the ID of the top-level run r belongs to, which is r's own
+// ID for a top-level run. It returns "" if r is nil.
+func (r *Run) RunID() string {
+	if r == nil {
+		return ""
+	}
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.root
+}
+
+// Record adds the token usage of resp to the run.
+func (r *Run) Record(resp *provider.Response) {
+	if r == nil || resp == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Usage.Add(usage.FromResponse(resp))
+}
+
+// Finish completes the record. model is the "provider/model" that was
+// requested; a response's ModelRef takes precedence. req holds the
+// conversation so far and resp, if non-nil, the final response, which is
+// appended as the last assistant message. A non-nil err is stored as the
+// run's error.
+func (r *Run) Finish(model string, req *provider.Request, resp *provider.Response, err error) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.DurationMs = Now().Sub(r.StartedAt).Milliseconds()
+	r.Model = model
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if req != nil {
+		r.System = req.System
+		r.Messages = Messages(req.Messages)
+	}
+	if resp != nil {
+		if resp.ModelRef != "" {
+			r.Model = resp.ModelRef
+		}
+		r.Output = resp.Content
+		r.Messages = append(r.Messages, Messages([]provider.Message{{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		}})...)
+	}
+}
+
+// Truncated adds t to the run's shortened tool results.
+func (r *Run) Truncated(t Truncation) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Truncations = append(r.Truncations, t)
+}
+
+// AllTruncations returns the shortened tool results of the run and all of
+// its sub-agents, the run's own first.
+func (r *Run) AllTruncations() []Truncation {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all := append([]Truncation(nil), r.Truncations...)
+	for _, sub := range r.SubAgents {
+		all = append(all, sub.AllTruncations()...)
+	}
+	return all
+}
+
+// TotalUsage returns the token usage of the run and all of its sub-agents.
+func (r *Run) TotalUsage() usage.Counts {
+	if r == nil {
+		return usage.Counts{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := r.Usage
+	for _, sub := range r.SubAgents {
+		total.Add(sub.TotalUsage())
+	}
+	return total
+}
+
+// Messages converts provider messages to their stored form.
+func Messages(msgs []provider.Message) []Message {
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		sm := Message{Role: m.Role, Content: m.Content}
+		for _, p := range m.Parts {
+			sm.Attachments = append(sm.Attachments, Attachment{Name: p.Name, MediaType: p.MediaType, Size: len(p.Data)})
+		}
+		for _, tc := range m.ToolCalls {
+			sm.ToolCalls = append(sm.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Name, Arguments: storedArguments(tc.Arguments)})
+		}
+		for _, tr := range m.ToolResults {
+			sm.ToolResults = append(sm.ToolResults, ToolResult(tr))
+		}
+		out = append(out, sm)
+	}
+	return out
+}
+
+// storedArguments returns args if it is valid JSON. Malformed arguments from
+// the model are stored as a JSON string so the record stays valid JSON.
+func storedArguments(args json.RawMessage) json.RawMessage {
+	if len(args) > 0 && json.Valid(args) {
+		return args
+	}
+	quoted, _ := json.Marshal(string(args))
+	return quoted
+}
+
+// newID returns a run ID that sorts by start time, with a random suffix so
+// runs started in the same second do not collide.
+func newID(t time.Time) string {
+	var suffix [3]byte
+	_, _ = rand.Read(suffix[:])
+	return t.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// Dir returns the directory run records are stored in:
+// <xdg-data-dir>/runs. It does not create the directory.
+func Dir() (string, error) {
+	dataDir, err := xdg.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "runs"), nil
+}
+
+// Save writes r to <Dir>/<r.ID>.json, creating the directory if needed.
+// The record is written to a temp file that is then renamed into place, so
+// an interrupted save never leaves a partial record behind.
+func Save(r *Run) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+r.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, r.ID+".json"))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+// Load reads the run with the given ID. It returns an error wrapping
+// ErrNotFound if no such run is stored.
+func Load(id string) (*Run, error) {
+	path, err := runPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read run %s: %w", id, err)
+	}
+
+	var r Run
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", id, err)
+	}
+	return &r, nil
+}
+
+// List returns all stored runs, newest first. If the runs directory does
+// not exist, it returns an empty list. Records that cannot be read or
+// parsed are left out; skipped holds one error for each, for the caller to
+// warn about.
+func List() (runs []*Run, skipped []error, err error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read runs directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		r, err := Load(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		runs = append(runs, r)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		}
+		return runs[i].ID > runs[j].ID
+	})
+	return runs, skipped, nil
+}
+
+// Remove deletes the run with the given ID. It returns an error wrapping
+// ErrNotFound if no such run is stored.
+func Remove(id string) error {
+	path, err := runPath(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return fmt.Errorf("failed to remove run %s: %w", id, err)
+	}
+	return nil
+}
+
+// runPath returns the file path of the run with the given ID, rejecting IDs
+// that would escape the runs directory.
+func runPath(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid run ID %q", id)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
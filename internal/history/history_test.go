@@ -0,0 +1,249 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrswab/axe/internal/provider"
+)
+
+func setNow(t *testing.T, ts time.Time) {
+	t.Helper()
+	orig := Now
+	Now = func() time.Time { return ts }
+	t.Cleanup(func() { Now = orig })
+}
+
+func TestNew_IDSortsByStartTime(t *testing.T) {
+	setNow(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	early := New("agent")
+	setNow(t, time.Date(2026, 3, 1, 12, 0, 1, 0, time.UTC))
+	late := New("agent")
+
+	if early.ID >= late.ID {
+		t.Errorf("IDs do not sort by start time: %q >= %q", early.ID, late.ID)
+	}
+	if early.ID[:15] != "20260301T120000" {
+		t.Errorf("ID = %q, want timestamp prefix 20260301T120000", early.ID)
+	}
+}
+
+func TestRun_FinishRecordsConversation(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	setNow(t, start)
+	r := New("parent")
+
+	req := &provider.Request{
+		System: "be helpful",
+		Messages: []provider.Message{
+			{Role: "user", Content: "hi", Parts: []provider.ContentPart{{Type: provider.PartImage, MediaType: "image/png", Name: "a.png", Data: []byte("1234")}}},
+			{Role: "assistant", ToolCalls: []provider.ToolCall{{ID: "c1", Name: "call_agent", Arguments: json.RawMessage(`{"agent":"x"`)}}},
+			{Role: "tool", ToolResults: []provider.ToolResult{{CallID: "c1", Content: "boom", IsError: true}}},
+		},
+	}
+	resp := &provider.Response{Content: "done", ModelRef: "openai/gpt-4o", InputTokens: 10, OutputTokens: 2}
+	r.Record(resp)
+
+	setNow(t, start.Add(1500*time.Millisecond))
+	r.Finish("anthropic/claude-sonnet-4", req, resp, nil)
+
+	if r.Model != "openai/gpt-4o" {
+		t.Errorf("Model = %q, want the model that answered", r.Model)
+	}
+	if r.DurationMs != 1500 {
+		t.Errorf("DurationMs = %d, want 1500", r.DurationMs)
+	}
+	if r.Output != "done" || r.System != "be helpful" {
+		t.Errorf("Output = %q, System = %q", r.Output, r.System)
+	}
+	if len(r.Messages) != 4 || r.Messages[3].Role != "assistant" || r.Messages[3].Content != "done" {
+		t.Fatalf("Messages = %+v, want request messages plus final response", r.Messages)
+	}
+	if a := r.Messages[0].Attachments; len(a) != 1 || a[0].Size != 4 || a[0].MediaType != "image/png" {
+		t.Errorf("Attachments = %+v", a)
+	}
+	if got := string(r.Messages[1].ToolCalls[0].Arguments); got != `"{\"agent\":\"x\""` {
+		t.Errorf("malformed arguments stored as %s, want a JSON string", got)
+	}
+	if !r.Messages[2].ToolResults[0].IsError {
+		t.Error("tool result error flag not recorded")
+	}
+	if r.Usage.InputTokens != 10 || r.Usage.OutputTokens != 2 {
+		t.Errorf("Usage = %+v", r.Usage)
+	}
+}
+
+func TestRun_FinishError(t *testing.T) {
+	r := New("agent")
+	r.Finish("anthropic/claude-sonnet-4", nil, nil, errors.New("rate limited"))
+
+	if r.Error != "rate limited" || r.Model != "anthropic/claude-sonnet-4" {
+		t.Errorf("Error = %q, Model = %q", r.Error, r.Model)
+	}
+}
+
+func TestRun_ChildrenAndTotalUsage(t *testing.T) {
+	r := New("parent")
+	r.Record(&provider.Response{InputTokens: 10, OutputTokens: 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			child := r.Child("helper", "call")
+			child.Record(&provider.Response{InputTokens: 2, OutputTokens: 1})
+			child.Child("grandchild", "call").Record(&provider.Response{InputTokens: 1})
+		}()
+	}
+	wg.Wait()
+
+	if len(r.SubAgents) != 10 {
+		t.Fatalf("SubAgents = %d, want 10", len(r.SubAgents))
+	}
+	if r.SubAgents[0].ID != "" || r.SubAgents[0].CallID != "call" {
+		t.Errorf("child ID = %q, CallID = %q; want no ID and the call ID", r.SubAgents[0].ID, r.SubAgents[0].CallID)
+	}
+	total := r.TotalUsage()
+	if total.InputTokens != 40 || total.OutputTokens != 11 {
+		t.Errorf("TotalUsage = %+v, want 40 input, 11 output", total)
+	}
+}
+
+func TestRun_AllTruncations(t *testing.T) {
+	r := New("parent")
+	r.Truncated(Truncation{Agent: "parent", CallID: "c1", Tool: "read_file", Strategy: "truncate", OriginalBytes: 900, Bytes: 100})
+	child := r.Child("helper", "c2")
+	child.Truncated(Truncation{Agent: "helper", CallID: "c3", Tool: "http_get", Strategy: "summarize", OriginalBytes: 5000, Bytes: 300})
+
+	all := r.AllTruncations()
+	if len(all) != 2 || all[0].CallID != "c1" || all[1].Agent != "helper" {
+		t.Errorf("AllTruncations = %+v, want the parent's then the child's", all)
+	}
+	if child.RunID() != r.ID || child.Child("grandchild", "c4").RunID() != r.ID {
+		t.Errorf("sub-agent RunID = %q, want the top-level run's %q", child.RunID(), r.ID)
+	}
+	if len(r.Truncations) != 1 {
+		t.Errorf("Truncations = %+v, want only the parent's own", r.Truncations)
+	}
+}
+
+func TestRun_Nil(t *testing.T) {
+	var r *Run
+	if child := r.Child("helper", "call"); child != nil {
+		t.Errorf("Child of nil run = %v, want nil", child)
+	}
+	r.Record(&provider.Response{InputTokens: 1})
+	r.Truncated(Truncation{})
+	if r.RunID() != "" {
+		t.Error("RunID of nil run should be empty")
+	}
+	if r.AllTruncations() != nil {
+		t.Error("AllTruncations of nil run should be nil")
+	}
+	r.Finish("model", nil, nil, nil)
+}
+
+func TestSaveLoadListRemove(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", tmp)
+
+	setNow(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	older := New("alpha")
+	older.ExitCode = 3
+	older.Child("beta", "toolu_1").Finish("openai/gpt-4o", nil, &provider.Response{Content: "sub"}, nil)
+	setNow(t, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	newer := New("alpha")
+
+	for _, r := range []*Run{older, newer} {
+		if err := Save(r); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "axe", "runs", older.ID+".json")); err != nil {
+		t.Errorf("run file not written: %v", err)
+	}
+
+	loaded, err := Load(older.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.ExitCode != 3 || len(loaded.SubAgents) != 1 || loaded.SubAgents[0].Output != "sub" {
+		t.Errorf("loaded run = %+v", loaded)
+	}
+
+	runs, _, err := List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != newer.ID {
+		t.Fatalf("List returned %d runs, first %q; want newest first", len(runs), runs[0].ID)
+	}
+
+	if err := Remove(older.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := Load(older.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load after Remove: err = %v, want ErrNotFound", err)
+	}
+	if err := Remove(older.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Remove: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestList_SkipsUnparsableRecords(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", tmp)
+
+	r := New("alpha")
+	if err := Save(r); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	dir := filepath.Join(tmp, "axe", "runs")
+	os.WriteFile(filepath.Join(dir, "20260101T000000-broken.json"), []byte(`{"id": `), 0644)
+
+	runs, skipped, err := List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 1 || runs[0].ID != r.ID {
+		t.Errorf("List = %d runs, want only %s", len(runs), r.ID)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Error(), "failed to parse run 20260101T000000-broken") {
+		t.Errorf("skipped = %v, want the broken record", skipped)
+	}
+
+	// Saving leaves only the record behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("runs directory has %d files, want 2", len(entries))
+	}
+}
+
+func TestList_NoDirectory(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	runs, _, err := List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("List = %d runs, want 0", len(runs))
+	}
+}
+
+func TestLoad_RejectsPathTraversal(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	for _, id := range []string{"", "../secret", "a/b", ".hidden"} {
+		if _, err := Load(id); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Load(%q): err = %v, want invalid ID error", id, err)
+		}
+	}
+}
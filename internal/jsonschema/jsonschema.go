@@ -0,0 +1,360 @@
+// Package jsonschema validates JSON documents against JSON Schema.
+//
+// It implements the validation keywords tool definitions commonly use: type,
+// enum, const, properties, required, additionalProperties, items, minItems,
+// maxItems, minimum, maximum, exclusiveMinimum, exclusiveMaximum, minLength,
+// maxLength, pattern, allOf, anyOf, oneOf and not. Other keywords, including
+// $ref and format, are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError describes the first place an instance fails its schema.
+type ValidationError struct {
+	// Path locates the failing value, e.g. "$.items[2].name".
+	Path string
+	// Message explains the failure.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Validate checks that instance conforms to schema. An empty schema accepts
+// any instance. It returns a *ValidationError if the instance does not match,
+// or a plain error if either document is not valid JSON.
+func Validate(schema, instance json.RawMessage) error {
+	var s interface{} = true
+	if len(bytes.TrimSpace(schema)) > 0 {
+		if err := json.Unmarshal(schema, &s); err != nil {
+			return fmt.Errorf("invalid schema: %w", err)
+		}
+	}
+
+	v, err := decode(instance)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	return validate(s, v, "$")
+}
+
+// decode parses a single JSON value, keeping numbers as json.Number so that
+// integers can be told apart from other numbers.
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+// validate checks v against schema s. path is the location of v, used in errors.
+func validate(s interface{}, v interface{}, path string) error {
+	switch schema := s.(type) {
+	case bool:
+		if !schema {
+			return &ValidationError{Path: path, Message: "no value is allowed here"}
+		}
+		return nil
+	case map[string]interface{}:
+		return validateObjectSchema(schema, v, path)
+	default:
+		return nil
+	}
+}
+
+func validateObjectSchema(schema map[string]interface{}, v interface{}, path string) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if t, ok := schema["type"]; ok {
+		types := typeList(t)
+		if len(types) > 0 && !matchesAnyType(v, types) {
+			return fail("expected %s, got %s", strings.Join(types, " or "), typeName(v))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("value must be one of %s", compactJSON(enum))
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !equal(c, v) {
+		return fail("value must be %s", compactJSON(c))
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if err := validateObject(schema, val, path); err != nil {
+			return err
+		}
+	case []interface{}:
+		if n, ok := number(schema["minItems"]); ok && float64(len(val)) < n {
+			return fail("expected at least %s items, got %d", formatNumber(n), len(val))
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(val)) > n {
+			return fail("expected at most %s items, got %d", formatNumber(n), len(val))
+		}
+		if items, ok := schema["items"]; ok {
+			for i, item := range val {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(val))
+		if n, ok := number(schema["minLength"]); ok && length < n {
+			return fail("expected at least %s characters, got %d", formatNumber(n), int(length))
+		}
+		if n, ok := number(schema["maxLength"]); ok && length > n {
+			return fail("expected at most %s characters, got %d", formatNumber(n), int(length))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fail("schema pattern %q is invalid: %v", pattern, err)
+			}
+			if !re.MatchString(val) {
+				return fail("value does not match pattern %q", pattern)
+			}
+		}
+	case json.Number:
+		f, _ := val.Float64()
+		if n, ok := number(schema["minimum"]); ok && f < n {
+			return fail("must be >= %s", formatNumber(n))
+		}
+		if n, ok := number(schema["maximum"]); ok && f > n {
+			return fail("must be <= %s", formatNumber(n))
+		}
+		if n, ok := number(schema["exclusiveMinimum"]); ok && f <= n {
+			return fail("must be > %s", formatNumber(n))
+		}
+		if n, ok := number(schema["exclusiveMaximum"]); ok && f >= n {
+			return fail("must be < %s", formatNumber(n))
+		}
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if err := validate(sub, v, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if validate(sub, v, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fail("value does not match any of the allowed schemas")
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if validate(sub, v, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fail("value must match exactly one schema, matched %d", matches)
+		}
+	}
+
+	if not, ok := schema["not"]; ok && validate(not, v, path) == nil {
+		return fail("value matches a schema it must not match")
+	}
+
+	return nil
+}
+
+// validateObject applies the object keywords of schema to obj.
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := obj[name]; !present {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+
+	// Check properties in a stable order so errors are deterministic.
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := path + "." + k
+		if propSchema, ok := properties[k]; ok {
+			if err := validate(propSchema, obj[k], childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("unexpected property %q", k)}
+		}
+		if err := validate(additional, obj[k], childPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// typeList normalizes the "type" keyword, which may be a string or an array.
+func typeList(t interface{}) []string {
+	switch tt := t.(type) {
+	case string:
+		return []string{tt}
+	case []interface{}:
+		var types []string
+		for _, x := range tt {
+			if s, ok := x.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesAnyType(v interface{}, types []string) bool {
+	actual := typeName(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName returns the JSON Schema type name of a decoded value.
+func typeName(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if f, err := val.Float64(); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// number reads a numeric schema keyword value.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// equal compares two decoded JSON values, treating numbers by value.
+func equal(a, b interface{}) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, x := range av {
+			y, present := bv[k]
+			if !present || !equal(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
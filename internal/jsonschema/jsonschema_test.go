@@ -0,0 +1,106 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		wantErr  string
+	}{
+		{"empty schema accepts anything", ``, `{"a": [1, 2]}`, ""},
+		{"true schema", `true`, `"x"`, ""},
+		{"false schema", `false`, `"x"`, "$: no value is allowed here"},
+
+		{"type match", `{"type": "string"}`, `"x"`, ""},
+		{"type mismatch", `{"type": "string"}`, `1`, "$: expected string, got integer"},
+		{"type list", `{"type": ["string", "null"]}`, `null`, ""},
+		{"integer rejects fraction", `{"type": "integer"}`, `1.5`, "$: expected integer, got number"},
+		{"number accepts integer", `{"type": "number"}`, `3`, ""},
+		{"large integer", `{"type": "integer"}`, `12345678901234567890`, ""},
+
+		{"enum match", `{"enum": ["a", "b"]}`, `"b"`, ""},
+		{"enum mismatch", `{"enum": ["a", "b"]}`, `"c"`, `$: value must be one of ["a","b"]`},
+		{"const numbers by value", `{"const": 1}`, `1.0`, ""},
+
+		{"required present", `{"type": "object", "required": ["a"]}`, `{"a": 1}`, ""},
+		{"required missing", `{"type": "object", "required": ["a"]}`, `{}`, `$: missing required property "a"`},
+		{"nested property", `{"properties": {"a": {"properties": {"b": {"type": "boolean"}}}}}`, `{"a": {"b": "yes"}}`, "$.a.b: expected boolean, got string"},
+		{"additional allowed by default", `{"properties": {"a": {}}}`, `{"b": 1}`, ""},
+		{"additional forbidden", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"b": 1}`, `$: unexpected property "b"`},
+		{"additional schema", `{"additionalProperties": {"type": "string"}}`, `{"b": 1}`, "$.b: expected string, got integer"},
+
+		{"items", `{"items": {"type": "integer"}}`, `[1, 2, "x"]`, "$[2]: expected integer, got string"},
+		{"minItems", `{"minItems": 2}`, `[1]`, "$: expected at least 2 items, got 1"},
+		{"maxItems", `{"maxItems": 1}`, `[1, 2]`, "$: expected at most 1 items, got 2"},
+
+		{"minLength counts runes", `{"minLength": 2}`, `"é"`, "$: expected at least 2 characters, got 1"},
+		{"maxLength", `{"maxLength": 1}`, `"ab"`, "$: expected at most 1 characters, got 2"},
+		{"pattern match", `{"pattern": "^[a-z]+$"}`, `"abc"`, ""},
+		{"pattern mismatch", `{"pattern": "^[a-z]+$"}`, `"ABC"`, `$: value does not match pattern "^[a-z]+$"`},
+
+		{"minimum", `{"minimum": 1}`, `0`, "$: must be >= 1"},
+		{"maximum", `{"maximum": 1.5}`, `2`, "$: must be <= 1.5"},
+		{"exclusiveMinimum", `{"exclusiveMinimum": 1}`, `1`, "$: must be > 1"},
+		{"exclusiveMaximum", `{"exclusiveMaximum": 1}`, `1`, "$: must be < 1"},
+
+		{"allOf", `{"allOf": [{"type": "integer"}, {"minimum": 5}]}`, `3`, "$: must be >= 5"},
+		{"anyOf match", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `3`, ""},
+		{"anyOf mismatch", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, "$: value does not match any of the allowed schemas"},
+		{"oneOf ambiguous", `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `3`, "$: value must match exactly one schema, matched 2"},
+		{"not", `{"not": {"type": "null"}}`, `null`, "$: value matches a schema it must not match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(json.RawMessage(tt.schema), json.RawMessage(tt.instance))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tt.wantErr)
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_InvalidDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+	}{
+		{"invalid schema", `{`, `{}`},
+		{"invalid instance", `{}`, `{`},
+		{"empty instance", `{}`, ``},
+		{"trailing data", `{}`, `{} {}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(json.RawMessage(tt.schema), json.RawMessage(tt.instance))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			var ve *ValidationError
+			if errors.As(err, &ve) {
+				t.Errorf("expected a parse error, got ValidationError: %v", err)
+			}
+		})
+	}
+}
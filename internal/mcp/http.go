@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jrswab/axe/internal/config"
+)
+
+// httpTransport talks to a server over the streamable HTTP transport: each
+// message is POSTed to the endpoint, and the response arrives either as a
+// JSON body or as a server-sent event stream.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func newHTTPTransport(cfg config.MCPServerConfig) (*httpTransport, error) {
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return nil, fmt.Errorf("url must start with http:// or https://: %q", cfg.URL)
+	}
+	return &httpTransport{url: cfg.URL, headers: cfg.Headers, client: &http.Client{}}, nil
+}
+
+func (t *httpTransport) setProtocolVersion(v string) {
+	t.mu.Lock()
+	t.protocolVersion = v
+	t.mu.Unlock()
+}
+
+// post sends msg and returns the HTTP response. The session ID the server
+// assigns during initialization is remembered and sent with later requests.
+func (t *httpTransport) post(ctx context.Context, msg *Message) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setSessionHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) setSessionHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, msg *Message) (*Message, error) {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		var reply Message
+		if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+			return nil, fmt.Errorf("invalid response: %w", err)
+		}
+		return &reply, nil
+	case "text/event-stream":
+		return readEventStream(resp.Body, msg.ID)
+	default:
+		return nil, fmt.Errorf("unexpected response content type %q", mediaType)
+	}
+}
+
+func (t *httpTransport) notify(ctx context.Context, msg *Message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// close ends the session on the server, if one was assigned.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setSessionHeaders(req)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// readEventStream reads server-sent events until one carries the response
+// to the request with the given ID. Other messages on the stream, such as
+// progress notifications, are skipped.
+func readEventStream(r io.Reader, id json.RawMessage) (*Message, error) {
+	br := bufio.NewReader(r)
+	var data []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(trimmed, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(trimmed, "data:"), " "))
+		}
+		if trimmed == "" || err == io.EOF {
+			if len(data) > 0 {
+				var msg Message
+				if json.Unmarshal([]byte(strings.Join(data, "\n")), &msg) == nil && msg.IsResponse() && bytes.Equal(msg.ID, id) {
+					return &msg, nil
+				}
+				data = nil
+			}
+		}
+
+		if err == io.EOF {
+			return nil, errors.New("event stream ended without a response")
+		}
+	}
+}
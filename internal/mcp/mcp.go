@@ -0,0 +1,288 @@
+// Package mcp implements the Model Context Protocol. The client lets axe use
+// the tools of MCP servers running as a stdio subprocess or reachable over
+// streamable HTTP; the stdio server offers axe agents to MCP clients.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrswab/axe/internal/config"
+)
+
+// ProtocolVersion is the MCP revision axe speaks.
+const ProtocolVersion = "2025-06-18"
+
+// DefaultTimeout bounds each request to a server whose config sets no
+// timeout.
+const DefaultTimeout = 60 * time.Second
+
+// Implementation identifies a client or server during initialization.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Tool is a tool listed by an MCP server.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// CallResult is the outcome of a tools/call request, with the content blocks
+// rendered as text.
+type CallResult struct {
+	Content string
+	IsError bool
+}
+
+// RPCError is a JSON-RPC error returned by a server.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// JSON-RPC error codes used by MCP.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Message is a JSON-RPC 2.0 message: a request (Method and ID set), a
+// notification (Method set, no ID) or a response (ID with Result or Error).
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// IsResponse reports whether m answers a request.
+func (m *Message) IsResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// transport carries JSON-RPC messages to and from one server.
+type transport interface {
+	// roundTrip sends a request and returns the response with the same ID.
+	roundTrip(ctx context.Context, msg *Message) (*Message, error)
+	// notify sends a notification, which has no response.
+	notify(ctx context.Context, msg *Message) error
+	close() error
+}
+
+// ClientInfo is what axe reports about itself to servers. The CLI sets the
+// version at startup.
+var ClientInfo = Implementation{Name: "axe", Version: "dev"}
+
+// Client is a connection to one MCP server. It is safe for concurrent use.
+type Client struct {
+	name      string
+	transport transport
+	timeout   time.Duration
+	nextID    atomic.Int64
+
+	// ServerInfo is what the server reported about itself.
+	ServerInfo Implementation
+}
+
+// Connect starts or dials the server described by cfg and performs the
+// initialize handshake. The caller must Close the client.
+func Connect(ctx context.Context, name string, cfg config.MCPServerConfig) (*Client, error) {
+	c := &Client{name: name, timeout: DefaultTimeout}
+	if cfg.Timeout > 0 {
+		c.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	var err error
+	switch {
+	case cfg.Command != "" && cfg.URL != "":
+		return nil, errors.New("set either command or url, not both")
+	case cfg.Command != "":
+		c.transport, err = newStdioTransport(cfg)
+	case cfg.URL != "":
+		c.transport, err = newHTTPTransport(cfg)
+	default:
+		return nil, errors.New("missing command or url")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.initialize(ctx); err != nil {
+		c.transport.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Name returns the server's configured name.
+func (c *Client) Name() string {
+	return c.name
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      ClientInfo,
+	}
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+	}
+	if err := c.request(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	c.ServerInfo = result.ServerInfo
+
+	if t, ok := c.transport.(*httpTransport); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.transport.notify(ctx, &Message{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// ListTools returns every tool the server offers, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.request(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("tools/list failed: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls the named tool with args, a JSON object. A tool that fails
+// is reported in the result with IsError set; the returned error is for
+// protocol and transport failures.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallResult, error) {
+	if len(strings.TrimSpace(string(args))) == 0 {
+		args = json.RawMessage("{}")
+	}
+	params := map[string]interface{}{"name": name, "arguments": args}
+
+	var result struct {
+		Content           []ContentBlock  `json:"content"`
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	if err := c.request(ctx, "tools/call", params, &result); err != nil {
+		return nil, err
+	}
+
+	content := RenderContent(result.Content)
+	if content == "" && len(result.StructuredContent) > 0 {
+		content = string(result.StructuredContent)
+	}
+	return &CallResult{Content: content, IsError: result.IsError}, nil
+}
+
+// Close ends the session and stops the server if axe started it.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// request sends method with params and decodes the result into result.
+func (c *Client) request(ctx context.Context, method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := c.nextID.Add(1)
+	msg := &Message{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(fmt.Sprintf("%d", id)),
+		Method:  method,
+		Params:  rawParams,
+	}
+
+	resp, err := c.transport.roundTrip(ctx, msg)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("no response within %s", c.timeout)
+		}
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %w", method, err)
+	}
+	return nil
+}
+
+// ContentBlock is one item of a tool result's content.
+type ContentBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Data     string `json:"data,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	Resource *struct {
+		URI  string `json:"uri"`
+		Text string `json:"text,omitempty"`
+	} `json:"resource,omitempty"`
+}
+
+// RenderContent joins content blocks into text for the model. Binary blocks
+// are replaced with a short placeholder.
+func RenderContent(blocks []ContentBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			parts = append(parts, b.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s content omitted: %s]", b.Type, b.MimeType))
+		case "resource":
+			if b.Resource != nil && b.Resource.Text != "" {
+				parts = append(parts, b.Resource.Text)
+			} else if b.Resource != nil {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", b.Resource.URI))
+			}
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource link: %s]", b.URI))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
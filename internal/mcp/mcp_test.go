@@ -0,0 +1,330 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrswab/axe/internal/config"
+)
+
+// TestMain lets the test binary act as a stdio MCP server: tests start
+// os.Args[0] with AXE_MCP_STUB=1 in its environment.
+func TestMain(m *testing.M) {
+	if os.Getenv("AXE_MCP_STUB") == "1" {
+		serveStubStdio()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// stubTools are listed in two pages to exercise pagination.
+var stubTools = [][]Tool{
+	{{Name: "echo", Description: "Echo text", InputSchema: json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`)}},
+	{{Name: "fail", Description: "Always fails"}, {Name: "slow", Description: "Sleeps"}},
+}
+
+// stubHandle answers one request from a client. It returns nil for
+// notifications.
+func stubHandle(msg *Message) *Message {
+	if len(msg.ID) == 0 {
+		return nil
+	}
+	reply := &Message{JSONRPC: "2.0", ID: msg.ID}
+	var result interface{}
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      Implementation{Name: "stub", Version: "1.0"},
+		}
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if params.Cursor == "" {
+			result = map[string]interface{}{"tools": stubTools[0], "nextCursor": "page2"}
+		} else {
+			result = map[string]interface{}{"tools": stubTools[1]}
+		}
+	case "tools/call":
+		var params struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				Text string `json:"text"`
+			} `json:"arguments"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		switch params.Name {
+		case "echo":
+			result = map[string]interface{}{"content": []ContentBlock{{Type: "text", Text: "echo: " + params.Arguments.Text}}}
+		case "fail":
+			result = map[string]interface{}{"content": []ContentBlock{{Type: "text", Text: "it broke"}}, "isError": true}
+		case "slow":
+			time.Sleep(2 * time.Second)
+			result = map[string]interface{}{"content": []ContentBlock{}}
+		default:
+			reply.Error = &RPCError{Code: CodeInvalidParams, Message: "unknown tool: " + params.Name}
+		}
+	default:
+		reply.Error = &RPCError{Code: CodeMethodNotFound, Message: "method not found"}
+	}
+	if result != nil {
+		reply.Result, _ = json.Marshal(result)
+	}
+	return reply
+}
+
+func serveStubStdio() {
+	scanner := bufio.NewScanner(os.Stdin)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if os.Getenv("AXE_MCP_STUB_CRASH") == "1" && msg.Method == "tools/list" {
+			fmt.Fprintln(os.Stderr, "stub crashed")
+			os.Exit(1)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if reply := stubHandle(&msg); reply != nil {
+				data, _ := json.Marshal(reply)
+				mu.Lock()
+				fmt.Printf("%s\n", data)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// stubHTTPServer serves the stub over streamable HTTP. When sse is true,
+// responses are sent as an event stream preceded by a notification.
+func stubHTTPServer(t *testing.T, sse bool) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var sessions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			mu.Lock()
+			sessions = append(sessions, "deleted:"+r.Header.Get("Mcp-Session-Id"))
+			mu.Unlock()
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var msg Message
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		sessions = append(sessions, msg.Method+":"+r.Header.Get("Mcp-Session-Id"))
+		mu.Unlock()
+
+		if msg.Method == "initialize" {
+			w.Header().Set("Mcp-Session-Id", "session-1")
+		}
+		reply := stubHandle(&msg)
+		if reply == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := json.Marshal(reply)
+		if sse {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server, &sessions
+}
+
+func stdioConfig(t *testing.T) config.MCPServerConfig {
+	t.Helper()
+	return config.MCPServerConfig{Command: os.Args[0], Env: map[string]string{"AXE_MCP_STUB": "1"}}
+}
+
+// exerciseClient runs the handshake, listing and calls that every
+// transport must support.
+func exerciseClient(t *testing.T, cfg config.MCPServerConfig) {
+	t.Helper()
+	ctx := context.Background()
+	c, err := Connect(ctx, "stub", cfg)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+
+	if c.ServerInfo.Name != "stub" {
+		t.Errorf("ServerInfo = %+v", c.ServerInfo)
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 3 || tools[0].Name != "echo" || tools[2].Name != "slow" {
+		t.Fatalf("tools = %+v, want echo, fail, slow", tools)
+	}
+
+	result, err := c.CallTool(ctx, "echo", json.RawMessage(`{"text": "hi"}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.Content != "echo: hi" || result.IsError {
+		t.Errorf("echo result = %+v", result)
+	}
+
+	result, err = c.CallTool(ctx, "fail", nil)
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.Content != "it broke" || !result.IsError {
+		t.Errorf("fail result = %+v, want IsError", result)
+	}
+
+	_, err = c.CallTool(ctx, "missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown tool: missing") {
+		t.Errorf("err = %v, want JSON-RPC error", err)
+	}
+}
+
+func TestStdio(t *testing.T) {
+	exerciseClient(t, stdioConfig(t))
+}
+
+func TestStdio_ConcurrentCalls(t *testing.T) {
+	c, err := Connect(context.Background(), "stub", stdioConfig(t))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text := fmt.Sprintf("call %d", i)
+			result, err := c.CallTool(context.Background(), "echo", json.RawMessage(fmt.Sprintf(`{"text": %q}`, text)))
+			if err != nil || result.Content != "echo: "+text {
+				t.Errorf("call %d: result = %+v, err = %v", i, result, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestStdio_Timeout(t *testing.T) {
+	cfg := stdioConfig(t)
+	cfg.Timeout = 1
+	c, err := Connect(context.Background(), "stub", cfg)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.CallTool(context.Background(), "slow", nil)
+	if err == nil || !strings.Contains(err.Error(), "no response within 1s") {
+		t.Errorf("err = %v, want timeout", err)
+	}
+}
+
+func TestStdio_ServerExits(t *testing.T) {
+	cfg := stdioConfig(t)
+	cfg.Env["AXE_MCP_STUB_CRASH"] = "1"
+	c, err := Connect(context.Background(), "stub", cfg)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.ListTools(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "server closed the connection: stub crashed") {
+		t.Errorf("err = %v, want connection closed with stderr", err)
+	}
+}
+
+func TestStdio_CommandNotFound(t *testing.T) {
+	_, err := Connect(context.Background(), "stub", config.MCPServerConfig{Command: "axe-no-such-mcp-server"})
+	if err == nil || !strings.Contains(err.Error(), "failed to start") {
+		t.Errorf("err = %v, want start failure", err)
+	}
+}
+
+func TestHTTP_JSON(t *testing.T) {
+	server, sessions := stubHTTPServer(t, false)
+	exerciseClient(t, config.MCPServerConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+
+	got := strings.Join(*sessions, ",")
+	if !strings.HasPrefix(got, "initialize:,notifications/initialized:session-1,tools/list:session-1") {
+		t.Errorf("requests = %s, want session ID sent after initialize", got)
+	}
+	if !strings.HasSuffix(got, "deleted:session-1") {
+		t.Errorf("requests = %s, want session deleted on Close", got)
+	}
+}
+
+func TestHTTP_EventStream(t *testing.T) {
+	server, _ := stubHTTPServer(t, true)
+	exerciseClient(t, config.MCPServerConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+}
+
+func TestHTTP_Unauthorized(t *testing.T) {
+	server, _ := stubHTTPServer(t, false)
+	_, err := Connect(context.Background(), "stub", config.MCPServerConfig{URL: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "initialize failed: HTTP 401") {
+		t.Errorf("err = %v, want HTTP 401", err)
+	}
+}
+
+func TestConnect_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		cfg  config.MCPServerConfig
+		want string
+	}{
+		{config.MCPServerConfig{}, "missing command or url"},
+		{config.MCPServerConfig{Command: "x", URL: "http://localhost"}, "not both"},
+		{config.MCPServerConfig{URL: "localhost:8080"}, "url must start with http:// or https://"},
+	}
+	for _, tt := range tests {
+		_, err := Connect(context.Background(), "bad", tt.cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Connect(%+v) err = %v, want %q", tt.cfg, err, tt.want)
+		}
+	}
+}
+
+func TestRenderContent(t *testing.T) {
+	var blocks []ContentBlock
+	json.Unmarshal([]byte(`[
+		{"type": "text", "text": "hello"},
+		{"type": "image", "data": "aGk=", "mimeType": "image/png"},
+		{"type": "resource", "resource": {"uri": "file:///a.txt", "text": "file body"}},
+		{"type": "resource", "resource": {"uri": "file:///b.bin", "blob": "AA=="}},
+		{"type": "resource_link", "uri": "file:///c.txt"}
+	]`), &blocks)
+
+	want := "hello\n[image content omitted: image/png]\nfile body\n[resource: file:///b.bin]\n[resource link: file:///c.txt]"
+	if got := RenderContent(blocks); got != want {
+		t.Errorf("RenderContent = %q, want %q", got, want)
+	}
+}
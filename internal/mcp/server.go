@@ -0,0 +1,224 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ToolHandler runs a tool call for a Server. Failures of the tool itself
+// should be reported in the result with IsError set; a returned error is
+// sent to the client as a JSON-RPC error.
+type ToolHandler func(ctx context.Context, name string, args json.RawMessage) (*CallResult, error)
+
+// Server serves a fixed set of tools over the stdio transport. Tool calls
+// run concurrently, so one slow call does not block others.
+type Server struct {
+	Info  Implementation
+	Tools []Tool
+	Call  ToolHandler
+
+	writeMu sync.Mutex
+	w       io.Writer
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes
+// responses to w until r is exhausted or ctx is cancelled. Tool calls still
+// running when r is exhausted are answered before Serve returns; when ctx
+// is cancelled they are cancelled too.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+	s.inflight = map[string]context.CancelFunc{}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(strings.TrimSpace(string(line))) > 0 {
+				select {
+				case lines <- line:
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.cancelAll()
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			var msg Message
+			if err := json.Unmarshal(line, &msg); err != nil {
+				s.reply(&Message{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &RPCError{Code: CodeParseError, Message: "parse error"}})
+				continue
+			}
+			if msg.Method == "tools/call" && len(msg.ID) > 0 {
+				callCtx, cancel := context.WithCancel(ctx)
+				s.mu.Lock()
+				s.inflight[string(msg.ID)] = cancel
+				s.mu.Unlock()
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer s.finish(string(msg.ID))
+					s.reply(s.callTool(callCtx, &msg))
+				}()
+				continue
+			}
+			if reply := s.handle(&msg); reply != nil {
+				s.reply(reply)
+			}
+		}
+	}
+}
+
+// handle answers every message except tools/call. It returns nil for
+// notifications and responses.
+func (s *Server) handle(msg *Message) *Message {
+	if msg.Method == "notifications/cancelled" {
+		var params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.mu.Lock()
+			if cancel, ok := s.inflight[string(params.RequestID)]; ok {
+				cancel()
+			}
+			s.mu.Unlock()
+		}
+		return nil
+	}
+	if msg.Method == "" || len(msg.ID) == 0 {
+		return nil
+	}
+
+	var result interface{}
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		// Older clients get their own revision back; the methods axe serves
+		// are the same in all of them.
+		version := ProtocolVersion
+		if params.ProtocolVersion != "" && params.ProtocolVersion < ProtocolVersion {
+			version = params.ProtocolVersion
+		}
+		result = map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{"listChanged": false}},
+			"serverInfo":      s.Info,
+		}
+	case "ping":
+		result = map[string]interface{}{}
+	case "tools/list":
+		tools := s.Tools
+		if tools == nil {
+			tools = []Tool{}
+		}
+		result = map[string]interface{}{"tools": tools}
+	default:
+		return errorReply(msg.ID, CodeMethodNotFound, "method not found: "+msg.Method)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorReply(msg.ID, CodeInternalError, err.Error())
+	}
+	return &Message{JSONRPC: "2.0", ID: msg.ID, Result: data}
+}
+
+func (s *Server) callTool(ctx context.Context, msg *Message) *Message {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+		return errorReply(msg.ID, CodeInvalidParams, "tools/call requires a tool name")
+	}
+	if !s.hasTool(params.Name) {
+		return errorReply(msg.ID, CodeInvalidParams, fmt.Sprintf("unknown tool: %s", params.Name))
+	}
+
+	result, err := s.Call(ctx, params.Name, params.Arguments)
+	if err != nil {
+		return errorReply(msg.ID, CodeInternalError, err.Error())
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"content": []ContentBlock{{Type: "text", Text: result.Content}},
+		"isError": result.IsError,
+	})
+	if err != nil {
+		return errorReply(msg.ID, CodeInternalError, err.Error())
+	}
+	return &Message{JSONRPC: "2.0", ID: msg.ID, Result: data}
+}
+
+func (s *Server) hasTool(name string) bool {
+	for _, t := range s.Tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) finish(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.inflight[id]; ok {
+		cancel()
+		delete(s.inflight, id)
+	}
+}
+
+func (s *Server) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cancel := range s.inflight {
+		cancel()
+	}
+}
+
+func (s *Server) reply(msg *Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.w.Write(append(data, '\n'))
+}
+
+func errorReply(id json.RawMessage, code int, message string) *Message {
+	return &Message{JSONRPC: "2.0", ID: id, Error: &RPCError{Code: code, Message: message}}
+}
@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer safe for a concurrent writer and reader.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// serveLines runs a Server over the given request lines and returns the
+// responses keyed by ID.
+func serveLines(t *testing.T, s *Server, lines ...string) map[string]Message {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	replies := map[string]Message{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg Message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("invalid response line %q: %v", line, err)
+		}
+		replies[string(msg.ID)] = msg
+	}
+	return replies
+}
+
+func testServer() *Server {
+	return &Server{
+		Info:  Implementation{Name: "axe", Version: "test"},
+		Tools: []Tool{{Name: "reviewer", Description: "Reviews code", InputSchema: json.RawMessage(`{"type":"object"}`)}},
+		Call: func(ctx context.Context, name string, args json.RawMessage) (*CallResult, error) {
+			var a struct {
+				Task string `json:"task"`
+			}
+			json.Unmarshal(args, &a)
+			switch a.Task {
+			case "fail":
+				return &CallResult{Content: "agent failed", IsError: true}, nil
+			case "crash":
+				return nil, errors.New("handler crashed")
+			}
+			return &CallResult{Content: name + " did " + a.Task}, nil
+		},
+	}
+}
+
+func TestServer_Handshake(t *testing.T) {
+	replies := serveLines(t, testServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"editor","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+	)
+
+	if len(replies) != 4 {
+		t.Fatalf("got %d replies, want 4 (no reply to the notification)", len(replies))
+	}
+
+	var init struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+	}
+	json.Unmarshal(replies["1"].Result, &init)
+	if init.ProtocolVersion != "2025-03-26" || init.ServerInfo.Name != "axe" {
+		t.Errorf("initialize result = %s", replies["1"].Result)
+	}
+
+	var list struct {
+		Tools []Tool `json:"tools"`
+	}
+	json.Unmarshal(replies["2"].Result, &list)
+	if len(list.Tools) != 1 || list.Tools[0].Name != "reviewer" {
+		t.Errorf("tools/list result = %s", replies["2"].Result)
+	}
+
+	if string(replies["3"].Result) != "{}" {
+		t.Errorf("ping result = %s", replies["3"].Result)
+	}
+	if replies["4"].Error == nil || replies["4"].Error.Code != CodeMethodNotFound {
+		t.Errorf("resources/list = %+v, want method not found", replies["4"])
+	}
+}
+
+func TestServer_ToolCalls(t *testing.T) {
+	replies := serveLines(t, testServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"reviewer","arguments":{"task":"review"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"reviewer","arguments":{"task":"fail"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"reviewer","arguments":{"task":"crash"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"missing","arguments":{}}}`,
+		`not json`,
+	)
+
+	var result struct {
+		Content []ContentBlock `json:"content"`
+		IsError bool           `json:"isError"`
+	}
+	json.Unmarshal(replies["1"].Result, &result)
+	if RenderContent(result.Content) != "reviewer did review" || result.IsError {
+		t.Errorf("call 1 = %s", replies["1"].Result)
+	}
+
+	result.IsError = false
+	json.Unmarshal(replies["2"].Result, &result)
+	if RenderContent(result.Content) != "agent failed" || !result.IsError {
+		t.Errorf("call 2 = %s, want isError", replies["2"].Result)
+	}
+
+	if e := replies["3"].Error; e == nil || e.Code != CodeInternalError || e.Message != "handler crashed" {
+		t.Errorf("call 3 = %+v, want internal error", replies["3"])
+	}
+	if e := replies["4"].Error; e == nil || e.Code != CodeInvalidParams {
+		t.Errorf("call 4 = %+v, want invalid params", replies["4"])
+	}
+	if e := replies["null"].Error; e == nil || e.Code != CodeParseError {
+		t.Errorf("bad line = %+v, want parse error", replies["null"])
+	}
+}
+
+func TestServer_Cancellation(t *testing.T) {
+	s := testServer()
+	started := make(chan struct{})
+	s.Call = func(ctx context.Context, name string, args json.RawMessage) (*CallResult, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return &CallResult{Content: "cancelled", IsError: true}, nil
+		case <-time.After(5 * time.Second):
+			return &CallResult{Content: "finished"}, nil
+		}
+	}
+
+	in, inW := io.Pipe()
+	var out lockedBuffer
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Serve(context.Background(), in, &out) }()
+
+	inW.Write([]byte(`{"jsonrpc":"2.0","id":"a","method":"tools/call","params":{"name":"reviewer","arguments":{}}}` + "\n"))
+	<-started
+	inW.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"a"}}` + "\n"))
+	inW.Close()
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	if !strings.Contains(out.String(), "cancelled") {
+		t.Errorf("output = %s, want cancelled call", out.String())
+	}
+}
@@ -0,0 +1,224 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrswab/axe/internal/config"
+)
+
+// closeTimeout is how long a server may take to exit after its stdin is
+// closed before it is killed, and how long ending an HTTP session may take.
+const closeTimeout = 2 * time.Second
+
+// maxStderrTail is how much of a server's stderr is kept for error messages.
+const maxStderrTail = 4 << 10
+
+// stdioTransport talks to a server subprocess using newline-delimited
+// JSON-RPC messages on its stdin and stdout.
+type stdioTransport struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *Message
+	done    chan struct{} // closed when the server's stdout ends
+	readErr error
+
+	closeOnce sync.Once
+}
+
+func newStdioTransport(cfg config.MCPServerConfig) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  &tailBuffer{max: maxStderrTail},
+		pending: map[string]chan *Message{},
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = t.stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %q: %w", cfg.Command, err)
+	}
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+// readLoop delivers responses to their waiting requests and answers
+// requests the server sends to the client.
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	br := bufio.NewReader(stdout)
+	var err error
+	for {
+		var line []byte
+		line, err = br.ReadBytes('\n')
+		if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+			var msg Message
+			if json.Unmarshal([]byte(trimmed), &msg) == nil {
+				t.handle(&msg)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	if err == io.EOF {
+		err = errors.New("server closed the connection")
+	}
+	if detail := strings.TrimSpace(t.stderr.String()); detail != "" {
+		err = fmt.Errorf("%w: %s", err, detail)
+	}
+	t.readErr = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) handle(msg *Message) {
+	switch {
+	case msg.IsResponse():
+		t.mu.Lock()
+		ch, ok := t.pending[string(msg.ID)]
+		delete(t.pending, string(msg.ID))
+		t.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	case msg.Method != "" && len(msg.ID) > 0:
+		// axe declares no client capabilities, so ping is the only server
+		// request it supports.
+		reply := &Message{JSONRPC: "2.0", ID: msg.ID}
+		if msg.Method == "ping" {
+			reply.Result = json.RawMessage("{}")
+		} else {
+			reply.Error = &RPCError{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method}
+		}
+		t.write(reply)
+	}
+	// Notifications from the server (logging, progress) are ignored.
+}
+
+func (t *stdioTransport) write(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, msg *Message) (*Message, error) {
+	ch := make(chan *Message, 1)
+	key := string(msg.ID)
+	t.mu.Lock()
+	t.pending[key] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(msg); err != nil {
+		return nil, t.failure(err)
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		return nil, t.failure(nil)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, msg *Message) error {
+	if err := t.write(msg); err != nil {
+		return t.failure(err)
+	}
+	return nil
+}
+
+// failure explains why the server cannot be reached, preferring the reason
+// its output ended over a write error.
+func (t *stdioTransport) failure(err error) error {
+	select {
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.readErr
+	case <-time.After(100 * time.Millisecond):
+		return err
+	}
+}
+
+// close closes the server's stdin and waits for it to exit, killing it if it
+// does not exit within closeTimeout.
+func (t *stdioTransport) close() error {
+	t.closeOnce.Do(func() {
+		t.stdin.Close()
+		// Wait for stdout to end before Wait closes the pipe under readLoop
+		select {
+		case <-t.done:
+		case <-time.After(closeTimeout):
+			t.cmd.Process.Kill()
+			<-t.done
+		}
+		t.cmd.Wait()
+	})
+	return nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileLocks holds a mutex per memory file, so writers in this process, e.g.
+// parallel sub-agents sharing a pool, take turns even where lockFile cannot
+// lock across processes.
+var fileLocks sync.Map
+
+// openLocked opens the memory file at path with flag and holds an exclusive
+// lock on it until unlock is called. If the file is replaced (see
+// replaceFile) while waiting for the lock, it is opened again, so a writer
+// never writes to a file that is no longer at path.
+func openLocked(path string, flag int) (f *os.File, unlock func(), err error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+	v, _ := fileLocks.LoadOrStore(key, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+
+	for {
+		f, err = os.OpenFile(path, flag, 0644)
+		if err != nil {
+			mu.Unlock()
+			return nil, nil, err
+		}
+		if err = lockFile(f); err != nil {
+			f.Close()
+			mu.Unlock()
+			return nil, nil, fmt.Errorf("failed to lock memory file: %w", err)
+		}
+
+		opened, statErr := f.Stat()
+		current, pathErr := os.Stat(path)
+		if statErr == nil && pathErr == nil && os.SameFile(opened, current) {
+			// Closing f releases the file lock.
+			return f, func() { f.Close(); mu.Unlock() }, nil
+		}
+		f.Close()
+		if statErr != nil || (pathErr != nil && !errors.Is(pathErr, fs.ErrNotExist)) {
+			mu.Unlock()
+			return nil, nil, fmt.Errorf("failed to lock memory file: %w", errors.Join(statErr, pathErr))
+		}
+	}
+}
+
+// update locks the memory file at path and rewrites it with the entries fn
+// returns for its current ones, unless fn returns false. A missing file is
+// left alone.
+func update(path string, fn func(entries []Entry) ([]Entry, bool)) error {
+	_, unlock, err := openLocked(path, os.O_RDONLY)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := Load(path)
+	if err != nil {
+		return err
+	}
+	kept, changed := fn(entries)
+	if !changed {
+		return nil
+	}
+	return Rewrite(path, kept)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package memory
+
+import "os"
+
+// lockFile does nothing on platforms without flock: memory writers are only
+// serialized within a process (see fileLocks).
+func lockFile(f *os.File) error {
+	return nil
+}
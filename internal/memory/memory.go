@@ -1,11 +1,14 @@
 package memory
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/jrswab/axe/internal/xdg"
 )
@@ -16,7 +19,8 @@ var Now func() time.Time = time.Now
 
 // FilePath returns the memory file path for the given agent.
 // If customPath is non-empty it is returned as-is.
-// Otherwise the default path is <xdg-data-dir>/memory/<agentName>.md.
+// Otherwise the default path is <xdg-data-dir>/memory/<agentName>.jsonl, or
+// the legacy <agentName>.md if only that exists (see Migrate).
 // The function does not create any directories or files.
 func FilePath(agentName, customPath string) (string, error) {
 	if customPath != "" {
@@ -28,25 +32,71 @@ func FilePath(agentName, customPath string) (string, error) {
 		return "", err
 	}
 
-	return filepath.Join(dataDir, "memory", agentName+".md"), nil
+	path := filepath.Join(dataDir, "memory", agentName+".jsonl")
+	legacy := filepath.Join(dataDir, "memory", agentName+".md")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy, nil
+		}
+	}
+	return path, nil
 }
 
-// AppendEntry appends a timestamped memory entry to the file at path.
-// Parent directories are created if they do not exist.
-func AppendEntry(path, task, result string) error {
-	// Create parent directory
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create memory directory: %w", err)
+// SharedFilePath returns the path of the shared memory pool name:
+// <xdg-data-dir>/memory/shared/<name>.jsonl. Every agent that lists name in
+// memory.shared reads and appends to it.
+func SharedFilePath(name string) (string, error) {
+	dataDir, err := xdg.GetDataDir()
+	if err != nil {
+		return "", err
 	}
+	return filepath.Join(dataDir, "memory", "shared", name+".jsonl"), nil
+}
+
+// AgentTag returns the tag that marks entries of a shared pool with the
+// agent that wrote them.
+func AgentTag(agentName string) string {
+	return "agent:" + agentName
+}
 
-	// Open file in append mode
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// IsMarkdown reports whether the memory file at path uses the legacy
+// markdown format. The content decides, since earlier versions wrote
+// markdown to a custom memory.path whatever its extension: a JSONL file
+// starts with "{", anything else is markdown. A missing or empty file is
+// markdown only if path has the ".md" extension.
+func IsMarkdown(path string) bool {
+	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open memory file: %w", err)
+		return isMarkdown(strings.NewReader(""), path)
 	}
 	defer f.Close()
+	return isMarkdown(f, path)
+}
+
+// isMarkdown is IsMarkdown for the memory file at path whose content r
+// reads.
+func isMarkdown(r io.Reader, path string) bool {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return strings.EqualFold(filepath.Ext(path), ".md")
+		}
+		if !unicode.IsSpace(rune(b)) {
+			return b != '{'
+		}
+	}
+}
 
+// AppendEntry appends a timestamped memory entry with task and result to
+// the file at path. Parent directories are created if they do not exist.
+func AppendEntry(path, task, result string) error {
+	return Append(path, Entry{Task: task, Result: result})
+}
+
+// appendMarkdownEntry appends a timestamped memory entry to f, a legacy
+// markdown file opened for appending.
+func appendMarkdownEntry(f *os.File, task, result string) error {
 	// Format timestamp
 	ts := Now().UTC().Format(time.RFC3339)
 
@@ -75,11 +125,32 @@ func AppendEntry(path, task, result string) error {
 	return nil
 }
 
-// LoadEntries reads memory entries from the file at path.
+// LoadEntries reads memory entries from the file at path, rendered as
+// markdown for prompts (see Render). Digest entries come first and do not
+// count towards lastN.
 // If the file does not exist, it returns ("", nil).
+// If lastN is 0, all entries are returned.
+// If lastN > 0, only the last N entries are returned.
+func LoadEntries(path string, lastN int) (string, error) {
+	if IsMarkdown(path) {
+		return loadMarkdownEntries(path, lastN)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		return "", err
+	}
+	digests, rest := Pinned(entries)
+	if lastN > 0 && lastN < len(rest) {
+		rest = rest[len(rest)-lastN:]
+	}
+	return Render(append(digests, rest...)), nil
+}
+
+// loadMarkdownEntries reads entries from the legacy markdown file at path.
 // If lastN is 0, all content is returned.
 // If lastN > 0, only the last N entries (starting with "## ") are returned.
-func LoadEntries(path string, lastN int) (string, error) {
+func loadMarkdownEntries(path string, lastN int) (string, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -127,7 +198,8 @@ func LoadEntries(path string, lastN int) (string, error) {
 	return result.String(), nil
 }
 
-// TrimEntries keeps only the last keepN entries in the memory file at path.
+// TrimEntries keeps only the last keepN entries in the memory file at path,
+// plus any digest entries.
 // If keepN is 0, it returns (0, nil) without modifying the file (keep all).
 // If keepN is negative, it returns an error.
 // If the file does not exist, it returns (0, nil).
@@ -141,6 +213,37 @@ func TrimEntries(path string, keepN int) (int, error) {
 		return 0, nil
 	}
 
+	if IsMarkdown(path) {
+		return trimMarkdownEntries(path, keepN)
+	}
+
+	removed := 0
+	err := update(path, func(entries []Entry) ([]Entry, bool) {
+		digests, rest := Pinned(entries)
+		if len(rest) <= keepN {
+			return nil, false
+		}
+		removed = len(rest) - keepN
+		return append(digests, rest[removed:]...), true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// trimMarkdownEntries keeps only the last keepN entries in the legacy
+// markdown file at path.
+func trimMarkdownEntries(path string, keepN int) (int, error) {
+	_, unlock, err := openLocked(path, os.O_RDONLY)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer unlock()
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -182,49 +285,69 @@ func TrimEntries(path string, keepN int) (int, error) {
 		result.WriteString(lines[i])
 	}
 
+	if err := replaceFile(path, []byte(result.String())); err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// replaceFile atomically replaces the file at path with data, keeping its
+// permissions: data is written to a temp file in the same directory, which
+// is then renamed over path. A file that does not exist yet is created with
+// mode 0644.
+func replaceFile(path string, data []byte) error {
 	// Preserve original file permissions for the atomic replace.
+	perm := os.FileMode(0644)
 	origInfo, err := os.Stat(path)
-	if err != nil {
-		return 0, fmt.Errorf("failed to stat original file: %w", err)
+	if err == nil {
+		perm = origInfo.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat original file: %w", err)
 	}
 
 	// Atomic write: temp file in same directory, then rename
 	dir := filepath.Dir(path)
 	tmpFile, err := os.CreateTemp(dir, ".axe-trim-*.tmp")
 	if err != nil {
-		return 0, fmt.Errorf("failed to create temp file: %w", err)
+		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tmpPath := tmpFile.Name()
 
-	if err := tmpFile.Chmod(origInfo.Mode().Perm()); err != nil {
+	if err := tmpFile.Chmod(perm); err != nil {
 		tmpFile.Close()
 		os.Remove(tmpPath)
-		return 0, fmt.Errorf("failed to set temp file permissions: %w", err)
+		return fmt.Errorf("failed to set temp file permissions: %w", err)
 	}
 
-	if _, err := tmpFile.WriteString(result.String()); err != nil {
+	if _, err := tmpFile.Write(data); err != nil {
 		tmpFile.Close()
 		os.Remove(tmpPath)
-		return 0, fmt.Errorf("failed to write temp file: %w", err)
+		return fmt.Errorf("failed to write temp file: %w", err)
 	}
 
 	if err := tmpFile.Close(); err != nil {
 		os.Remove(tmpPath)
-		return 0, fmt.Errorf("failed to close temp file: %w", err)
+		return fmt.Errorf("failed to close temp file: %w", err)
 	}
 
 	if err := os.Rename(tmpPath, path); err != nil {
 		os.Remove(tmpPath)
-		return 0, fmt.Errorf("failed to rename temp file: %w", err)
+		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
-	return removed, nil
+	return nil
 }
 
 // CountEntries counts the number of entries in the memory file at path.
-// An entry is any line starting with "## ".
+// In the legacy markdown format, an entry is any line starting with "## ".
 // If the file does not exist, it returns (0, nil).
 func CountEntries(path string) (int, error) {
+	if !IsMarkdown(path) {
+		entries, err := Load(path)
+		return len(entries), err
+	}
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -20,12 +20,35 @@ func TestFilePath_Default(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := filepath.Join(tmpDir, "axe", "memory", "myagent.md")
+	want := filepath.Join(tmpDir, "axe", "memory", "myagent.jsonl")
 	if got != want {
 		t.Errorf("FilePath() = %q, want %q", got, want)
 	}
 }
 
+func TestFilePath_LegacyMarkdown(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", tmpDir)
+	memDir := filepath.Join(tmpDir, "axe", "memory")
+	os.MkdirAll(memDir, 0755)
+	os.WriteFile(filepath.Join(memDir, "myagent.md"), []byte(""), 0644)
+
+	got, err := FilePath("myagent", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(memDir, "myagent.md"); got != want {
+		t.Errorf("FilePath() = %q, want the legacy file %q", got, want)
+	}
+
+	// Once migrated, the JSONL file wins
+	os.WriteFile(filepath.Join(memDir, "myagent.jsonl"), []byte(""), 0644)
+	got, _ = FilePath("myagent", "")
+	if want := filepath.Join(memDir, "myagent.jsonl"); got != want {
+		t.Errorf("FilePath() = %q, want %q", got, want)
+	}
+}
+
 func TestFilePath_CustomPath(t *testing.T) {
 	got, err := FilePath("myagent", "/custom/path/mem.md")
 	if err != nil {
@@ -47,8 +70,8 @@ func TestFilePath_EmptyAgentName(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if !strings.HasSuffix(got, filepath.Join("memory", ".md")) {
-		t.Errorf("FilePath() = %q, want suffix %q", got, filepath.Join("memory", ".md"))
+	if !strings.HasSuffix(got, filepath.Join("memory", ".jsonl")) {
+		t.Errorf("FilePath() = %q, want suffix %q", got, filepath.Join("memory", ".jsonl"))
 	}
 }
 
@@ -0,0 +1,268 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters: term frequency saturation and length normalization.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Embedder computes embeddings for relevant-memory retrieval.
+type Embedder struct {
+	// Model identifies the embedding model, e.g. "ollama/nomic-embed-text".
+	// Cached vectors are discarded when it changes.
+	Model string
+	// Embed returns one vector per text, in the order of texts.
+	Embed func(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// LoadRelevant returns up to k entries of the memory file at path that are
+// most relevant to query, oldest first, after any digest entries, which are
+// always returned. Entries are compared by their markdown rendering.
+//
+// With a nil embedder, entries are ranked lexically with BM25, and entries
+// that share no terms with query are never returned. Otherwise they are
+// ranked by the cosine similarity of their embeddings to the query's. Entry
+// embeddings are cached in the index file next to the memory file (see
+// IndexPath), so each entry is embedded only once per model.
+func LoadRelevant(ctx context.Context, path, query string, k int, embedder *Embedder) ([]Entry, error) {
+	all, err := Load(path)
+	if err != nil || len(all) == 0 || k <= 0 {
+		return nil, err
+	}
+	digests, entries := Pinned(all)
+	if len(entries) == 0 {
+		return digests, nil
+	}
+	texts := make([]string, len(entries))
+	for i, e := range entries {
+		texts[i] = e.Markdown()
+	}
+
+	var scores []float64
+	if embedder == nil {
+		scores = bm25Scores(query, texts)
+	} else {
+		scores, err = embeddingScores(ctx, path, query, texts, embedder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Highest score first; among equal scores, the most recent entry first.
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if scores[order[a]] != scores[order[b]] {
+			return scores[order[a]] > scores[order[b]]
+		}
+		return order[a] > order[b]
+	})
+
+	var picked []int
+	for _, i := range order {
+		if len(picked) == k || (embedder == nil && scores[i] <= 0) {
+			break
+		}
+		picked = append(picked, i)
+	}
+	sort.Ints(picked)
+
+	selected := digests
+	for _, i := range picked {
+		selected = append(selected, entries[i])
+	}
+	return selected, nil
+}
+
+// tokenize splits text into lowercase runs of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// bm25Scores scores each document against query with Okapi BM25.
+func bm25Scores(query string, docs []string) []float64 {
+	docTerms := make([]map[string]int, len(docs))
+	docLens := make([]int, len(docs))
+	df := map[string]int{}
+	totalLen := 0
+	for i, doc := range docs {
+		tokens := tokenize(doc)
+		terms := map[string]int{}
+		for _, t := range tokens {
+			terms[t]++
+		}
+		for t := range terms {
+			df[t]++
+		}
+		docTerms[i] = terms
+		docLens[i] = len(tokens)
+		totalLen += len(tokens)
+	}
+	avgLen := float64(totalLen) / float64(len(docs))
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	n := float64(len(docs))
+	seen := map[string]bool{}
+	scores := make([]float64, len(docs))
+	for _, term := range tokenize(query) {
+		if seen[term] || df[term] == 0 {
+			continue
+		}
+		seen[term] = true
+		idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+		for i, terms := range docTerms {
+			tf := float64(terms[term])
+			if tf == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(docLens[i])/avgLen
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+	return scores
+}
+
+// embedBatchSize is the most texts sent in one embedding request, to stay
+// within providers' per-request input limits.
+const embedBatchSize = 64
+
+// index is the on-disk cache of entry embeddings, keyed by the SHA-256 of
+// each entry's text.
+type index struct {
+	Model   string               `json:"model"`
+	Vectors map[string][]float64 `json:"vectors"`
+}
+
+// IndexPath returns the path of the embedding index of the memory file at
+// path.
+func IndexPath(path string) string {
+	return path + ".index.json"
+}
+
+// embeddingScores scores each entry by the cosine similarity of its
+// embedding to the query's, embedding only entries missing from the index.
+// Texts are embedded embedBatchSize at a time, and the index is saved after
+// each batch but the last, so a large memory file is indexed over several
+// runs even if a later batch fails. The index is finally replaced, under its
+// lock, with exactly the current entries; failing to write it only costs
+// re-embedding on the next run.
+func embeddingScores(ctx context.Context, path, query string, entries []string, embedder *Embedder) ([]float64, error) {
+	cached := loadIndex(path, embedder.Model)
+
+	keys := make([]string, len(entries))
+	var missing, missingKeys []string
+	for i, entry := range entries {
+		sum := sha256.Sum256([]byte(entry))
+		keys[i] = hex.EncodeToString(sum[:])
+		if _, ok := cached.Vectors[keys[i]]; !ok {
+			missing = append(missing, entry)
+			missingKeys = append(missingKeys, keys[i])
+		}
+	}
+
+	texts := append(missing, query)
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += embedBatchSize {
+		batch := texts[start:min(start+embedBatchSize, len(texts))]
+		got, err := embedder.Embed(ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed memory: %w", err)
+		}
+		if len(got) != len(batch) {
+			return nil, fmt.Errorf("failed to embed memory: expected %d embeddings, got %d", len(batch), len(got))
+		}
+		vectors = append(vectors, got...)
+
+		if start+len(batch) < len(texts) {
+			for i, vec := range got {
+				cached.Vectors[missingKeys[start+i]] = vec
+			}
+			if data, err := json.Marshal(cached); err == nil {
+				writeIndex(path, data)
+			}
+		}
+	}
+	queryVec := vectors[len(missing)]
+
+	updated := index{Model: embedder.Model, Vectors: make(map[string][]float64, len(entries))}
+	next := 0
+	scores := make([]float64, len(entries))
+	for i, key := range keys {
+		vec, ok := cached.Vectors[key]
+		if !ok {
+			vec = vectors[next]
+			next++
+		}
+		updated.Vectors[key] = vec
+		scores[i] = cosine(queryVec, vec)
+	}
+
+	if data, err := json.Marshal(updated); err == nil {
+		writeIndex(path, data)
+	}
+	return scores, nil
+}
+
+// writeIndex replaces the embedding index of the memory file at path with
+// data while holding the index's lock, so runs sharing a memory file do not
+// interleave their writes.
+func writeIndex(path string, data []byte) error {
+	_, unlock, err := openLocked(IndexPath(path), os.O_RDONLY|os.O_CREATE)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return replaceFile(IndexPath(path), data)
+}
+
+// loadIndex reads the embedding index of the memory file at path. A missing
+// or unreadable index, or one built with a different model, is empty.
+func loadIndex(path, model string) index {
+	empty := index{Model: model, Vectors: map[string][]float64{}}
+	data, err := os.ReadFile(IndexPath(path))
+	if err != nil {
+		return empty
+	}
+	var idx index
+	if json.Unmarshal(data, &idx) != nil || idx.Model != model || idx.Vectors == nil {
+		return empty
+	}
+	return idx
+}
+
+// cosine returns the cosine similarity of a and b, or 0 if their lengths
+// differ or either is zero.
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
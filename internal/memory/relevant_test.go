@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeMemory writes a JSONL memory file with entries for the given tasks,
+// oldest first, and returns its path.
+func writeMemory(t *testing.T, tasks ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent.jsonl")
+	for i, task := range tasks {
+		e := Entry{Timestamp: time.Date(2026, 3, i+1, 0, 0, 0, 0, time.UTC), Task: task, Result: "done"}
+		if err := Append(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// tasksOf returns the task of each entry.
+func tasksOf(entries []Entry) []string {
+	var tasks []string
+	for _, e := range entries {
+		tasks = append(tasks, e.Task)
+	}
+	return tasks
+}
+
+func TestLoadRelevant_BM25(t *testing.T) {
+	path := writeMemory(t,
+		"fix the flaky auth test in login_test.go",
+		"update the changelog for release 1.2",
+		"review the auth middleware for token expiry",
+		"bump dependencies",
+	)
+
+	entries, err := LoadRelevant(context.Background(), path, "auth token refresh is failing", 2, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The two auth entries, in file order
+	got := strings.Join(tasksOf(entries), " | ")
+	want := "fix the flaky auth test in login_test.go | review the auth middleware for token expiry"
+	if got != want {
+		t.Errorf("LoadRelevant = %q, want %q", got, want)
+	}
+
+	entries, err = LoadRelevant(context.Background(), path, "kubernetes", 2, nil)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("LoadRelevant with no matching terms = %q, %v; want none", entries, err)
+	}
+}
+
+func TestLoadRelevant_Embeddings(t *testing.T) {
+	path := writeMemory(t, "alpha", "beta", "gamma")
+
+	// Each text maps to a fixed direction; the query is closest to gamma,
+	// then alpha.
+	vectors := map[string][]float64{"alpha": {1, 0.2}, "beta": {0, 1}, "gamma": {1, 0}, "query": {1, 0.05}}
+	var embedded []string
+	embedder := &Embedder{Model: "ollama/test-embed", Embed: func(ctx context.Context, texts []string) ([][]float64, error) {
+		var out [][]float64
+		for _, text := range texts {
+			embedded = append(embedded, text)
+			key := "query"
+			for k := range vectors {
+				if strings.Contains(text, "**Task:** "+k+"\n") {
+					key = k
+				}
+			}
+			out = append(out, vectors[key])
+		}
+		return out, nil
+	}}
+
+	entries, err := LoadRelevant(context.Background(), path, "query", 2, embedder)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(tasksOf(entries), ","); got != "alpha,gamma" {
+		t.Errorf("LoadRelevant = %q, want alpha,gamma in file order", got)
+	}
+	if len(embedded) != 4 {
+		t.Errorf("embedded %d texts, want 3 entries and the query", len(embedded))
+	}
+
+	var idx index
+	data, err := os.ReadFile(IndexPath(path))
+	if err != nil || json.Unmarshal(data, &idx) != nil || idx.Model != "ollama/test-embed" || len(idx.Vectors) != 3 {
+		t.Fatalf("index = %s, %v; want 3 vectors for the model", data, err)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".axe-*.tmp")); len(tmps) != 0 {
+		t.Errorf("temp files left behind: %v", tmps)
+	}
+
+	// Cached entries are not embedded again
+	embedded = nil
+	if _, err := LoadRelevant(context.Background(), path, "query", 2, embedder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(embedded) != 1 || embedded[0] != "query" {
+		t.Errorf("embedded %q on second load, want only the query", embedded)
+	}
+
+	// A different model rebuilds the index
+	embedded = nil
+	embedder.Model = "openai/other"
+	if _, err := LoadRelevant(context.Background(), path, "query", 2, embedder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(embedded) != 4 {
+		t.Errorf("embedded %d texts after model change, want 4", len(embedded))
+	}
+}
+
+func TestLoadRelevant_EmbeddingBatches(t *testing.T) {
+	tasks := make([]string, 2*embedBatchSize+10)
+	for i := range tasks {
+		tasks[i] = fmt.Sprintf("task %d", i)
+	}
+	path := writeMemory(t, tasks...)
+
+	// The third request fails: the first two batches stay indexed
+	var batches []int
+	embedder := &Embedder{Model: "m", Embed: func(ctx context.Context, texts []string) ([][]float64, error) {
+		batches = append(batches, len(texts))
+		if len(batches) == 3 {
+			return nil, errors.New("rate limited")
+		}
+		out := make([][]float64, len(texts))
+		for i := range out {
+			out[i] = []float64{1, 0}
+		}
+		return out, nil
+	}}
+	if _, err := LoadRelevant(context.Background(), path, "query", 2, embedder); err == nil {
+		t.Fatal("expected an embedding error")
+	}
+	if len(batches) != 3 || batches[0] != embedBatchSize || batches[1] != embedBatchSize {
+		t.Errorf("batches = %v, want two full batches before the failure", batches)
+	}
+	if idx := loadIndex(path, "m"); len(idx.Vectors) != 2*embedBatchSize {
+		t.Errorf("index has %d vectors, want %d", len(idx.Vectors), 2*embedBatchSize)
+	}
+
+	// The next run only embeds the rest and the query
+	batches = nil
+	embedder.Embed = func(ctx context.Context, texts []string) ([][]float64, error) {
+		batches = append(batches, len(texts))
+		out := make([][]float64, len(texts))
+		for i := range out {
+			out[i] = []float64{1, 0}
+		}
+		return out, nil
+	}
+	if _, err := LoadRelevant(context.Background(), path, "query", 2, embedder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches) != 1 || batches[0] != 11 {
+		t.Errorf("batches = %v, want one of 10 entries and the query", batches)
+	}
+	if idx := loadIndex(path, "m"); len(idx.Vectors) != len(tasks) {
+		t.Errorf("index has %d vectors, want %d", len(idx.Vectors), len(tasks))
+	}
+}
+
+func TestLoadRelevant_EmbeddingError(t *testing.T) {
+	path := writeMemory(t, "alpha")
+	embedder := &Embedder{Model: "m", Embed: func(ctx context.Context, texts []string) ([][]float64, error) {
+		return nil, errors.New("connection refused")
+	}}
+	_, err := LoadRelevant(context.Background(), path, "query", 2, embedder)
+	if err == nil || err.Error() != "failed to embed memory: connection refused" {
+		t.Errorf("err = %v, want embedding error", err)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if got := cosine([]float64{1, 0}, []float64{2, 0}); got != 1 {
+		t.Errorf("cosine of parallel vectors = %v, want 1", got)
+	}
+	if got := cosine([]float64{1, 0}, []float64{0, 1}); got != 0 {
+		t.Errorf("cosine of orthogonal vectors = %v, want 0", got)
+	}
+	if got := cosine([]float64{1}, []float64{1, 0}); got != 0 {
+		t.Errorf("cosine of mismatched lengths = %v, want 0", got)
+	}
+}
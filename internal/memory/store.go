@@ -0,0 +1,444 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// promptResultLimit is the number of bytes of a result Render keeps.
+const promptResultLimit = 1000
+
+// DigestTag marks the digest entry that "axe gc" consolidates trimmed
+// entries into. Digest entries are never trimmed and always come first in
+// the prompt (see Pinned).
+const DigestTag = "digest"
+
+// Entry is one memory entry. In JSONL memory files each line holds one
+// Entry as a JSON object.
+type Entry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Task         string    `json:"task"`
+	Result       string    `json:"result"`
+	Model        string    `json:"model,omitempty"` // "provider/model" that produced the result
+	InputTokens  int       `json:"input_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
+	RunID        string    `json:"run_id,omitempty"` // Run history record of the run, see "axe runs"
+	Tags         []string  `json:"tags,omitempty"`
+}
+
+// IsDigest reports whether e is a digest entry.
+func (e Entry) IsDigest() bool {
+	for _, tag := range e.Tags {
+		if tag == DigestTag {
+			return true
+		}
+	}
+	return false
+}
+
+// Pinned splits entries into digest entries and the rest, keeping their
+// order.
+func Pinned(entries []Entry) (digests, rest []Entry) {
+	for _, e := range entries {
+		if e.IsDigest() {
+			digests = append(digests, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	return digests, rest
+}
+
+// Markdown renders the entry for people to read, with its full result.
+func (e Entry) Markdown() string {
+	return e.markdown(0)
+}
+
+// markdown renders the entry in the format of legacy markdown memory files,
+// cutting the result at maxResult bytes unless maxResult is 0.
+func (e Entry) markdown(maxResult int) string {
+	task := strings.ReplaceAll(e.Task, "\n", " ")
+	if task == "" {
+		task = "(none)"
+	}
+	result := e.Result
+	if result == "" {
+		result = "(none)"
+	} else if maxResult > 0 && len(result) > maxResult {
+		end := maxResult
+		for end > 0 && !utf8.RuneStart(result[end]) {
+			end--
+		}
+		result = result[:end] + "..."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n**Task:** %s\n", e.Timestamp.UTC().Format(time.RFC3339), task)
+	if len(e.Tags) > 0 {
+		fmt.Fprintf(&b, "**Tags:** %s\n", strings.Join(e.Tags, ", "))
+	}
+	fmt.Fprintf(&b, "**Result:** %s\n\n", result)
+	return b.String()
+}
+
+// Render renders entries as markdown for a system prompt. Results are cut
+// at 1000 bytes, except those of digest entries.
+func Render(entries []Entry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		if e.IsDigest() {
+			b.WriteString(e.Markdown())
+			continue
+		}
+		b.WriteString(e.markdown(promptResultLimit))
+	}
+	return b.String()
+}
+
+// Load reads all entries of the memory file at path, oldest first, in
+// either format. If the file does not exist, it returns (nil, nil).
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if isMarkdown(bytes.NewReader(data), path) {
+		return parseMarkdown(string(data)), nil
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("memory file %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Append adds e to the memory file at path, stamped with the current time
+// if e has no timestamp. Parent directories are created if they do not
+// exist. Writers are serialized with a file lock, so several agents can
+// append to the same file. Legacy markdown files only keep the timestamp,
+// task and result.
+func Append(path string, e Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	f, unlock, err := openLocked(path, os.O_APPEND|os.O_CREATE|os.O_RDWR)
+	if err != nil {
+		return fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer unlock()
+
+	// The format is checked under the lock, as Migrate may convert the file
+	// in place.
+	if isMarkdown(f, path) {
+		return appendMarkdownEntry(f, e.Task, e.Result)
+	}
+
+	if e.Timestamp.IsZero() {
+		e.Timestamp = Now()
+	}
+	e.Timestamp = e.Timestamp.UTC().Truncate(time.Second)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode memory entry: %w", err)
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write memory entry: %w", err)
+	}
+	return nil
+}
+
+// Rewrite atomically replaces the contents of the memory file at path with
+// entries, in the file's format.
+func Rewrite(path string, entries []Entry) error {
+	return rewrite(path, entries, IsMarkdown(path))
+}
+
+// rewrite is Rewrite in the markdown format or in JSONL.
+func rewrite(path string, entries []Entry, markdown bool) error {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		if markdown {
+			buf.WriteString(e.Markdown())
+			continue
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode memory entry: %w", err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+	return replaceFile(path, buf.Bytes())
+}
+
+// ErrAmbiguousEntry is returned by Remove when more than one entry has the
+// given timestamp and none of them was picked.
+var ErrAmbiguousEntry = errors.New("more than one memory entry has that timestamp")
+
+// Remove deletes one entry of the memory file at path stamped with ts and
+// returns how many were removed, 0 or 1. Timestamps have one-second
+// resolution, so entries written in the same second, e.g. by agents sharing
+// a pool, share one; n picks the nth of them in file order, counting from
+// 1. With n = 0, ts must match a single entry: if it matches more, Remove
+// returns an error wrapping ErrAmbiguousEntry and removes nothing. The file
+// is rewritten atomically, and only if an entry is removed.
+func Remove(path string, ts time.Time, n int) (int, error) {
+	removed := 0
+	var matchErr error
+	err := update(path, func(entries []Entry) ([]Entry, bool) {
+		var matches []int
+		for i, e := range entries {
+			if e.Timestamp.Equal(ts) {
+				matches = append(matches, i)
+			}
+		}
+		switch {
+		case len(matches) == 0:
+			return nil, false
+		case n == 0 && len(matches) > 1:
+			matchErr = fmt.Errorf("%w: %d entries at %s", ErrAmbiguousEntry, len(matches), ts.UTC().Format(time.RFC3339))
+			return nil, false
+		case n > len(matches):
+			return nil, false
+		}
+		i := matches[max(n, 1)-1]
+		removed = 1
+		return append(entries[:i:i], entries[i+1:]...), true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, matchErr
+}
+
+// Clear removes every entry of the memory file at path, leaving it empty,
+// and deletes its embedding index. It returns the number of entries
+// removed. If the file does not exist, it returns (0, nil).
+func Clear(path string) (int, error) {
+	n := 0
+	err := update(path, func(entries []Entry) ([]Entry, bool) {
+		n = len(entries)
+		return nil, true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Remove(IndexPath(path)); err != nil && !os.IsNotExist(err) {
+		return n, fmt.Errorf("failed to remove memory index: %w", err)
+	}
+	return n, nil
+}
+
+// Matches reports whether every word of query appears, ignoring case, in
+// the entry's task, result or tags. An empty query matches every entry.
+func (e Entry) Matches(query string) bool {
+	text := strings.ToLower(e.Task + "\n" + e.Result + "\n" + strings.Join(e.Tags, " "))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Consolidate folds all but the last keepN entries of the JSONL memory file
+// at path, along with its current digest, into a single digest entry made
+// by digest, and returns the number of entries folded, not counting the old
+// digest. The new digest is tagged DigestTag and written first in the file.
+//
+// digest runs without holding the file lock, so runs can keep appending
+// while it summarizes; only the entries it was given are replaced.
+func Consolidate(path string, keepN int, digest func(folded []Entry) (Entry, error)) (int, error) {
+	if keepN < 0 {
+		return 0, fmt.Errorf("keepN must be non-negative")
+	}
+	if IsMarkdown(path) {
+		return 0, fmt.Errorf("cannot consolidate legacy markdown memory file %s: migrate it to JSONL first", path)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		return 0, err
+	}
+	digests, rest := Pinned(entries)
+	if len(rest) <= keepN {
+		return 0, nil
+	}
+	old := rest[:len(rest)-keepN]
+	folded := append(digests, old...)
+
+	d, err := digest(folded)
+	if err != nil {
+		return 0, err
+	}
+	if !d.IsDigest() {
+		d.Tags = append(d.Tags, DigestTag)
+	}
+	if d.Timestamp.IsZero() {
+		d.Timestamp = Now()
+	}
+	d.Timestamp = d.Timestamp.UTC().Truncate(time.Second)
+
+	// The file may have changed while digest ran: drop the folded entries
+	// that are still there and keep everything else.
+	remove := map[string]int{}
+	for _, e := range folded {
+		remove[entryKey(e)]++
+	}
+	err = update(path, func(current []Entry) ([]Entry, bool) {
+		kept := []Entry{d}
+		for _, e := range current {
+			if key := entryKey(e); remove[key] > 0 {
+				remove[key]--
+				continue
+			}
+			kept = append(kept, e)
+		}
+		return kept, true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(old), nil
+}
+
+// entryKey identifies an entry by its contents.
+func entryKey(e Entry) string {
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+// Migrate converts the legacy markdown memory file at path, whatever its
+// extension, to a JSONL file of the same name with a ".jsonl" extension,
+// and renames the original to "<path>.bak". A ".jsonl" file holding
+// markdown is converted in place, keeping a copy of the original as
+// "<path>.bak". It returns the new path and the number of entries
+// converted. Results that were cut when they were written stay cut. The
+// original stays locked until it is replaced, so an entry appended to it
+// meanwhile is neither lost nor migrated twice.
+func Migrate(path string) (string, int, error) {
+	f, unlock, err := openLocked(path, os.O_RDONLY)
+	if errors.Is(err, fs.ErrNotExist) && !IsMarkdown(path) {
+		return "", 0, fmt.Errorf("%s is not a markdown memory file", path)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	defer unlock()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", 0, err
+	}
+	if !isMarkdown(bytes.NewReader(data), path) {
+		return "", 0, fmt.Errorf("%s is not a markdown memory file", path)
+	}
+	entries := parseMarkdown(string(data))
+
+	ext := filepath.Ext(path)
+	if strings.EqualFold(ext, ".jsonl") {
+		if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+			return "", 0, fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		if err := rewrite(path, entries, false); err != nil {
+			return "", 0, err
+		}
+		return path, len(entries), nil
+	}
+
+	target := strings.TrimSuffix(path, ext) + ".jsonl"
+	if _, err := os.Stat(target); err == nil {
+		return "", 0, fmt.Errorf("cannot migrate %s: %s already exists", path, target)
+	}
+	if err := rewrite(target, entries, false); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(path, path+".bak"); err != nil {
+		os.Remove(target)
+		return "", 0, fmt.Errorf("failed to rename %s: %w", path, err)
+	}
+	return target, len(entries), nil
+}
+
+// parseMarkdown parses legacy markdown memory. An entry starts at a "## "
+// line holding an RFC 3339 timestamp, so other "## " lines, e.g. headings
+// in a result, stay part of the entry they appear in. Text before the first
+// entry is ignored.
+func parseMarkdown(content string) []Entry {
+	var entries []Entry
+	var result []string
+	inResult := false
+
+	finish := func() {
+		if len(entries) == 0 {
+			return
+		}
+		e := &entries[len(entries)-1]
+		e.Result = strings.TrimRight(strings.Join(result, "\n"), "\n ")
+		if e.Result == "(none)" {
+			e.Result = ""
+		}
+		result, inResult = nil, false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if ts, ok := strings.CutPrefix(line, "## "); ok {
+			if t, err := time.Parse(time.RFC3339, strings.TrimSpace(ts)); err == nil {
+				finish()
+				entries = append(entries, Entry{Timestamp: t.UTC()})
+				continue
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		e := &entries[len(entries)-1]
+
+		switch {
+		case inResult:
+			result = append(result, line)
+		case strings.HasPrefix(line, "**Task:** "):
+			e.Task = strings.TrimPrefix(line, "**Task:** ")
+			if e.Task == "(none)" {
+				e.Task = ""
+			}
+		case strings.HasPrefix(line, "**Tags:** "):
+			for _, tag := range strings.Split(strings.TrimPrefix(line, "**Tags:** "), ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					e.Tags = append(e.Tags, tag)
+				}
+			}
+		case strings.HasPrefix(line, "**Result:** "):
+			result = append(result, strings.TrimPrefix(line, "**Result:** "))
+			inResult = true
+		case strings.TrimSpace(line) != "":
+			result = append(result, line)
+		}
+	}
+	finish()
+	return entries
+}
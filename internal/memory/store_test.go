@@ -0,0 +1,411 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAppendLoad_JSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "agent.jsonl")
+	fixed := time.Date(2026, 2, 28, 15, 4, 5, 0, time.UTC)
+	origNow := Now
+	Now = func() time.Time { return fixed }
+	defer func() { Now = origNow }()
+
+	// A result with a "## " line, which used to split an entry in two
+	result := "Summary\n## Details\n" + strings.Repeat("x", 1500)
+	want := Entry{Task: "review\nthe PR", Result: result, Model: "anthropic/claude-sonnet-4", InputTokens: 10, OutputTokens: 5, RunID: "r1", Tags: []string{"review"}}
+	if err := Append(path, want); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := AppendEntry(path, "second", "ok"); err != nil {
+		t.Fatalf("AppendEntry: %v", err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Load = %d entries, want 2", len(entries))
+	}
+	got := entries[0]
+	if !got.Timestamp.Equal(fixed) || got.Task != want.Task || got.Result != result || got.Model != want.Model || got.InputTokens != 10 || got.RunID != "r1" || len(got.Tags) != 1 {
+		t.Errorf("entry = %+v, want the fields appended with the full result", got)
+	}
+
+	if n, _ := CountEntries(path); n != 2 {
+		t.Errorf("CountEntries = %d, want 2", n)
+	}
+	loaded, _ := LoadEntries(path, 1)
+	if loaded != "## 2026-02-28T15:04:05Z\n**Task:** second\n**Result:** ok\n\n" {
+		t.Errorf("LoadEntries(1) = %q", loaded)
+	}
+	all, _ := LoadEntries(path, 0)
+	if !strings.Contains(all, "**Task:** review the PR\n**Tags:** review\n**Result:** Summary\n## Details\n") || !strings.Contains(all, strings.Repeat("x", 981)+"...\n\n") {
+		t.Errorf("LoadEntries(0) = %q, want markdown with the result cut at 1000 bytes", all)
+	}
+}
+
+func TestLoad_MalformedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.jsonl")
+	os.WriteFile(path, []byte(`{"task": "ok"}`+"\n\nnot json\n"), 0644)
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "agent.jsonl line 3") {
+		t.Errorf("err = %v, want the bad line reported", err)
+	}
+}
+
+func TestTrimEntries_JSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.jsonl")
+	for _, task := range []string{"t1", "t2", "t3"} {
+		Append(path, Entry{Task: task, Result: "line\n## not an entry"})
+	}
+	os.Chmod(path, 0600)
+
+	removed, err := TrimEntries(path, 2)
+	if err != nil || removed != 1 {
+		t.Fatalf("TrimEntries = %d, %v; want 1, nil", removed, err)
+	}
+	entries, _ := Load(path)
+	if len(entries) != 2 || entries[0].Task != "t2" || entries[1].Result != "line\n## not an entry" {
+		t.Errorf("entries = %+v, want t2 and t3 intact", entries)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
+
+func TestLegacyFormat_AnyExtension(t *testing.T) {
+	dir := t.TempDir()
+	if IsMarkdown(filepath.Join(dir, "new.txt")) || !IsMarkdown(filepath.Join(dir, "new.md")) {
+		t.Error("a new file's format should follow its extension")
+	}
+
+	// Earlier versions wrote markdown to a custom path whatever its name
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("## 2026-01-01T00:00:00Z\n**Task:** t1\n**Result:** r1\n\n"), 0644)
+	if !IsMarkdown(path) {
+		t.Fatal("IsMarkdown = false for a file of markdown entries")
+	}
+	if err := Append(path, Entry{Task: "t2", Result: "r2"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Load(path)
+	if err != nil || len(entries) != 2 || entries[1].Task != "t2" {
+		t.Fatalf("entries = %+v, %v; want both as markdown", entries, err)
+	}
+
+	target, n, err := Migrate(path)
+	if err != nil || target != filepath.Join(dir, "notes.jsonl") || n != 2 {
+		t.Fatalf("Migrate = %q, %d, %v", target, n, err)
+	}
+	if IsMarkdown(target) {
+		t.Error("migrated file is still markdown")
+	}
+
+	// A ".jsonl" path holding markdown is converted in place
+	path = filepath.Join(dir, "old.jsonl")
+	legacy := "## 2026-01-01T00:00:00Z\n**Task:** t1\n**Result:** r1\n\n"
+	os.WriteFile(path, []byte(legacy), 0644)
+	if target, n, err := Migrate(path); err != nil || target != path || n != 1 {
+		t.Fatalf("Migrate in place = %q, %d, %v", target, n, err)
+	}
+	if entries, err := Load(path); err != nil || len(entries) != 1 || IsMarkdown(path) {
+		t.Errorf("entries = %+v, %v; want JSONL", entries, err)
+	}
+	if bak, _ := os.ReadFile(path + ".bak"); string(bak) != legacy {
+		t.Errorf("backup = %q, want the original", bak)
+	}
+}
+
+func TestMigrate_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent.md")
+	os.WriteFile(path, []byte("## 2026-01-01T00:00:00Z\n**Task:** t1\n**Result:** r1\n\n"), 0644)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	migrated := 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := Migrate(path); err == nil {
+				mu.Lock()
+				migrated++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if migrated != 1 {
+		t.Errorf("%d migrations succeeded, want 1", migrated)
+	}
+	entries, err := Load(filepath.Join(dir, "agent.jsonl"))
+	if err != nil || len(entries) != 1 || entries[0].Task != "t1" {
+		t.Errorf("entries = %+v, %v; want the migrated entry", entries, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent.md")
+	content := "Preamble\n\n" +
+		"## 2026-01-01T00:00:00Z\n**Task:** t1\n**Result:** line1\n## Heading in a result\nline3\n\n" +
+		"## 2026-01-02T00:00:00Z\n**Task:** (none)\n**Result:** (none)\n\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	target, n, err := Migrate(path)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if target != filepath.Join(dir, "agent.jsonl") || n != 2 {
+		t.Errorf("Migrate = %q, %d; want agent.jsonl, 2", target, n)
+	}
+	entries, _ := Load(target)
+	if len(entries) != 2 || entries[0].Result != "line1\n## Heading in a result\nline3" || entries[1].Task != "" || entries[1].Result != "" {
+		t.Errorf("entries = %+v", entries)
+	}
+	if !entries[1].Timestamp.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("timestamp = %v", entries[1].Timestamp)
+	}
+	if _, err := os.Stat(path + ".bak"); err != nil {
+		t.Errorf("original not kept as .bak: %v", err)
+	}
+
+	if _, _, err := Migrate(target); err == nil || !strings.Contains(err.Error(), "is not a markdown memory file") {
+		t.Errorf("Migrate(jsonl) err = %v", err)
+	}
+	os.WriteFile(path, []byte(content), 0644)
+	if _, _, err := Migrate(path); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Migrate over existing err = %v", err)
+	}
+}
+
+func TestRender_MarkdownRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Timestamp: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Task: "t1", Result: "r1", Tags: []string{"a", "b"}},
+		{Timestamp: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Task: "t2", Result: "r2"},
+	}
+	parsed := parseMarkdown(Render(entries))
+	if len(parsed) != 2 || parsed[0].Task != "t1" || parsed[0].Result != "r1" || strings.Join(parsed[0].Tags, ",") != "a,b" || parsed[1].Result != "r2" {
+		t.Errorf("parseMarkdown(Render) = %+v", parsed)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "m.jsonl")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, task := range []string{"a", "b", "c"} {
+		if err := Append(path, Entry{Timestamp: start.Add(time.Duration(i) * time.Hour), Task: task}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := Remove(path, start.Add(time.Hour), 0)
+	if err != nil || removed != 1 {
+		t.Fatalf("Remove = %d, %v; want 1", removed, err)
+	}
+	entries, _ := Load(path)
+	if len(entries) != 2 || entries[0].Task != "a" || entries[1].Task != "c" {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	before, _ := os.ReadFile(path)
+	if removed, err := Remove(path, start.Add(time.Hour), 0); err != nil || removed != 0 {
+		t.Errorf("second Remove = %d, %v; want 0", removed, err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("file changed although nothing was removed")
+	}
+}
+
+func TestRemove_SharedTimestamp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.jsonl")
+	ts := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, task := range []string{"mine", "theirs", "other"} {
+		if err := Append(path, Entry{Timestamp: ts, Task: task}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, _ := os.ReadFile(path)
+	if removed, err := Remove(path, ts, 0); !errors.Is(err, ErrAmbiguousEntry) || removed != 0 || !strings.Contains(err.Error(), "3 entries") {
+		t.Errorf("Remove = %d, %v; want an ambiguity error", removed, err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("file changed although the timestamp was ambiguous")
+	}
+
+	if removed, err := Remove(path, ts, 2); err != nil || removed != 1 {
+		t.Fatalf("Remove(2) = %d, %v; want 1", removed, err)
+	}
+	entries, _ := Load(path)
+	if len(entries) != 2 || entries[0].Task != "mine" || entries[1].Task != "other" {
+		t.Errorf("entries = %+v, want only the second removed", entries)
+	}
+	if removed, err := Remove(path, ts, 3); err != nil || removed != 0 {
+		t.Errorf("Remove(3) = %d, %v; want 0", removed, err)
+	}
+}
+
+func TestClear_Missing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "m.jsonl")
+	if n, err := Clear(path); err != nil || n != 0 {
+		t.Errorf("Clear = %d, %v; want 0, nil", n, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Clear created %s", path)
+	}
+}
+
+func TestEntry_Matches(t *testing.T) {
+	e := Entry{Task: "Review PR #42", Result: "Found a nil deref", Tags: []string{"backend"}}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"review", true},
+		{"NIL pr", true},
+		{"backend deref", true},
+		{"frontend", false},
+		{"review frontend", false},
+	}
+	for _, tt := range tests {
+		if got := e.Matches(tt.query); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestAppend_ConcurrentWithRewrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.jsonl")
+	const writers, perWriter = 8, 20
+
+	// Rewrites replace the file while appends wait for the lock; no append
+	// may land in a replaced file.
+	done := make(chan struct{})
+	rewrites := make(chan int)
+	go func() {
+		n := 0
+		for {
+			select {
+			case <-done:
+				rewrites <- n
+				return
+			default:
+			}
+			if err := update(path, func(entries []Entry) ([]Entry, bool) { return entries, true }); err != nil {
+				t.Error(err)
+			}
+			n++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if err := Append(path, Entry{Task: fmt.Sprintf("%d-%d", w, i), Tags: []string{AgentTag("writer")}}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(done)
+	<-rewrites
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != writers*perWriter {
+		t.Errorf("got %d entries, want %d", len(entries), writers*perWriter)
+	}
+}
+
+func TestSharedFilePath(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataDir)
+	got, err := SharedFilePath("project-x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dataDir, "axe", "memory", "shared", "project-x.jsonl"); got != want {
+		t.Errorf("SharedFilePath = %q, want %q", got, want)
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "m.jsonl")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		Append(path, Entry{Timestamp: start.AddDate(0, 0, i), Task: fmt.Sprintf("task%d", i), Result: strings.Repeat("x", 2000)})
+	}
+
+	var got []string
+	folded, err := Consolidate(path, 2, func(entries []Entry) (Entry, error) {
+		for _, e := range entries {
+			got = append(got, e.Task)
+		}
+		// A run finishing while the digest is written must not be lost
+		Append(path, Entry{Timestamp: start.AddDate(0, 0, 6), Task: "task6"})
+		return Entry{Task: "Memory digest", Result: "facts"}, nil
+	})
+	if err != nil || folded != 3 {
+		t.Fatalf("Consolidate = %d, %v; want 3", folded, err)
+	}
+	if strings.Join(got, ",") != "task1,task2,task3" {
+		t.Errorf("folded entries = %v", got)
+	}
+
+	entries, _ := Load(path)
+	var tasks []string
+	for _, e := range entries {
+		tasks = append(tasks, e.Task)
+	}
+	if strings.Join(tasks, ",") != "Memory digest,task4,task5,task6" || !entries[0].IsDigest() {
+		t.Errorf("entries after consolidation = %v", tasks)
+	}
+
+	// The digest outlives trimming and leads the prompt, whatever lastN
+	if removed, err := TrimEntries(path, 1); err != nil || removed != 2 {
+		t.Errorf("TrimEntries = %d, %v; want 2", removed, err)
+	}
+	content, _ := LoadEntries(path, 1)
+	if !strings.HasPrefix(content, "## 2026") || !strings.Contains(content, "**Tags:** digest\n**Result:** facts") || !strings.Contains(content, "task6") {
+		t.Errorf("LoadEntries = %q", content)
+	}
+	relevant, _ := LoadRelevant(context.Background(), path, "task6", 1, nil)
+	if len(relevant) != 2 || !relevant[0].IsDigest() {
+		t.Errorf("LoadRelevant = %+v, want the digest and task6", relevant)
+	}
+
+	if folded, err := Consolidate(path, 1, nil); err != nil || folded != 0 {
+		t.Errorf("Consolidate with nothing to fold = %d, %v", folded, err)
+	}
+	if _, err := Consolidate(filepath.Join(t.TempDir(), "m.md"), 1, nil); err == nil {
+		t.Error("expected an error for a markdown file")
+	}
+}
+
+func TestRender_DigestNotCut(t *testing.T) {
+	long := strings.Repeat("y", 3000)
+	out := Render([]Entry{{Task: "Memory digest", Result: long, Tags: []string{DigestTag}}, {Task: "t", Result: long}})
+	if !strings.Contains(out, long+"\n") || strings.Count(out, "...") != 1 {
+		t.Errorf("digest result was cut, or the other one was not")
+	}
+}
@@ -3,10 +3,12 @@ package provider
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -63,12 +65,13 @@ func NewAnthropic(apiKey string, opts ...AnthropicOption) (*Anthropic, error) {
 
 // anthropicRequest is the JSON body sent to the Anthropic Messages API.
 type anthropicRequest struct {
-	Model       string             `json:"model"`
-	MaxTokens   int                `json:"max_tokens"`
-	Messages    []anthropicMessage `json:"messages"`
-	System      string             `json:"system,omitempty"`
-	Temperature *float64           `json:"temperature,omitempty"`
-	Tools       []anthropicToolDef `json:"tools,omitempty"`
+	Model       string                  `json:"model"`
+	MaxTokens   int                     `json:"max_tokens"`
+	Messages    []anthropicMessage      `json:"messages"`
+	System      []anthropicContentBlock `json:"system,omitempty"`
+	Temperature *float64                `json:"temperature,omitempty"`
+	Tools       []anthropicToolDef      `json:"tools,omitempty"`
+	Stream      bool                    `json:"stream,omitempty"`
 }
 
 // anthropicMessage is the wire format for a message in the Anthropic API.
@@ -81,38 +84,68 @@ type anthropicMessage struct {
 // Note: We use pointers/interfaces for optional fields so that omitempty works
 // correctly. For tool_result blocks, is_error must always be present.
 type anthropicContentBlock struct {
-	Type      string                 `json:"type"`
-	Text      string                 `json:"text,omitempty"`
-	ID        string                 `json:"id,omitempty"`
-	Name      string                 `json:"name,omitempty"`
-	Input     map[string]interface{} `json:"input,omitempty"`
-	ToolUseID string                 `json:"tool_use_id,omitempty"`
-	Content   string                 `json:"content,omitempty"`
-	IsError   *bool                  `json:"is_error,omitempty"`
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text,omitempty"`
+	ID           string                 `json:"id,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	Input        json.RawMessage        `json:"input,omitempty"`
+	ToolUseID    string                 `json:"tool_use_id,omitempty"`
+	Content      string                 `json:"content,omitempty"`
+	IsError      *bool                  `json:"is_error,omitempty"`
+	Source       *anthropicSource       `json:"source,omitempty"`
+	Title        string                 `json:"title,omitempty"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicCacheControl marks a prompt caching breakpoint. Everything up to
+// and including the marked block is cached and reused by later requests that
+// share the same prefix.
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// ephemeralCache is the cache_control value for the default cache lifetime.
+var ephemeralCache = &anthropicCacheControl{Type: "ephemeral"}
+
+// AnthropicMaxImageSize is the largest image, in bytes, the Anthropic
+// Messages API accepts.
+const AnthropicMaxImageSize = 5 << 20
+
+// anthropicSource is the base64 payload of an image or document block.
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
 // anthropicToolDef is the wire format for a tool definition in the Anthropic API.
 type anthropicToolDef struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"input_schema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  json.RawMessage        `json:"input_schema"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
 }
 
 // anthropicResponse represents the JSON response from the Anthropic Messages API.
 type anthropicResponse struct {
 	Content []struct {
-		Type  string                 `json:"type"`
-		Text  string                 `json:"text"`
-		ID    string                 `json:"id"`
-		Name  string                 `json:"name"`
-		Input map[string]interface{} `json:"input"`
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
 	} `json:"content"`
-	Model      string `json:"model"`
-	StopReason string `json:"stop_reason"`
-	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	Model      string         `json:"model"`
+	StopReason string         `json:"stop_reason"`
+	Usage      anthropicUsage `json:"usage"`
+}
+
+// anthropicUsage is the token usage reported by the Messages API.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
 // anthropicErrorResponse represents an Anthropic API error response.
@@ -154,18 +187,38 @@ func convertToAnthropicMessages(msgs []Message) []anthropicMessage {
 				})
 			}
 			for _, tc := range msg.ToolCalls {
-				input := make(map[string]interface{})
-				for k, v := range tc.Arguments {
-					input[k] = v
-				}
 				blocks = append(blocks, anthropicContentBlock{
 					Type:  "tool_use",
 					ID:    tc.ID,
 					Name:  tc.Name,
-					Input: input,
+					Input: objectArguments(tc.Arguments),
 				})
 			}
 			am.Content = blocks
+		} else if len(msg.Parts) > 0 {
+			// Messages with attachments need content blocks
+			var blocks []anthropicContentBlock
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{
+					Type: "text",
+					Text: msg.Content,
+				})
+			}
+			for _, part := range msg.Parts {
+				block := anthropicContentBlock{
+					Type: part.Type,
+					Source: &anthropicSource{
+						Type:      "base64",
+						MediaType: part.MediaType,
+						Data:      base64.StdEncoding.EncodeToString(part.Data),
+					},
+				}
+				if part.Type == PartDocument {
+					block.Title = part.Name
+				}
+				blocks = append(blocks, block)
+			}
+			am.Content = blocks
 		} else {
 			// Standard text message
 			am.Content = msg.Content
@@ -180,37 +233,24 @@ func convertToAnthropicMessages(msgs []Message) []anthropicMessage {
 func convertToAnthropicTools(tools []Tool) []anthropicToolDef {
 	var result []anthropicToolDef
 	for _, tool := range tools {
-		properties := make(map[string]interface{})
-		var required []string
-		for name, param := range tool.Parameters {
-			properties[name] = map[string]interface{}{
-				"type":        param.Type,
-				"description": param.Description,
-			}
-			if param.Required {
-				required = append(required, name)
-			}
-		}
-
-		schema := map[string]interface{}{
-			"type":       "object",
-			"properties": properties,
-		}
-		if len(required) > 0 {
-			schema["required"] = required
-		}
-
 		result = append(result, anthropicToolDef{
 			Name:        tool.Name,
 			Description: tool.Description,
-			InputSchema: schema,
+			InputSchema: toolSchema(tool),
 		})
 	}
 	return result
 }
 
-// Send makes a completion request to the Anthropic Messages API.
-func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
+// buildRequest converts a provider Request into the Anthropic wire format.
+//
+// The parts of a request that stay the same across turns of a conversation
+// are marked as prompt caching breakpoints: the tool definitions, the system
+// prompt, and the attachments of the first user message. The fourth and last
+// breakpoint the API allows goes on the final block of the last message, so
+// the next turn reads the whole conversation so far from the cache instead
+// of paying for it again.
+func (a *Anthropic) buildRequest(req *Request) anthropicRequest {
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
 		maxTokens = defaultMaxTokens
@@ -220,7 +260,14 @@ func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
 		Model:     req.Model,
 		MaxTokens: maxTokens,
 		Messages:  convertToAnthropicMessages(req.Messages),
-		System:    req.System,
+	}
+
+	if req.System != "" {
+		body.System = []anthropicContentBlock{{
+			Type:         "text",
+			Text:         req.System,
+			CacheControl: ephemeralCache,
+		}}
 	}
 
 	if req.Temperature != 0 {
@@ -230,8 +277,35 @@ func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
 
 	if len(req.Tools) > 0 {
 		body.Tools = convertToAnthropicTools(req.Tools)
+		body.Tools[len(body.Tools)-1].CacheControl = ephemeralCache
+	}
+
+	if len(req.Messages) > 0 && len(req.Messages[0].Parts) > 0 {
+		if blocks, ok := body.Messages[0].Content.([]anthropicContentBlock); ok {
+			blocks[len(blocks)-1].CacheControl = ephemeralCache
+		}
 	}
 
+	if n := len(body.Messages); n > 0 {
+		last := &body.Messages[n-1]
+		switch content := last.Content.(type) {
+		case []anthropicContentBlock:
+			content[len(content)-1].CacheControl = ephemeralCache
+		case string:
+			// Empty text blocks are rejected, so an empty message stays as is
+			if content != "" {
+				last.Content = []anthropicContentBlock{{Type: "text", Text: content, CacheControl: ephemeralCache}}
+			}
+		}
+	}
+
+	return body
+}
+
+// post sends body to the Anthropic Messages API. Transport failures and non-2xx
+// responses are returned as ProviderErrors. On success the caller must close
+// the returned response body.
+func (a *Anthropic) post(ctx context.Context, body anthropicRequest) (*http.Response, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -262,6 +336,28 @@ func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
 			Err:      err,
 		}
 	}
+
+	// Handle non-2xx responses
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		defer httpResp.Body.Close()
+		respBody, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		provErr := a.handleErrorResponse(httpResp.StatusCode, respBody)
+		provErr.RetryAfter = parseRetryAfter(httpResp.Header.Get("Retry-After"))
+		return nil, provErr
+	}
+
+	return httpResp, nil
+}
+
+// Send makes a completion request to the Anthropic Messages API.
+func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
+	httpResp, err := a.post(ctx, a.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
 	defer httpResp.Body.Close()
 
 	respBody, err := io.ReadAll(httpResp.Body)
@@ -269,11 +365,6 @@ func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Handle non-2xx responses
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		return nil, a.handleErrorResponse(httpResp.StatusCode, respBody)
-	}
-
 	// Parse success response
 	var apiResp anthropicResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
@@ -300,14 +391,10 @@ func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
 		case "text":
 			textContent += block.Text
 		case "tool_use":
-			args := make(map[string]string)
-			for k, v := range block.Input {
-				args[k] = fmt.Sprintf("%v", v)
-			}
 			toolCalls = append(toolCalls, ToolCall{
 				ID:        block.ID,
 				Name:      block.Name,
-				Arguments: args,
+				Arguments: rawArguments(block.Input),
 			})
 		}
 	}
@@ -319,9 +406,168 @@ func (a *Anthropic) Send(ctx context.Context, req *Request) (*Response, error) {
 		OutputTokens: apiResp.Usage.OutputTokens,
 		StopReason:   apiResp.StopReason,
 		ToolCalls:    toolCalls,
+
+		CacheCreationTokens: apiResp.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     apiResp.Usage.CacheReadInputTokens,
 	}, nil
 }
 
+// anthropicStreamEvent is the JSON payload of a Messages API server-sent event.
+// Only the fields used to reassemble a Response are decoded.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		Model string         `json:"model"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamBlock accumulates one content block across stream events.
+type anthropicStreamBlock struct {
+	typ   string
+	id    string
+	name  string
+	input strings.Builder
+}
+
+// SendStream makes a streaming completion request to the Anthropic Messages API.
+// Text deltas are passed to fn as they arrive; tool_use input is assembled from
+// input_json_delta events and returned in the final Response.
+func (a *Anthropic) SendStream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	body := a.buildRequest(req)
+	body.Stream = true
+
+	httpResp, err := a.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	resp := &Response{}
+	var textContent strings.Builder
+	var blocks []*anthropicStreamBlock
+
+	err = readSSE(httpResp.Body, func(ev sseEvent) error {
+		if ev.Data == "" {
+			return nil
+		}
+
+		var data anthropicStreamEvent
+		if err := json.Unmarshal([]byte(ev.Data), &data); err != nil {
+			return &ProviderError{
+				Category: ErrCategoryServer,
+				Message:  fmt.Sprintf("failed to parse stream event: %s", err),
+				Err:      err,
+			}
+		}
+
+		switch data.Type {
+		case "message_start":
+			resp.Model = data.Message.Model
+			resp.InputTokens = data.Message.Usage.InputTokens
+			resp.OutputTokens = data.Message.Usage.OutputTokens
+			resp.CacheCreationTokens = data.Message.Usage.CacheCreationInputTokens
+			resp.CacheReadTokens = data.Message.Usage.CacheReadInputTokens
+		case "content_block_start":
+			block := &anthropicStreamBlock{
+				typ:  data.ContentBlock.Type,
+				id:   data.ContentBlock.ID,
+				name: data.ContentBlock.Name,
+			}
+			blocks = append(blocks, block)
+			if block.typ == "text" {
+				textContent.WriteString(data.ContentBlock.Text)
+				fn.emit(data.ContentBlock.Text)
+			}
+		case "content_block_delta":
+			if data.Index < 0 || data.Index >= len(blocks) {
+				return nil
+			}
+			switch data.Delta.Type {
+			case "text_delta":
+				textContent.WriteString(data.Delta.Text)
+				fn.emit(data.Delta.Text)
+			case "input_json_delta":
+				blocks[data.Index].input.WriteString(data.Delta.PartialJSON)
+			}
+		case "message_delta":
+			if data.Delta.StopReason != "" {
+				resp.StopReason = data.Delta.StopReason
+			}
+			if data.Usage.OutputTokens != 0 {
+				resp.OutputTokens = data.Usage.OutputTokens
+			}
+		case "error":
+			return &ProviderError{
+				Category: anthropicStreamErrorCategory(data.Error.Type),
+				Message:  data.Error.Message,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, streamReadError(ctx, err)
+	}
+
+	if len(blocks) == 0 {
+		return nil, &ProviderError{
+			Category: ErrCategoryServer,
+			Message:  "response contains no content",
+		}
+	}
+
+	for _, block := range blocks {
+		if block.typ != "tool_use" {
+			continue
+		}
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ID:        block.id,
+			Name:      block.name,
+			Arguments: rawArguments([]byte(block.input.String())),
+		})
+	}
+
+	resp.Content = textContent.String()
+	return resp, nil
+}
+
+// anthropicStreamErrorCategory maps an error type reported inside an event
+// stream to an error category. Mid-stream errors carry no HTTP status.
+func anthropicStreamErrorCategory(errType string) ErrorCategory {
+	switch errType {
+	case "overloaded_error":
+		return ErrCategoryOverloaded
+	case "rate_limit_error":
+		return ErrCategoryRateLimit
+	case "authentication_error", "permission_error":
+		return ErrCategoryAuth
+	case "invalid_request_error":
+		return ErrCategoryBadRequest
+	default:
+		return ErrCategoryServer
+	}
+}
+
 // handleErrorResponse maps HTTP error responses to ProviderError.
 func (a *Anthropic) handleErrorResponse(status int, body []byte) *ProviderError {
 	// Attempt to parse Anthropic error response
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -134,8 +135,17 @@ func TestAnthropic_Send_RequestFormat(t *testing.T) {
 	if gotBody["model"] != "claude-sonnet-4-20250514" {
 		t.Errorf("body model = %v, want %q", gotBody["model"], "claude-sonnet-4-20250514")
 	}
-	if gotBody["system"] != "Be helpful" {
-		t.Errorf("body system = %v, want %q", gotBody["system"], "Be helpful")
+	// The system prompt is sent as a text block marked for prompt caching
+	system, _ := gotBody["system"].([]interface{})
+	if len(system) != 1 {
+		t.Fatalf("body system = %v, want one text block", gotBody["system"])
+	}
+	systemBlock := system[0].(map[string]interface{})
+	if systemBlock["type"] != "text" || systemBlock["text"] != "Be helpful" {
+		t.Errorf("system block = %v, want text %q", systemBlock, "Be helpful")
+	}
+	if cc, _ := systemBlock["cache_control"].(map[string]interface{}); cc["type"] != "ephemeral" {
+		t.Errorf("system cache_control = %v, want ephemeral", systemBlock["cache_control"])
 	}
 	// JSON numbers decode as float64
 	if gotBody["max_tokens"] != float64(1024) {
@@ -150,8 +160,9 @@ func TestAnthropic_Send_RequestFormat(t *testing.T) {
 		t.Fatalf("body messages = %v, want 1-element array", gotBody["messages"])
 	}
 	msg := msgs[0].(map[string]interface{})
-	if msg["role"] != "user" || msg["content"] != "Hi" {
-		t.Errorf("body messages[0] = %v, want role=user content=Hi", msg)
+	blocks, _ := msg["content"].([]interface{})
+	if msg["role"] != "user" || len(blocks) != 1 || blocks[0].(map[string]interface{})["text"] != "Hi" {
+		t.Errorf("body messages[0] = %v, want role=user with a text block Hi", msg)
 	}
 }
 
@@ -491,11 +502,15 @@ func TestAnthropic_Send_WithTools(t *testing.T) {
 			{
 				Name:        "call_agent",
 				Description: "Delegate a task to a sub-agent.",
-				Parameters: map[string]ToolParameter{
-					"agent":   {Type: "string", Description: "Agent name", Required: true},
-					"task":    {Type: "string", Description: "Task description", Required: true},
-					"context": {Type: "string", Description: "Additional context", Required: false},
-				},
+				InputSchema: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"agent": {"type": "string", "description": "Agent name"},
+						"task": {"type": "string", "description": "Task description"},
+						"context": {"type": "string", "description": "Additional context"}
+					},
+					"required": ["agent", "task"]
+				}`),
 			},
 		},
 	})
@@ -619,10 +634,7 @@ func TestAnthropic_Send_ToolCallResponse(t *testing.T) {
 		Model:    "claude-sonnet-4-20250514",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{
-				"agent": {Type: "string", Description: "agent", Required: true},
-				"task":  {Type: "string", Description: "task", Required: true},
-			}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {"agent": {"type": "string"}, "task": {"type": "string"}}, "required": ["agent", "task"]}`)},
 		},
 	})
 	if err != nil {
@@ -638,11 +650,11 @@ func TestAnthropic_Send_ToolCallResponse(t *testing.T) {
 	if resp.ToolCalls[0].Name != "call_agent" {
 		t.Errorf("ToolCalls[0].Name = %q, want %q", resp.ToolCalls[0].Name, "call_agent")
 	}
-	if resp.ToolCalls[0].Arguments["agent"] != "helper" {
-		t.Errorf("ToolCalls[0].Arguments[agent] = %q, want %q", resp.ToolCalls[0].Arguments["agent"], "helper")
+	if decodeArgs(t, resp.ToolCalls[0].Arguments)["agent"] != "helper" {
+		t.Errorf("ToolCalls[0].Arguments[agent] = %q, want %q", decodeArgs(t, resp.ToolCalls[0].Arguments)["agent"], "helper")
 	}
-	if resp.ToolCalls[0].Arguments["task"] != "run tests" {
-		t.Errorf("ToolCalls[0].Arguments[task] = %q, want %q", resp.ToolCalls[0].Arguments["task"], "run tests")
+	if decodeArgs(t, resp.ToolCalls[0].Arguments)["task"] != "run tests" {
+		t.Errorf("ToolCalls[0].Arguments[task] = %q, want %q", decodeArgs(t, resp.ToolCalls[0].Arguments)["task"], "run tests")
 	}
 }
 
@@ -667,7 +679,7 @@ func TestAnthropic_Send_ToolCallWithText(t *testing.T) {
 		Model:    "claude-sonnet-4-20250514",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -702,7 +714,7 @@ func TestAnthropic_Send_ToolCallNoText(t *testing.T) {
 		Model:    "claude-sonnet-4-20250514",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -737,7 +749,7 @@ func TestAnthropic_Send_ToolsStopReason(t *testing.T) {
 		Model:    "claude-sonnet-4-20250514",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -774,7 +786,7 @@ func TestAnthropic_Send_ToolResultMessage(t *testing.T) {
 		Messages: []Message{
 			{Role: "user", Content: "Hi"},
 			{Role: "assistant", Content: "", ToolCalls: []ToolCall{
-				{ID: "toolu_1", Name: "call_agent", Arguments: map[string]string{"agent": "helper"}},
+				{ID: "toolu_1", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper"}`)},
 			}},
 			{Role: "tool", ToolResults: []ToolResult{
 				{CallID: "toolu_1", Content: "result text", IsError: false},
@@ -837,7 +849,7 @@ func TestAnthropic_Send_AssistantToolCallMessage(t *testing.T) {
 		Messages: []Message{
 			{Role: "user", Content: "Hi"},
 			{Role: "assistant", Content: "I'll help", ToolCalls: []ToolCall{
-				{ID: "toolu_1", Name: "call_agent", Arguments: map[string]string{"agent": "helper", "task": "work"}},
+				{ID: "toolu_1", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper", "task": "work"}`)},
 			}},
 			{Role: "tool", ToolResults: []ToolResult{
 				{CallID: "toolu_1", Content: "done", IsError: false},
@@ -890,3 +902,281 @@ func TestAnthropic_Send_AssistantToolCallMessage(t *testing.T) {
 		t.Error("assistant message missing tool_use content block")
 	}
 }
+
+// --- Streaming ---
+
+func TestAnthropic_Send_AttachmentParts(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	a, _ := NewAnthropic("key", WithBaseURL(server.URL))
+	_, err := a.Send(context.Background(), &Request{
+		Model: "claude-sonnet-4-20250514",
+		Messages: []Message{{Role: "user", Content: "Describe these", Parts: []ContentPart{
+			{Type: PartImage, MediaType: "image/png", Name: "shot.png", Data: []byte("png")},
+			{Type: PartDocument, MediaType: "application/pdf", Name: "spec.pdf", Data: []byte("pdf")},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := gotBody["messages"].([]interface{})[0].(map[string]interface{})
+	blocks := msg["content"].([]interface{})
+	if len(blocks) != 3 {
+		t.Fatalf("content blocks = %d, want 3", len(blocks))
+	}
+	if text := blocks[0].(map[string]interface{}); text["type"] != "text" || text["text"] != "Describe these" {
+		t.Errorf("blocks[0] = %v, want text block", text)
+	}
+
+	image := blocks[1].(map[string]interface{})
+	if image["type"] != "image" {
+		t.Errorf("blocks[1].type = %v, want %q", image["type"], "image")
+	}
+	source := image["source"].(map[string]interface{})
+	if source["type"] != "base64" || source["media_type"] != "image/png" || source["data"] != "cG5n" {
+		t.Errorf("image source = %v, want base64 image/png", source)
+	}
+
+	doc := blocks[2].(map[string]interface{})
+	if doc["type"] != "document" || doc["title"] != "spec.pdf" {
+		t.Errorf("blocks[2] = %v, want document titled spec.pdf", doc)
+	}
+	if doc["source"].(map[string]interface{})["media_type"] != "application/pdf" {
+		t.Errorf("document source = %v, want application/pdf", doc["source"])
+	}
+}
+
+func TestAnthropic_Send_PromptCaching(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{
+			"content": [{"type": "text", "text": "ok"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 12, "output_tokens": 3, "cache_creation_input_tokens": 2048, "cache_read_input_tokens": 512}
+		}`))
+	}))
+	defer server.Close()
+
+	a, _ := NewAnthropic("key", WithBaseURL(server.URL))
+	resp, err := a.Send(context.Background(), &Request{
+		Model:  "claude-sonnet-4-20250514",
+		System: "Be helpful",
+		Messages: []Message{
+			{Role: "user", Content: "Look", Parts: []ContentPart{
+				{Type: PartImage, MediaType: "image/png", Data: []byte("png")},
+			}},
+			{Role: "assistant", Content: "Looked"},
+			{Role: "user", Content: "Again"},
+		},
+		Tools: []Tool{{Name: "first"}, {Name: "second"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.InputTokens != 12 || resp.CacheCreationTokens != 2048 || resp.CacheReadTokens != 512 {
+		t.Errorf("tokens = %d input, %d cache creation, %d cache read; want 12, 2048, 512",
+			resp.InputTokens, resp.CacheCreationTokens, resp.CacheReadTokens)
+	}
+
+	tools := gotBody["tools"].([]interface{})
+	if _, ok := tools[0].(map[string]interface{})["cache_control"]; ok {
+		t.Error("first tool has cache_control, want only the last tool marked")
+	}
+	if _, ok := tools[1].(map[string]interface{})["cache_control"]; !ok {
+		t.Error("last tool missing cache_control")
+	}
+
+	messages := gotBody["messages"].([]interface{})
+	first := messages[0].(map[string]interface{})["content"].([]interface{})
+	if _, ok := first[len(first)-1].(map[string]interface{})["cache_control"]; !ok {
+		t.Error("attachment block of first message missing cache_control")
+	}
+	if _, ok := messages[1].(map[string]interface{})["content"].(string); !ok {
+		t.Errorf("assistant message = %v, want plain string content", messages[1])
+	}
+	last, _ := messages[2].(map[string]interface{})["content"].([]interface{})
+	if len(last) != 1 || last[0].(map[string]interface{})["text"] != "Again" {
+		t.Fatalf("last message = %v, want one text block", messages[2])
+	}
+	if _, ok := last[0].(map[string]interface{})["cache_control"]; !ok {
+		t.Error("last message block missing cache_control")
+	}
+	body, _ := json.Marshal(gotBody)
+	if n := strings.Count(string(body), `"cache_control"`); n != 4 {
+		t.Errorf("request has %d cache breakpoints, want the 4 the API allows", n)
+	}
+}
+
+func TestAnthropic_SendStream_TextAndUsage(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`event: message_start
+data: {"type":"message_start","message":{"model":"claude-sonnet-4-20250514","usage":{"input_tokens":12,"output_tokens":1,"cache_read_input_tokens":300}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`))
+	}))
+	defer server.Close()
+
+	a, _ := NewAnthropic("key", WithBaseURL(server.URL))
+	var deltas []string
+	resp, err := a.SendStream(context.Background(), &Request{
+		Model:    "claude-sonnet-4-20250514",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, func(text string) { deltas = append(deltas, text) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotBody["stream"] != true {
+		t.Errorf("stream = %v, want true", gotBody["stream"])
+	}
+	if len(deltas) != 2 || deltas[0] != "Hello" || deltas[1] != " world" {
+		t.Errorf("deltas = %q, want [Hello  world]", deltas)
+	}
+	if resp.Content != "Hello world" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello world")
+	}
+	if resp.Model != "claude-sonnet-4-20250514" {
+		t.Errorf("Model = %q", resp.Model)
+	}
+	if resp.InputTokens != 12 || resp.OutputTokens != 7 {
+		t.Errorf("tokens = %d/%d, want 12/7", resp.InputTokens, resp.OutputTokens)
+	}
+	if resp.CacheReadTokens != 300 {
+		t.Errorf("CacheReadTokens = %d, want 300", resp.CacheReadTokens)
+	}
+	if resp.StopReason != "end_turn" {
+		t.Errorf("StopReason = %q, want end_turn", resp.StopReason)
+	}
+}
+
+func TestAnthropic_SendStream_ToolUseDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`event: message_start
+data: {"type":"message_start","message":{"model":"claude-sonnet-4-20250514","usage":{"input_tokens":5,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"call_agent","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"agent\": \"hel"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"per\", \"task\": \"go\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}
+
+`))
+	}))
+	defer server.Close()
+
+	a, _ := NewAnthropic("key", WithBaseURL(server.URL))
+	resp, err := a.SendStream(context.Background(), &Request{
+		Model:    "claude-sonnet-4-20250514",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID != "toolu_1" || tc.Name != "call_agent" {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if decodeArgs(t, tc.Arguments)["agent"] != "helper" || decodeArgs(t, tc.Arguments)["task"] != "go" {
+		t.Errorf("Arguments = %v, want agent=helper task=go", tc.Arguments)
+	}
+	if resp.StopReason != "tool_use" {
+		t.Errorf("StopReason = %q, want tool_use", resp.StopReason)
+	}
+}
+
+func TestAnthropic_SendStream_ErrorEvent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	a, _ := NewAnthropic("key", WithBaseURL(server.URL))
+	_, err := a.SendStream(context.Background(), &Request{
+		Model:    "claude-sonnet-4-20250514",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, nil)
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected *ProviderError, got %T: %v", err, err)
+	}
+	if provErr.Category != ErrCategoryOverloaded {
+		t.Errorf("Category = %q, want %q", provErr.Category, ErrCategoryOverloaded)
+	}
+}
+
+func TestAnthropic_SendStream_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"bad key"}}`))
+	}))
+	defer server.Close()
+
+	a, _ := NewAnthropic("key", WithBaseURL(server.URL))
+	_, err := a.SendStream(context.Background(), &Request{
+		Model:    "claude-sonnet-4-20250514",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, nil)
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected *ProviderError, got %T: %v", err, err)
+	}
+	if provErr.Category != ErrCategoryAuth {
+		t.Errorf("Category = %q, want %q", provErr.Category, ErrCategoryAuth)
+	}
+	if provErr.Message != "bad key" {
+		t.Errorf("Message = %q, want %q", provErr.Message, "bad key")
+	}
+}
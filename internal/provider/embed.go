@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Embedder is implemented by providers that can compute text embeddings.
+// Embed returns one vector per text, in the order of texts.
+type Embedder interface {
+	Embed(ctx context.Context, model string, texts []string) ([][]float64, error)
+}
+
+// openaiEmbeddingRequest is the JSON body sent to the OpenAI Embeddings API.
+type openaiEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// openaiEmbeddingResponse is the JSON body returned by the OpenAI Embeddings API.
+type openaiEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed computes embeddings with the OpenAI Embeddings API.
+func (o *OpenAI) Embed(ctx context.Context, model string, texts []string) ([][]float64, error) {
+	httpResp, err := o.post(ctx, "/embeddings", openaiEmbeddingRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var apiResp openaiEmbeddingResponse
+	if err := decodeEmbeddings(httpResp.Body, &apiResp); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, d := range apiResp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, &ProviderError{
+				Category: ErrCategoryServer,
+				Message:  fmt.Sprintf("embedding index %d out of range", d.Index),
+			}
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, checkEmbeddings(vectors)
+}
+
+// ollamaEmbeddingRequest is the JSON body sent to the Ollama Embed API.
+type ollamaEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// ollamaEmbeddingResponse is the JSON body returned by the Ollama Embed API.
+type ollamaEmbeddingResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// Embed computes embeddings with the Ollama Embed API.
+func (o *Ollama) Embed(ctx context.Context, model string, texts []string) ([][]float64, error) {
+	httpResp, err := o.post(ctx, "/api/embed", ollamaEmbeddingRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var apiResp ollamaEmbeddingResponse
+	if err := decodeEmbeddings(httpResp.Body, &apiResp); err != nil {
+		return nil, err
+	}
+	if len(apiResp.Embeddings) != len(texts) {
+		return nil, &ProviderError{
+			Category: ErrCategoryServer,
+			Message:  fmt.Sprintf("expected %d embeddings, got %d", len(texts), len(apiResp.Embeddings)),
+		}
+	}
+	return apiResp.Embeddings, checkEmbeddings(apiResp.Embeddings)
+}
+
+// decodeEmbeddings reads an embeddings response body into v.
+func decodeEmbeddings(body io.Reader, v any) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &ProviderError{
+			Category: ErrCategoryServer,
+			Message:  fmt.Sprintf("failed to parse response: %s", err),
+			Err:      err,
+		}
+	}
+	return nil
+}
+
+// checkEmbeddings reports a server error if any text was left without a
+// vector.
+func checkEmbeddings(vectors [][]float64) error {
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return &ProviderError{
+				Category: ErrCategoryServer,
+				Message:  fmt.Sprintf("response contains no embedding for input %d", i),
+			}
+		}
+	}
+	return nil
+}
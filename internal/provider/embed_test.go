@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAI_Embed(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody openaiEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		// Out of order, as the API allows
+		w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOpenAI("test-key", WithOpenAIBaseURL(server.URL))
+	vectors, err := o.Embed(context.Background(), "text-embedding-3-small", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotPath != "/v1/embeddings" || gotAuth != "Bearer test-key" || gotBody.Model != "text-embedding-3-small" || len(gotBody.Input) != 2 {
+		t.Errorf("request = %s %q %+v", gotPath, gotAuth, gotBody)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v, want them in input order", vectors)
+	}
+}
+
+func TestOllama_Embed(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte(`{"embeddings": [[0.5, 0.5]]}`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	vectors, err := o.Embed(context.Background(), "nomic-embed-text", []string{"a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotPath != "/api/embed" || len(vectors) != 1 || vectors[0][0] != 0.5 {
+		t.Errorf("path = %s, vectors = %v", gotPath, vectors)
+	}
+
+	// A missing vector is a server error
+	_, err = o.Embed(context.Background(), "nomic-embed-text", []string{"a", "b"})
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) || provErr.Category != ErrCategoryServer {
+		t.Errorf("err = %v, want server ProviderError", err)
+	}
+}
+
+func TestEmbed_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "model \"nope\" not found"}`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	_, err := o.Embed(context.Background(), "nope", []string{"a"})
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) || provErr.Category != ErrCategoryBadRequest || provErr.Message != `model "nope" not found` {
+		t.Errorf("err = %v, want bad request ProviderError", err)
+	}
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// FallbackModel is one entry in a fallback chain.
+type FallbackModel struct {
+	// Ref is the "provider/model" reference as written in the agent config.
+	Ref string
+	// Provider sends requests for this entry (typically already wrapped with Retry).
+	Provider Provider
+	// Model is the model name passed in Request.Model for this entry.
+	Model string
+}
+
+// FallbackNotifyFunc is called when the chain moves from one model to the next
+// because of err.
+type FallbackNotifyFunc func(from, to string, err error)
+
+// FallbackOption is a functional option for configuring a Fallback provider.
+type FallbackOption func(*Fallback)
+
+// WithFallbackNotify sets a callback that is invoked each time the chain falls
+// back to the next model.
+func WithFallbackNotify(fn FallbackNotifyFunc) FallbackOption {
+	return func(f *Fallback) {
+		f.notify = fn
+	}
+}
+
+// Fallback is a Provider that tries an ordered list of models, moving to the
+// next entry when the current one fails with an auth, overloaded, or server
+// error. Once the chain has moved on it stays on the working model for later
+// requests, so multi-turn conversations do not re-probe a failing provider.
+// Every successful Response has ModelRef set to the entry that answered.
+type Fallback struct {
+	models []FallbackModel
+	notify FallbackNotifyFunc
+
+	mu      sync.Mutex
+	current int
+}
+
+// NewFallback creates a fallback chain over models. The first entry is the
+// primary model. models must not be empty.
+func NewFallback(models []FallbackModel, opts ...FallbackOption) *Fallback {
+	f := &Fallback{models: models}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// Send sends req to the current model, falling back down the chain on failure.
+func (f *Fallback) Send(ctx context.Context, req *Request) (*Response, error) {
+	return f.do(ctx, req, func(m FallbackModel, r *Request) (*Response, error) {
+		return m.Provider.Send(ctx, r)
+	})
+}
+
+// SendStream streams req from the current model, falling back down the chain
+// on failure. Once text has been passed to fn the chain no longer falls back,
+// since delivered output cannot be taken back.
+func (f *Fallback) SendStream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	emitted := false
+	tracked := func(text string) {
+		emitted = true
+		fn.emit(text)
+	}
+
+	return f.do(ctx, req, func(m FallbackModel, r *Request) (*Response, error) {
+		resp, err := Stream(ctx, m.Provider, r, tracked)
+		if err != nil && emitted {
+			return nil, &permanentError{err: err}
+		}
+		return resp, err
+	})
+}
+
+// do runs send against each remaining model in order until one succeeds or an
+// error that does not warrant a fallback occurs.
+func (f *Fallback) do(ctx context.Context, req *Request, send func(FallbackModel, *Request) (*Response, error)) (*Response, error) {
+	f.mu.Lock()
+	start := f.current
+	f.mu.Unlock()
+
+	for i := start; i < len(f.models); i++ {
+		m := f.models[i]
+
+		r := *req
+		r.Model = m.Model
+
+		resp, err := send(m, &r)
+		if err == nil {
+			f.mu.Lock()
+			f.current = i
+			f.mu.Unlock()
+			resp.ModelRef = m.Ref
+			return resp, nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return nil, perm.err
+		}
+
+		if i == len(f.models)-1 || !shouldFallback(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		if f.notify != nil {
+			f.notify(m.Ref, f.models[i+1].Ref, err)
+		}
+	}
+
+	return nil, errors.New("fallback chain has no models")
+}
+
+// shouldFallback reports whether err indicates the current model is unusable
+// and the next model in the chain should be tried.
+func shouldFallback(err error) bool {
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		return false
+	}
+	switch provErr.Category {
+	case ErrCategoryAuth, ErrCategoryOverloaded, ErrCategoryServer:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// recordingProvider fails with err (if set) and records the models it was asked for.
+type recordingProvider struct {
+	err    error
+	models []string
+}
+
+func (p *recordingProvider) Send(ctx context.Context, req *Request) (*Response, error) {
+	p.models = append(p.models, req.Model)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Response{Content: "from " + req.Model, Model: req.Model}, nil
+}
+
+// partialStreamer emits text and then fails with err.
+type partialStreamer struct {
+	text string
+	err  error
+}
+
+func (p *partialStreamer) Send(ctx context.Context, req *Request) (*Response, error) {
+	return nil, p.err
+}
+
+func (p *partialStreamer) SendStream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	fn.emit(p.text)
+	return nil, p.err
+}
+
+func TestFallback_PrimarySucceeds(t *testing.T) {
+	primary := &recordingProvider{}
+	backup := &recordingProvider{}
+	f := NewFallback([]FallbackModel{
+		{Ref: "anthropic/claude", Provider: primary, Model: "claude"},
+		{Ref: "openai/gpt-4o", Provider: backup, Model: "gpt-4o"},
+	})
+
+	resp, err := f.Send(context.Background(), &Request{Model: "claude"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ModelRef != "anthropic/claude" {
+		t.Errorf("ModelRef = %q, want anthropic/claude", resp.ModelRef)
+	}
+	if len(backup.models) != 0 {
+		t.Errorf("backup called %d times, want 0", len(backup.models))
+	}
+}
+
+func TestFallback_MovesOnForFallbackCategories(t *testing.T) {
+	for _, cat := range []ErrorCategory{ErrCategoryAuth, ErrCategoryOverloaded, ErrCategoryServer} {
+		t.Run(string(cat), func(t *testing.T) {
+			primary := &recordingProvider{err: &ProviderError{Category: cat, Message: "down"}}
+			backup := &recordingProvider{}
+			var notified []string
+			f := NewFallback([]FallbackModel{
+				{Ref: "anthropic/claude", Provider: primary, Model: "claude"},
+				{Ref: "openai/gpt-4o", Provider: backup, Model: "gpt-4o"},
+			}, WithFallbackNotify(func(from, to string, err error) {
+				notified = append(notified, from+"->"+to)
+			}))
+
+			req := &Request{Model: "claude"}
+			resp, err := f.Send(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.ModelRef != "openai/gpt-4o" {
+				t.Errorf("ModelRef = %q, want openai/gpt-4o", resp.ModelRef)
+			}
+			if len(backup.models) != 1 || backup.models[0] != "gpt-4o" {
+				t.Errorf("backup models = %v, want [gpt-4o]", backup.models)
+			}
+			if req.Model != "claude" {
+				t.Errorf("caller's request was modified: Model = %q", req.Model)
+			}
+			if len(notified) != 1 || notified[0] != "anthropic/claude->openai/gpt-4o" {
+				t.Errorf("notified = %v", notified)
+			}
+		})
+	}
+}
+
+func TestFallback_DoesNotMoveOnOtherErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"bad_request", &ProviderError{Category: ErrCategoryBadRequest, Message: "bad"}},
+		{"rate_limit", &ProviderError{Category: ErrCategoryRateLimit, Message: "slow"}},
+		{"plain", errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &recordingProvider{err: tt.err}
+			backup := &recordingProvider{}
+			f := NewFallback([]FallbackModel{
+				{Ref: "anthropic/claude", Provider: primary, Model: "claude"},
+				{Ref: "openai/gpt-4o", Provider: backup, Model: "gpt-4o"},
+			})
+
+			_, err := f.Send(context.Background(), &Request{})
+			if err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if len(backup.models) != 0 {
+				t.Errorf("backup called %d times, want 0", len(backup.models))
+			}
+		})
+	}
+}
+
+func TestFallback_ReturnsLastErrorWhenAllFail(t *testing.T) {
+	last := &ProviderError{Category: ErrCategoryServer, Message: "also down"}
+	f := NewFallback([]FallbackModel{
+		{Ref: "a/1", Provider: &recordingProvider{err: &ProviderError{Category: ErrCategoryOverloaded, Message: "down"}}, Model: "1"},
+		{Ref: "b/2", Provider: &recordingProvider{err: last}, Model: "2"},
+	})
+
+	_, err := f.Send(context.Background(), &Request{})
+	if err != last {
+		t.Errorf("err = %v, want last model's error", err)
+	}
+}
+
+func TestFallback_StaysOnWorkingModel(t *testing.T) {
+	primary := &recordingProvider{err: &ProviderError{Category: ErrCategoryOverloaded, Message: "down"}}
+	backup := &recordingProvider{}
+	f := NewFallback([]FallbackModel{
+		{Ref: "anthropic/claude", Provider: primary, Model: "claude"},
+		{Ref: "openai/gpt-4o", Provider: backup, Model: "gpt-4o"},
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.Send(context.Background(), &Request{}); err != nil {
+			t.Fatalf("send %d: unexpected error: %v", i, err)
+		}
+	}
+	if len(primary.models) != 1 {
+		t.Errorf("primary called %d times, want 1", len(primary.models))
+	}
+	if len(backup.models) != 3 {
+		t.Errorf("backup called %d times, want 3", len(backup.models))
+	}
+}
+
+func TestFallback_SendStream_NoFallbackAfterOutput(t *testing.T) {
+	wantErr := &ProviderError{Category: ErrCategoryServer, Message: "stream broke"}
+	primary := &partialStreamer{text: "partial", err: wantErr}
+	backup := &recordingProvider{}
+	f := NewFallback([]FallbackModel{
+		{Ref: "a/1", Provider: primary, Model: "1"},
+		{Ref: "b/2", Provider: backup, Model: "2"},
+	})
+
+	var got string
+	_, err := f.SendStream(context.Background(), &Request{}, func(text string) { got += text })
+	if err != wantErr {
+		t.Errorf("err = %v, want %v", err, wantErr)
+	}
+	if got != "partial" {
+		t.Errorf("streamed = %q, want partial", got)
+	}
+	if len(backup.models) != 0 {
+		t.Errorf("backup called %d times, want 0", len(backup.models))
+	}
+}
+
+func TestFallback_SendStream_FallsBackBeforeOutput(t *testing.T) {
+	primary := &recordingProvider{err: &ProviderError{Category: ErrCategoryAuth, Message: "bad key"}}
+	backup := &recordingProvider{}
+	f := NewFallback([]FallbackModel{
+		{Ref: "a/1", Provider: primary, Model: "1"},
+		{Ref: "b/2", Provider: backup, Model: "2"},
+	})
+
+	var got string
+	resp, err := f.SendStream(context.Background(), &Request{}, func(text string) { got += text })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "from 2" {
+		t.Errorf("streamed = %q, want %q", got, "from 2")
+	}
+	if resp.ModelRef != "b/2" {
+		t.Errorf("ModelRef = %q, want b/2", resp.ModelRef)
+	}
+}
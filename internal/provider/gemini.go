@@ -0,0 +1,408 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// defaultGeminiBaseURL is the default Google Gemini API base URL.
+	defaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+	// geminiCallIDPrefix starts the IDs axe makes up for function calls that
+	// came without one. They are only used within axe, never sent back.
+	geminiCallIDPrefix = "gemini_"
+)
+
+// GeminiOption is a functional option for configuring the Gemini provider.
+type GeminiOption func(*Gemini)
+
+// WithGeminiBaseURL sets a custom base URL for the Gemini provider.
+func WithGeminiBaseURL(url string) GeminiOption {
+	return func(g *Gemini) {
+		g.baseURL = url
+	}
+}
+
+// Gemini implements the Provider interface for the Google Gemini
+// generateContent API.
+type Gemini struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewGemini creates a new Gemini provider. Returns an error if apiKey is empty.
+func NewGemini(apiKey string, opts ...GeminiOption) (*Gemini, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key is required")
+	}
+
+	g := &Gemini{
+		apiKey:  apiKey,
+		baseURL: defaultGeminiBaseURL,
+		client: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g, nil
+}
+
+// geminiRequest is the JSON body sent to the generateContent API.
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+}
+
+// geminiContent is a single turn in a Gemini conversation.
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart is one part of a content turn: text, a function call, or a
+// function response.
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	// ThoughtSignature accompanies function calls of thinking models and
+	// must be returned on the same part when the call is replayed.
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+}
+
+// geminiBlob is inline binary data such as an image or PDF.
+type geminiBlob struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"` // base64-encoded
+}
+
+// geminiFunctionCall is a function call requested by the model.
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+}
+
+// geminiFunctionResponse is the result of a function call sent back to the model.
+type geminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// geminiGenerationConfig holds sampling parameters.
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
+}
+
+// geminiTool wraps the function declarations offered to the model.
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// geminiFunctionDeclaration is the wire format for a tool definition. The
+// schema is sent as parametersJsonSchema, which accepts full JSON Schema
+// rather than the OpenAPI subset allowed in parameters.
+type geminiFunctionDeclaration struct {
+	Name                 string          `json:"name"`
+	Description          string          `json:"description"`
+	ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema"`
+}
+
+// geminiResponse represents the JSON response from the generateContent API.
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"` // included in promptTokenCount
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`      // billed as output, not in candidatesTokenCount
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+// geminiErrorResponse represents a Gemini API error response.
+type geminiErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Reason string `json:"reason"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// convertToGeminiContents converts provider Messages to Gemini content turns.
+// Gemini identifies function responses by function name, so tool results are
+// matched to the name of the call they answer from earlier assistant messages.
+func convertToGeminiContents(msgs []Message) []geminiContent {
+	callNames := make(map[string]string)
+
+	var result []geminiContent
+	for _, msg := range msgs {
+		if msg.Role == "tool" && len(msg.ToolResults) > 0 {
+			var parts []geminiPart
+			for _, tr := range msg.ToolResults {
+				key := "result"
+				if tr.IsError {
+					key = "error"
+				}
+				parts = append(parts, geminiPart{
+					FunctionResponse: &geminiFunctionResponse{
+						ID:       geminiCallID(tr.CallID),
+						Name:     callNames[tr.CallID],
+						Response: map[string]interface{}{key: tr.Content},
+					},
+				})
+			}
+			result = append(result, geminiContent{Role: "user", Parts: parts})
+		} else if msg.Role == "assistant" {
+			var parts []geminiPart
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Name
+				parts = append(parts, geminiPart{
+					FunctionCall:     &geminiFunctionCall{ID: geminiCallID(tc.ID), Name: tc.Name, Args: objectArguments(tc.Arguments)},
+					ThoughtSignature: tc.Signature,
+				})
+			}
+			result = append(result, geminiContent{Role: "model", Parts: parts})
+		} else {
+			var parts []geminiPart
+			if msg.Content != "" || len(msg.Parts) == 0 {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, part := range msg.Parts {
+				parts = append(parts, geminiPart{
+					InlineData: &geminiBlob{MIMEType: part.MediaType, Data: base64.StdEncoding.EncodeToString(part.Data)},
+				})
+			}
+			result = append(result, geminiContent{Role: "user", Parts: parts})
+		}
+	}
+	return result
+}
+
+// geminiCallID returns the ID to send for a function call or response: id
+// itself, or empty for an ID axe made up.
+func geminiCallID(id string) string {
+	if strings.HasPrefix(id, geminiCallIDPrefix) {
+		return ""
+	}
+	return id
+}
+
+// convertToGeminiTools converts provider Tools to Gemini function declarations.
+func convertToGeminiTools(tools []Tool) []geminiTool {
+	var decls []geminiFunctionDeclaration
+	for _, tool := range tools {
+		decls = append(decls, geminiFunctionDeclaration{
+			Name:                 tool.Name,
+			Description:          tool.Description,
+			ParametersJSONSchema: toolSchema(tool),
+		})
+	}
+	return []geminiTool{{FunctionDeclarations: decls}}
+}
+
+// buildRequest converts a provider Request into the Gemini wire format.
+func (g *Gemini) buildRequest(req *Request) geminiRequest {
+	body := geminiRequest{
+		Contents: convertToGeminiContents(req.Messages),
+	}
+
+	if req.System != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: req.System}}}
+	}
+
+	if req.Temperature != 0 || req.MaxTokens != 0 {
+		body.GenerationConfig = &geminiGenerationConfig{}
+		if req.Temperature != 0 {
+			temp := req.Temperature
+			body.GenerationConfig.Temperature = &temp
+		}
+		if req.MaxTokens != 0 {
+			mt := req.MaxTokens
+			body.GenerationConfig.MaxOutputTokens = &mt
+		}
+	}
+
+	if len(req.Tools) > 0 {
+		body.Tools = convertToGeminiTools(req.Tools)
+	}
+
+	return body
+}
+
+// Send makes a completion request to the Gemini generateContent API.
+func (g *Gemini) Send(ctx context.Context, req *Request) (*Response, error) {
+	jsonBody, err := json.Marshal(g.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := g.baseURL + "/v1beta/models/" + url.PathEscape(req.Model) + ":generateContent"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("x-goog-api-key", g.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := g.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, &ProviderError{
+				Category: ErrCategoryTimeout,
+				Message:  ctx.Err().Error(),
+				Err:      ctx.Err(),
+			}
+		}
+		return nil, &ProviderError{
+			Category: ErrCategoryServer,
+			Message:  err.Error(),
+			Err:      err,
+		}
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		provErr := g.handleErrorResponse(httpResp.StatusCode, respBody)
+		provErr.RetryAfter = parseRetryAfter(httpResp.Header.Get("Retry-After"))
+		return nil, provErr
+	}
+
+	var apiResp geminiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return nil, &ProviderError{
+			Category: ErrCategoryServer,
+			Message:  fmt.Sprintf("failed to parse response: %s", err),
+			Err:      err,
+		}
+	}
+
+	if len(apiResp.Candidates) == 0 {
+		if apiResp.PromptFeedback != nil && apiResp.PromptFeedback.BlockReason != "" {
+			return nil, &ProviderError{
+				Category: ErrCategoryBadRequest,
+				Message:  "prompt blocked: " + apiResp.PromptFeedback.BlockReason,
+			}
+		}
+		return nil, &ProviderError{
+			Category: ErrCategoryServer,
+			Message:  "response contains no candidates",
+		}
+	}
+
+	candidate := apiResp.Candidates[0]
+
+	var content strings.Builder
+	var toolCalls []ToolCall
+	for _, part := range candidate.Content.Parts {
+		if part.FunctionCall != nil {
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("%s%d", geminiCallIDPrefix, len(toolCalls))
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        id,
+				Name:      part.FunctionCall.Name,
+				Arguments: rawArguments(part.FunctionCall.Args),
+				Signature: part.ThoughtSignature,
+			})
+			continue
+		}
+		content.WriteString(part.Text)
+	}
+
+	model := apiResp.ModelVersion
+	if model == "" {
+		model = req.Model
+	}
+
+	return &Response{
+		Content:      content.String(),
+		Model:        model,
+		InputTokens:  apiResp.UsageMetadata.PromptTokenCount - apiResp.UsageMetadata.CachedContentTokenCount,
+		OutputTokens: apiResp.UsageMetadata.CandidatesTokenCount + apiResp.UsageMetadata.ThoughtsTokenCount,
+		StopReason:   candidate.FinishReason,
+		ToolCalls:    toolCalls,
+
+		CacheReadTokens: apiResp.UsageMetadata.CachedContentTokenCount,
+	}, nil
+}
+
+// handleErrorResponse maps HTTP error responses to ProviderError.
+func (g *Gemini) handleErrorResponse(status int, body []byte) *ProviderError {
+	message := http.StatusText(status)
+	category := g.mapStatusToCategory(status)
+
+	var errResp geminiErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		message = errResp.Error.Message
+		// An invalid API key is reported as 400 INVALID_ARGUMENT.
+		for _, d := range errResp.Error.Details {
+			if d.Reason == "API_KEY_INVALID" {
+				category = ErrCategoryAuth
+			}
+		}
+	}
+
+	return &ProviderError{
+		Category: category,
+		Status:   status,
+		Message:  message,
+	}
+}
+
+// mapStatusToCategory maps HTTP status codes to error categories.
+func (g *Gemini) mapStatusToCategory(status int) ErrorCategory {
+	switch status {
+	case 401, 403:
+		return ErrCategoryAuth
+	case 400, 404:
+		return ErrCategoryBadRequest
+	case 429:
+		return ErrCategoryRateLimit
+	case 503:
+		return ErrCategoryOverloaded
+	case 504:
+		return ErrCategoryTimeout
+	default:
+		return ErrCategoryServer
+	}
+}
@@ -0,0 +1,516 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewGemini_EmptyAPIKey(t *testing.T) {
+	_, err := NewGemini("")
+	if err == nil {
+		t.Fatal("expected error for empty API key")
+	}
+	if !strings.Contains(err.Error(), "API key is required") {
+		t.Errorf("expected 'API key is required', got %q", err.Error())
+	}
+}
+
+func TestNewGemini_ValidAPIKey(t *testing.T) {
+	g, err := NewGemini("test-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g == nil {
+		t.Fatal("expected non-nil Gemini")
+	}
+}
+
+func TestGemini_Send_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello "}, {"text": "from Gemini"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5},
+			"modelVersion": "gemini-2.0-flash-001"
+		}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	resp, err := g.Send(context.Background(), &Request{
+		Model:    "gemini-2.0-flash",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "Hello from Gemini" {
+		t.Errorf("expected 'Hello from Gemini', got %q", resp.Content)
+	}
+	if resp.Model != "gemini-2.0-flash-001" {
+		t.Errorf("expected model 'gemini-2.0-flash-001', got %q", resp.Model)
+	}
+	if resp.InputTokens != 10 {
+		t.Errorf("expected 10 input tokens, got %d", resp.InputTokens)
+	}
+	if resp.OutputTokens != 5 {
+		t.Errorf("expected 5 output tokens, got %d", resp.OutputTokens)
+	}
+	if resp.StopReason != "STOP" {
+		t.Errorf("expected stop reason 'STOP', got %q", resp.StopReason)
+	}
+}
+
+func TestGemini_Send_CountsThoughtTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "42"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "thoughtsTokenCount": 120}
+		}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	resp, err := g.Send(context.Background(), &Request{
+		Model:    "gemini-2.5-pro",
+		Messages: []Message{{Role: "user", Content: "Think"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.OutputTokens != 125 {
+		t.Errorf("expected 125 output tokens including thoughts, got %d", resp.OutputTokens)
+	}
+}
+
+func TestGemini_Send_RequestFormat(t *testing.T) {
+	var gotMethod, gotPath, gotKey, gotCT string
+	var gotBody map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("x-goog-api-key")
+		gotCT = r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	resp, err := g.Send(context.Background(), &Request{
+		Model:       "gemini-2.0-flash",
+		System:      "You are helpful.",
+		Messages:    []Message{{Role: "user", Content: "Hi"}},
+		Temperature: 0.5,
+		MaxTokens:   256,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotMethod != "POST" {
+		t.Errorf("expected POST, got %s", gotMethod)
+	}
+	if gotPath != "/v1beta/models/gemini-2.0-flash:generateContent" {
+		t.Errorf("unexpected path %q", gotPath)
+	}
+	if gotKey != "test-key" {
+		t.Errorf("expected x-goog-api-key 'test-key', got %q", gotKey)
+	}
+	if gotCT != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", gotCT)
+	}
+	if resp.Model != "gemini-2.0-flash" {
+		t.Errorf("expected model to fall back to request model, got %q", resp.Model)
+	}
+
+	sys, ok := gotBody["systemInstruction"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected systemInstruction, got %v", gotBody["systemInstruction"])
+	}
+	sysParts := sys["parts"].([]interface{})
+	if sysParts[0].(map[string]interface{})["text"] != "You are helpful." {
+		t.Errorf("unexpected system instruction: %v", sys)
+	}
+
+	contents := gotBody["contents"].([]interface{})
+	first := contents[0].(map[string]interface{})
+	if first["role"] != "user" {
+		t.Errorf("expected role 'user', got %v", first["role"])
+	}
+
+	gen := gotBody["generationConfig"].(map[string]interface{})
+	if gen["temperature"] != 0.5 {
+		t.Errorf("expected temperature 0.5, got %v", gen["temperature"])
+	}
+	if gen["maxOutputTokens"] != float64(256) {
+		t.Errorf("expected maxOutputTokens 256, got %v", gen["maxOutputTokens"])
+	}
+}
+
+func TestGemini_Send_OmitsEmptyOptionalFields(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	_, err := g.Send(context.Background(), &Request{
+		Model:    "gemini-2.0-flash",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, key := range []string{"systemInstruction", "generationConfig", "tools"} {
+		if _, ok := gotBody[key]; ok {
+			t.Errorf("expected %s to be omitted, got %v", key, gotBody[key])
+		}
+	}
+}
+
+func TestGemini_Send_WithTools(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	_, err := g.Send(context.Background(), &Request{
+		Model:    "gemini-2.0-flash",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+		Tools: []Tool{{
+			Name:        "call_agent",
+			Description: "Delegate a task",
+			InputSchema: json.RawMessage(`{"type": "object", "properties": {"agent": {"type": "string", "description": "Agent name"}}, "required": ["agent"]}`),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tools := gotBody["tools"].([]interface{})
+	decls := tools[0].(map[string]interface{})["functionDeclarations"].([]interface{})
+	decl := decls[0].(map[string]interface{})
+	if decl["name"] != "call_agent" {
+		t.Errorf("expected name 'call_agent', got %v", decl["name"])
+	}
+	params := decl["parametersJsonSchema"].(map[string]interface{})
+	if params["type"] != "object" {
+		t.Errorf("expected parameters type 'object', got %v", params["type"])
+	}
+	required := params["required"].([]interface{})
+	if len(required) != 1 || required[0] != "agent" {
+		t.Errorf("expected required [agent], got %v", required)
+	}
+}
+
+func TestGemini_Send_FunctionCallResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [
+				{"functionCall": {"name": "call_agent", "args": {"agent": "helper", "task": "summarize", "count": 3}}},
+				{"functionCall": {"id": "call-xyz", "name": "call_agent", "args": {"agent": "runner"}}}
+			]}, "finishReason": "STOP"}]
+		}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	resp, err := g.Send(context.Background(), &Request{
+		Model:    "gemini-2.0-flash",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %d", len(resp.ToolCalls))
+	}
+	first := resp.ToolCalls[0]
+	if first.ID != "gemini_0" {
+		t.Errorf("expected generated ID 'gemini_0', got %q", first.ID)
+	}
+	if first.Name != "call_agent" {
+		t.Errorf("expected name 'call_agent', got %q", first.Name)
+	}
+	if decodeArgs(t, first.Arguments)["agent"] != "helper" || decodeArgs(t, first.Arguments)["task"] != "summarize" || decodeArgs(t, first.Arguments)["count"] != float64(3) {
+		t.Errorf("unexpected arguments: %v", first.Arguments)
+	}
+	if resp.ToolCalls[1].ID != "call-xyz" {
+		t.Errorf("expected API-provided ID 'call-xyz', got %q", resp.ToolCalls[1].ID)
+	}
+}
+
+func TestGemini_Send_ToolConversation(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "done"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	_, err := g.Send(context.Background(), &Request{
+		Model: "gemini-2.0-flash",
+		Messages: []Message{
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", Content: "Delegating.", ToolCalls: []ToolCall{
+				{ID: "gemini_0", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper"}`)},
+			}},
+			{Role: "tool", ToolResults: []ToolResult{
+				{CallID: "gemini_0", Content: "helper output"},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contents := gotBody["contents"].([]interface{})
+	if len(contents) != 3 {
+		t.Fatalf("expected 3 contents, got %d", len(contents))
+	}
+
+	model := contents[1].(map[string]interface{})
+	if model["role"] != "model" {
+		t.Errorf("expected role 'model', got %v", model["role"])
+	}
+	modelParts := model["parts"].([]interface{})
+	if len(modelParts) != 2 {
+		t.Fatalf("expected text and functionCall parts, got %v", modelParts)
+	}
+	fc := modelParts[1].(map[string]interface{})["functionCall"].(map[string]interface{})
+	if fc["name"] != "call_agent" {
+		t.Errorf("expected functionCall name 'call_agent', got %v", fc["name"])
+	}
+
+	result := contents[2].(map[string]interface{})
+	if result["role"] != "user" {
+		t.Errorf("expected role 'user' for function response, got %v", result["role"])
+	}
+	fr := result["parts"].([]interface{})[0].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	if fr["name"] != "call_agent" {
+		t.Errorf("expected functionResponse name 'call_agent', got %v", fr["name"])
+	}
+	if fr["response"].(map[string]interface{})["result"] != "helper output" {
+		t.Errorf("unexpected functionResponse payload: %v", fr["response"])
+	}
+}
+
+func TestGemini_Send_ThoughtSignatureRoundTrip(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if len(bodies) == 1 {
+			w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [
+				{"functionCall": {"name": "read_file", "args": {"path": "a.go"}}, "thoughtSignature": "c2lnLTE="}
+			]}, "finishReason": "STOP"}]}`))
+			return
+		}
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "done"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	req := &Request{Model: "gemini-3-pro-preview", Messages: []Message{{Role: "user", Content: "Read a.go"}}}
+	resp, err := g.Send(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Signature != "c2lnLTE=" {
+		t.Fatalf("expected the thought signature on the tool call, got %+v", resp.ToolCalls)
+	}
+
+	req.Messages = append(req.Messages,
+		Message{Role: "assistant", ToolCalls: resp.ToolCalls},
+		Message{Role: "tool", ToolResults: []ToolResult{{CallID: resp.ToolCalls[0].ID, Content: "package a"}}},
+	)
+	if _, err := g.Send(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contents := bodies[1]["contents"].([]interface{})
+	callPart := contents[1].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})
+	if callPart["thoughtSignature"] != "c2lnLTE=" {
+		t.Errorf("expected thoughtSignature on the replayed functionCall part, got %v", callPart)
+	}
+	if _, ok := callPart["functionCall"].(map[string]interface{})["id"]; ok {
+		t.Errorf("made-up call ID sent back: %v", callPart)
+	}
+	respPart := contents[2].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})
+	fr := respPart["functionResponse"].(map[string]interface{})
+	if _, ok := fr["id"]; ok || fr["name"] != "read_file" {
+		t.Errorf("unexpected functionResponse: %v", fr)
+	}
+}
+
+func TestGemini_Send_InlineDataParts(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("test-key", WithGeminiBaseURL(server.URL))
+	_, err := g.Send(context.Background(), &Request{
+		Model: "gemini-2.0-flash",
+		Messages: []Message{{Role: "user", Content: "Describe", Parts: []ContentPart{
+			{Type: PartDocument, MediaType: "application/pdf", Name: "spec.pdf", Data: []byte("pdf")},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parts := gotBody["contents"].([]interface{})[0].(map[string]interface{})["parts"].([]interface{})
+	if len(parts) != 2 {
+		t.Fatalf("expected text and inlineData parts, got %v", parts)
+	}
+	blob := parts[1].(map[string]interface{})["inlineData"].(map[string]interface{})
+	if blob["mimeType"] != "application/pdf" || blob["data"] != "cGRm" {
+		t.Errorf("unexpected inlineData: %v", blob)
+	}
+}
+
+func TestGemini_Send_ErrorCategories(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   ErrorCategory
+	}{
+		{"invalid key", 400, `{"error": {"code": 400, "message": "API key not valid.", "status": "INVALID_ARGUMENT", "details": [{"reason": "API_KEY_INVALID"}]}}`, ErrCategoryAuth},
+		{"bad request", 400, `{"error": {"code": 400, "message": "Invalid JSON payload", "status": "INVALID_ARGUMENT"}}`, ErrCategoryBadRequest},
+		{"forbidden", 403, `{"error": {"code": 403, "message": "Permission denied", "status": "PERMISSION_DENIED"}}`, ErrCategoryAuth},
+		{"not found", 404, `{"error": {"code": 404, "message": "models/nope is not found", "status": "NOT_FOUND"}}`, ErrCategoryBadRequest},
+		{"rate limit", 429, `{"error": {"code": 429, "message": "Resource exhausted", "status": "RESOURCE_EXHAUSTED"}}`, ErrCategoryRateLimit},
+		{"server", 500, `{"error": {"code": 500, "message": "Internal error", "status": "INTERNAL"}}`, ErrCategoryServer},
+		{"overloaded", 503, `{"error": {"code": 503, "message": "The model is overloaded.", "status": "UNAVAILABLE"}}`, ErrCategoryOverloaded},
+		{"deadline", 504, `{"error": {"code": 504, "message": "Deadline exceeded", "status": "DEADLINE_EXCEEDED"}}`, ErrCategoryTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			g, _ := NewGemini("key", WithGeminiBaseURL(server.URL))
+			_, err := g.Send(context.Background(), &Request{Model: "gemini-2.0-flash", Messages: []Message{{Role: "user", Content: "Hi"}}})
+
+			var provErr *ProviderError
+			if !errors.As(err, &provErr) {
+				t.Fatalf("expected ProviderError, got %T", err)
+			}
+			if provErr.Category != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, provErr.Category)
+			}
+			if provErr.Status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, provErr.Status)
+			}
+		})
+	}
+}
+
+func TestGemini_Send_ErrorResponseParsing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error": {"code": 400, "message": "Invalid JSON payload received.", "status": "INVALID_ARGUMENT"}}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("key", WithGeminiBaseURL(server.URL))
+	_, err := g.Send(context.Background(), &Request{Model: "gemini-2.0-flash", Messages: []Message{{Role: "user", Content: "Hi"}}})
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected ProviderError, got %T", err)
+	}
+	if provErr.Message != "Invalid JSON payload received." {
+		t.Errorf("expected API error message, got %q", provErr.Message)
+	}
+}
+
+func TestGemini_Send_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("key", WithGeminiBaseURL(server.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := g.Send(ctx, &Request{Model: "gemini-2.0-flash", Messages: []Message{{Role: "user", Content: "Hi"}}})
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected ProviderError, got %T", err)
+	}
+	if provErr.Category != ErrCategoryTimeout {
+		t.Errorf("expected ErrCategoryTimeout, got %s", provErr.Category)
+	}
+}
+
+func TestGemini_Send_PromptBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"promptFeedback": {"blockReason": "SAFETY"}}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("key", WithGeminiBaseURL(server.URL))
+	_, err := g.Send(context.Background(), &Request{Model: "gemini-2.0-flash", Messages: []Message{{Role: "user", Content: "Hi"}}})
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected ProviderError, got %T", err)
+	}
+	if provErr.Category != ErrCategoryBadRequest {
+		t.Errorf("expected ErrCategoryBadRequest, got %s", provErr.Category)
+	}
+	if !strings.Contains(provErr.Message, "SAFETY") {
+		t.Errorf("expected block reason in message, got %q", provErr.Message)
+	}
+}
+
+func TestGemini_Send_NoCandidates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"candidates": []}`))
+	}))
+	defer server.Close()
+
+	g, _ := NewGemini("key", WithGeminiBaseURL(server.URL))
+	_, err := g.Send(context.Background(), &Request{Model: "gemini-2.0-flash", Messages: []Message{{Role: "user", Content: "Hi"}}})
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected ProviderError, got %T", err)
+	}
+	if provErr.Category != ErrCategoryServer {
+		t.Errorf("expected ErrCategoryServer, got %s", provErr.Category)
+	}
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -70,6 +71,7 @@ type ollamaRequest struct {
 type ollamaMessage struct {
 	Role      string               `json:"role"`
 	Content   string               `json:"content"`
+	Images    []string             `json:"images,omitempty"` // base64-encoded images
 	ToolCalls []ollamaToolCallWire `json:"tool_calls,omitempty"`
 }
 
@@ -81,9 +83,9 @@ type ollamaToolDef struct {
 
 // ollamaToolFunction is the function definition inside an Ollama tool.
 type ollamaToolFunction struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	Parameters  map[string]interface{} `json:"parameters"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
 }
 
 // ollamaToolCallWire is the wire format for a tool call in Ollama responses.
@@ -93,8 +95,8 @@ type ollamaToolCallWire struct {
 
 // ollamaToolCallFunctionWire is the function info inside an Ollama tool call.
 type ollamaToolCallFunctionWire struct {
-	Name      string                 `json:"name"`
-	Arguments map[string]interface{} `json:"arguments"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
 }
 
 // ollamaResponse represents the JSON response from the Ollama Chat API.
@@ -115,7 +117,8 @@ type ollamaErrorResponse struct {
 }
 
 // convertToOllamaMessages converts provider Messages to the Ollama wire format.
-func convertToOllamaMessages(msgs []Message) []ollamaMessage {
+// Ollama accepts images only, so a document part is an error.
+func convertToOllamaMessages(msgs []Message) ([]ollamaMessage, error) {
 	var result []ollamaMessage
 	for _, msg := range msgs {
 		if msg.Role == "tool" && len(msg.ToolResults) > 0 {
@@ -131,14 +134,10 @@ func convertToOllamaMessages(msgs []Message) []ollamaMessage {
 			// Assistant message with tool calls
 			var toolCalls []ollamaToolCallWire
 			for _, tc := range msg.ToolCalls {
-				args := make(map[string]interface{})
-				for k, v := range tc.Arguments {
-					args[k] = v
-				}
 				toolCalls = append(toolCalls, ollamaToolCallWire{
 					Function: ollamaToolCallFunctionWire{
 						Name:      tc.Name,
-						Arguments: args,
+						Arguments: objectArguments(tc.Arguments),
 					},
 				})
 			}
@@ -148,64 +147,60 @@ func convertToOllamaMessages(msgs []Message) []ollamaMessage {
 				ToolCalls: toolCalls,
 			})
 		} else {
-			// Standard text message
-			result = append(result, ollamaMessage{
+			// Standard text message, with any images attached
+			om := ollamaMessage{
 				Role:    msg.Role,
 				Content: msg.Content,
-			})
+			}
+			for _, part := range msg.Parts {
+				if part.Type != PartImage {
+					return nil, &ProviderError{
+						Category: ErrCategoryBadRequest,
+						Message:  fmt.Sprintf("ollama does not support %s attachments (%s)", part.Type, part.Name),
+					}
+				}
+				om.Images = append(om.Images, base64.StdEncoding.EncodeToString(part.Data))
+			}
+			result = append(result, om)
 		}
 	}
-	return result
+	return result, nil
 }
 
 // convertToOllamaTools converts provider Tools to the Ollama wire format.
 func convertToOllamaTools(tools []Tool) []ollamaToolDef {
 	var result []ollamaToolDef
 	for _, tool := range tools {
-		properties := make(map[string]interface{})
-		var required []string
-		for name, param := range tool.Parameters {
-			properties[name] = map[string]interface{}{
-				"type":        param.Type,
-				"description": param.Description,
-			}
-			if param.Required {
-				required = append(required, name)
-			}
-		}
-
-		params := map[string]interface{}{
-			"type":       "object",
-			"properties": properties,
-		}
-		if len(required) > 0 {
-			params["required"] = required
-		}
-
 		result = append(result, ollamaToolDef{
 			Type: "function",
 			Function: ollamaToolFunction{
 				Name:        tool.Name,
 				Description: tool.Description,
-				Parameters:  params,
+				Parameters:  toolSchema(tool),
 			},
 		})
 	}
 	return result
 }
 
-// Send makes a completion request to the Ollama Chat API.
-func (o *Ollama) Send(ctx context.Context, req *Request) (*Response, error) {
+// buildRequest converts a provider Request into the Ollama wire format.
+// The system prompt is sent as a leading "system" message.
+func (o *Ollama) buildRequest(req *Request, stream bool) (ollamaRequest, error) {
 	var messages []Message
 	if req.System != "" {
 		messages = append(messages, Message{Role: "system", Content: req.System})
 	}
 	messages = append(messages, req.Messages...)
 
+	converted, err := convertToOllamaMessages(messages)
+	if err != nil {
+		return ollamaRequest{}, err
+	}
+
 	body := ollamaRequest{
 		Model:    req.Model,
-		Messages: convertToOllamaMessages(messages),
-		Stream:   false,
+		Messages: converted,
+		Stream:   stream,
 	}
 
 	// Build options only if needed
@@ -229,12 +224,19 @@ func (o *Ollama) Send(ctx context.Context, req *Request) (*Response, error) {
 		body.Tools = convertToOllamaTools(req.Tools)
 	}
 
+	return body, nil
+}
+
+// post sends body to the Ollama API endpoint at path, e.g. "/api/chat".
+// Transport failures and non-2xx responses are returned as ProviderErrors. On
+// success the caller must close the returned response body.
+func (o *Ollama) post(ctx context.Context, path string, body any) (*http.Response, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/chat", bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+path, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -264,6 +266,31 @@ func (o *Ollama) Send(ctx context.Context, req *Request) (*Response, error) {
 			Err:      err,
 		}
 	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		defer httpResp.Body.Close()
+		respBody, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		provErr := o.handleErrorResponse(httpResp.StatusCode, respBody)
+		provErr.RetryAfter = parseRetryAfter(httpResp.Header.Get("Retry-After"))
+		return nil, provErr
+	}
+
+	return httpResp, nil
+}
+
+// Send makes a completion request to the Ollama Chat API.
+func (o *Ollama) Send(ctx context.Context, req *Request) (*Response, error) {
+	body, err := o.buildRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := o.post(ctx, "/api/chat", body)
+	if err != nil {
+		return nil, err
+	}
 	defer httpResp.Body.Close()
 
 	respBody, err := io.ReadAll(httpResp.Body)
@@ -271,10 +298,6 @@ func (o *Ollama) Send(ctx context.Context, req *Request) (*Response, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		return nil, o.handleErrorResponse(httpResp.StatusCode, respBody)
-	}
-
 	var apiResp ollamaResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
 		return nil, &ProviderError{
@@ -284,34 +307,36 @@ func (o *Ollama) Send(ctx context.Context, req *Request) (*Response, error) {
 		}
 	}
 
+	return ollamaBuildResponse(apiResp.Model, apiResp.Message.Content, apiResp.Message.ToolCalls, apiResp.DoneReason, apiResp.PromptEvalCount, apiResp.EvalCount)
+}
+
+// ollamaBuildResponse assembles a Response from the parts of an Ollama reply.
+// A reply with neither content nor tool calls is reported as a server error.
+func ollamaBuildResponse(model, content string, wireCalls []ollamaToolCallWire, doneReason string, inputTokens, outputTokens int) (*Response, error) {
 	// Parse tool calls from response
 	var toolCalls []ToolCall
-	for i, tc := range apiResp.Message.ToolCalls {
-		args := make(map[string]string)
-		for k, v := range tc.Function.Arguments {
-			args[k] = fmt.Sprintf("%v", v)
-		}
+	for i, tc := range wireCalls {
 		toolCalls = append(toolCalls, ToolCall{
 			ID:        fmt.Sprintf("ollama_%d", i),
 			Name:      tc.Function.Name,
-			Arguments: args,
+			Arguments: rawArguments(tc.Function.Arguments),
 		})
 	}
 
 	// If there are tool calls, return them (content may be empty)
 	if len(toolCalls) > 0 {
 		return &Response{
-			Content:      apiResp.Message.Content,
-			Model:        apiResp.Model,
-			InputTokens:  apiResp.PromptEvalCount,
-			OutputTokens: apiResp.EvalCount,
-			StopReason:   apiResp.DoneReason,
+			Content:      content,
+			Model:        model,
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+			StopReason:   doneReason,
 			ToolCalls:    toolCalls,
 		}, nil
 	}
 
 	// No tool calls: standard text response
-	if apiResp.Message.Content == "" {
+	if content == "" {
 		return nil, &ProviderError{
 			Category: ErrCategoryServer,
 			Message:  "response contains no content",
@@ -319,14 +344,81 @@ func (o *Ollama) Send(ctx context.Context, req *Request) (*Response, error) {
 	}
 
 	return &Response{
-		Content:      apiResp.Message.Content,
-		Model:        apiResp.Model,
-		InputTokens:  apiResp.PromptEvalCount,
-		OutputTokens: apiResp.EvalCount,
-		StopReason:   apiResp.DoneReason,
+		Content:      content,
+		Model:        model,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		StopReason:   doneReason,
 	}, nil
 }
 
+// ollamaStreamChunk is a single NDJSON object from a streaming Ollama response.
+type ollamaStreamChunk struct {
+	ollamaResponse
+	Done  bool   `json:"done"`
+	Error string `json:"error"`
+}
+
+// SendStream makes a streaming request to the Ollama Chat API. The response is
+// newline-delimited JSON; content deltas are passed to fn as they arrive and
+// token counts are taken from the final "done" chunk.
+func (o *Ollama) SendStream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	body, err := o.buildRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := o.post(ctx, "/api/chat", body)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var (
+		model        string
+		content      strings.Builder
+		toolCalls    []ollamaToolCallWire
+		doneReason   string
+		inputTokens  int
+		outputTokens int
+	)
+
+	err = readNDJSON(httpResp.Body, func(line []byte) error {
+		var chunk ollamaStreamChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return &ProviderError{
+				Category: ErrCategoryServer,
+				Message:  fmt.Sprintf("failed to parse stream chunk: %s", err),
+				Err:      err,
+			}
+		}
+
+		if chunk.Error != "" {
+			return &ProviderError{
+				Category: ErrCategoryServer,
+				Message:  chunk.Error,
+			}
+		}
+
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		content.WriteString(chunk.Message.Content)
+		fn.emit(chunk.Message.Content)
+		toolCalls = append(toolCalls, chunk.Message.ToolCalls...)
+		if chunk.Done {
+			doneReason = chunk.DoneReason
+			inputTokens = chunk.PromptEvalCount
+			outputTokens = chunk.EvalCount
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, streamReadError(ctx, err)
+	}
+
+	return ollamaBuildResponse(model, content.String(), toolCalls, doneReason, inputTokens, outputTokens)
+}
+
 // handleErrorResponse maps HTTP error responses to ProviderError.
 func (o *Ollama) handleErrorResponse(status int, body []byte) *ProviderError {
 	message := http.StatusText(status)
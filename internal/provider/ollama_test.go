@@ -532,10 +532,14 @@ func TestOllama_Send_WithTools(t *testing.T) {
 			{
 				Name:        "call_agent",
 				Description: "Delegate a task to a sub-agent.",
-				Parameters: map[string]ToolParameter{
-					"agent": {Type: "string", Description: "Agent name", Required: true},
-					"task":  {Type: "string", Description: "What to do", Required: true},
-				},
+				InputSchema: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"agent": {"type": "string", "description": "Agent name"},
+						"task": {"type": "string", "description": "What to do"}
+					},
+					"required": ["agent", "task"]
+				}`),
 			},
 		},
 	})
@@ -678,7 +682,7 @@ func TestOllama_Send_ToolCallResponse(t *testing.T) {
 		Model:    "llama3",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -701,19 +705,19 @@ func TestOllama_Send_ToolCallResponse(t *testing.T) {
 	if resp.ToolCalls[0].Name != "call_agent" {
 		t.Errorf("expected name 'call_agent', got %q", resp.ToolCalls[0].Name)
 	}
-	if resp.ToolCalls[0].Arguments["agent"] != "test-runner" {
-		t.Errorf("expected agent 'test-runner', got %q", resp.ToolCalls[0].Arguments["agent"])
+	if decodeArgs(t, resp.ToolCalls[0].Arguments)["agent"] != "test-runner" {
+		t.Errorf("expected agent 'test-runner', got %q", decodeArgs(t, resp.ToolCalls[0].Arguments)["agent"])
 	}
-	if resp.ToolCalls[0].Arguments["task"] != "run tests" {
-		t.Errorf("expected task 'run tests', got %q", resp.ToolCalls[0].Arguments["task"])
+	if decodeArgs(t, resp.ToolCalls[0].Arguments)["task"] != "run tests" {
+		t.Errorf("expected task 'run tests', got %q", decodeArgs(t, resp.ToolCalls[0].Arguments)["task"])
 	}
 
 	// Verify second tool call
 	if resp.ToolCalls[1].Name != "call_agent" {
 		t.Errorf("expected name 'call_agent', got %q", resp.ToolCalls[1].Name)
 	}
-	if resp.ToolCalls[1].Arguments["agent"] != "linter" {
-		t.Errorf("expected agent 'linter', got %q", resp.ToolCalls[1].Arguments["agent"])
+	if decodeArgs(t, resp.ToolCalls[1].Arguments)["agent"] != "linter" {
+		t.Errorf("expected agent 'linter', got %q", decodeArgs(t, resp.ToolCalls[1].Arguments)["agent"])
 	}
 }
 
@@ -729,7 +733,7 @@ func TestOllama_Send_NoToolCallsWithTools(t *testing.T) {
 		Model:    "llama3",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -763,7 +767,7 @@ func TestOllama_Send_ToolResultMessage(t *testing.T) {
 			{
 				Role: "assistant",
 				ToolCalls: []ToolCall{
-					{ID: "ollama_0", Name: "call_agent", Arguments: map[string]string{"agent": "helper", "task": "do it"}},
+					{ID: "ollama_0", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper", "task": "do it"}`)},
 				},
 			},
 			{
@@ -829,7 +833,7 @@ func TestOllama_Send_AssistantToolCallMessage(t *testing.T) {
 				Role:    "assistant",
 				Content: "I'll help you",
 				ToolCalls: []ToolCall{
-					{ID: "ollama_0", Name: "call_agent", Arguments: map[string]string{"agent": "helper", "task": "do it"}},
+					{ID: "ollama_0", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper", "task": "do it"}`)},
 				},
 			},
 			{
@@ -886,3 +890,154 @@ func TestOllama_Send_AssistantToolCallMessage(t *testing.T) {
 		t.Errorf("expected task 'do it', got %v", args["task"])
 	}
 }
+
+// --- Streaming ---
+
+func TestOllama_Send_ImageParts(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		json.NewEncoder(w).Encode(ollamaSuccessResponse())
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	_, err := o.Send(context.Background(), &Request{
+		Model: "llava",
+		Messages: []Message{{Role: "user", Content: "Describe this", Parts: []ContentPart{
+			{Type: PartImage, MediaType: "image/png", Name: "shot.png", Data: []byte("png")},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := gotBody["messages"].([]interface{})[0].(map[string]interface{})
+	if msg["content"] != "Describe this" {
+		t.Errorf("content = %v, want %q", msg["content"], "Describe this")
+	}
+	images := msg["images"].([]interface{})
+	if len(images) != 1 || images[0] != "cG5n" {
+		t.Errorf("images = %v, want [cG5n]", images)
+	}
+}
+
+func TestOllama_Send_DocumentPartUnsupported(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	_, err := o.Send(context.Background(), &Request{
+		Model: "llava",
+		Messages: []Message{{Role: "user", Content: "Summarize", Parts: []ContentPart{
+			{Type: PartDocument, MediaType: "application/pdf", Name: "spec.pdf", Data: []byte("pdf")},
+		}}},
+	})
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected ProviderError, got %T: %v", err, err)
+	}
+	if provErr.Category != ErrCategoryBadRequest {
+		t.Errorf("Category = %q, want %q", provErr.Category, ErrCategoryBadRequest)
+	}
+	if !strings.Contains(provErr.Message, "spec.pdf") {
+		t.Errorf("Message = %q, want it to name the file", provErr.Message)
+	}
+	if called {
+		t.Error("request was sent despite the unsupported attachment")
+	}
+}
+
+func TestOllama_SendStream_TextAndUsage(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"model":"llama3","message":{"role":"assistant","content":"Hel"},"done":false}
+{"model":"llama3","message":{"role":"assistant","content":"lo"},"done":false}
+{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":8,"eval_count":2}
+`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	var deltas []string
+	resp, err := o.SendStream(context.Background(), &Request{
+		Model:    "llama3",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, func(text string) { deltas = append(deltas, text) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotBody["stream"] != true {
+		t.Errorf("stream = %v, want true", gotBody["stream"])
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if resp.Content != "Hello" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello")
+	}
+	if resp.InputTokens != 8 || resp.OutputTokens != 2 {
+		t.Errorf("tokens = %d/%d, want 8/2", resp.InputTokens, resp.OutputTokens)
+	}
+	if resp.StopReason != "stop" {
+		t.Errorf("StopReason = %q, want stop", resp.StopReason)
+	}
+}
+
+func TestOllama_SendStream_ToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model":"llama3","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"call_agent","arguments":{"agent":"helper","task":"go"}}}]},"done":false}
+{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":3,"eval_count":4}
+`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	resp, err := o.SendStream(context.Background(), &Request{
+		Model:    "llama3",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
+	}
+	if resp.ToolCalls[0].ID != "ollama_0" || decodeArgs(t, resp.ToolCalls[0].Arguments)["task"] != "go" {
+		t.Errorf("ToolCalls[0] = %+v", resp.ToolCalls[0])
+	}
+}
+
+func TestOllama_SendStream_ErrorLine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model":"llama3","message":{"role":"assistant","content":"Hi"},"done":false}
+{"error":"model crashed"}
+`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	_, err := o.SendStream(context.Background(), &Request{
+		Model:    "llama3",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, nil)
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected *ProviderError, got %T: %v", err, err)
+	}
+	if provErr.Message != "model crashed" {
+		t.Errorf("Message = %q, want %q", provErr.Message, "model crashed")
+	}
+}
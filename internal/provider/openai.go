@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -24,11 +25,22 @@ func WithOpenAIBaseURL(url string) OpenAIOption {
 	}
 }
 
+// WithOpenAIHeaders sets extra HTTP headers sent with every request.
+func WithOpenAIHeaders(headers map[string]string) OpenAIOption {
+	return func(o *OpenAI) {
+		o.headers = headers
+	}
+}
+
 // OpenAI implements the Provider interface for the OpenAI Chat Completions API.
 type OpenAI struct {
 	apiKey  string
 	baseURL string
-	client  *http.Client
+	// apiPrefix is added between baseURL and the endpoint path: "/v1" for
+	// OpenAI, empty for compatible servers, whose base URL includes it.
+	apiPrefix string
+	headers   map[string]string
+	client    *http.Client
 }
 
 // NewOpenAI creates a new OpenAI provider. Returns an error if apiKey is empty.
@@ -37,9 +49,30 @@ func NewOpenAI(apiKey string, opts ...OpenAIOption) (*OpenAI, error) {
 		return nil, fmt.Errorf("API key is required")
 	}
 
+	o := newOpenAI(apiKey, defaultOpenAIBaseURL, opts...)
+	o.apiPrefix = "/v1"
+	return o, nil
+}
+
+// NewOpenAICompatible creates a provider for a server that implements the
+// OpenAI Chat Completions API, such as vLLM, LM Studio, llama.cpp server, Groq,
+// Together, or OpenRouter. baseURL is required and is used as given, with the
+// version path it includes: requests go to baseURL + "/chat/completions".
+// apiKey is optional; when empty no Authorization header is sent.
+func NewOpenAICompatible(baseURL, apiKey string, opts ...OpenAIOption) (*OpenAI, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+
+	return newOpenAI(apiKey, baseURL, opts...), nil
+}
+
+// newOpenAI builds an OpenAI provider and applies opts.
+func newOpenAI(apiKey, baseURL string, opts ...OpenAIOption) *OpenAI {
 	o := &OpenAI{
 		apiKey:  apiKey,
-		baseURL: defaultOpenAIBaseURL,
+		baseURL: baseURL,
 		client: &http.Client{
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse
@@ -51,26 +84,52 @@ func NewOpenAI(apiKey string, opts ...OpenAIOption) (*OpenAI, error) {
 		opt(o)
 	}
 
-	return o, nil
+	return o
 }
 
 // openaiRequest is the JSON body sent to the OpenAI Chat Completions API.
 type openaiRequest struct {
-	Model       string          `json:"model"`
-	Messages    []openaiMessage `json:"messages"`
-	Temperature *float64        `json:"temperature,omitempty"`
-	MaxTokens   *int            `json:"max_tokens,omitempty"`
-	Tools       []openaiToolDef `json:"tools,omitempty"`
+	Model         string               `json:"model"`
+	Messages      []openaiMessage      `json:"messages"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	MaxTokens     *int                 `json:"max_tokens,omitempty"`
+	Tools         []openaiToolDef      `json:"tools,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openaiStreamOptions `json:"stream_options,omitempty"`
+}
+
+// openaiStreamOptions configures a streaming Chat Completions request.
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // openaiMessage is the wire format for a message in the OpenAI API.
 type openaiMessage struct {
 	Role       string               `json:"role"`
-	Content    *string              `json:"content"`                // nullable for assistant tool-call messages
+	Content    interface{}          `json:"content"`                // *string (nullable for assistant tool-call messages) or []openaiContentPart
 	ToolCallID string               `json:"tool_call_id,omitempty"` // for role "tool" messages
 	ToolCalls  []openaiToolCallWire `json:"tool_calls,omitempty"`   // for assistant messages with tool calls
 }
 
+// openaiContentPart is an element of a multi-part message content array.
+type openaiContentPart struct {
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	ImageURL *openaiImageURL  `json:"image_url,omitempty"`
+	File     *openaiFileInput `json:"file,omitempty"`
+}
+
+// openaiImageURL references an image, here always as a base64 data URL.
+type openaiImageURL struct {
+	URL string `json:"url"`
+}
+
+// openaiFileInput carries an inline file such as a PDF.
+type openaiFileInput struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
+}
+
 // openaiToolDef is the wire format for a tool definition in the OpenAI API.
 type openaiToolDef struct {
 	Type     string             `json:"type"`
@@ -79,9 +138,9 @@ type openaiToolDef struct {
 
 // openaiToolFunction is the function definition inside an OpenAI tool.
 type openaiToolFunction struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	Parameters  map[string]interface{} `json:"parameters"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
 }
 
 // openaiToolCallWire is the wire format for a tool call in OpenAI request/response.
@@ -107,10 +166,26 @@ type openaiResponse struct {
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-	} `json:"usage"`
+	Usage openaiUsage `json:"usage"`
+}
+
+// openaiUsage is the token usage reported by the Chat Completions API.
+// prompt_tokens includes any tokens served from the prompt cache.
+type openaiUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// apply copies the usage into resp, counting cached prompt tokens as cache
+// reads rather than input tokens.
+func (u openaiUsage) apply(resp *Response) {
+	cached := u.PromptTokensDetails.CachedTokens
+	resp.InputTokens = u.PromptTokens - cached
+	resp.OutputTokens = u.CompletionTokens
+	resp.CacheReadTokens = cached
 }
 
 // openaiErrorResponse represents an OpenAI API error response.
@@ -140,18 +215,13 @@ func convertToOpenAIMessages(msgs []Message) []openaiMessage {
 			// Assistant message with tool calls
 			var toolCalls []openaiToolCallWire
 			for _, tc := range msg.ToolCalls {
-				// Encode arguments as JSON string
-				argsMap := make(map[string]interface{})
-				for k, v := range tc.Arguments {
-					argsMap[k] = v
-				}
-				argsJSON, _ := json.Marshal(argsMap)
+				// Arguments are sent as a JSON-encoded string
 				toolCalls = append(toolCalls, openaiToolCallWire{
 					ID:   tc.ID,
 					Type: "function",
 					Function: openaiToolCallFunctionWire{
 						Name:      tc.Name,
-						Arguments: string(argsJSON),
+						Arguments: string(objectArguments(tc.Arguments)),
 					},
 				})
 			}
@@ -164,6 +234,29 @@ func convertToOpenAIMessages(msgs []Message) []openaiMessage {
 				Content:   contentPtr,
 				ToolCalls: toolCalls,
 			})
+		} else if len(msg.Parts) > 0 {
+			// Message with attachments: content becomes an array of parts
+			var parts []openaiContentPart
+			if msg.Content != "" {
+				parts = append(parts, openaiContentPart{Type: "text", Text: msg.Content})
+			}
+			for _, part := range msg.Parts {
+				if part.Type == PartImage {
+					parts = append(parts, openaiContentPart{
+						Type:     "image_url",
+						ImageURL: &openaiImageURL{URL: part.dataURL()},
+					})
+				} else {
+					parts = append(parts, openaiContentPart{
+						Type: "file",
+						File: &openaiFileInput{Filename: part.Name, FileData: part.dataURL()},
+					})
+				}
+			}
+			result = append(result, openaiMessage{
+				Role:    msg.Role,
+				Content: parts,
+			})
 		} else {
 			// Standard text message
 			content := msg.Content
@@ -180,40 +273,21 @@ func convertToOpenAIMessages(msgs []Message) []openaiMessage {
 func convertToOpenAITools(tools []Tool) []openaiToolDef {
 	var result []openaiToolDef
 	for _, tool := range tools {
-		properties := make(map[string]interface{})
-		var required []string
-		for name, param := range tool.Parameters {
-			properties[name] = map[string]interface{}{
-				"type":        param.Type,
-				"description": param.Description,
-			}
-			if param.Required {
-				required = append(required, name)
-			}
-		}
-
-		params := map[string]interface{}{
-			"type":       "object",
-			"properties": properties,
-		}
-		if len(required) > 0 {
-			params["required"] = required
-		}
-
 		result = append(result, openaiToolDef{
 			Type: "function",
 			Function: openaiToolFunction{
 				Name:        tool.Name,
 				Description: tool.Description,
-				Parameters:  params,
+				Parameters:  toolSchema(tool),
 			},
 		})
 	}
 	return result
 }
 
-// Send makes a completion request to the OpenAI Chat Completions API.
-func (o *OpenAI) Send(ctx context.Context, req *Request) (*Response, error) {
+// buildRequest converts a provider Request into the OpenAI wire format.
+// The system prompt is sent as a leading "system" message.
+func (o *OpenAI) buildRequest(req *Request) openaiRequest {
 	var messages []Message
 	if req.System != "" {
 		messages = append(messages, Message{Role: "system", Content: req.System})
@@ -239,18 +313,31 @@ func (o *OpenAI) Send(ctx context.Context, req *Request) (*Response, error) {
 		body.Tools = convertToOpenAITools(req.Tools)
 	}
 
+	return body
+}
+
+// post sends body to the OpenAI API endpoint at path, e.g.
+// "/chat/completions". Transport failures and non-2xx responses are
+// returned as ProviderErrors. On success the caller must close the returned
+// response body.
+func (o *OpenAI) post(ctx context.Context, path string, body any) (*http.Response, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/v1/chat/completions", bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+o.apiPrefix+path, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	if o.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range o.headers {
+		httpReq.Header.Set(k, v)
+	}
 
 	httpResp, err := o.client.Do(httpReq)
 	if err != nil {
@@ -267,6 +354,27 @@ func (o *OpenAI) Send(ctx context.Context, req *Request) (*Response, error) {
 			Err:      err,
 		}
 	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		defer httpResp.Body.Close()
+		respBody, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		provErr := o.handleErrorResponse(httpResp.StatusCode, respBody)
+		provErr.RetryAfter = parseRetryAfter(httpResp.Header.Get("Retry-After"))
+		return nil, provErr
+	}
+
+	return httpResp, nil
+}
+
+// Send makes a completion request to the OpenAI Chat Completions API.
+func (o *OpenAI) Send(ctx context.Context, req *Request) (*Response, error) {
+	httpResp, err := o.post(ctx, "/chat/completions", o.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
 	defer httpResp.Body.Close()
 
 	respBody, err := io.ReadAll(httpResp.Body)
@@ -274,10 +382,6 @@ func (o *OpenAI) Send(ctx context.Context, req *Request) (*Response, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
-		return nil, o.handleErrorResponse(httpResp.StatusCode, respBody)
-	}
-
 	var apiResp openaiResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
 		return nil, &ProviderError{
@@ -303,28 +407,156 @@ func (o *OpenAI) Send(ctx context.Context, req *Request) (*Response, error) {
 	// Parse tool calls from response
 	var toolCalls []ToolCall
 	for _, tc := range apiResp.Choices[0].Message.ToolCalls {
-		args := make(map[string]string)
-		var rawArgs map[string]interface{}
-		if err := json.Unmarshal([]byte(tc.Function.Arguments), &rawArgs); err == nil {
-			for k, v := range rawArgs {
-				args[k] = fmt.Sprintf("%v", v)
-			}
-		}
 		toolCalls = append(toolCalls, ToolCall{
 			ID:        tc.ID,
 			Name:      tc.Function.Name,
-			Arguments: args,
+			Arguments: rawArguments([]byte(tc.Function.Arguments)),
+		})
+	}
+
+	resp := &Response{
+		Content:    content,
+		Model:      apiResp.Model,
+		StopReason: apiResp.Choices[0].FinishReason,
+		ToolCalls:  toolCalls,
+	}
+	apiResp.Usage.apply(resp)
+	return resp, nil
+}
+
+// openaiStreamChunk is a single chat.completion.chunk object from a stream.
+type openaiStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   *string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openaiUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// openaiStreamToolCall accumulates one tool call across stream chunks.
+type openaiStreamToolCall struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+// SendStream makes a streaming request to the OpenAI Chat Completions API.
+// Content deltas are passed to fn as they arrive; tool calls are assembled from
+// indexed deltas. Usage is requested via stream_options so token counts are
+// still reported.
+func (o *OpenAI) SendStream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	body := o.buildRequest(req)
+	body.Stream = true
+	body.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
+
+	httpResp, err := o.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	resp := &Response{}
+	var content strings.Builder
+	var calls []*openaiStreamToolCall
+	sawChoice := false
+
+	err = readSSE(httpResp.Body, func(ev sseEvent) error {
+		if ev.Data == "" || ev.Data == "[DONE]" {
+			return nil
+		}
+
+		var chunk openaiStreamChunk
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			return &ProviderError{
+				Category: ErrCategoryServer,
+				Message:  fmt.Sprintf("failed to parse stream chunk: %s", err),
+				Err:      err,
+			}
+		}
+
+		if chunk.Error != nil {
+			return &ProviderError{
+				Category: ErrCategoryServer,
+				Message:  chunk.Error.Message,
+			}
+		}
+
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			chunk.Usage.apply(resp)
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+
+		sawChoice = true
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != nil {
+			content.WriteString(*choice.Delta.Content)
+			fn.emit(*choice.Delta.Content)
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			// Indexes count up from 0, so a new call takes the next one.
+			if tc.Index < 0 || tc.Index > len(calls) {
+				return &ProviderError{
+					Category: ErrCategoryServer,
+					Message:  fmt.Sprintf("stream chunk has tool call index %d, after %d tool calls", tc.Index, len(calls)),
+				}
+			}
+			for len(calls) <= tc.Index {
+				calls = append(calls, &openaiStreamToolCall{})
+			}
+			call := calls[tc.Index]
+			if tc.ID != "" {
+				call.id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				call.name = tc.Function.Name
+			}
+			call.arguments.WriteString(tc.Function.Arguments)
+		}
+		if choice.FinishReason != nil {
+			resp.StopReason = *choice.FinishReason
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, streamReadError(ctx, err)
+	}
+
+	if !sawChoice {
+		return nil, &ProviderError{
+			Category: ErrCategoryServer,
+			Message:  "response contains no choices",
+		}
+	}
+
+	for _, call := range calls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ID:        call.id,
+			Name:      call.name,
+			Arguments: rawArguments([]byte(call.arguments.String())),
 		})
 	}
 
-	return &Response{
-		Content:      content,
-		Model:        apiResp.Model,
-		InputTokens:  apiResp.Usage.PromptTokens,
-		OutputTokens: apiResp.Usage.CompletionTokens,
-		StopReason:   apiResp.Choices[0].FinishReason,
-		ToolCalls:    toolCalls,
-	}, nil
+	resp.Content = content.String()
+	return resp, nil
 }
 
 // handleErrorResponse maps HTTP error responses to ProviderError.
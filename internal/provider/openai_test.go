@@ -492,11 +492,15 @@ func TestOpenAI_Send_WithTools(t *testing.T) {
 			{
 				Name:        "call_agent",
 				Description: "Delegate a task to a sub-agent.",
-				Parameters: map[string]ToolParameter{
-					"agent":   {Type: "string", Description: "Agent name", Required: true},
-					"task":    {Type: "string", Description: "Task description", Required: true},
-					"context": {Type: "string", Description: "Additional context", Required: false},
-				},
+				InputSchema: json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"agent": {"type": "string", "description": "Agent name"},
+						"task": {"type": "string", "description": "Task description"},
+						"context": {"type": "string", "description": "Additional context"}
+					},
+					"required": ["agent", "task"]
+				}`),
 			},
 		},
 	})
@@ -641,10 +645,7 @@ func TestOpenAI_Send_ToolCallResponse(t *testing.T) {
 		Model:    "gpt-4o",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{
-				"agent": {Type: "string", Description: "agent", Required: true},
-				"task":  {Type: "string", Description: "task", Required: true},
-			}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {"agent": {"type": "string"}, "task": {"type": "string"}}, "required": ["agent", "task"]}`)},
 		},
 	})
 	if err != nil {
@@ -660,11 +661,72 @@ func TestOpenAI_Send_ToolCallResponse(t *testing.T) {
 	if resp.ToolCalls[0].Name != "call_agent" {
 		t.Errorf("ToolCalls[0].Name = %q, want %q", resp.ToolCalls[0].Name, "call_agent")
 	}
-	if resp.ToolCalls[0].Arguments["agent"] != "helper" {
-		t.Errorf("ToolCalls[0].Arguments[agent] = %q, want %q", resp.ToolCalls[0].Arguments["agent"], "helper")
+	if decodeArgs(t, resp.ToolCalls[0].Arguments)["agent"] != "helper" {
+		t.Errorf("ToolCalls[0].Arguments[agent] = %q, want %q", decodeArgs(t, resp.ToolCalls[0].Arguments)["agent"], "helper")
+	}
+	if decodeArgs(t, resp.ToolCalls[0].Arguments)["task"] != "run tests" {
+		t.Errorf("ToolCalls[0].Arguments[task] = %q, want %q", decodeArgs(t, resp.ToolCalls[0].Arguments)["task"], "run tests")
+	}
+}
+
+func TestOpenAI_Send_StructuredToolArguments(t *testing.T) {
+	const args = `{"paths":["a.go","b.go"],"limit":3,"options":{"recursive":true}}`
+
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = nil
+		json.Unmarshal(body, &gotBody)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": "gpt-4o",
+			"choices": []map[string]interface{}{{
+				"message": map[string]interface{}{
+					"role": "assistant",
+					"tool_calls": []map[string]interface{}{{
+						"id":       "call_1",
+						"type":     "function",
+						"function": map[string]string{"name": "list_files", "arguments": args},
+					}},
+				},
+				"finish_reason": "tool_calls",
+			}},
+			"usage": map[string]int{"prompt_tokens": 1, "completion_tokens": 1},
+		})
+	}))
+	defer server.Close()
+
+	o, _ := NewOpenAI("key", WithOpenAIBaseURL(server.URL))
+	resp, err := o.Send(context.Background(), &Request{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
 	}
-	if resp.ToolCalls[0].Arguments["task"] != "run tests" {
-		t.Errorf("ToolCalls[0].Arguments[task] = %q, want %q", resp.ToolCalls[0].Arguments["task"], "run tests")
+	if string(resp.ToolCalls[0].Arguments) != args {
+		t.Errorf("Arguments = %s, want %s", resp.ToolCalls[0].Arguments, args)
+	}
+
+	// Replaying the call must send the same arguments back unchanged.
+	_, err = o.Send(context.Background(), &Request{
+		Model: "gpt-4o",
+		Messages: []Message{
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", ToolCalls: resp.ToolCalls},
+			{Role: "tool", ToolResults: []ToolResult{{CallID: "call_1", Content: "ok"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	messages := gotBody["messages"].([]interface{})
+	call := messages[1].(map[string]interface{})["tool_calls"].([]interface{})[0].(map[string]interface{})
+	if got := call["function"].(map[string]interface{})["arguments"]; got != args {
+		t.Errorf("replayed arguments = %v, want %s", got, args)
 	}
 }
 
@@ -700,7 +762,7 @@ func TestOpenAI_Send_ToolCallNullContent(t *testing.T) {
 		Model:    "gpt-4o",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -747,7 +809,7 @@ func TestOpenAI_Send_InvalidToolCallArguments(t *testing.T) {
 		Model:    "gpt-4o",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -760,8 +822,9 @@ func TestOpenAI_Send_InvalidToolCallArguments(t *testing.T) {
 	if resp.ToolCalls[0].Name != "call_agent" {
 		t.Errorf("ToolCalls[0].Name = %q, want %q", resp.ToolCalls[0].Name, "call_agent")
 	}
-	if len(resp.ToolCalls[0].Arguments) != 0 {
-		t.Errorf("ToolCalls[0].Arguments = %v, want empty map", resp.ToolCalls[0].Arguments)
+	// Malformed arguments are passed through so the tool layer can report them.
+	if string(resp.ToolCalls[0].Arguments) != "not valid json" {
+		t.Errorf("ToolCalls[0].Arguments = %s, want raw %q", resp.ToolCalls[0].Arguments, "not valid json")
 	}
 }
 
@@ -797,7 +860,7 @@ func TestOpenAI_Send_ToolsStopReason(t *testing.T) {
 		Model:    "gpt-4o",
 		Messages: []Message{{Role: "user", Content: "Hi"}},
 		Tools: []Tool{
-			{Name: "call_agent", Description: "test", Parameters: map[string]ToolParameter{}},
+			{Name: "call_agent", Description: "test", InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`)},
 		},
 	})
 	if err != nil {
@@ -834,7 +897,7 @@ func TestOpenAI_Send_ToolResultMessage(t *testing.T) {
 		Messages: []Message{
 			{Role: "user", Content: "Hi"},
 			{Role: "assistant", Content: "", ToolCalls: []ToolCall{
-				{ID: "call_1", Name: "call_agent", Arguments: map[string]string{"agent": "helper"}},
+				{ID: "call_1", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper"}`)},
 			}},
 			{Role: "tool", ToolResults: []ToolResult{
 				{CallID: "call_1", Content: "result text", IsError: false},
@@ -890,7 +953,7 @@ func TestOpenAI_Send_AssistantToolCallMessage(t *testing.T) {
 		Messages: []Message{
 			{Role: "user", Content: "Hi"},
 			{Role: "assistant", Content: "I'll help", ToolCalls: []ToolCall{
-				{ID: "call_1", Name: "call_agent", Arguments: map[string]string{"agent": "helper", "task": "work"}},
+				{ID: "call_1", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper", "task": "work"}`)},
 			}},
 			{Role: "tool", ToolResults: []ToolResult{
 				{CallID: "call_1", Content: "done", IsError: false},
@@ -963,3 +1026,299 @@ func TestOpenAI_Send_AssistantToolCallMessage(t *testing.T) {
 		t.Error("no assistant message with tool_calls found in request")
 	}
 }
+
+// --- Streaming ---
+
+func TestOpenAI_Send_AttachmentParts(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+		w.Write([]byte(`{"model": "gpt-4o", "choices": [{"message": {"content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOpenAI("key", WithOpenAIBaseURL(server.URL))
+	_, err := o.Send(context.Background(), &Request{
+		Model: "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Describe these", Parts: []ContentPart{
+			{Type: PartImage, MediaType: "image/png", Name: "shot.png", Data: []byte("png")},
+			{Type: PartDocument, MediaType: "application/pdf", Name: "spec.pdf", Data: []byte("pdf")},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := gotBody["messages"].([]interface{})[0].(map[string]interface{})
+	parts := msg["content"].([]interface{})
+	if len(parts) != 3 {
+		t.Fatalf("content parts = %d, want 3", len(parts))
+	}
+	if text := parts[0].(map[string]interface{}); text["type"] != "text" || text["text"] != "Describe these" {
+		t.Errorf("parts[0] = %v, want text part", text)
+	}
+
+	image := parts[1].(map[string]interface{})
+	if image["type"] != "image_url" {
+		t.Errorf("parts[1].type = %v, want %q", image["type"], "image_url")
+	}
+	if url := image["image_url"].(map[string]interface{})["url"]; url != "data:image/png;base64,cG5n" {
+		t.Errorf("image_url.url = %v, want data URL", url)
+	}
+
+	file := parts[2].(map[string]interface{})
+	if file["type"] != "file" {
+		t.Errorf("parts[2].type = %v, want %q", file["type"], "file")
+	}
+	fileInput := file["file"].(map[string]interface{})
+	if fileInput["filename"] != "spec.pdf" || fileInput["file_data"] != "data:application/pdf;base64,cGRm" {
+		t.Errorf("file = %v, want spec.pdf data URL", fileInput)
+	}
+}
+
+func TestOpenAI_Send_CachedPromptTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"model": "gpt-4o",
+			"choices": [{"message": {"content": "ok"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 2000, "completion_tokens": 5, "prompt_tokens_details": {"cached_tokens": 1536}}
+		}`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOpenAI("key", WithOpenAIBaseURL(server.URL))
+	resp, err := o.Send(context.Background(), &Request{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Cached tokens are reported separately and not double counted as input.
+	if resp.InputTokens != 464 || resp.CacheReadTokens != 1536 {
+		t.Errorf("tokens = %d input, %d cache read; want 464, 1536", resp.InputTokens, resp.CacheReadTokens)
+	}
+	if resp.CacheCreationTokens != 0 {
+		t.Errorf("CacheCreationTokens = %d, want 0", resp.CacheCreationTokens)
+	}
+}
+
+func TestOpenAI_SendStream_TextAndUsage(t *testing.T) {
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotBody)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"content":" there"},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: {"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":4}}
+
+data: [DONE]
+
+`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOpenAI("key", WithOpenAIBaseURL(server.URL))
+	var deltas []string
+	resp, err := o.SendStream(context.Background(), &Request{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, func(text string) { deltas = append(deltas, text) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotBody["stream"] != true {
+		t.Errorf("stream = %v, want true", gotBody["stream"])
+	}
+	streamOpts, _ := gotBody["stream_options"].(map[string]interface{})
+	if streamOpts["include_usage"] != true {
+		t.Errorf("stream_options.include_usage = %v, want true", streamOpts["include_usage"])
+	}
+	if strings.Join(deltas, "|") != "Hello| there" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if resp.Content != "Hello there" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello there")
+	}
+	if resp.InputTokens != 20 || resp.OutputTokens != 4 {
+		t.Errorf("tokens = %d/%d, want 20/4", resp.InputTokens, resp.OutputTokens)
+	}
+	if resp.StopReason != "stop" {
+		t.Errorf("StopReason = %q, want stop", resp.StopReason)
+	}
+}
+
+func TestOpenAI_SendStream_ToolCallDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"call_agent","arguments":""}}]},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"agent\":\"hel"}}]},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"call_agent","arguments":"{\"agent\":\"runner\"}"}}]},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"per\"}"}}]},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: [DONE]
+
+`))
+	}))
+	defer server.Close()
+
+	o, _ := NewOpenAI("key", WithOpenAIBaseURL(server.URL))
+	resp, err := o.SendStream(context.Background(), &Request{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.ToolCalls) != 2 {
+		t.Fatalf("len(ToolCalls) = %d, want 2", len(resp.ToolCalls))
+	}
+	if resp.ToolCalls[0].ID != "call_a" || decodeArgs(t, resp.ToolCalls[0].Arguments)["agent"] != "helper" {
+		t.Errorf("ToolCalls[0] = %+v", resp.ToolCalls[0])
+	}
+	if resp.ToolCalls[1].ID != "call_b" || decodeArgs(t, resp.ToolCalls[1].Arguments)["agent"] != "runner" {
+		t.Errorf("ToolCalls[1] = %+v", resp.ToolCalls[1])
+	}
+	if resp.StopReason != "tool_calls" {
+		t.Errorf("StopReason = %q, want tool_calls", resp.StopReason)
+	}
+}
+
+func TestOpenAI_SendStream_InvalidToolCallIndex(t *testing.T) {
+	for _, index := range []string{"-1", "1", "1000000000"} {
+		t.Run(index, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(`data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":` + index + `,"id":"call_a","type":"function","function":{"name":"call_agent","arguments":""}}]},"finish_reason":null}]}
+
+data: [DONE]
+
+`))
+			}))
+			defer server.Close()
+
+			o, _ := NewOpenAI("key", WithOpenAIBaseURL(server.URL))
+			_, err := o.SendStream(context.Background(), &Request{
+				Model:    "gpt-4o",
+				Messages: []Message{{Role: "user", Content: "Hi"}},
+			}, nil)
+
+			var provErr *ProviderError
+			if !errors.As(err, &provErr) {
+				t.Fatalf("expected *ProviderError, got %T: %v", err, err)
+			}
+			if provErr.Category != ErrCategoryServer || !strings.Contains(provErr.Message, "tool call index "+index) {
+				t.Errorf("error = %+v", provErr)
+			}
+		})
+	}
+}
+
+func TestOpenAI_SendStream_NoChoices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	o, _ := NewOpenAI("key", WithOpenAIBaseURL(server.URL))
+	_, err := o.SendStream(context.Background(), &Request{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+	}, nil)
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected *ProviderError, got %T: %v", err, err)
+	}
+	if provErr.Category != ErrCategoryServer {
+		t.Errorf("Category = %q, want %q", provErr.Category, ErrCategoryServer)
+	}
+}
+
+func TestOpenAICompatible_Send_HeadersAndOptionalKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiKey   string
+		wantAuth string
+	}{
+		{"with key", "gsk-test", "Bearer gsk-test"},
+		{"without key", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotAuth, gotTitle string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotAuth = r.Header.Get("Authorization")
+				gotTitle = r.Header.Get("X-Title")
+				w.Write([]byte(`{"model":"llama-3-70b","choices":[{"message":{"content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+			}))
+			defer server.Close()
+
+			o, err := NewOpenAICompatible(server.URL+"/v1/", tt.apiKey, WithOpenAIHeaders(map[string]string{"X-Title": "axe"}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := o.Send(context.Background(), &Request{Model: "llama-3-70b", Messages: []Message{{Role: "user", Content: "Hi"}}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Content != "hi" {
+				t.Errorf("Content = %q, want hi", resp.Content)
+			}
+			if gotPath != "/v1/chat/completions" {
+				t.Errorf("path = %q, want /v1/chat/completions", gotPath)
+			}
+			if gotAuth != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", gotAuth, tt.wantAuth)
+			}
+			if gotTitle != "axe" {
+				t.Errorf("X-Title = %q, want axe", gotTitle)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatible_Send_BaseURLUsedAsGiven(t *testing.T) {
+	for _, prefix := range []string{"/v1beta/openai", "/api/v2", ""} {
+		var gotPath string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath = r.URL.Path
+			w.Write([]byte(`{"model":"m","choices":[{"message":{"content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+		}))
+
+		o, _ := NewOpenAICompatible(server.URL+prefix, "")
+		if _, err := o.Send(context.Background(), &Request{Model: "m", Messages: []Message{{Role: "user", Content: "Hi"}}}); err != nil {
+			t.Errorf("%s: unexpected error: %v", prefix, err)
+		}
+		if gotPath != prefix+"/chat/completions" {
+			t.Errorf("path = %q, want %s/chat/completions", gotPath, prefix)
+		}
+		server.Close()
+	}
+}
+
+func TestNewOpenAICompatible_EmptyBaseURL(t *testing.T) {
+	_, err := NewOpenAICompatible("", "key")
+	if err == nil {
+		t.Fatal("expected error for empty base URL")
+	}
+}
@@ -1,8 +1,12 @@
 package provider
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // ErrorCategory classifies provider errors for exit code mapping.
@@ -23,25 +27,55 @@ const (
 	ErrCategoryServer ErrorCategory = "server"
 )
 
-// ToolParameter describes a single parameter of a tool definition.
-type ToolParameter struct {
-	Type        string
-	Description string
-	Required    bool
-}
-
 // Tool represents a tool definition sent to the LLM.
 type Tool struct {
 	Name        string
 	Description string
-	Parameters  map[string]ToolParameter
+	// InputSchema is a JSON Schema describing the tool's arguments. It must
+	// describe an object. A nil schema is sent as an empty object schema.
+	InputSchema json.RawMessage
 }
 
 // ToolCall represents a tool invocation requested by the LLM.
 type ToolCall struct {
-	ID        string
-	Name      string
-	Arguments map[string]string
+	ID   string
+	Name string
+	// Arguments is the JSON arguments object as produced by the model. It is
+	// kept verbatim, so it may be malformed if the model emitted invalid JSON.
+	Arguments json.RawMessage
+	// Signature is an opaque token some providers attach to a call (Gemini's
+	// thoughtSignature) and require back when the call is replayed.
+	Signature string
+}
+
+// toolSchema returns the tool's input schema, or an empty object schema if
+// none is set.
+func toolSchema(t Tool) json.RawMessage {
+	if len(bytes.TrimSpace(t.InputSchema)) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return t.InputSchema
+}
+
+// rawArguments converts tool arguments received from a provider into
+// ToolCall.Arguments. Empty input becomes "{}"; anything else is copied
+// verbatim so malformed JSON can be reported back to the model.
+func rawArguments(raw []byte) json.RawMessage {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return json.RawMessage("{}")
+	}
+	return append(json.RawMessage(nil), raw...)
+}
+
+// objectArguments returns args if it is a valid JSON object and "{}"
+// otherwise. It is used when replaying tool calls to a provider, so malformed
+// arguments from an earlier turn cannot make the whole request invalid.
+func objectArguments(args json.RawMessage) json.RawMessage {
+	trimmed := bytes.TrimSpace(args)
+	if len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		return trimmed
+	}
+	return json.RawMessage("{}")
 }
 
 // ToolResult represents the result of a tool execution.
@@ -51,12 +85,35 @@ type ToolResult struct {
 	IsError bool
 }
 
+// ContentPart types.
+const (
+	// PartImage is an image such as a PNG, JPEG, GIF or WebP file.
+	PartImage = "image"
+	// PartDocument is a document such as a PDF file.
+	PartDocument = "document"
+)
+
+// ContentPart is a binary attachment sent with a message, such as an image or
+// a PDF. Each provider encodes parts in its own wire format.
+type ContentPart struct {
+	Type      string // PartImage or PartDocument
+	MediaType string // MIME type, e.g. "image/png" or "application/pdf"
+	Name      string // File name, used by providers that label documents
+	Data      []byte
+}
+
+// dataURL returns the part encoded as a base64 data URL.
+func (p ContentPart) dataURL() string {
+	return "data:" + p.MediaType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
 // Message represents a single message in the conversation.
 type Message struct {
-	Role        string       `json:"role"`
-	Content     string       `json:"content"`
-	ToolCalls   []ToolCall   // Tool calls in an assistant message (non-nil when LLM called tools)
-	ToolResults []ToolResult // Tool results in a tool-result message (non-nil when role is "tool")
+	Role        string        `json:"role"`
+	Content     string        `json:"content"`
+	Parts       []ContentPart // Attachments sent after Content in a user message
+	ToolCalls   []ToolCall    // Tool calls in an assistant message (non-nil when LLM called tools)
+	ToolResults []ToolResult  // Tool results in a tool-result message (non-nil when role is "tool")
 }
 
 // Request represents an LLM completion request.
@@ -77,6 +134,13 @@ type Response struct {
 	OutputTokens int
 	StopReason   string
 	ToolCalls    []ToolCall // Tool calls requested by the LLM. Empty if no tools called.
+	ModelRef     string     // "provider/model" that answered. Set by Fallback; empty otherwise.
+
+	// CacheCreationTokens and CacheReadTokens count input tokens written to
+	// and read from the provider's prompt cache. They are zero for providers
+	// that do not report caching. InputTokens excludes cache reads.
+	CacheCreationTokens int
+	CacheReadTokens     int
 }
 
 // Provider defines the interface for LLM providers.
@@ -84,12 +148,48 @@ type Provider interface {
 	Send(ctx context.Context, req *Request) (*Response, error)
 }
 
+// StreamFunc receives incremental response text as it arrives from a provider.
+type StreamFunc func(text string)
+
+// emit calls fn with text, ignoring empty deltas and a nil fn.
+func (fn StreamFunc) emit(text string) {
+	if fn != nil && text != "" {
+		fn(text)
+	}
+}
+
+// Streamer is implemented by providers that can stream a response as it is
+// generated. fn may be nil. The returned Response carries the same fields as Send, including
+// token counts and any tool calls assembled from streamed deltas.
+type Streamer interface {
+	SendStream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error)
+}
+
+// Stream sends req through p, calling fn with text deltas as they arrive.
+// If p does not implement Streamer, Send is used and fn receives the full
+// content once. A nil fn is allowed.
+func Stream(ctx context.Context, p Provider, req *Request, fn StreamFunc) (*Response, error) {
+	if s, ok := p.(Streamer); ok {
+		return s.SendStream(ctx, req, fn)
+	}
+
+	resp, err := p.Send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	fn.emit(resp.Content)
+	return resp, nil
+}
+
 // ProviderError wraps provider-specific errors with categorization.
 type ProviderError struct {
 	Category ErrorCategory
 	Status   int
 	Message  string
 	Err      error
+	// RetryAfter is the delay requested by the provider's Retry-After header,
+	// or zero if none was sent.
+	RetryAfter time.Duration
 }
 
 // Error returns a formatted error message: "<category>: <message>".
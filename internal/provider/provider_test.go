@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"testing"
@@ -77,8 +78,8 @@ func TestTool_ZeroValue(t *testing.T) {
 	if tool.Description != "" {
 		t.Errorf("Tool.Description = %q, want empty", tool.Description)
 	}
-	if tool.Parameters != nil {
-		t.Errorf("Tool.Parameters = %v, want nil", tool.Parameters)
+	if tool.InputSchema != nil {
+		t.Errorf("Tool.InputSchema = %s, want nil", tool.InputSchema)
 	}
 }
 
@@ -148,7 +149,7 @@ func TestMessage_ToolCallsField(t *testing.T) {
 		Role:    "assistant",
 		Content: "I'll call a tool",
 		ToolCalls: []ToolCall{
-			{ID: "tc_1", Name: "call_agent", Arguments: map[string]string{"agent": "helper", "task": "do stuff"}},
+			{ID: "tc_1", Name: "call_agent", Arguments: json.RawMessage(`{"agent": "helper", "task": "do stuff"}`)},
 		},
 	}
 	if len(msg.ToolCalls) != 1 {
@@ -160,8 +161,8 @@ func TestMessage_ToolCallsField(t *testing.T) {
 	if msg.ToolCalls[0].Name != "call_agent" {
 		t.Errorf("ToolCalls[0].Name = %q, want %q", msg.ToolCalls[0].Name, "call_agent")
 	}
-	if msg.ToolCalls[0].Arguments["agent"] != "helper" {
-		t.Errorf("ToolCalls[0].Arguments[agent] = %q, want %q", msg.ToolCalls[0].Arguments["agent"], "helper")
+	if decodeArgs(t, msg.ToolCalls[0].Arguments)["agent"] != "helper" {
+		t.Errorf("ToolCalls[0].Arguments[agent] = %q, want %q", decodeArgs(t, msg.ToolCalls[0].Arguments)["agent"], "helper")
 	}
 }
 
@@ -186,3 +187,54 @@ func TestMessage_ToolResultsField(t *testing.T) {
 		t.Error("ToolResults[1].IsError = false, want true")
 	}
 }
+
+// --- Streaming ---
+
+func TestStream_FallsBackToSend(t *testing.T) {
+	p := &staticProvider{resp: &Response{Content: "full text", InputTokens: 3}}
+
+	var deltas []string
+	resp, err := Stream(context.Background(), p, &Request{}, func(text string) {
+		deltas = append(deltas, text)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deltas) != 1 || deltas[0] != "full text" {
+		t.Errorf("deltas = %q, want single full-content delta", deltas)
+	}
+	if resp.InputTokens != 3 {
+		t.Errorf("InputTokens = %d, want 3", resp.InputTokens)
+	}
+}
+
+func TestStream_NilFunc(t *testing.T) {
+	p := &staticProvider{resp: &Response{Content: "ok"}}
+
+	resp, err := Stream(context.Background(), p, &Request{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("Content = %q, want %q", resp.Content, "ok")
+	}
+}
+
+// decodeArgs unmarshals tool call arguments into a map for assertions.
+func decodeArgs(t *testing.T, raw json.RawMessage) map[string]interface{} {
+	t.Helper()
+	var args map[string]interface{}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		t.Fatalf("tool call arguments %s are not a JSON object: %v", raw, err)
+	}
+	return args
+}
+
+// staticProvider returns a fixed response from Send and does not stream.
+type staticProvider struct {
+	resp *Response
+}
+
+func (s *staticProvider) Send(ctx context.Context, req *Request) (*Response, error) {
+	return s.resp, nil
+}
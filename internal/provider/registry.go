@@ -7,6 +7,36 @@ var supportedProviders = map[string]bool{
 	"anthropic": true,
 	"openai":    true,
 	"ollama":    true,
+	"gemini":    true,
+}
+
+// TypeOpenAICompatible is the provider type for custom providers that speak the
+// OpenAI Chat Completions API.
+const TypeOpenAICompatible = "openai-compatible"
+
+// Option configures how New constructs a provider.
+type Option func(*newOptions)
+
+type newOptions struct {
+	providerType string
+	headers      map[string]string
+}
+
+// WithType sets the provider type from config.toml. A non-empty type takes
+// precedence over dispatching on the provider name, which lets any name map
+// to an OpenAI-compatible server.
+func WithType(providerType string) Option {
+	return func(o *newOptions) {
+		o.providerType = providerType
+	}
+}
+
+// WithHeaders sets extra HTTP headers for providers that support them
+// (currently openai and openai-compatible).
+func WithHeaders(headers map[string]string) Option {
+	return func(o *newOptions) {
+		o.headers = headers
+	}
 }
 
 // Supported reports whether providerName is a known provider.
@@ -14,8 +44,24 @@ func Supported(providerName string) bool {
 	return supportedProviders[providerName]
 }
 
-// New creates a Provider by dispatching to the correct constructor based on providerName.
-func New(providerName, apiKey, baseURL string) (Provider, error) {
+// New creates a Provider by dispatching to the correct constructor based on
+// providerName, or on the provider type when one is set via WithType.
+func New(providerName, apiKey, baseURL string, options ...Option) (Provider, error) {
+	var o newOptions
+	for _, opt := range options {
+		opt(&o)
+	}
+
+	if o.providerType != "" {
+		if o.providerType != TypeOpenAICompatible {
+			return nil, fmt.Errorf("unsupported type %q for provider %q: supported types are %s", o.providerType, providerName, TypeOpenAICompatible)
+		}
+		if baseURL == "" {
+			return nil, fmt.Errorf("provider %q of type %s requires base_url", providerName, TypeOpenAICompatible)
+		}
+		return NewOpenAICompatible(baseURL, apiKey, WithOpenAIHeaders(o.headers))
+	}
+
 	switch providerName {
 	case "anthropic":
 		var opts []AnthropicOption
@@ -25,7 +71,7 @@ func New(providerName, apiKey, baseURL string) (Provider, error) {
 		return NewAnthropic(apiKey, opts...)
 
 	case "openai":
-		var opts []OpenAIOption
+		opts := []OpenAIOption{WithOpenAIHeaders(o.headers)}
 		if baseURL != "" {
 			opts = append(opts, WithOpenAIBaseURL(baseURL))
 		}
@@ -38,7 +84,14 @@ func New(providerName, apiKey, baseURL string) (Provider, error) {
 		}
 		return NewOllama(opts...)
 
+	case "gemini":
+		var opts []GeminiOption
+		if baseURL != "" {
+			opts = append(opts, WithGeminiBaseURL(baseURL))
+		}
+		return NewGemini(apiKey, opts...)
+
 	default:
-		return nil, fmt.Errorf("unsupported provider %q: supported providers are anthropic, openai, ollama", providerName)
+		return nil, fmt.Errorf("unsupported provider %q: supported providers are anthropic, openai, ollama, gemini", providerName)
 	}
 }
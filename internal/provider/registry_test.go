@@ -35,6 +35,23 @@ func TestNew_Ollama(t *testing.T) {
 	}
 }
 
+func TestNew_Gemini(t *testing.T) {
+	p, err := New("gemini", "test-key", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := p.(*Gemini); !ok {
+		t.Fatalf("expected *Gemini, got %T", p)
+	}
+}
+
+func TestNew_GeminiEmptyAPIKey(t *testing.T) {
+	_, err := New("gemini", "", "")
+	if err == nil {
+		t.Fatal("expected error for empty API key")
+	}
+}
+
 func TestNew_OllamaIgnoresAPIKey(t *testing.T) {
 	_, err := New("ollama", "ignored-key", "")
 	if err != nil {
@@ -57,7 +74,7 @@ func TestNew_UnsupportedProvider(t *testing.T) {
 	if !strings.Contains(err.Error(), `unsupported provider "groq"`) {
 		t.Errorf("expected error to mention 'unsupported provider \"groq\"', got %q", err.Error())
 	}
-	if !strings.Contains(err.Error(), "anthropic, openai, ollama") {
+	if !strings.Contains(err.Error(), "anthropic, openai, ollama, gemini") {
 		t.Errorf("expected error to list supported providers, got %q", err.Error())
 	}
 }
@@ -118,3 +135,37 @@ func TestSupported_UnknownProvider(t *testing.T) {
 		t.Error("expected 'Anthropic' (mixed case) to not be supported")
 	}
 }
+
+func TestNew_OpenAICompatible(t *testing.T) {
+	p, err := New("groq", "", "https://api.groq.com/openai/v1", WithType(TypeOpenAICompatible))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o, ok := p.(*OpenAI)
+	if !ok {
+		t.Fatalf("expected *OpenAI, got %T", p)
+	}
+	if o.baseURL != "https://api.groq.com/openai/v1" || o.apiPrefix != "" {
+		t.Errorf("baseURL = %q, prefix %q, want the base URL as given", o.baseURL, o.apiPrefix)
+	}
+}
+
+func TestNew_OpenAICompatibleRequiresBaseURL(t *testing.T) {
+	_, err := New("groq", "key", "", WithType(TypeOpenAICompatible))
+	if err == nil {
+		t.Fatal("expected error for missing base_url")
+	}
+	if !strings.Contains(err.Error(), "requires base_url") {
+		t.Errorf("expected error to mention base_url, got %q", err.Error())
+	}
+}
+
+func TestNew_UnsupportedType(t *testing.T) {
+	_, err := New("groq", "key", "http://x", WithType("anthropic-compatible"))
+	if err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+	if !strings.Contains(err.Error(), `unsupported type "anthropic-compatible"`) {
+		t.Errorf("unexpected error: %q", err.Error())
+	}
+}
@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how Retry re-sends requests that fail with a retryable error.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first.
+	// Values below 1 are treated as 1 (no retries).
+	MaxAttempts int
+	// InitialBackoff is the base delay before the first retry. It doubles on
+	// each subsequent retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay before a retry: the computed exponential one
+	// and a Retry-After value sent by the provider alike.
+	MaxBackoff time.Duration
+}
+
+// RetryNotifyFunc is called before each retry with the attempt number about to
+// be made, the configured maximum, the error that triggered the retry, and the
+// delay before the next attempt.
+type RetryNotifyFunc func(attempt, maxAttempts int, err error, wait time.Duration)
+
+// RetryOption is a functional option for configuring a Retry provider.
+type RetryOption func(*Retry)
+
+// WithRetryNotify sets a callback that is invoked before each retry.
+func WithRetryNotify(fn RetryNotifyFunc) RetryOption {
+	return func(r *Retry) {
+		r.notify = fn
+	}
+}
+
+// Retry is a Provider decorator that re-sends requests failing with a
+// retryable ProviderError, using jittered exponential backoff.
+type Retry struct {
+	next   Provider
+	policy RetryPolicy
+	notify RetryNotifyFunc
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+}
+
+// NewRetry wraps next with retry behavior described by policy.
+func NewRetry(next Provider, policy RetryPolicy, opts ...RetryOption) *Retry {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	r := &Retry{
+		next:   next,
+		policy: policy,
+		sleep:  sleepContext,
+		jitter: rand.Float64,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Send sends req through the wrapped provider, retrying retryable failures.
+func (r *Retry) Send(ctx context.Context, req *Request) (*Response, error) {
+	return r.do(ctx, func() (*Response, error) {
+		return r.next.Send(ctx, req)
+	})
+}
+
+// SendStream streams req through the wrapped provider. A failed attempt is
+// only retried if no text has been passed to fn yet, since delivered output
+// cannot be taken back.
+func (r *Retry) SendStream(ctx context.Context, req *Request, fn StreamFunc) (*Response, error) {
+	emitted := false
+	tracked := func(text string) {
+		emitted = true
+		fn.emit(text)
+	}
+
+	return r.do(ctx, func() (*Response, error) {
+		resp, err := Stream(ctx, r.next, req, tracked)
+		if err != nil && emitted {
+			return nil, &permanentError{err: err}
+		}
+		return resp, err
+	})
+}
+
+// do runs attempt until it succeeds, fails with a non-retryable error, or the
+// attempt budget is exhausted. The last error is returned.
+func (r *Retry) do(ctx context.Context, attempt func() (*Response, error)) (*Response, error) {
+	for n := 1; ; n++ {
+		resp, err := attempt()
+		if err == nil {
+			return resp, nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return nil, perm.err
+		}
+
+		if n >= r.policy.MaxAttempts || !IsRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := r.backoff(n, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return nil, err
+		}
+
+		if r.notify != nil {
+			r.notify(n+1, r.policy.MaxAttempts, err, wait)
+		}
+
+		if sleepErr := r.sleep(ctx, wait); sleepErr != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before retry number n (1-based). A Retry-After
+// value on the error takes precedence over the computed exponential delay,
+// but is capped at MaxBackoff too, so a server cannot stall a run.
+func (r *Retry) backoff(n int, err error) time.Duration {
+	var provErr *ProviderError
+	if errors.As(err, &provErr) && provErr.RetryAfter > 0 {
+		if r.policy.MaxBackoff > 0 && provErr.RetryAfter > r.policy.MaxBackoff {
+			return r.policy.MaxBackoff
+		}
+		return provErr.RetryAfter
+	}
+
+	base := r.policy.InitialBackoff
+	for i := 1; i < n && (r.policy.MaxBackoff <= 0 || base < r.policy.MaxBackoff); i++ {
+		base *= 2
+	}
+	if r.policy.MaxBackoff > 0 && base > r.policy.MaxBackoff {
+		base = r.policy.MaxBackoff
+	}
+
+	// Equal jitter: half the delay is fixed, half is random.
+	half := base / 2
+	return half + time.Duration(r.jitter()*float64(base-half))
+}
+
+// permanentError marks an error that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// IsRetryable reports whether err is a ProviderError whose category indicates
+// a transient failure: rate limiting, overload, server error, or timeout.
+func IsRetryable(err error) bool {
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		return false
+	}
+	switch provErr.Category {
+	case ErrCategoryRateLimit, ErrCategoryOverloaded, ErrCategoryServer, ErrCategoryTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header value given either as a number
+// of seconds or as an HTTP date. It returns 0 if the value is empty or invalid.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,298 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// scriptedProvider returns the queued errors in order, then succeeds.
+type scriptedProvider struct {
+	errs  []error
+	calls int
+}
+
+func (s *scriptedProvider) Send(ctx context.Context, req *Request) (*Response, error) {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return nil, s.errs[s.calls-1]
+	}
+	return &Response{Content: "ok"}, nil
+}
+
+// newTestRetry builds a Retry that records waits instead of sleeping.
+func newTestRetry(next Provider, maxAttempts int, waits *[]time.Duration, opts ...RetryOption) *Retry {
+	r := NewRetry(next, RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}, opts...)
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	r.jitter = func() float64 { return 1 }
+	return r
+}
+
+func TestRetry_RetriesRetryableThenSucceeds(t *testing.T) {
+	next := &scriptedProvider{errs: []error{
+		&ProviderError{Category: ErrCategoryOverloaded, Status: 529, Message: "Overloaded"},
+		&ProviderError{Category: ErrCategoryServer, Status: 500, Message: "boom"},
+	}}
+	var waits []time.Duration
+	r := newTestRetry(next, 3, &waits)
+
+	resp, err := r.Send(context.Background(), &Request{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "ok" {
+		t.Errorf("Content = %q, want ok", resp.Content)
+	}
+	if next.calls != 3 {
+		t.Errorf("calls = %d, want 3", next.calls)
+	}
+	// jitter=1 yields the full exponential delay: 100ms, then 200ms.
+	if len(waits) != 2 || waits[0] != 100*time.Millisecond || waits[1] != 200*time.Millisecond {
+		t.Errorf("waits = %v, want [100ms 200ms]", waits)
+	}
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	last := &ProviderError{Category: ErrCategoryRateLimit, Status: 429, Message: "slow down"}
+	next := &scriptedProvider{errs: []error{
+		&ProviderError{Category: ErrCategoryRateLimit, Status: 429, Message: "first"},
+		last,
+		&ProviderError{Category: ErrCategoryRateLimit, Status: 429, Message: "never reached"},
+	}}
+	var waits []time.Duration
+	r := newTestRetry(next, 2, &waits)
+
+	_, err := r.Send(context.Background(), &Request{})
+	if err != last {
+		t.Fatalf("err = %v, want last attempt's error", err)
+	}
+	if next.calls != 2 {
+		t.Errorf("calls = %d, want 2", next.calls)
+	}
+}
+
+func TestRetry_DoesNotRetryNonRetryable(t *testing.T) {
+	for _, cat := range []ErrorCategory{ErrCategoryAuth, ErrCategoryBadRequest} {
+		next := &scriptedProvider{errs: []error{&ProviderError{Category: cat, Message: "no"}}}
+		var waits []time.Duration
+		r := newTestRetry(next, 5, &waits)
+
+		if _, err := r.Send(context.Background(), &Request{}); err == nil {
+			t.Fatalf("%s: expected error, got nil", cat)
+		}
+		if next.calls != 1 {
+			t.Errorf("%s: calls = %d, want 1", cat, next.calls)
+		}
+	}
+}
+
+func TestRetry_DoesNotRetryPlainErrors(t *testing.T) {
+	next := &scriptedProvider{errs: []error{errors.New("marshal failure")}}
+	var waits []time.Duration
+	r := newTestRetry(next, 3, &waits)
+
+	if _, err := r.Send(context.Background(), &Request{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if next.calls != 1 {
+		t.Errorf("calls = %d, want 1", next.calls)
+	}
+}
+
+func TestRetry_HonorsRetryAfter(t *testing.T) {
+	next := &scriptedProvider{errs: []error{
+		&ProviderError{Category: ErrCategoryRateLimit, Status: 429, RetryAfter: 5 * time.Second},
+	}}
+	var waits []time.Duration
+	r := newTestRetry(next, 2, &waits)
+
+	if _, err := r.Send(context.Background(), &Request{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Retry-After exceeds MaxBackoff (1s), which caps it.
+	if len(waits) != 1 || waits[0] != time.Second {
+		t.Errorf("waits = %v, want [1s]", waits)
+	}
+
+	next = &scriptedProvider{errs: []error{
+		&ProviderError{Category: ErrCategoryRateLimit, Status: 429, RetryAfter: 700 * time.Millisecond},
+	}}
+	waits = nil
+	r = newTestRetry(next, 2, &waits)
+	if _, err := r.Send(context.Background(), &Request{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(waits) != 1 || waits[0] != 700*time.Millisecond {
+		t.Errorf("waits = %v, want [700ms] from Retry-After", waits)
+	}
+}
+
+func TestRetry_BackoffCappedAndJittered(t *testing.T) {
+	r := NewRetry(nil, RetryPolicy{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond})
+	r.jitter = func() float64 { return 0 }
+
+	err := &ProviderError{Category: ErrCategoryServer}
+	if got := r.backoff(1, err); got != 50*time.Millisecond {
+		t.Errorf("backoff(1) with zero jitter = %v, want 50ms", got)
+	}
+	if got := r.backoff(6, err); got != 150*time.Millisecond {
+		t.Errorf("backoff(6) with zero jitter = %v, want 150ms (half of capped 300ms)", got)
+	}
+}
+
+func TestRetry_NotifyCalledPerRetry(t *testing.T) {
+	next := &scriptedProvider{errs: []error{
+		&ProviderError{Category: ErrCategoryOverloaded},
+		&ProviderError{Category: ErrCategoryOverloaded},
+	}}
+	var attempts []int
+	var waits []time.Duration
+	r := newTestRetry(next, 3, &waits, WithRetryNotify(func(attempt, maxAttempts int, err error, wait time.Duration) {
+		if maxAttempts != 3 {
+			t.Errorf("maxAttempts = %d, want 3", maxAttempts)
+		}
+		attempts = append(attempts, attempt)
+	}))
+
+	if _, err := r.Send(context.Background(), &Request{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(attempts) != 2 || attempts[0] != 2 || attempts[1] != 3 {
+		t.Errorf("notified attempts = %v, want [2 3]", attempts)
+	}
+}
+
+func TestRetry_StopsWhenDeadlineTooClose(t *testing.T) {
+	next := &scriptedProvider{errs: []error{
+		&ProviderError{Category: ErrCategoryRateLimit, RetryAfter: time.Hour},
+	}}
+	var waits []time.Duration
+	r := newTestRetry(next, 3, &waits)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := r.Send(ctx, &Request{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if next.calls != 1 || len(waits) != 0 {
+		t.Errorf("calls = %d, waits = %v; want no retry past the deadline", next.calls, waits)
+	}
+}
+
+func TestRetry_SendStream_NoRetryAfterOutput(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte("{\"model\":\"llama3\",\"message\":{\"content\":\"partial\"},\"done\":false}\n{\"error\":\"crashed\"}\n"))
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	var waits []time.Duration
+	r := newTestRetry(o, 3, &waits)
+
+	var out string
+	_, err := r.SendStream(context.Background(), &Request{Model: "llama3"}, func(text string) { out += text })
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1 (output already delivered)", calls)
+	}
+	if out != "partial" {
+		t.Errorf("out = %q, want %q", out, "partial")
+	}
+}
+
+func TestRetry_SendStream_RetriesBeforeOutput(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(503)
+			w.Write([]byte(`{"error":"loading model"}`))
+			return
+		}
+		w.Write([]byte("{\"model\":\"llama3\",\"message\":{\"content\":\"hi\"},\"done\":true}\n"))
+	}))
+	defer server.Close()
+
+	o, _ := NewOllama(WithOllamaBaseURL(server.URL))
+	var waits []time.Duration
+	r := newTestRetry(o, 3, &waits)
+
+	resp, err := r.SendStream(context.Background(), &Request{Model: "llama3"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "hi" || calls != 2 {
+		t.Errorf("Content = %q, calls = %d; want hi after 2 calls", resp.Content, calls)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&ProviderError{Category: ErrCategoryRateLimit}, true},
+		{&ProviderError{Category: ErrCategoryOverloaded}, true},
+		{&ProviderError{Category: ErrCategoryServer}, true},
+		{&ProviderError{Category: ErrCategoryTimeout}, true},
+		{&ProviderError{Category: ErrCategoryAuth}, false},
+		{&ProviderError{Category: ErrCategoryBadRequest}, false},
+		{errors.New("plain"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Errorf("parseRetryAfter(7) = %v, want 7s", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("parseRetryAfter(empty) = %v, want 0", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Errorf("parseRetryAfter(soon) = %v, want 0", got)
+	}
+	date := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 20*time.Second || got > 30*time.Second {
+		t.Errorf("parseRetryAfter(date) = %v, want ~30s", got)
+	}
+}
+
+func TestAnthropic_Send_RetryAfterHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		w.WriteHeader(429)
+		w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`))
+	}))
+	defer server.Close()
+
+	a, _ := NewAnthropic("key", WithBaseURL(server.URL))
+	_, err := a.Send(context.Background(), &Request{Model: "m", Messages: []Message{{Role: "user", Content: "Hi"}}})
+
+	var provErr *ProviderError
+	if !errors.As(err, &provErr) {
+		t.Fatalf("expected *ProviderError, got %T: %v", err, err)
+	}
+	if provErr.RetryAfter != 12*time.Second {
+		t.Errorf("RetryAfter = %v, want 12s", provErr.RetryAfter)
+	}
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sseEvent is a single server-sent event read from a streaming response.
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// readSSE reads server-sent events from r and calls fn for each complete event.
+// Multiple data lines within one event are joined with newlines, and comment
+// lines (starting with ":") are ignored. Reading stops at EOF or as soon as fn
+// returns an error, which is then returned to the caller.
+func readSSE(r io.Reader, fn func(sseEvent) error) error {
+	br := bufio.NewReader(r)
+
+	var ev sseEvent
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 && ev.Event == "" {
+			return nil
+		}
+		ev.Data = strings.Join(data, "\n")
+		err := fn(ev)
+		ev = sseEvent{}
+		data = nil
+		return err
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		switch {
+		case trimmed == "":
+			if dErr := dispatch(); dErr != nil {
+				return dErr
+			}
+		case strings.HasPrefix(trimmed, ":"):
+			// Comment / keep-alive line.
+		default:
+			field, value, _ := strings.Cut(trimmed, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				ev.Event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+
+		if err == io.EOF {
+			return dispatch()
+		}
+	}
+}
+
+// readNDJSON reads newline-delimited JSON values from r and calls fn with each
+// non-empty line. Reading stops at EOF or as soon as fn returns an error.
+func readNDJSON(r io.Reader, fn func(line []byte) error) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+			if fnErr := fn([]byte(trimmed)); fnErr != nil {
+				return fnErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// streamReadError converts an error encountered while reading a streaming
+// response body into a ProviderError. Errors caused by context cancellation or
+// deadline are reported as timeouts.
+func streamReadError(ctx context.Context, err error) error {
+	var provErr *ProviderError
+	if errors.As(err, &provErr) {
+		return err
+	}
+	if ctx.Err() != nil {
+		return &ProviderError{
+			Category: ErrCategoryTimeout,
+			Message:  ctx.Err().Error(),
+			Err:      ctx.Err(),
+		}
+	}
+	return &ProviderError{
+		Category: ErrCategoryServer,
+		Message:  "failed to read stream: " + err.Error(),
+		Err:      err,
+	}
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE_Events(t *testing.T) {
+	input := "event: message_start\ndata: {\"a\":1}\n\n: keep-alive\n\ndata: line one\ndata: line two\n\n"
+
+	var got []sseEvent
+	err := readSSE(strings.NewReader(input), func(ev sseEvent) error {
+		got = append(got, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("len(events) = %d, want 2", len(got))
+	}
+	if got[0].Event != "message_start" || got[0].Data != `{"a":1}` {
+		t.Errorf("events[0] = %+v, want message_start with {\"a\":1}", got[0])
+	}
+	if got[1].Event != "" || got[1].Data != "line one\nline two" {
+		t.Errorf("events[1] = %+v, want joined data lines", got[1])
+	}
+}
+
+func TestReadSSE_CRLFAndNoTrailingBlankLine(t *testing.T) {
+	input := "data: first\r\n\r\ndata: last"
+
+	var data []string
+	err := readSSE(strings.NewReader(input), func(ev sseEvent) error {
+		data = append(data, ev.Data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data) != 2 || data[0] != "first" || data[1] != "last" {
+		t.Errorf("data = %q, want [first last]", data)
+	}
+}
+
+func TestReadSSE_CallbackErrorStops(t *testing.T) {
+	input := "data: one\n\ndata: two\n\n"
+	stop := errors.New("stop")
+
+	calls := 0
+	err := readSSE(strings.NewReader(input), func(ev sseEvent) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("err = %v, want stop", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestReadNDJSON_SkipsBlankLines(t *testing.T) {
+	input := "{\"n\":1}\n\n{\"n\":2}"
+
+	var lines []string
+	err := readNDJSON(strings.NewReader(input), func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 || lines[0] != `{"n":1}` || lines[1] != `{"n":2}` {
+		t.Errorf("lines = %q, want two JSON lines", lines)
+	}
+}
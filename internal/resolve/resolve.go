@@ -1,12 +1,17 @@
 package resolve
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/jrswab/axe/internal/provider"
 )
 
 // Workdir resolves the working directory using a priority chain:
@@ -24,30 +29,84 @@ func Workdir(flagValue, tomlValue string) string {
 	return "."
 }
 
-// FileContent holds a matched file's relative path and its text content.
+// MaxAttachmentSize is the largest image or PDF, in bytes, that Files will
+// return as an attachment. Larger files are skipped. Providers may accept
+// less; Anthropic, for one, rejects images over 5 MB.
+const MaxAttachmentSize = 20 << 20
+
+// ErrBinaryFile is returned by ReadFile for a binary file that is neither
+// an image nor a PDF.
+var ErrBinaryFile = errors.New("binary file detected")
+
+// attachmentTypes are the sniffed media types sent to providers as
+// attachments instead of being inlined as text.
+var attachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// FileContent holds a matched file's relative path and its content. Text files
+// have Content set. Images and PDFs are attachments: MediaType and Data are set
+// and Content is empty.
 type FileContent struct {
-	Path    string
-	Content string
+	Path      string
+	Content   string
+	MediaType string
+	Data      []byte
+}
+
+// IsAttachment reports whether f is a binary attachment rather than text.
+func (f FileContent) IsAttachment() bool {
+	return f.MediaType != ""
+}
+
+// Parts converts the attachments in files to provider content parts, in
+// order. Text files are skipped; they belong in the system prompt.
+func Parts(files []FileContent) []provider.ContentPart {
+	var parts []provider.ContentPart
+	for _, f := range files {
+		if !f.IsAttachment() {
+			continue
+		}
+		partType := provider.PartImage
+		if !strings.HasPrefix(f.MediaType, "image/") {
+			partType = provider.PartDocument
+		}
+		parts = append(parts, provider.ContentPart{
+			Type:      partType,
+			MediaType: f.MediaType,
+			Name:      f.Path,
+			Data:      f.Data,
+		})
+	}
+	return parts
 }
 
 // Files resolves file glob patterns relative to workdir and returns their contents.
 // It supports simple globs (via filepath.Glob) and ** patterns (via filepath.WalkDir).
-// Binary files, symlinks pointing outside workdir, and duplicates are skipped.
-func Files(patterns []string, workdir string) ([]FileContent, error) {
+// Images and PDFs are returned as attachments. Other binary files, symlinks
+// pointing outside workdir, and duplicates are skipped. Matched files that
+// cannot be read, including attachments over MaxAttachmentSize, are skipped
+// too; skipped holds one error naming each of them, for the caller to warn
+// about.
+func Files(patterns []string, workdir string) (files []FileContent, skipped []error, err error) {
 	if len(patterns) == 0 {
-		return []FileContent{}, nil
+		return []FileContent{}, nil, nil
 	}
 
 	absWorkdir, err := filepath.Abs(workdir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve workdir: %w", err)
+		return nil, nil, fmt.Errorf("failed to resolve workdir: %w", err)
 	}
 
 	// Resolve symlinks in workdir so containment checks work when the
 	// workdir path itself traverses symlinks (e.g. /tmp -> /private/tmp on macOS).
 	absWorkdir, err = filepath.EvalSymlinks(absWorkdir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve workdir symlinks: %w", err)
+		return nil, nil, fmt.Errorf("failed to resolve workdir symlinks: %w", err)
 	}
 
 	seen := make(map[string]bool)
@@ -64,7 +123,7 @@ func Files(patterns []string, workdir string) ([]FileContent, error) {
 		}
 
 		if matchErr != nil {
-			return nil, matchErr
+			return nil, nil, matchErr
 		}
 
 		for _, absPath := range matches {
@@ -87,17 +146,18 @@ func Files(patterns []string, workdir string) ([]FileContent, error) {
 				continue
 			}
 
-			content, err := readTextFile(absPath)
+			fc, err := ReadFile(absPath)
 			if err != nil {
-				// Skip files that can't be read
+				seen[relPath] = true
+				if !errors.Is(err, ErrBinaryFile) {
+					skipped = append(skipped, err)
+				}
 				continue
 			}
 
 			seen[relPath] = true
-			results = append(results, FileContent{
-				Path:    filepath.ToSlash(relPath),
-				Content: content,
-			})
+			fc.Path = filepath.ToSlash(relPath)
+			results = append(results, fc)
 		}
 	}
 
@@ -105,7 +165,7 @@ func Files(patterns []string, workdir string) ([]FileContent, error) {
 		return results[i].Path < results[j].Path
 	})
 
-	return results, nil
+	return results, skipped, nil
 }
 
 // simpleGlob resolves a single-level glob pattern relative to workdir.
@@ -224,39 +284,125 @@ func isSymlinkOutside(path, absWorkdir string) bool {
 		return true
 	}
 
-	// Check if target is within workdir
-	return !strings.HasPrefix(absTarget, absWorkdir+string(filepath.Separator)) && absTarget != absWorkdir
+	return !within(absTarget, absWorkdir)
 }
 
-// readTextFile reads a file and returns its content, or an error if it's binary.
-// Binary detection: if any null byte exists in the first 512 bytes, it's binary.
+// within reports whether absPath is absWorkdir or inside it.
+func within(absPath, absWorkdir string) bool {
+	return absPath == absWorkdir || strings.HasPrefix(absPath, absWorkdir+string(filepath.Separator))
+}
+
+// ErrOutsideWorkdir is returned by Path for a path that escapes the working
+// directory.
+var ErrOutsideWorkdir = errors.New("path is outside the working directory")
+
+// Path resolves name, relative to workdir unless absolute, and returns its
+// absolute path with symlinks resolved. It applies the same containment rules
+// as Files: a path that traverses out of workdir, or passes through a symlink
+// that resolves outside it, returns an error wrapping ErrOutsideWorkdir. name
+// need not exist, so Path can check a file before it is created.
+func Path(workdir, name string) (string, error) {
+	absWorkdir, err := filepath.Abs(workdir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workdir: %w", err)
+	}
+	absWorkdir, err = filepath.EvalSymlinks(absWorkdir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workdir symlinks: %w", err)
+	}
+
+	target := filepath.Clean(name)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(absWorkdir, target)
+	}
+	if !within(target, absWorkdir) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkdir, name)
+	}
+
+	// Resolve symlinks in the longest existing prefix of the path; the rest
+	// does not exist yet and so cannot be a symlink.
+	existing, rest := target, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkdir, name)
+	}
+	if !within(resolved, absWorkdir) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideWorkdir, name)
+	}
+
+	return filepath.Join(resolved, rest), nil
+}
+
+// ReadFile reads a file as text or, for images and PDFs, as an attachment.
+// The media type is sniffed from the first 512 bytes. Any other file with a
+// null byte in those bytes is binary and returns an error wrapping
+// ErrBinaryFile. An attachment larger than MaxAttachmentSize also returns
+// an error.
 // Only the header is read initially to avoid loading large binary files into memory.
-func readTextFile(path string) (string, error) {
+func ReadFile(path string) (FileContent, error) {
+	return ReadFilePrefix(path, -1)
+}
+
+// ReadFilePrefix is like ReadFile, but reads at most limit bytes of a text
+// file, so a large file is never loaded whole. A negative limit reads all
+// of it. The prefix may end partway through a UTF-8 character.
+func ReadFilePrefix(path string, limit int64) (FileContent, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return FileContent{}, err
 	}
 	defer f.Close()
 
-	// Read only the first 512 bytes for binary detection.
+	// Read only the first 512 bytes for type and binary detection.
 	header := make([]byte, 512)
-	n, err := f.Read(header)
-	if err != nil && err != io.EOF {
-		return "", err
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return FileContent{}, err
 	}
-	for i := 0; i < n; i++ {
-		if header[i] == 0 {
-			return "", fmt.Errorf("binary file detected: %s", path)
+	header = header[:n]
+
+	if mediaType := http.DetectContentType(header); attachmentTypes[mediaType] {
+		rest, err := io.ReadAll(io.LimitReader(f, MaxAttachmentSize-int64(n)+1))
+		if err != nil {
+			return FileContent{}, err
 		}
+		data := append(header, rest...)
+		if len(data) > MaxAttachmentSize {
+			return FileContent{}, fmt.Errorf("attachment exceeds %d bytes: %s", MaxAttachmentSize, path)
+		}
+		return FileContent{MediaType: mediaType, Data: data}, nil
+	}
+
+	if bytes.IndexByte(header, 0) >= 0 {
+		return FileContent{}, fmt.Errorf("%w: %s", ErrBinaryFile, path)
 	}
 
 	// File is text; read the remainder and combine with the header.
-	rest, err := io.ReadAll(f)
+	var r io.Reader = f
+	if limit >= 0 {
+		if int64(len(header)) > limit {
+			header = header[:limit]
+		}
+		r = io.LimitReader(f, limit-int64(len(header)))
+	}
+	rest, err := io.ReadAll(r)
 	if err != nil {
-		return "", err
+		return FileContent{}, err
 	}
 
-	return string(header[:n]) + string(rest), nil
+	return FileContent{Content: string(header) + string(rest)}, nil
 }
 
 // Stdin reads stdin content if it is piped (not a terminal).
@@ -282,6 +428,7 @@ func Stdin() (string, error) {
 
 // BuildSystemPrompt assembles a single system prompt string from non-empty sections.
 // Sections are: system prompt (as-is), skill (with delimiter), files (with delimiter and code blocks).
+// Attachments are not inlined; they are sent with the user message instead.
 func BuildSystemPrompt(systemPrompt, skillContent string, files []FileContent) string {
 	var b strings.Builder
 
@@ -294,9 +441,16 @@ func BuildSystemPrompt(systemPrompt, skillContent string, files []FileContent) s
 		b.WriteString(skillContent)
 	}
 
-	if len(files) > 0 {
+	var textFiles []FileContent
+	for _, f := range files {
+		if !f.IsAttachment() {
+			textFiles = append(textFiles, f)
+		}
+	}
+
+	if len(textFiles) > 0 {
 		b.WriteString("\n\n---\n\n## Context Files\n\n")
-		for i, f := range files {
+		for i, f := range textFiles {
 			if i > 0 {
 				b.WriteString("\n\n")
 			}
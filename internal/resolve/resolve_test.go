@@ -2,12 +2,15 @@ package resolve
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 	"testing"
+
+	"github.com/jrswab/axe/internal/provider"
 )
 
 // --- Workdir Tests ---
@@ -41,7 +44,7 @@ func TestWorkdir_CWDFallback(t *testing.T) {
 
 func TestFiles_EmptyPatterns(t *testing.T) {
 	dir := t.TempDir()
-	result, err := Files(nil, dir)
+	result, _, err := Files(nil, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -49,7 +52,7 @@ func TestFiles_EmptyPatterns(t *testing.T) {
 		t.Errorf("expected empty slice, got %d items", len(result))
 	}
 
-	result, err = Files([]string{}, dir)
+	result, _, err = Files([]string{}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -65,7 +68,7 @@ func TestFiles_SimpleGlob(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "world.txt"), []byte("world content"), 0644)
 	os.WriteFile(filepath.Join(dir, "readme.md"), []byte("markdown"), 0644)
 
-	result, err := Files([]string{"*.txt"}, dir)
+	result, _, err := Files([]string{"*.txt"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -94,7 +97,7 @@ func TestFiles_DoubleStarGlob(t *testing.T) {
 	os.WriteFile(filepath.Join(sub, "deep.go"), []byte("package deep"), 0644)
 	os.WriteFile(filepath.Join(sub, "deep.txt"), []byte("not go"), 0644)
 
-	result, err := Files([]string{"**/*.go"}, dir)
+	result, _, err := Files([]string{"**/*.go"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -117,7 +120,7 @@ func TestFiles_DoubleStarGlob(t *testing.T) {
 
 func TestFiles_InvalidPattern(t *testing.T) {
 	dir := t.TempDir()
-	_, err := Files([]string{"["}, dir)
+	_, _, err := Files([]string{"["}, dir)
 	if err == nil {
 		t.Error("expected error for invalid pattern, got nil")
 	}
@@ -128,7 +131,7 @@ func TestFiles_InvalidDoubleStarPattern(t *testing.T) {
 	// Create a file so the directory isn't empty
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0644)
 
-	_, err := Files([]string{"**/["}, dir)
+	_, _, err := Files([]string{"**/["}, dir)
 	if err == nil {
 		t.Fatal("expected error for invalid ** pattern '**/[', got nil")
 	}
@@ -139,7 +142,7 @@ func TestFiles_InvalidDoubleStarPattern(t *testing.T) {
 
 func TestFiles_NoMatches(t *testing.T) {
 	dir := t.TempDir()
-	result, err := Files([]string{"*.xyz"}, dir)
+	result, _, err := Files([]string{"*.xyz"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -152,7 +155,7 @@ func TestFiles_Deduplication(t *testing.T) {
 	dir := t.TempDir()
 	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("content"), 0644)
 
-	result, err := Files([]string{"*.txt", "file.txt"}, dir)
+	result, _, err := Files([]string{"*.txt", "file.txt"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -167,7 +170,7 @@ func TestFiles_SortedOutput(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "alpha.txt"), []byte("a"), 0644)
 	os.WriteFile(filepath.Join(dir, "bravo.txt"), []byte("b"), 0644)
 
-	result, err := Files([]string{"*.txt"}, dir)
+	result, _, err := Files([]string{"*.txt"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -187,7 +190,7 @@ func TestFiles_SkipsBinaryFiles(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "binary.dat"), binaryContent, 0644)
 	os.WriteFile(filepath.Join(dir, "text.dat"), []byte("hello world"), 0644)
 
-	result, err := Files([]string{"*.dat"}, dir)
+	result, _, err := Files([]string{"*.dat"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -199,6 +202,78 @@ func TestFiles_SkipsBinaryFiles(t *testing.T) {
 	}
 }
 
+// pngHeader is the PNG signature followed by the start of an IHDR chunk,
+// which contains null bytes.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestFiles_ImagesAndPDFsAreAttachments(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "diagram.png"), pngHeader, 0644)
+	os.WriteFile(filepath.Join(dir, "spec.pdf"), []byte("%PDF-1.7\nbody"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644)
+
+	result, _, err := Files([]string{"*"}, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(result))
+	}
+
+	png, txt, pdf := result[0], result[1], result[2]
+	if !png.IsAttachment() || png.MediaType != "image/png" || !bytes.Equal(png.Data, pngHeader) {
+		t.Errorf("diagram.png = %+v, want image/png attachment", png)
+	}
+	if png.Content != "" {
+		t.Errorf("attachment Content = %q, want empty", png.Content)
+	}
+	if txt.IsAttachment() || txt.Content != "hello" {
+		t.Errorf("notes.txt = %+v, want text file", txt)
+	}
+	if !pdf.IsAttachment() || pdf.MediaType != "application/pdf" {
+		t.Errorf("spec.pdf = %+v, want application/pdf attachment", pdf)
+	}
+}
+
+func TestFiles_ReportsSkippedFiles(t *testing.T) {
+	dir := t.TempDir()
+	big := append(append([]byte(nil), pngHeader...), make([]byte, MaxAttachmentSize)...)
+	os.WriteFile(filepath.Join(dir, "huge.png"), big, 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(dir, "blob.dat"), []byte("a\x00b"), 0644)
+
+	result, skipped, err := Files([]string{"*"}, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 1 || result[0].Path != "notes.txt" {
+		t.Errorf("result = %+v, want only notes.txt", result)
+	}
+	// Binary files are skipped quietly; the oversized image is reported
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Error(), "attachment exceeds") || !strings.Contains(skipped[0].Error(), "huge.png") {
+		t.Errorf("skipped = %v, want huge.png", skipped)
+	}
+}
+
+func TestParts(t *testing.T) {
+	files := []FileContent{
+		{Path: "a.go", Content: "package a"},
+		{Path: "b.png", MediaType: "image/png", Data: []byte("img")},
+		{Path: "c.pdf", MediaType: "application/pdf", Data: []byte("doc")},
+	}
+
+	parts := Parts(files)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	if parts[0].Type != provider.PartImage || parts[0].Name != "b.png" || string(parts[0].Data) != "img" {
+		t.Errorf("parts[0] = %+v, want image b.png", parts[0])
+	}
+	if parts[1].Type != provider.PartDocument || parts[1].MediaType != "application/pdf" {
+		t.Errorf("parts[1] = %+v, want application/pdf document", parts[1])
+	}
+}
+
 func TestFiles_SymlinkOutsideWorkdir(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("symlink tests unreliable on Windows")
@@ -212,7 +287,7 @@ func TestFiles_SymlinkOutsideWorkdir(t *testing.T) {
 	os.Symlink(outsideFile, filepath.Join(dir, "link.txt"))
 	os.WriteFile(filepath.Join(dir, "local.txt"), []byte("local"), 0644)
 
-	result, err := Files([]string{"*.txt"}, dir)
+	result, _, err := Files([]string{"*.txt"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -235,7 +310,7 @@ func TestFiles_PathTraversalBlocked(t *testing.T) {
 	// Create a file inside the workdir
 	os.WriteFile(filepath.Join(subDir, "local.txt"), []byte("local data"), 0644)
 
-	result, err := Files([]string{"../*"}, subDir)
+	result, _, err := Files([]string{"../*"}, subDir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -263,7 +338,7 @@ func TestFiles_SymlinkInsideWorkdir(t *testing.T) {
 	os.WriteFile(filepath.Join(dir, "real.txt"), []byte("real content"), 0644)
 	os.Symlink(filepath.Join(dir, "real.txt"), filepath.Join(dir, "link.txt"))
 
-	result, err := Files([]string{"*.txt"}, dir)
+	result, _, err := Files([]string{"*.txt"}, dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -421,3 +496,93 @@ func TestBuildSystemPrompt_FilesOnly(t *testing.T) {
 		t.Errorf("expected readme.md formatted with fenced code block, got %q", result)
 	}
 }
+
+func TestBuildSystemPrompt_SkipsAttachments(t *testing.T) {
+	files := []FileContent{
+		{Path: "diagram.png", MediaType: "image/png", Data: []byte("img")},
+	}
+	result := BuildSystemPrompt("Be helpful.", "", files)
+	if result != "Be helpful." {
+		t.Errorf("expected attachments to be left out of the system prompt, got %q", result)
+	}
+}
+
+func TestPath_InsideWorkdir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0644)
+
+	realDir, _ := filepath.EvalSymlinks(dir)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"src/main.go", filepath.Join(realDir, "src", "main.go")},
+		{"./src/../src/main.go", filepath.Join(realDir, "src", "main.go")},
+		{".", realDir},
+		{"new/dir/file.txt", filepath.Join(realDir, "new", "dir", "file.txt")},
+		{filepath.Join(realDir, "src"), filepath.Join(realDir, "src")},
+	}
+	for _, tt := range tests {
+		got, err := Path(dir, tt.name)
+		if err != nil {
+			t.Errorf("Path(%q): unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Path(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPath_OutsideWorkdir(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "project")
+	os.MkdirAll(dir, 0755)
+
+	for _, name := range []string{"..", "../secret.txt", "src/../../secret.txt", "/etc/passwd"} {
+		if _, err := Path(dir, name); !errors.Is(err, ErrOutsideWorkdir) {
+			t.Errorf("Path(%q): err = %v, want ErrOutsideWorkdir", name, err)
+		}
+	}
+}
+
+func TestPath_SymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink tests unreliable on Windows")
+	}
+	dir := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt"))
+	os.Symlink(outside, filepath.Join(dir, "linkdir"))
+	os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "dangling"))
+
+	// Reading through a symlink, or creating a file below a symlinked
+	// directory, must not escape the workdir.
+	for _, name := range []string{"link.txt", "linkdir/secret.txt", "linkdir/new.txt", "dangling"} {
+		if _, err := Path(dir, name); !errors.Is(err, ErrOutsideWorkdir) {
+			t.Errorf("Path(%q): err = %v, want ErrOutsideWorkdir", name, err)
+		}
+	}
+}
+
+func TestPath_SymlinkInsideWorkdir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink tests unreliable on Windows")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "real.txt"), []byte("real"), 0644)
+	os.Symlink(filepath.Join(dir, "real.txt"), filepath.Join(dir, "link.txt"))
+
+	got, err := Path(dir, "link.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	realDir, _ := filepath.EvalSymlinks(dir)
+	if want := filepath.Join(realDir, "real.txt"); got != want {
+		t.Errorf("Path = %q, want %q", got, want)
+	}
+}
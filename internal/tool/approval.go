@@ -0,0 +1,258 @@
+package tool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// Decision is the outcome of asking whether a tool call may run.
+type Decision struct {
+	Approved bool
+	// Reason explains a denial to the model.
+	Reason string
+	// Arguments replaces the call's arguments when they were edited before
+	// approval. Nil keeps the model's arguments.
+	Arguments json.RawMessage
+}
+
+// Approver decides whether an agent may make a tool call that requires
+// approval. Implementations must be safe for concurrent use, since
+// sub-agents can run in parallel.
+type Approver interface {
+	Approve(ctx context.Context, agentName string, call provider.ToolCall) Decision
+}
+
+// Prompter is an Approver that shows each tool call to a person and asks
+// whether to run it, deny it or edit its arguments first. Questions are
+// asked one at a time. If the input ends or the context is done before an
+// answer, the call is denied.
+type Prompter struct {
+	mu  sync.Mutex
+	in  *bufio.Reader
+	out io.Writer
+
+	start   sync.Once
+	lines   chan string // Answers read by the reader goroutine
+	readErr error       // Why lines was closed
+	// abandoned is set when a question was left unanswered, so a late
+	// answer to it is not taken as the answer to the next one.
+	abandoned bool
+}
+
+// NewPrompter returns a Prompter that reads answers from in and writes
+// questions to out, normally both the terminal.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Approve asks whether agentName may make call.
+func (p *Prompter) Approve(ctx context.Context, agentName string, call provider.ToolCall) Decision {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dropLateAnswers()
+
+	args := call.Arguments
+	edited := false
+	for {
+		fmt.Fprintf(p.out, "\n[approve] agent %q wants to call %s with:\n%s\n", agentName, call.Name, indentArguments(args))
+		fmt.Fprint(p.out, "Run it? [y]es, [n]o, [e]dit arguments: ")
+		answer, err := p.readLine(ctx)
+		if err != nil {
+			return p.unanswered(ctx)
+		}
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			d := Decision{Approved: true}
+			if edited {
+				d.Arguments = args
+			}
+			return d
+		case "n", "no":
+			fmt.Fprint(p.out, "Reason (optional): ")
+			reason, _ := p.readLine(ctx)
+			if reason == "" {
+				reason = "the user declined to run it"
+			}
+			return Decision{Reason: reason}
+		case "e", "edit":
+			fmt.Fprint(p.out, "New arguments (a JSON object on one line): ")
+			line, err := p.readLine(ctx)
+			if err != nil {
+				return p.unanswered(ctx)
+			}
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(line), &obj); err != nil {
+				fmt.Fprintf(p.out, "Not a JSON object (%v); arguments unchanged.\n", err)
+				continue
+			}
+			args = json.RawMessage(line)
+			edited = true
+		}
+	}
+}
+
+// unanswered denies a call whose question got no answer.
+func (p *Prompter) unanswered(ctx context.Context) Decision {
+	if ctx.Err() != nil {
+		fmt.Fprintln(p.out)
+		return Decision{Reason: "the run ended before approval was given"}
+	}
+	return Decision{Reason: "no approval was given"}
+}
+
+// readLine waits for one answer, without surrounding whitespace, until ctx
+// is done. A final line without a newline still counts; an error is
+// returned only when there is no more input or ctx is done.
+func (p *Prompter) readLine(ctx context.Context) (string, error) {
+	p.start.Do(func() {
+		p.lines = make(chan string)
+		go p.read()
+	})
+	select {
+	case line, ok := <-p.lines:
+		if !ok {
+			return "", p.readErr
+		}
+		return line, nil
+	case <-ctx.Done():
+		p.abandoned = true
+		return "", ctx.Err()
+	}
+}
+
+// read sends each line of input to p.lines. Reads block, so they run in
+// their own goroutine, which is left blocked when nobody answers.
+func (p *Prompter) read() {
+	for {
+		line, err := p.in.ReadString('\n')
+		if err != nil && line == "" {
+			p.readErr = err
+			close(p.lines)
+			return
+		}
+		p.lines <- strings.TrimSpace(line)
+	}
+}
+
+// dropLateAnswers discards an answer typed for a question that was
+// abandoned, so it cannot approve a different call.
+func (p *Prompter) dropLateAnswers() {
+	if !p.abandoned {
+		return
+	}
+	p.abandoned = false
+	for {
+		select {
+		case _, ok := <-p.lines:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// indentArguments formats tool call arguments for display.
+func indentArguments(args json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, args, "  ", "  "); err != nil {
+		return "  " + string(args)
+	}
+	return "  " + buf.String()
+}
+
+// PolicyRule decides the tool calls that match it. Tool and Agent are
+// patterns where "*" matches any run of characters; empty matches anything.
+// Args maps argument names to patterns the argument must match: string
+// arguments are matched as is, others as JSON.
+type PolicyRule struct {
+	Tool     string            `toml:"tool"`
+	Agent    string            `toml:"agent"`
+	Args     map[string]string `toml:"args"`
+	Decision string            `toml:"decision"` // "allow" or "deny"
+	Reason   string            `toml:"reason"`   // Returned to the model when the rule denies a call
+}
+
+// Policy is an Approver that decides tool calls without asking, for
+// non-interactive runs. The first rule that matches a call decides it;
+// calls no rule matches are denied.
+type Policy struct {
+	Rules []PolicyRule `toml:"rules"`
+}
+
+// LoadPolicy reads an approval policy from a TOML file of [[rules]] tables.
+func LoadPolicy(path string) (*Policy, error) {
+	var p Policy
+	if _, err := toml.DecodeFile(path, &p); err != nil {
+		return nil, fmt.Errorf("failed to load approval policy %s: %w", path, err)
+	}
+	for i, rule := range p.Rules {
+		if rule.Decision != "allow" && rule.Decision != "deny" {
+			return nil, fmt.Errorf("approval policy %s: rules[%d].decision must be \"allow\" or \"deny\", got %q", path, i, rule.Decision)
+		}
+	}
+	return &p, nil
+}
+
+// Approve decides call by the first matching rule.
+func (p *Policy) Approve(ctx context.Context, agentName string, call provider.ToolCall) Decision {
+	for _, rule := range p.Rules {
+		if !rule.matches(agentName, call) {
+			continue
+		}
+		if rule.Decision == "allow" {
+			return Decision{Approved: true}
+		}
+		reason := rule.Reason
+		if reason == "" {
+			reason = "the approval policy denies it"
+		}
+		return Decision{Reason: reason}
+	}
+	return Decision{Reason: "no rule in the approval policy allows it"}
+}
+
+// matches reports whether the rule applies to agentName making call.
+func (r PolicyRule) matches(agentName string, call provider.ToolCall) bool {
+	if r.Tool != "" && !wildcardMatch(r.Tool, call.Name) {
+		return false
+	}
+	if r.Agent != "" && !wildcardMatch(r.Agent, agentName) {
+		return false
+	}
+	if len(r.Args) == 0 {
+		return true
+	}
+
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return false
+	}
+	for name, pattern := range r.Args {
+		raw, ok := args[name]
+		if !ok {
+			return false
+		}
+		var value string
+		if json.Unmarshal(raw, &value) != nil {
+			var buf bytes.Buffer
+			json.Compact(&buf, raw)
+			value = buf.String()
+		}
+		if !wildcardMatch(pattern, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrswab/axe/internal/provider"
+)
+
+func TestPrompter_Approve(t *testing.T) {
+	call := provider.ToolCall{ID: "w1", Name: WriteFileToolName, Arguments: json.RawMessage(`{"path": "a.txt", "content": "hi"}`)}
+
+	tests := []struct {
+		name  string
+		input string
+		want  Decision
+	}{
+		{"yes", "y\n", Decision{Approved: true}},
+		{"no with reason", "n\nuse b.txt instead\n", Decision{Reason: "use b.txt instead"}},
+		{"no without reason", "no\n\n", Decision{Reason: "the user declined to run it"}},
+		{"edit then yes", "e\nnot json\ne\n{\"path\": \"b.txt\", \"content\": \"hi\"}\nyes\n", Decision{Approved: true, Arguments: json.RawMessage(`{"path": "b.txt", "content": "hi"}`)}},
+		{"unknown answer then yes", "maybe\ny", Decision{Approved: true}},
+		{"no input", "", Decision{Reason: "no approval was given"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got := NewPrompter(strings.NewReader(tt.input), &out).Approve(context.Background(), "writer", call)
+			if got.Approved != tt.want.Approved || got.Reason != tt.want.Reason || string(got.Arguments) != string(tt.want.Arguments) {
+				t.Errorf("Approve() = %+v, want %+v", got, tt.want)
+			}
+			if !strings.Contains(out.String(), `[approve] agent "writer" wants to call write_file with:`) || !strings.Contains(out.String(), `"path": "a.txt"`) {
+				t.Errorf("prompt = %q, want the tool name and arguments", out.String())
+			}
+		})
+	}
+}
+
+func TestPrompter_Approve_ContextDone(t *testing.T) {
+	call := provider.ToolCall{ID: "w1", Name: WriteFileToolName, Arguments: json.RawMessage(`{"path": "a.txt"}`)}
+	in, answer := io.Pipe()
+	defer answer.Close()
+	p := NewPrompter(in, io.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	got := p.Approve(ctx, "writer", call)
+	if got.Approved || got.Reason != "the run ended before approval was given" {
+		t.Errorf("Approve() = %+v, want a denial when the run ends", got)
+	}
+
+	// The late answer to the abandoned question must not approve the next call
+	go answer.Write([]byte("y\n"))
+	time.Sleep(50 * time.Millisecond)
+	go answer.Write([]byte("n\nnot now\n"))
+	got = p.Approve(context.Background(), "writer", call)
+	if got.Approved || got.Reason != "not now" {
+		t.Errorf("Approve() = %+v, want the answer given after the question", got)
+	}
+}
+
+func TestPolicy_Approve(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.toml")
+	os.WriteFile(path, []byte(`
+[[rules]]
+tool = "run_command"
+decision = "allow"
+[rules.args]
+command = "go test *"
+
+[[rules]]
+tool = "write_file"
+agent = "docs-*"
+decision = "allow"
+
+[[rules]]
+tool = "write_file"
+decision = "deny"
+reason = "only docs agents may write"
+`), 0644)
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy: %v", err)
+	}
+
+	tests := []struct {
+		agent, tool, args string
+		approved          bool
+		reason            string
+	}{
+		{"ci", RunCommandToolName, `{"command": "go test ./..."}`, true, ""},
+		{"ci", RunCommandToolName, `{"command": "rm -rf /"}`, false, "no rule in the approval policy allows it"},
+		{"docs-writer", WriteFileToolName, `{"path": "a.md", "content": ""}`, true, ""},
+		{"ci", WriteFileToolName, `{"path": "a.md", "content": ""}`, false, "only docs agents may write"},
+		{"ci", ReadFileToolName, `{"path": "a.md"}`, false, "no rule in the approval policy allows it"},
+	}
+	for _, tt := range tests {
+		got := policy.Approve(context.Background(), tt.agent, provider.ToolCall{Name: tt.tool, Arguments: json.RawMessage(tt.args)})
+		if got.Approved != tt.approved || got.Reason != tt.reason {
+			t.Errorf("%s %s %s: got %+v, want approved=%v reason=%q", tt.agent, tt.tool, tt.args, got, tt.approved, tt.reason)
+		}
+	}
+}
+
+func TestLoadPolicy_Errors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.toml")
+	os.WriteFile(path, []byte("[[rules]]\ntool = \"*\"\ndecision = \"maybe\"\n"), 0644)
+
+	if _, err := LoadPolicy(path); err == nil || !strings.Contains(err.Error(), `rules[0].decision must be "allow" or "deny", got "maybe"`) {
+		t.Errorf("err = %v, want invalid decision", err)
+	}
+	if _, err := LoadPolicy(filepath.Join(dir, "missing.toml")); err == nil || !strings.Contains(err.Error(), "failed to load approval policy") {
+		t.Errorf("err = %v, want load error", err)
+	}
+}
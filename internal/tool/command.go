@@ -0,0 +1,224 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/resolve"
+)
+
+// RunCommandToolName is the name of the built-in shell command tool.
+const RunCommandToolName = "run_command"
+
+// Defaults for run_command when the agent's [run_command] section leaves
+// them unset.
+const (
+	defaultCommandTimeout     = 60 * time.Second
+	defaultCommandOutputBytes = 64 << 10
+)
+
+// commandBaseEnv are the environment variables every command inherits.
+// Anything else must be listed in run_command.env.
+var commandBaseEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "LANG", "LC_ALL", "TERM", "TMPDIR", "TZ"}
+
+var runCommandTool = provider.Tool{
+	Name:        RunCommandToolName,
+	Description: "Run a command in the working directory and return its exit code, stdout and stderr. The command is executed directly, not through a shell, and only allowlisted commands may run.",
+	InputSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"command": {"type": "string", "description": "Executable to run, e.g. \"go\""},
+			"args": {"type": "array", "items": {"type": "string"}, "description": "Arguments passed to the executable, e.g. [\"test\", \"./...\"]"}
+		},
+		"required": ["command"]
+	}`),
+}
+
+func init() {
+	registerBuiltin(runCommandTool, func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+		return executeRunCommand(ctx, call, opts.Workdir, opts.RunCommand)
+	})
+}
+
+// runCommandArgs are the decoded arguments of a run_command tool call.
+type runCommandArgs struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// executeRunCommand runs an allowlisted command in workdir with a scrubbed
+// environment, a timeout and capped output.
+func executeRunCommand(ctx context.Context, call provider.ToolCall, workdir string, cfg agent.RunCommandConfig) provider.ToolResult {
+	if result, ok := CheckArguments(runCommandTool, call); !ok {
+		return result
+	}
+	var args runCommandArgs
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return commandError(call, fmt.Sprintf("invalid arguments: %s", err))
+	}
+	if strings.TrimSpace(args.Command) == "" {
+		return commandError(call, `"command" argument is required`)
+	}
+
+	line := strings.Join(append([]string{args.Command}, args.Args...), " ")
+	if !commandAllowed(cfg.Allow, args.Command, line) {
+		return commandError(call, fmt.Sprintf("command %q is not allowed (allowed: %s)", line, strings.Join(cfg.Allow, ", ")))
+	}
+
+	dir, err := resolve.Path(workdir, ".")
+	if err != nil {
+		return commandError(call, err.Error())
+	}
+
+	timeout := defaultCommandTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	maxOutput := defaultCommandOutputBytes
+	if cfg.MaxOutputBytes > 0 {
+		maxOutput = cfg.MaxOutputBytes
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{max: maxOutput}
+	stderr := &cappedBuffer{max: maxOutput}
+
+	cmd := exec.CommandContext(cmdCtx, args.Command, args.Args...)
+	cmd.Dir = dir
+	cmd.Env = commandEnv(cfg.Env)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Do not wait forever for children that inherited the output pipes
+	cmd.WaitDelay = time.Second
+
+	runErr := cmd.Run()
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	switch {
+	case cmdCtx.Err() == context.DeadlineExceeded:
+		return provider.ToolResult{
+			CallID:  call.ID,
+			Content: fmt.Sprintf("run_command error: %q timed out after %s\n\n%s", line, timeout, formatOutput(stdout, stderr)),
+			IsError: true,
+		}
+	case errors.As(runErr, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case runErr != nil:
+		return commandError(call, fmt.Sprintf("failed to run %q: %s", line, runErr))
+	}
+
+	return provider.ToolResult{
+		CallID:  call.ID,
+		Content: fmt.Sprintf("exit code: %d\n\n%s", exitCode, formatOutput(stdout, stderr)),
+	}
+}
+
+// commandError creates an error ToolResult for a run_command call.
+func commandError(call provider.ToolCall, msg string) provider.ToolResult {
+	return provider.ToolResult{
+		CallID:  call.ID,
+		Content: "run_command error: " + msg,
+		IsError: true,
+	}
+}
+
+// commandAllowed reports whether the command is permitted by allow. Entries
+// without spaces or "*" must equal the executable exactly; other entries are
+// patterns matched against the full command line.
+func commandAllowed(allow []string, executable, line string) bool {
+	for _, entry := range allow {
+		if !strings.ContainsAny(entry, " *") {
+			if entry == executable {
+				return true
+			}
+			continue
+		}
+		if wildcardMatch(entry, line) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch matches s against pattern, where "*" matches any run of
+// characters (including none) and everything else matches literally.
+func wildcardMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(s, part)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
+
+// commandEnv returns the environment for a command: the base variables plus
+// those named in extra, taken from axe's own environment when set.
+func commandEnv(extra []string) []string {
+	var env []string
+	for _, name := range append(append([]string{}, commandBaseEnv...), extra...) {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// formatOutput renders a command's stdout and stderr for the model.
+func formatOutput(stdout, stderr *cappedBuffer) string {
+	return "--- stdout ---\n" + stdout.String() + "\n--- stderr ---\n" + stderr.String()
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the rest.
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	max     int
+	dropped int
+}
+
+// Write stores p up to the cap. It never fails, so a command is not killed
+// by a broken pipe once its output exceeds the cap.
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := b.max - b.buf.Len()
+	if room >= len(p) {
+		b.buf.Write(p)
+	} else {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		b.dropped += len(p) - max(room, 0)
+	}
+	return len(p), nil
+}
+
+// String returns the captured output with a note if any was dropped.
+func (b *cappedBuffer) String() string {
+	if b.dropped > 0 {
+		return b.buf.String() + fmt.Sprintf("\n[output truncated: %d bytes omitted]", b.dropped)
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,156 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+func skipWithoutSh(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("run_command tests use sh")
+	}
+}
+
+func runCommandCall(args string) provider.ToolCall {
+	return provider.ToolCall{ID: "cmd-1", Name: RunCommandToolName, Arguments: json.RawMessage(args)}
+}
+
+func commandOpts(workdir string, cfg agent.RunCommandConfig) ExecuteOptions {
+	return ExecuteOptions{Workdir: workdir, RunCommand: cfg}
+}
+
+func TestRunCommand_OutputAndExitCode(t *testing.T) {
+	skipWithoutSh(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "marker.txt"), []byte("x"), 0644)
+
+	call := runCommandCall(`{"command": "sh", "args": ["-c", "ls; echo oops >&2; exit 3"]}`)
+	result := testRegistry(t, RunCommandToolName).Execute(context.Background(), call, commandOpts(dir, agent.RunCommandConfig{Allow: []string{"sh"}}))
+
+	if result.IsError {
+		t.Fatalf("a non-zero exit is not a tool error: %s", result.Content)
+	}
+	want := "exit code: 3\n\n--- stdout ---\nmarker.txt\n\n--- stderr ---\noops\n"
+	if result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+	if result.CallID != "cmd-1" {
+		t.Errorf("CallID = %q, want cmd-1", result.CallID)
+	}
+}
+
+func TestRunCommand_NotAllowed(t *testing.T) {
+	skipWithoutSh(t)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name  string
+		allow []string
+		args  string
+	}{
+		{"executable not listed", []string{"go"}, `{"command": "sh", "args": ["-c", "true"]}`},
+		{"path to allowed name", []string{"sh"}, `{"command": "/bin/sh", "args": ["-c", "true"]}`},
+		{"pattern does not match", []string{"sh -c echo*"}, `{"command": "sh", "args": ["-c", "touch pwned"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := testRegistry(t, RunCommandToolName).Execute(context.Background(), runCommandCall(tt.args), commandOpts(dir, agent.RunCommandConfig{Allow: tt.allow}))
+			if !result.IsError || !strings.Contains(result.Content, "is not allowed") {
+				t.Errorf("result = %+v, want not allowed error", result)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+		t.Error("disallowed command ran")
+	}
+}
+
+func TestRunCommand_PatternAllowed(t *testing.T) {
+	skipWithoutSh(t)
+
+	result := testRegistry(t, RunCommandToolName).Execute(context.Background(), runCommandCall(`{"command": "sh", "args": ["-c", "echo hi"]}`),
+		commandOpts(t.TempDir(), agent.RunCommandConfig{Allow: []string{"sh -c echo*"}}))
+	if result.IsError || !strings.Contains(result.Content, "hi") {
+		t.Errorf("result = %+v, want command output", result)
+	}
+}
+
+func TestRunCommand_ScrubsEnvironment(t *testing.T) {
+	skipWithoutSh(t)
+	t.Setenv("ANTHROPIC_API_KEY", "sk-secret")
+	t.Setenv("AXE_TEST_PASSTHROUGH", "visible")
+
+	call := runCommandCall(`{"command": "sh", "args": ["-c", "echo key=$ANTHROPIC_API_KEY pass=$AXE_TEST_PASSTHROUGH"]}`)
+	cfg := agent.RunCommandConfig{Allow: []string{"sh"}, Env: []string{"AXE_TEST_PASSTHROUGH"}}
+	result := testRegistry(t, RunCommandToolName).Execute(context.Background(), call, commandOpts(t.TempDir(), cfg))
+
+	if strings.Contains(result.Content, "sk-secret") {
+		t.Errorf("API key leaked into command environment: %q", result.Content)
+	}
+	if !strings.Contains(result.Content, "key= pass=visible") {
+		t.Errorf("Content = %q, want passthrough variable only", result.Content)
+	}
+}
+
+func TestRunCommand_Timeout(t *testing.T) {
+	skipWithoutSh(t)
+
+	call := runCommandCall(`{"command": "sh", "args": ["-c", "echo started; sleep 10"]}`)
+	result := testRegistry(t, RunCommandToolName).Execute(context.Background(), call, commandOpts(t.TempDir(), agent.RunCommandConfig{Allow: []string{"sh"}, Timeout: 1}))
+
+	if !result.IsError || !strings.Contains(result.Content, "timed out after 1s") {
+		t.Fatalf("result = %+v, want timeout error", result)
+	}
+	if !strings.Contains(result.Content, "started") {
+		t.Errorf("partial output missing: %q", result.Content)
+	}
+}
+
+func TestRunCommand_OutputCap(t *testing.T) {
+	skipWithoutSh(t)
+
+	call := runCommandCall(`{"command": "sh", "args": ["-c", "printf '%0100d' 0"]}`)
+	result := testRegistry(t, RunCommandToolName).Execute(context.Background(), call, commandOpts(t.TempDir(), agent.RunCommandConfig{Allow: []string{"sh"}, MaxOutputBytes: 10}))
+
+	if !strings.Contains(result.Content, "--- stdout ---\n0000000000\n[output truncated: 90 bytes omitted]") {
+		t.Errorf("Content = %q, want output capped at 10 bytes", result.Content)
+	}
+}
+
+func TestRunCommand_NotFound(t *testing.T) {
+	result := testRegistry(t, RunCommandToolName).Execute(context.Background(), runCommandCall(`{"command": "axe-no-such-command"}`),
+		commandOpts(t.TempDir(), agent.RunCommandConfig{Allow: []string{"axe-no-such-command"}}))
+	if !result.IsError || !strings.Contains(result.Content, "failed to run") {
+		t.Errorf("result = %+v, want start failure", result)
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"go test*", "go test ./...", true},
+		{"go test*", "go test", true},
+		{"go test*", "go vet ./...", false},
+		{"npm run * --silent", "npm run lint --silent", true},
+		{"npm run * --silent", "npm run lint", false},
+		{"*lint*", "golangci-lint run", true},
+		{"make", "make", true},
+		{"make", "make all", false},
+	}
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("wildcardMatch(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/resolve"
+)
+
+// Built-in filesystem tool names. Agents opt in by listing them in the
+// tools field of their TOML config.
+const (
+	ReadFileToolName  = "read_file"
+	ListDirToolName   = "list_dir"
+	WriteFileToolName = "write_file"
+)
+
+// maxReadFileBytes is the most file content read_file returns. Longer files
+// are truncated with a note so a single call cannot flood the context.
+const maxReadFileBytes = 100_000
+
+// maxListDirEntries is the most entries list_dir returns.
+const maxListDirEntries = 1000
+
+// fsTools are the built-in filesystem tool definitions, keyed by name.
+var fsTools = map[string]provider.Tool{
+	ReadFileToolName: {
+		Name:        ReadFileToolName,
+		Description: "Read a text file in the working directory. Paths are relative to the working directory.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "Path of the file to read"}
+			},
+			"required": ["path"]
+		}`),
+	},
+	ListDirToolName: {
+		Name:        ListDirToolName,
+		Description: "List the entries of a directory in the working directory. Directories are shown with a trailing slash.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "Path of the directory to list (default: the working directory)"},
+				"recursive": {"type": "boolean", "description": "List all entries below the directory, not just its direct children"}
+			}
+		}`),
+	},
+	WriteFileToolName: {
+		Name:        WriteFileToolName,
+		Description: "Create or overwrite a file in the working directory with the given content. Parent directories are created as needed.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "Path of the file to write"},
+				"content": {"type": "string", "description": "Full content of the file"}
+			},
+			"required": ["path", "content"]
+		}`),
+	},
+}
+
+func init() {
+	for _, def := range fsTools {
+		registerBuiltin(def, func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+			return executeFSTool(call, opts.Workdir)
+		})
+	}
+}
+
+// fsArgs are the decoded arguments of a filesystem tool call.
+type fsArgs struct {
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Recursive bool   `json:"recursive"`
+}
+
+// executeFSTool runs a built-in filesystem tool call inside workdir.
+func executeFSTool(call provider.ToolCall, workdir string) provider.ToolResult {
+	if result, ok := CheckArguments(fsTools[call.Name], call); !ok {
+		return result
+	}
+	var args fsArgs
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return fsError(call, fmt.Sprintf("invalid arguments: %s", err))
+	}
+
+	var content string
+	var err error
+	switch call.Name {
+	case ReadFileToolName:
+		content, err = readFileTool(workdir, args.Path)
+	case ListDirToolName:
+		content, err = listDirTool(workdir, args.Path, args.Recursive)
+	case WriteFileToolName:
+		content, err = writeFileTool(workdir, args.Path, args.Content)
+	}
+	if err != nil {
+		return fsError(call, err.Error())
+	}
+
+	return provider.ToolResult{CallID: call.ID, Content: content}
+}
+
+// fsError creates an error ToolResult for a filesystem tool call.
+func fsError(call provider.ToolCall, msg string) provider.ToolResult {
+	return provider.ToolResult{
+		CallID:  call.ID,
+		Content: fmt.Sprintf("%s error: %s", call.Name, msg),
+		IsError: true,
+	}
+}
+
+func readFileTool(workdir, name string) (string, error) {
+	path, err := resolve.Path(workdir, name)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fileError(name, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory; use %s", name, ListDirToolName)
+	}
+
+	// One byte past the limit tells whether the file was cut.
+	fc, err := resolve.ReadFilePrefix(path, maxReadFileBytes+1)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s as text", name)
+	}
+	if fc.IsAttachment() {
+		return "", fmt.Errorf("%s is a %s file and cannot be read as text", name, fc.MediaType)
+	}
+
+	if len(fc.Content) > maxReadFileBytes {
+		content := cutAtRune(fc.Content, maxReadFileBytes)
+		return content + fmt.Sprintf("\n\n[truncated: showing %d of %d bytes]", len(content), max(info.Size(), int64(len(fc.Content)))), nil
+	}
+	return fc.Content, nil
+}
+
+// cutAtRune returns the longest prefix of s of at most n bytes that does
+// not split a UTF-8 character.
+func cutAtRune(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func listDirTool(workdir, name string, recursive bool) (string, error) {
+	if name == "" {
+		name = "."
+	}
+	root, err := resolve.Path(workdir, name)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fileError(name, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", name)
+	}
+
+	var lines []string
+	truncated := false
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil // skip unreadable entries
+		}
+		if len(lines) >= maxListDirEntries {
+			truncated = true
+			return filepath.SkipAll
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		entry := filepath.ToSlash(rel)
+		if d.IsDir() {
+			entry += "/"
+		}
+		lines = append(lines, entry)
+
+		if d.IsDir() && !recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fileError(name, err)
+	}
+
+	if len(lines) == 0 {
+		return "(empty directory)", nil
+	}
+	if truncated {
+		lines = append(lines, fmt.Sprintf("[truncated after %d entries]", maxListDirEntries))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func writeFileTool(workdir, name, content string) (string, error) {
+	path, err := resolve.Path(workdir, name)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return fmt.Sprintf("Wrote %d bytes to %s", len(content), name), nil
+}
+
+// fileError converts a filesystem error into a message that does not expose
+// absolute paths outside the agent's view of the working directory.
+func fileError(name string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s does not exist", name)
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("permission denied: %s", name)
+	}
+	return fmt.Errorf("cannot access %s", name)
+}
@@ -0,0 +1,198 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// helper: create a workdir with a few files for filesystem tool tests.
+func setupFSWorkdir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0755)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Project"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "pkg", "util.go"), []byte("package pkg"), 0644)
+	return dir
+}
+
+func fsCall(name, args string) provider.ToolCall {
+	return provider.ToolCall{ID: "fs-1", Name: name, Arguments: json.RawMessage(args)}
+}
+
+func fsOpts(workdir string) ExecuteOptions {
+	return ExecuteOptions{Workdir: workdir}
+}
+
+func TestRegistry_Enable(t *testing.T) {
+	defs := testRegistry(t, WriteFileToolName, ReadFileToolName).Definitions()
+	if len(defs) != 2 || defs[0].Name != WriteFileToolName || defs[1].Name != ReadFileToolName {
+		t.Fatalf("Definitions = %+v, want write_file then read_file", defs)
+	}
+	for _, def := range defs {
+		if schema := decodeSchema(t, def.InputSchema); schema.Type != "object" {
+			t.Errorf("%s schema type = %q, want object", def.Name, schema.Type)
+		}
+	}
+}
+
+func TestRegistry_EnableUnknownTool(t *testing.T) {
+	err := NewRegistry().Enable([]string{"read_file", "launch_missiles"})
+	if err == nil || !strings.Contains(err.Error(), `unknown tool "launch_missiles"`) {
+		t.Errorf("err = %v, want unknown tool error", err)
+	}
+}
+
+func TestExecute_ReadFile(t *testing.T) {
+	dir := setupFSWorkdir(t)
+
+	result := testRegistry(t, ReadFileToolName).Execute(context.Background(), fsCall(ReadFileToolName, `{"path": "src/main.go"}`), fsOpts(dir))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	if result.Content != "package main" || result.CallID != "fs-1" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestExecute_ReadFileErrors(t *testing.T) {
+	dir := setupFSWorkdir(t)
+	os.WriteFile(filepath.Join(dir, "image.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0644)
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"path": "missing.txt"}`, "missing.txt does not exist"},
+		{`{"path": "src"}`, "is a directory"},
+		{`{"path": "../outside.txt"}`, "outside the working directory"},
+		{`{"path": "image.png"}`, "image/png"},
+		{`{}`, "invalid arguments"},
+	}
+	for _, tt := range tests {
+		result := testRegistry(t, ReadFileToolName).Execute(context.Background(), fsCall(ReadFileToolName, tt.args), fsOpts(dir))
+		if !result.IsError {
+			t.Errorf("%s: expected error, got %q", tt.args, result.Content)
+			continue
+		}
+		if !strings.HasPrefix(result.Content, "read_file error: ") || !strings.Contains(result.Content, tt.want) {
+			t.Errorf("%s: Content = %q, want to contain %q", tt.args, result.Content, tt.want)
+		}
+	}
+}
+
+func TestExecute_ReadFileTruncatesLargeFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(strings.Repeat("a", maxReadFileBytes+10)), 0644)
+
+	result := testRegistry(t, ReadFileToolName).Execute(context.Background(), fsCall(ReadFileToolName, `{"path": "big.txt"}`), fsOpts(dir))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	if !strings.HasSuffix(result.Content, "[truncated: showing 100000 of 100010 bytes]") {
+		t.Errorf("missing truncation note: %q", result.Content[len(result.Content)-60:])
+	}
+
+	// A cut never splits a character
+	os.WriteFile(filepath.Join(dir, "wide.txt"), []byte("a"+strings.Repeat("é", maxReadFileBytes)), 0644)
+	result = testRegistry(t, ReadFileToolName).Execute(context.Background(), fsCall(ReadFileToolName, `{"path": "wide.txt"}`), fsOpts(dir))
+	if result.IsError || !utf8.ValidString(result.Content) || !strings.HasSuffix(result.Content, "[truncated: showing 99999 of 200001 bytes]") {
+		t.Errorf("wide file result ends %q, want a cut on a character boundary", result.Content[len(result.Content)-60:])
+	}
+}
+
+func TestExecute_ReadFileSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink tests unreliable on Windows")
+	}
+	dir := setupFSWorkdir(t)
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt"))
+
+	result := testRegistry(t, ReadFileToolName).Execute(context.Background(), fsCall(ReadFileToolName, `{"path": "link.txt"}`), fsOpts(dir))
+	if !result.IsError || !strings.Contains(result.Content, "outside the working directory") {
+		t.Errorf("expected containment error, got %+v", result)
+	}
+}
+
+func TestExecute_ListDir(t *testing.T) {
+	dir := setupFSWorkdir(t)
+
+	result := testRegistry(t, ListDirToolName).Execute(context.Background(), fsCall(ListDirToolName, `{}`), fsOpts(dir))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	if result.Content != "README.md\nsrc/" {
+		t.Errorf("Content = %q, want %q", result.Content, "README.md\nsrc/")
+	}
+
+	result = testRegistry(t, ListDirToolName).Execute(context.Background(), fsCall(ListDirToolName, `{"path": "src", "recursive": true}`), fsOpts(dir))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	if want := "main.go\npkg/\npkg/util.go"; result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+}
+
+func TestExecute_ListDirErrors(t *testing.T) {
+	dir := setupFSWorkdir(t)
+
+	for _, args := range []string{`{"path": "README.md"}`, `{"path": ".."}`, `{"path": "nope"}`} {
+		result := testRegistry(t, ListDirToolName).Execute(context.Background(), fsCall(ListDirToolName, args), fsOpts(dir))
+		if !result.IsError || !strings.HasPrefix(result.Content, "list_dir error: ") {
+			t.Errorf("%s: expected list_dir error, got %+v", args, result)
+		}
+	}
+}
+
+func TestExecute_WriteFile(t *testing.T) {
+	dir := setupFSWorkdir(t)
+
+	result := testRegistry(t, WriteFileToolName).Execute(context.Background(), fsCall(WriteFileToolName, `{"path": "out/notes.txt", "content": "hello"}`), fsOpts(dir))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	if result.Content != "Wrote 5 bytes to out/notes.txt" {
+		t.Errorf("Content = %q", result.Content)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "out", "notes.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("file content = %q, err = %v", data, err)
+	}
+}
+
+func TestExecute_WriteFileOutsideWorkdir(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "project")
+	os.MkdirAll(dir, 0755)
+
+	result := testRegistry(t, WriteFileToolName).Execute(context.Background(), fsCall(WriteFileToolName, `{"path": "../escape.txt", "content": "x"}`), fsOpts(dir))
+	if !result.IsError || !strings.Contains(result.Content, "outside the working directory") {
+		t.Errorf("expected containment error, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("file was written outside the workdir")
+	}
+}
+
+func TestExecute_ToolNotEnabled(t *testing.T) {
+	dir := setupFSWorkdir(t)
+
+	result := testRegistry(t, ReadFileToolName).Execute(context.Background(), fsCall(WriteFileToolName, `{"path": "x.txt", "content": "x"}`), fsOpts(dir))
+	if !result.IsError || result.Content != `Unknown tool: "write_file"` {
+		t.Errorf("result = %+v, want unknown tool error", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x.txt")); !os.IsNotExist(err) {
+		t.Error("disabled tool wrote a file")
+	}
+}
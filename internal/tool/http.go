@@ -0,0 +1,352 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// HTTPGetToolName is the name of the built-in HTTP fetch tool.
+const HTTPGetToolName = "http_get"
+
+// Defaults for http_get when the agent's [http_get] section leaves them
+// unset.
+const (
+	defaultHTTPTimeout  = 30 * time.Second
+	defaultHTTPMaxBytes = 1 << 20
+)
+
+// maxHTTPRedirects is the most redirects http_get follows.
+const maxHTTPRedirects = 10
+
+// maxHTTPTextBytes is the most text http_get returns, after HTML pages are
+// converted to text. Longer responses are truncated with a note.
+const maxHTTPTextBytes = 100_000
+
+// defaultHTTPContentTypes are the media types http_get accepts when the
+// agent lists none.
+var defaultHTTPContentTypes = []string{"text/*", "application/json", "application/*+json", "application/xml", "application/*+xml"}
+
+var httpGetTool = provider.Tool{
+	Name:        HTTPGetToolName,
+	Description: "Fetch a URL with an HTTP GET request and return the status, content type and body. HTML pages are converted to plain text. Only allowlisted hosts may be fetched.",
+	InputSchema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"url": {"type": "string", "description": "Absolute http or https URL to fetch"}
+		},
+		"required": ["url"]
+	}`),
+}
+
+func init() {
+	registerBuiltin(httpGetTool, func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+		return executeHTTPGet(ctx, call, opts.HTTPGet)
+	})
+}
+
+// executeHTTPGet fetches the URL of an http_get call if its host is
+// allowlisted, with a timeout, a size cap and a content-type filter.
+func executeHTTPGet(ctx context.Context, call provider.ToolCall, cfg agent.HTTPGetConfig) provider.ToolResult {
+	if result, ok := CheckArguments(httpGetTool, call); !ok {
+		return result
+	}
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(call.Arguments, &args); err != nil {
+		return httpGetError(call, fmt.Sprintf("invalid arguments: %s", err))
+	}
+
+	u, err := url.Parse(strings.TrimSpace(args.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return httpGetError(call, fmt.Sprintf("%q is not an absolute http or https URL", args.URL))
+	}
+	if !hostAllowed(cfg.Allow, u) {
+		return httpGetError(call, fmt.Sprintf("host %q is not allowed (allowed: %s)", u.Host, strings.Join(cfg.Allow, ", ")))
+	}
+
+	timeout := defaultHTTPTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	maxBytes := defaultHTTPMaxBytes
+	if cfg.MaxBytes > 0 {
+		maxBytes = cfg.MaxBytes
+	}
+	contentTypes := cfg.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultHTTPContentTypes
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxHTTPRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxHTTPRedirects)
+			}
+			if !hostAllowed(cfg.Allow, req.URL) {
+				return fmt.Errorf("redirect to host %q is not allowed", req.URL.Host)
+			}
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return httpGetError(call, err.Error())
+	}
+	req.Header.Set("User-Agent", "axe")
+	req.Header.Set("Accept", strings.Join(contentTypes, ", "))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return httpGetError(call, fmt.Sprintf("%s timed out after %s", u, timeout))
+		}
+		return httpGetError(call, err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return httpGetError(call, fmt.Sprintf("failed to read response: %s", err))
+	}
+	truncated := len(body) > maxBytes
+	if truncated {
+		body = []byte(cutAtRune(string(body), maxBytes))
+	}
+
+	mediaType := responseMediaType(resp.Header.Get("Content-Type"), body)
+	if !contentTypeAllowed(contentTypes, mediaType) {
+		return httpGetError(call, fmt.Sprintf("content type %q is not allowed (allowed: %s)", mediaType, strings.Join(contentTypes, ", ")))
+	}
+
+	text := string(body)
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		text = htmlToText(text)
+	}
+	if len(text) > maxHTTPTextBytes {
+		text = cutAtRune(text, maxHTTPTextBytes)
+		truncated = true
+	}
+	if truncated {
+		text += "\n[response truncated]"
+	}
+
+	return provider.ToolResult{
+		CallID:  call.ID,
+		Content: fmt.Sprintf("status: %s\ncontent-type: %s\n\n%s", resp.Status, mediaType, text),
+		IsError: resp.StatusCode >= 400,
+	}
+}
+
+// httpGetError creates an error ToolResult for an http_get call.
+func httpGetError(call provider.ToolCall, msg string) provider.ToolResult {
+	return provider.ToolResult{
+		CallID:  call.ID,
+		Content: "http_get error: " + msg,
+		IsError: true,
+	}
+}
+
+// hostAllowed reports whether u's host is permitted by allow. Entries are a
+// host name, a host and port, or "*." followed by a domain whose subdomains
+// are all allowed.
+func hostAllowed(allow []string, u *url.URL) bool {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		default:
+			if _, _, err := net.SplitHostPort(entry); err == nil {
+				if entry == net.JoinHostPort(host, port) {
+					return true
+				}
+			} else if strings.Trim(entry, "[]") == host {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// responseMediaType returns the media type of a response from its
+// Content-Type header, sniffing the body when the header is missing.
+func responseMediaType(header string, body []byte) string {
+	if header == "" {
+		header = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(header, ";")[0]))
+	}
+	return mediaType
+}
+
+// contentTypeAllowed reports whether mediaType matches one of the patterns,
+// where "*" matches any run of characters.
+func contentTypeAllowed(patterns []string, mediaType string) bool {
+	for _, p := range patterns {
+		if wildcardMatch(strings.ToLower(strings.TrimSpace(p)), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlSkipElements are elements whose content is not text for the reader.
+var htmlSkipElements = map[string]bool{"script": true, "style": true, "noscript": true, "template": true, "svg": true, "head": true}
+
+// htmlBlockElements start a new line in the text rendering of a page.
+var htmlBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// htmlSpace turns line breaks and tabs in HTML text into spaces.
+var htmlSpace = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// htmlToText renders an HTML document as plain text: tags are dropped,
+// scripts, styles and the head are removed, block elements become line
+// breaks, list items become "- " lines and entities are decoded.
+// Whitespace is collapsed, including inside <pre>.
+func htmlToText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '<' || !isTagStart(s, i) {
+			if s[i] == '<' {
+				b.WriteByte('<')
+				i++
+				continue
+			}
+			next := strings.IndexByte(s[i:], '<')
+			if next < 0 {
+				next = len(s) - i
+			}
+			// Line breaks in the source are plain whitespace in HTML
+			b.WriteString(htmlSpace.Replace(s[i : i+next]))
+			i += next
+			continue
+		}
+
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+
+		end := tagEnd(s, i)
+		tag := s[i+1 : end]
+		i = end + 1
+		if strings.HasPrefix(tag, "!") || strings.HasPrefix(tag, "?") {
+			continue
+		}
+
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimLeft(tag, "/"))
+		if n := strings.IndexAny(name, " \t\r\n/"); n >= 0 {
+			name = name[:n]
+		}
+
+		switch {
+		case htmlSkipElements[name] && !closing && !strings.HasSuffix(tag, "/"):
+			closeIdx := strings.Index(strings.ToLower(s[i:]), "</"+name)
+			if closeIdx < 0 {
+				i = len(s)
+				continue
+			}
+			i = tagEnd(s, i+closeIdx) + 1
+		case name == "li":
+			if !closing {
+				b.WriteString("\n- ")
+			}
+		case name == "td" || name == "th":
+			b.WriteString(" ")
+		case htmlBlockElements[name]:
+			b.WriteString("\n")
+		}
+	}
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(html.UnescapeString(b.String()), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if len(lines) > 0 {
+				blank = true
+			}
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isTagStart reports whether the "<" at i opens a tag, comment or
+// declaration rather than being a literal less-than sign.
+func isTagStart(s string, i int) bool {
+	if i+1 >= len(s) {
+		return false
+	}
+	c := s[i+1]
+	return c == '/' || c == '!' || c == '?' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// tagEnd returns the index of the ">" closing the tag that starts at
+// start, skipping quoted attribute values. It returns len(s)-1 for an
+// unterminated tag.
+func tagEnd(s string, start int) int {
+	var quote byte
+	for j := start + 1; j < len(s); j++ {
+		switch c := s[j]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return j
+		}
+	}
+	return len(s) - 1
+}
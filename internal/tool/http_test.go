@@ -0,0 +1,195 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+func httpGetCall(u string) provider.ToolCall {
+	args, _ := json.Marshal(map[string]string{"url": u})
+	return provider.ToolCall{ID: "get-1", Name: HTTPGetToolName, Arguments: args}
+}
+
+// httpGet runs an http_get call for u with cfg.
+func httpGet(t *testing.T, u string, cfg agent.HTTPGetConfig) provider.ToolResult {
+	t.Helper()
+	return testRegistry(t, HTTPGetToolName).Execute(context.Background(), httpGetCall(u), ExecuteOptions{HTTPGet: cfg})
+}
+
+// helper: start a server with a few pages of different content types.
+func startHTTPGetServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>T</title><style>p{}</style></head><body><h1>Docs</h1><p>Fish &amp; chips</p><script>alert(1)</script><ul><li>one</li><li>two</li></ul></body></html>`))
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true}`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("a", 500)))
+	})
+	mux.HandleFunc("/wide", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("a" + strings.Repeat("é", maxHTTPTextBytes)))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such page", http.StatusNotFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	mux.HandleFunc("/away", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://elsewhere.example/", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPGet_HTMLToText(t *testing.T) {
+	server := startHTTPGetServer(t)
+	result := httpGet(t, server.URL+"/page", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}})
+
+	if result.IsError || result.CallID != "get-1" {
+		t.Fatalf("result = %+v", result)
+	}
+	want := "status: 200 OK\ncontent-type: text/html\n\nDocs\n\nFish & chips\n\n- one\n- two"
+	if result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+}
+
+func TestHTTPGet_JSON(t *testing.T) {
+	server := startHTTPGetServer(t)
+	result := httpGet(t, server.URL+"/api", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}})
+
+	if result.IsError || !strings.HasSuffix(result.Content, "content-type: application/json\n\n{\"ok\": true}") {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestHTTPGet_DeniedHosts(t *testing.T) {
+	server := startHTTPGetServer(t)
+	cfg := agent.HTTPGetConfig{Allow: []string{"docs.example.com"}}
+
+	result := httpGet(t, server.URL+"/page", cfg)
+	if !result.IsError || !strings.Contains(result.Content, "http_get error: host \"127.0.0.1:") || !strings.Contains(result.Content, "is not allowed (allowed: docs.example.com)") {
+		t.Errorf("result = %+v, want denied host", result)
+	}
+
+	result = httpGet(t, "file:///etc/passwd", cfg)
+	if !result.IsError || !strings.Contains(result.Content, "not an absolute http or https URL") {
+		t.Errorf("result = %+v, want rejected scheme", result)
+	}
+
+	result = httpGet(t, server.URL+"/away", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}})
+	if !result.IsError || !strings.Contains(result.Content, `redirect to host "elsewhere.example" is not allowed`) {
+		t.Errorf("result = %+v, want denied redirect", result)
+	}
+}
+
+func TestHTTPGet_ContentTypeFilter(t *testing.T) {
+	server := startHTTPGetServer(t)
+
+	result := httpGet(t, server.URL+"/image", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}})
+	if !result.IsError || !strings.Contains(result.Content, `content type "image/png" is not allowed`) {
+		t.Errorf("result = %+v, want content type rejected by default", result)
+	}
+
+	result = httpGet(t, server.URL+"/api", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}, ContentTypes: []string{"text/*"}})
+	if !result.IsError || !strings.Contains(result.Content, `content type "application/json" is not allowed (allowed: text/*)`) {
+		t.Errorf("result = %+v, want content type rejected by config", result)
+	}
+}
+
+func TestHTTPGet_SizeCapStatusAndTimeout(t *testing.T) {
+	server := startHTTPGetServer(t)
+
+	result := httpGet(t, server.URL+"/big", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}, MaxBytes: 100})
+	if result.IsError || !strings.HasSuffix(result.Content, "\n\n"+strings.Repeat("a", 100)+"\n[response truncated]") {
+		t.Errorf("result = %+v, want body capped at 100 bytes", result)
+	}
+
+	// Cuts, at max_bytes and at the text limit, never split a character
+	for _, maxBytes := range []int{100, 0} {
+		result = httpGet(t, server.URL+"/wide", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}, MaxBytes: maxBytes})
+		body := strings.TrimSuffix(result.Content[strings.Index(result.Content, "\n\n")+2:], "\n[response truncated]")
+		if result.IsError || !utf8.ValidString(body) || len(body) > maxHTTPTextBytes || !strings.HasSuffix(result.Content, "[response truncated]") {
+			t.Errorf("max_bytes %d: body of %d bytes, valid UTF-8 %v, want a truncated valid body", maxBytes, len(body), utf8.ValidString(body))
+		}
+	}
+
+	result = httpGet(t, server.URL+"/missing", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}})
+	if !result.IsError || !strings.HasPrefix(result.Content, "status: 404 Not Found") || !strings.Contains(result.Content, "no such page") {
+		t.Errorf("result = %+v, want 404 reported as error", result)
+	}
+
+	result = httpGet(t, server.URL+"/slow", agent.HTTPGetConfig{Allow: []string{"127.0.0.1"}, Timeout: 1})
+	if !result.IsError || !strings.Contains(result.Content, "timed out after 1s") {
+		t.Errorf("result = %+v, want timeout", result)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	tests := []struct {
+		url   string
+		allow []string
+		want  bool
+	}{
+		{"https://docs.example.com/a", []string{"docs.example.com"}, true},
+		{"https://DOCS.example.com./a", []string{"docs.example.com"}, true},
+		{"https://example.com/", []string{"docs.example.com"}, false},
+		{"https://api.github.com/", []string{"*.github.com"}, true},
+		{"https://github.com/", []string{"*.github.com"}, false},
+		{"https://evilgithub.com/", []string{"*.github.com"}, false},
+		{"http://localhost:8080/", []string{"localhost:8080"}, true},
+		{"http://localhost:9090/", []string{"localhost:8080"}, false},
+		{"https://example.com/", []string{"example.com:443"}, true},
+		{"http://[::1]:8080/", []string{"::1"}, true},
+		{"http://[::1]:8080/", []string{"[::1]:8080"}, true},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := hostAllowed(tt.allow, u); got != tt.want {
+			t.Errorf("hostAllowed(%v, %s) = %v, want %v", tt.allow, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<p>a</p><p>b</p>", "a\n\nb"},
+		{"x <b>bold</b>  and\n  <a href=\"/y?a>b\">link</a>", "x bold and link"},
+		{"1 < 2 &lt;tag&gt;", "1 < 2 <tag>"},
+		{"<!-- hidden --><div>shown</div><noscript>no</noscript>", "shown"},
+		{"<table><tr><td>a</td><td>b</td></tr></table>", "a b"},
+	}
+	for _, tt := range tests {
+		if got := htmlToText(tt.in); got != tt.want {
+			t.Errorf("htmlToText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/mcp"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// MCPTools are the tools of the MCP servers an agent declares, connected
+// for the duration of one agent run. A nil *MCPTools has no tools.
+type MCPTools struct {
+	clients []*mcp.Client
+	owners  map[string]*mcp.Client // tool name -> server offering it
+	defs    []provider.Tool
+}
+
+// ResolveMCPServers combines an agent's mcp_servers with the servers of the
+// same name in config.toml. It returns an error if a server ends up with
+// neither a command nor a URL.
+func ResolveMCPServers(servers map[string]agent.MCPServerConfig, globalCfg *config.GlobalConfig) (map[string]config.MCPServerConfig, error) {
+	if globalCfg == nil {
+		globalCfg = &config.GlobalConfig{}
+	}
+	resolved := make(map[string]config.MCPServerConfig, len(servers))
+	for name, server := range servers {
+		cfg := globalCfg.ResolveMCPServer(name, config.MCPServerConfig(server))
+		if cfg.Command == "" && cfg.URL == "" {
+			return nil, fmt.Errorf("mcp server %q: set command or url, in the agent or in config.toml", name)
+		}
+		resolved[name] = cfg
+	}
+	return resolved, nil
+}
+
+// ConnectMCP connects to each server, in name order, and adds its tools to
+// registry. Tool names must be unique across servers and must not shadow
+// the agent's other tools. Call arguments are checked against each tool's
+// input schema before they are sent. On error, servers already connected
+// are closed.
+func ConnectMCP(ctx context.Context, servers map[string]config.MCPServerConfig, registry *Registry) (*MCPTools, error) {
+	if len(servers) == 0 {
+		return nil, nil
+	}
+
+	taken := map[string]string{}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m := &MCPTools{owners: map[string]*mcp.Client{}}
+	for _, name := range names {
+		client, err := mcp.Connect(ctx, name, servers[name])
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("mcp server %q: %w", name, err)
+		}
+		m.clients = append(m.clients, client)
+
+		tools, err := client.ListTools(ctx)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("mcp server %q: %w", name, err)
+		}
+		for _, t := range tools {
+			other, ok := taken[t.Name]
+			if registry.Has(t.Name) {
+				other, ok = "a built-in tool or plugin", true
+			}
+			if ok {
+				m.Close()
+				return nil, fmt.Errorf("mcp server %q: tool %q conflicts with %s", name, t.Name, other)
+			}
+			taken[t.Name] = fmt.Sprintf("mcp server %q", name)
+			m.owners[t.Name] = client
+			schema := t.InputSchema
+			if len(schema) == 0 {
+				schema = json.RawMessage(`{"type": "object", "properties": {}}`)
+			}
+			m.defs = append(m.defs, provider.Tool{Name: t.Name, Description: t.Description, InputSchema: schema})
+		}
+	}
+
+	for _, def := range m.defs {
+		err := registry.Register(def, func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+			if result, ok := CheckArguments(def, call); !ok {
+				return result
+			}
+			return m.call(ctx, call)
+		})
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("mcp server %q: %w", m.owners[def.Name].Name(), err)
+		}
+	}
+	return m, nil
+}
+
+// Definitions returns the tool definitions of all connected servers.
+func (m *MCPTools) Definitions() []provider.Tool {
+	if m == nil {
+		return nil
+	}
+	return m.defs
+}
+
+// Close disconnects from every server.
+func (m *MCPTools) Close() {
+	if m == nil {
+		return
+	}
+	for _, c := range m.clients {
+		c.Close()
+	}
+}
+
+// call routes a tool call to the server offering the tool. Transport and
+// protocol failures are returned as error results.
+func (m *MCPTools) call(ctx context.Context, call provider.ToolCall) provider.ToolResult {
+	client := m.owners[call.Name]
+	result, err := client.CallTool(ctx, call.Name, call.Arguments)
+	if err != nil {
+		return toolError(call.ID, call.Name, fmt.Sprintf("mcp server %q: %s", client.Name(), err))
+	}
+	return provider.ToolResult{CallID: call.ID, Content: result.Content, IsError: result.IsError}
+}
@@ -0,0 +1,141 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// helper: start an MCP server over HTTP offering the named tools. Each call
+// returns "<tool>: <arguments>"; a tool named "broken" reports an error.
+func startMCPServer(t *testing.T, tools ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"`
+			} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		if len(msg.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			result = map[string]interface{}{"protocolVersion": "2025-06-18", "capabilities": map[string]interface{}{}, "serverInfo": map[string]string{"name": "test", "version": "1"}}
+		case "tools/list":
+			var list []map[string]interface{}
+			for _, name := range tools {
+				list = append(list, map[string]interface{}{"name": name, "description": "MCP tool " + name})
+			}
+			result = map[string]interface{}{"tools": list}
+		case "tools/call":
+			result = map[string]interface{}{
+				"content": []map[string]string{{"type": "text", "text": msg.Params.Name + ": " + string(msg.Params.Arguments)}},
+				"isError": msg.Params.Name == "broken",
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveMCPServers(t *testing.T) {
+	globalCfg := &config.GlobalConfig{MCPServers: map[string]config.MCPServerConfig{
+		"docs": {URL: "http://localhost:9000/mcp", Timeout: 5},
+	}}
+
+	resolved, err := ResolveMCPServers(map[string]agent.MCPServerConfig{"docs": {}}, globalCfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved["docs"].URL != "http://localhost:9000/mcp" || resolved["docs"].Timeout != 5 {
+		t.Errorf("resolved = %+v", resolved["docs"])
+	}
+
+	_, err = ResolveMCPServers(map[string]agent.MCPServerConfig{"missing": {}}, globalCfg)
+	if err == nil || !strings.Contains(err.Error(), `mcp server "missing": set command or url`) {
+		t.Errorf("err = %v, want missing transport error", err)
+	}
+}
+
+func TestConnectMCP_ExecuteRoutesCalls(t *testing.T) {
+	a := startMCPServer(t, "search", "broken")
+	b := startMCPServer(t, "fetch")
+
+	registry := testRegistry(t, ReadFileToolName)
+	m, err := ConnectMCP(context.Background(), map[string]config.MCPServerConfig{
+		"b": {URL: b.URL},
+		"a": {URL: a.URL},
+	}, registry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Close()
+
+	if defs := registry.Definitions(); len(defs) != 4 || defs[0].Name != ReadFileToolName || defs[1].Name != "search" {
+		t.Errorf("registry Definitions = %+v, want read_file then the MCP tools", defs)
+	}
+	defs := m.Definitions()
+	if len(defs) != 3 || defs[0].Name != "search" || defs[2].Name != "fetch" {
+		t.Fatalf("Definitions = %+v, want search, broken, fetch", defs)
+	}
+	if schema := decodeSchema(t, defs[2].InputSchema); schema.Type != "object" {
+		t.Errorf("missing inputSchema should default to an object schema, got %q", schema.Type)
+	}
+
+	result := registry.Execute(context.Background(), provider.ToolCall{ID: "m1", Name: "fetch", Arguments: json.RawMessage(`{"url":"x"}`)}, ExecuteOptions{})
+	if result.IsError || result.Content != `fetch: {"url":"x"}` || result.CallID != "m1" {
+		t.Errorf("fetch result = %+v", result)
+	}
+
+	result = registry.Execute(context.Background(), provider.ToolCall{ID: "m2", Name: "broken", Arguments: json.RawMessage(`{}`)}, ExecuteOptions{})
+	if !result.IsError || result.Content != "broken: {}" {
+		t.Errorf("broken result = %+v, want server error passed through", result)
+	}
+
+	result = registry.Execute(context.Background(), provider.ToolCall{ID: "m3", Name: "fetch", Arguments: json.RawMessage(`["x"]`)}, ExecuteOptions{})
+	if !result.IsError || !strings.HasPrefix(result.Content, "fetch error: invalid arguments:") {
+		t.Errorf("invalid arguments result = %+v, want a schema error without calling the server", result)
+	}
+}
+
+func TestConnectMCP_NameConflicts(t *testing.T) {
+	a := startMCPServer(t, "read_file")
+	_, err := ConnectMCP(context.Background(), map[string]config.MCPServerConfig{"a": {URL: a.URL}}, testRegistry(t, ReadFileToolName))
+	if err == nil || !strings.Contains(err.Error(), `tool "read_file" conflicts with a built-in tool or plugin`) {
+		t.Errorf("err = %v, want conflict with built-in", err)
+	}
+
+	b := startMCPServer(t, "search")
+	c := startMCPServer(t, "search")
+	_, err = ConnectMCP(context.Background(), map[string]config.MCPServerConfig{"b": {URL: b.URL}, "c": {URL: c.URL}}, NewRegistry())
+	if err == nil || !strings.Contains(err.Error(), `mcp server "c": tool "search" conflicts with mcp server "b"`) {
+		t.Errorf("err = %v, want conflict between servers", err)
+	}
+}
+
+func TestConnectMCP_ServerUnavailable(t *testing.T) {
+	server := startMCPServer(t, "search")
+	server.Close()
+
+	_, err := ConnectMCP(context.Background(), map[string]config.MCPServerConfig{"gone": {URL: server.URL}}, NewRegistry())
+	if err == nil || !strings.Contains(err.Error(), `mcp server "gone": initialize failed`) {
+		t.Errorf("err = %v, want initialize failure", err)
+	}
+}
@@ -0,0 +1,286 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jrswab/axe/internal/provider"
+	"github.com/jrswab/axe/internal/resolve"
+	"github.com/jrswab/axe/internal/xdg"
+)
+
+// defaultPluginTimeout applies to plugin calls whose manifest sets no timeout.
+const defaultPluginTimeout = 30 * time.Second
+
+// pluginDescribeTimeout limits how long an executable plugin may take to
+// print its definition.
+const pluginDescribeTimeout = 10 * time.Second
+
+// pluginNamePattern is the set of tool names every provider accepts.
+var pluginNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Plugin is an external tool installed in $XDG_CONFIG_HOME/axe/tools/.
+//
+// A plugin is either a manifest, <name>.toml, that points at a command, or a
+// bare executable, <name>, that prints its definition as JSON when run with
+// the single argument --describe. Either way, each call runs the command
+// with the tool arguments as a JSON object on stdin; stdout becomes the tool
+// result. A non-zero exit status or a timeout is reported as an error result.
+// Like run_command, plugins only inherit the base environment variables
+// plus those listed in Env, so they do not see API keys by default.
+type Plugin struct {
+	Name        string
+	Description string
+	Schema      json.RawMessage
+	Command     string
+	Args        []string
+	Env         []string // Extra environment variables passed through
+	Timeout     time.Duration
+}
+
+// pluginManifest is the TOML form of a plugin manifest.
+type pluginManifest struct {
+	Name        string                 `toml:"name"`
+	Description string                 `toml:"description"`
+	Command     string                 `toml:"command"`
+	Args        []string               `toml:"args"`
+	Env         []string               `toml:"env"`
+	Timeout     int                    `toml:"timeout"`
+	Parameters  map[string]interface{} `toml:"parameters"`
+}
+
+// pluginDescription is the JSON an executable plugin prints for --describe.
+type pluginDescription struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// PluginDir returns the directory plugins are installed in:
+// <xdg-config-dir>/tools. It does not create the directory.
+func PluginDir() (string, error) {
+	configDir, err := xdg.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "tools"), nil
+}
+
+// LoadPlugin loads the plugin with the given name from PluginDir. A
+// manifest takes precedence over an executable of the same name.
+func LoadPlugin(name string) (*Plugin, error) {
+	if !pluginNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid tool name %q", name)
+	}
+
+	dir, err := PluginDir()
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath := filepath.Join(dir, name+".toml")
+	if _, err := os.Stat(manifestPath); err == nil {
+		return loadManifest(manifestPath, name)
+	}
+
+	exePath := filepath.Join(dir, name)
+	info, err := os.Stat(exePath)
+	if err != nil || info.IsDir() {
+		return nil, fmt.Errorf("unknown tool %q", name)
+	}
+	if info.Mode()&0111 == 0 {
+		return nil, fmt.Errorf("tool plugin %q is not executable: %s", name, exePath)
+	}
+	return describeExecutable(exePath, name)
+}
+
+// loadManifest reads a plugin manifest. The [parameters] table is the JSON
+// Schema of the tool's arguments, written in TOML.
+func loadManifest(path, name string) (*Plugin, error) {
+	var m pluginManifest
+	if _, err := toml.DecodeFile(path, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse tool plugin %q: %w", name, err)
+	}
+
+	if m.Name != "" && m.Name != name {
+		return nil, fmt.Errorf("tool plugin %q: manifest name %q does not match file name", name, m.Name)
+	}
+	if strings.TrimSpace(m.Command) == "" {
+		return nil, fmt.Errorf("tool plugin %q: missing required field: command", name)
+	}
+	if m.Timeout < 0 {
+		return nil, fmt.Errorf("tool plugin %q: timeout must be non-negative", name)
+	}
+
+	// A command naming a file in the tools directory runs that file; any
+	// other bare name is looked up on PATH when the plugin runs.
+	command := m.Command
+	if local := filepath.Join(filepath.Dir(path), command); !filepath.IsAbs(command) && (strings.ContainsRune(command, '/') || fileExists(local)) {
+		command = local
+	}
+
+	var schema json.RawMessage
+	if m.Parameters != nil {
+		data, err := json.Marshal(m.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("tool plugin %q: invalid parameters: %w", name, err)
+		}
+		schema = data
+	}
+
+	p := &Plugin{
+		Name:        name,
+		Description: m.Description,
+		Schema:      schema,
+		Command:     command,
+		Args:        m.Args,
+		Env:         m.Env,
+		Timeout:     time.Duration(m.Timeout) * time.Second,
+	}
+	return p, p.validate()
+}
+
+// describeExecutable runs an executable plugin with --describe and parses
+// the definition it prints.
+func describeExecutable(path, name string) (*Plugin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginDescribeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, "--describe")
+	cmd.Env = commandEnv(nil)
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("tool plugin %q: --describe failed: %w", name, err)
+	}
+
+	var d pluginDescription
+	if err := json.Unmarshal(out, &d); err != nil {
+		return nil, fmt.Errorf("tool plugin %q: --describe output is not valid JSON: %w", name, err)
+	}
+	if d.Name != "" && d.Name != name {
+		return nil, fmt.Errorf("tool plugin %q: described name %q does not match file name", name, d.Name)
+	}
+
+	p := &Plugin{
+		Name:        name,
+		Description: d.Description,
+		Schema:      d.Parameters,
+		Command:     path,
+	}
+	return p, p.validate()
+}
+
+// validate checks that the plugin's parameter schema describes an object.
+// A plugin without parameters gets a schema accepting an empty object.
+func (p *Plugin) validate() error {
+	if len(bytes.TrimSpace(p.Schema)) == 0 || string(bytes.TrimSpace(p.Schema)) == "null" {
+		p.Schema = json.RawMessage(`{"type": "object", "properties": {}}`)
+		return nil
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(p.Schema, &schema); err != nil {
+		return fmt.Errorf("tool plugin %q: parameters must be a JSON Schema object", p.Name)
+	}
+	if t, ok := schema["type"]; ok && t != "object" {
+		return fmt.Errorf("tool plugin %q: parameters must describe an object, got type %v", p.Name, t)
+	}
+	return nil
+}
+
+// Tool returns the plugin's tool definition.
+func (p *Plugin) Tool() provider.Tool {
+	return provider.Tool{
+		Name:        p.Name,
+		Description: p.Description,
+		InputSchema: p.Schema,
+	}
+}
+
+// Run executes the plugin for call in workdir. It always returns a
+// ToolResult; failures are reported with IsError set.
+func (p *Plugin) Run(ctx context.Context, call provider.ToolCall, workdir string) provider.ToolResult {
+	call.Arguments = objectArgs(call.Arguments)
+	if result, ok := CheckArguments(p.Tool(), call); !ok {
+		return result
+	}
+
+	dir, err := resolve.Path(workdir, ".")
+	if err != nil {
+		return toolError(call.ID, p.Name, err.Error())
+	}
+
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = defaultPluginTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{max: defaultCommandOutputBytes}
+	stderr := &cappedBuffer{max: defaultCommandOutputBytes}
+
+	cmd := exec.CommandContext(runCtx, p.Command, p.Args...)
+	cmd.Dir = dir
+	cmd.Env = append(commandEnv(p.Env), "AXE_WORKDIR="+dir, "AXE_TOOL_CALL_ID="+call.ID)
+	cmd.Stdin = bytes.NewReader(call.Arguments)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Do not wait forever for children that inherited the output pipes
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if runCtx.Err() == context.DeadlineExceeded {
+		return toolError(call.ID, p.Name, fmt.Sprintf("timed out after %s", timeout))
+	}
+	if err != nil {
+		msg := err.Error()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			msg = fmt.Sprintf("exit status %d", exitErr.ExitCode())
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			msg += ": " + detail
+		} else if detail := strings.TrimSpace(stdout.String()); detail != "" {
+			msg += ": " + detail
+		}
+		return toolError(call.ID, p.Name, msg)
+	}
+
+	return provider.ToolResult{CallID: call.ID, Content: stdout.String()}
+}
+
+// toolError creates an error ToolResult for a failed external tool call, worded
+// like the sub-agent errorResult so the model handles both the same way.
+func toolError(callID, name, errMsg string) provider.ToolResult {
+	return provider.ToolResult{
+		CallID:  callID,
+		Content: fmt.Sprintf("Error: tool %q failed - %s. You may retry or proceed without this result.", name, errMsg),
+		IsError: true,
+	}
+}
+
+// objectArgs returns the call arguments, using "{}" when the model sent none.
+func objectArgs(args json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(args)) == 0 {
+		return json.RawMessage("{}")
+	}
+	return args
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,202 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// helper: point XDG_CONFIG_HOME at a temp dir and return its tools directory.
+func setupPluginDir(t *testing.T) string {
+	t.Helper()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	dir := filepath.Join(configHome, "axe", "tools")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// helper: write an executable sh script into dir.
+func writeScript(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const wordCountManifest = `description = "Count the words in a string"
+command = "wc.sh"
+timeout = 5
+
+[parameters]
+type = "object"
+required = ["text"]
+
+[parameters.properties.text]
+type = "string"
+`
+
+func pluginCall(name, args string) provider.ToolCall {
+	return provider.ToolCall{ID: "plug-1", Name: name, Arguments: json.RawMessage(args)}
+}
+
+func TestLoadPlugin_Manifest(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	os.WriteFile(filepath.Join(dir, "word_count.toml"), []byte(wordCountManifest), 0644)
+	writeScript(t, dir, "wc.sh", "wc -w\n")
+
+	p, err := LoadPlugin("word_count")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Command != filepath.Join(dir, "wc.sh") {
+		t.Errorf("Command = %q, want resolved against the tools directory", p.Command)
+	}
+
+	def := p.Tool()
+	if def.Name != "word_count" || def.Description != "Count the words in a string" {
+		t.Errorf("Tool = %+v", def)
+	}
+	schema := decodeSchema(t, def.InputSchema)
+	if schema.Type != "object" || len(schema.Required) != 1 || schema.Required[0] != "text" {
+		t.Errorf("schema = %+v, want object requiring text", schema)
+	}
+}
+
+func TestLoadPlugin_Executable(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	writeScript(t, dir, "echo_args", `if [ "$1" = "--describe" ]; then
+  echo '{"name": "echo_args", "description": "Echo the arguments", "parameters": {"type": "object", "properties": {"msg": {"type": "string"}}}}'
+  exit 0
+fi
+cat
+`)
+
+	p, err := LoadPlugin("echo_args")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Description != "Echo the arguments" || decodeSchema(t, p.Schema).Type != "object" {
+		t.Errorf("plugin = %+v", p)
+	}
+}
+
+func TestLoadPlugin_Errors(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	os.WriteFile(filepath.Join(dir, "no_command.toml"), []byte(`description = "x"`), 0644)
+	os.WriteFile(filepath.Join(dir, "renamed.toml"), []byte("name = \"other\"\ncommand = \"x\""), 0644)
+	os.WriteFile(filepath.Join(dir, "not_exec"), []byte("#!/bin/sh\n"), 0644)
+	writeScript(t, dir, "bad_describe", "echo not json\n")
+	os.WriteFile(filepath.Join(dir, "array_params.toml"), []byte("command = \"x\"\n[parameters]\ntype = \"array\""), 0644)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"missing", `unknown tool "missing"`},
+		{"../escape", `invalid tool name "../escape"`},
+		{"no_command", "missing required field: command"},
+		{"renamed", `manifest name "other" does not match`},
+		{"not_exec", "is not executable"},
+		{"bad_describe", "--describe output is not valid JSON"},
+		{"array_params", "parameters must describe an object"},
+	}
+	for _, tt := range tests {
+		_, err := LoadPlugin(tt.name)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadPlugin(%q) err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRegistry_EnablePlugin(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	os.WriteFile(filepath.Join(dir, "word_count.toml"), []byte(wordCountManifest), 0644)
+
+	defs := testRegistry(t, ReadFileToolName, "word_count").Definitions()
+	if len(defs) != 2 || defs[1].Name != "word_count" {
+		t.Errorf("Definitions = %+v, want read_file then word_count", defs)
+	}
+}
+
+func TestExecute_Plugin(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	os.WriteFile(filepath.Join(dir, "word_count.toml"), []byte(wordCountManifest), 0644)
+	writeScript(t, dir, "wc.sh", `input=$(cat); echo "stdin=$input cwd=$(pwd) workdir=$AXE_WORKDIR"`)
+	workdir := t.TempDir()
+
+	result := testRegistry(t, "word_count").Execute(context.Background(), pluginCall("word_count", `{"text": "a b c"}`), fsOpts(workdir))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	want := `stdin={"text": "a b c"} cwd=` + workdir + " workdir=" + workdir + "\n"
+	if result.Content != want || result.CallID != "plug-1" {
+		t.Errorf("result = %+v, want Content %q", result, want)
+	}
+}
+
+func TestExecute_PluginEnv(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	os.WriteFile(filepath.Join(dir, "show_env.toml"), []byte("command = \"env.sh\"\nenv = [\"PLUGIN_TOKEN\"]"), 0644)
+	writeScript(t, dir, "env.sh", `echo "key=$ANTHROPIC_API_KEY token=$PLUGIN_TOKEN call=$AXE_TOOL_CALL_ID"`)
+	t.Setenv("ANTHROPIC_API_KEY", "sk-secret")
+	t.Setenv("PLUGIN_TOKEN", "tok")
+
+	result := testRegistry(t, "show_env").Execute(context.Background(), pluginCall("show_env", `{}`), fsOpts(t.TempDir()))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	if want := "key= token=tok call=plug-1\n"; result.Content != want {
+		t.Errorf("Content = %q, want %q", result.Content, want)
+	}
+}
+
+func TestExecute_PluginErrors(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	os.WriteFile(filepath.Join(dir, "fails.toml"), []byte(`command = "fails.sh"`), 0644)
+	writeScript(t, dir, "fails.sh", "echo boom >&2; exit 2\n")
+	os.WriteFile(filepath.Join(dir, "slow.toml"), []byte("command = \"slow.sh\"\ntimeout = 1"), 0644)
+	writeScript(t, dir, "slow.sh", "sleep 10\n")
+	os.WriteFile(filepath.Join(dir, "word_count.toml"), []byte(wordCountManifest), 0644)
+
+	tests := []struct {
+		tool string
+		args string
+		want string
+	}{
+		{"fails", `{}`, `Error: tool "fails" failed - exit status 2: boom. You may retry or proceed without this result.`},
+		{"slow", `{}`, "timed out after 1s"},
+		{"word_count", `{"text": 3}`, "word_count error: invalid arguments"},
+	}
+	for _, tt := range tests {
+		result := testRegistry(t, tt.tool).Execute(context.Background(), pluginCall(tt.tool, tt.args), fsOpts(t.TempDir()))
+		if !result.IsError || !strings.Contains(result.Content, tt.want) {
+			t.Errorf("%s: result = %+v, want error containing %q", tt.tool, result, tt.want)
+		}
+	}
+}
+
+func TestExecute_PluginNotEnabled(t *testing.T) {
+	skipWithoutSh(t)
+	dir := setupPluginDir(t)
+	os.WriteFile(filepath.Join(dir, "word_count.toml"), []byte(wordCountManifest), 0644)
+
+	result := testRegistry(t).Execute(context.Background(), pluginCall("word_count", `{"text": "x"}`), fsOpts(t.TempDir()))
+	if !result.IsError || result.Content != `Unknown tool: "word_count"` {
+		t.Errorf("result = %+v, want unknown tool error", result)
+	}
+}
@@ -0,0 +1,134 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// Executor runs one tool call. It never returns an error: failures are
+// reported in the result with IsError set.
+type Executor func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult
+
+// builtinTool is a tool that agents can enable by name in their TOML.
+type builtinTool struct {
+	def  provider.Tool
+	exec Executor
+}
+
+// builtins are the built-in tools, keyed by name. Each tool adds itself
+// with registerBuiltin from an init function in its own file.
+var builtins = map[string]builtinTool{}
+
+// registerBuiltin makes a tool available to agents under def.Name. It
+// panics if the name is taken, since that is a programming error.
+func registerBuiltin(def provider.Tool, exec Executor) {
+	if _, ok := builtins[def.Name]; ok {
+		panic(fmt.Sprintf("tool: built-in %q registered twice", def.Name))
+	}
+	builtins[def.Name] = builtinTool{def: def, exec: exec}
+}
+
+// Registry is the set of tools one agent may call: a definition to offer the
+// model and an executor for each. Top-level runs and sub-agent runs both
+// dispatch tool calls through their agent's Registry. A nil *Registry has no
+// tools.
+type Registry struct {
+	defs  []provider.Tool
+	execs map[string]Executor
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{execs: map[string]Executor{}}
+}
+
+// ForAgent returns the Registry of the tools cfg enables: call_agent, run by
+// callAgent, when the agent has sub-agents and callAgent is not nil, then the
+// tools named in cfg.Tools. The caller passes a nil callAgent once the
+// sub-agent depth limit is reached. MCP tools are added by ConnectMCP.
+func ForAgent(cfg *agent.AgentConfig, callAgent Executor) (*Registry, error) {
+	r := NewRegistry()
+	if len(cfg.SubAgents) > 0 && callAgent != nil {
+		if err := r.Register(CallAgentTool(cfg.SubAgents), callAgent); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.Enable(cfg.Tools); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register adds a tool. It returns an error if a tool of the same name is
+// already registered.
+func (r *Registry) Register(def provider.Tool, exec Executor) error {
+	if _, ok := r.execs[def.Name]; ok {
+		return fmt.Errorf("tool %q is already registered", def.Name)
+	}
+	r.defs = append(r.defs, def)
+	r.execs[def.Name] = exec
+	return nil
+}
+
+// Enable registers the named tools, in order. Names that are not built-in
+// tools are loaded as plugins from PluginDir; it returns an error if a name
+// is neither.
+func (r *Registry) Enable(names []string) error {
+	for _, name := range names {
+		if b, ok := builtins[name]; ok {
+			if err := r.Register(b.def, b.exec); err != nil {
+				return err
+			}
+			continue
+		}
+		plugin, err := LoadPlugin(name)
+		if err != nil {
+			return err
+		}
+		err = r.Register(plugin.Tool(), func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+			return plugin.Run(ctx, call, opts.Workdir)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Has reports whether the named tool is registered.
+func (r *Registry) Has(name string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.execs[name]
+	return ok
+}
+
+// Definitions returns the definitions of the registered tools, in the order
+// they were registered.
+func (r *Registry) Definitions() []provider.Tool {
+	if r == nil {
+		return nil
+	}
+	return append([]provider.Tool(nil), r.defs...)
+}
+
+// Execute dispatches a tool call to the executor registered for its name.
+// Calls to any other tool return an error result.
+func (r *Registry) Execute(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+	if !r.Has(call.Name) {
+		return provider.ToolResult{
+			CallID:  call.ID,
+			Content: fmt.Sprintf("Unknown tool: %q", call.Name),
+			IsError: true,
+		}
+	}
+	// call_agent logs its own progress
+	if call.Name != CallAgentToolName && opts.Verbose && opts.Stderr != nil {
+		fmt.Fprintf(opts.Stderr, "[tool] %s %s\n", call.Name, call.Arguments)
+	}
+	return r.execs[call.Name](ctx, call, opts)
+}
@@ -0,0 +1,92 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jrswab/axe/internal/agent"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// testRegistry returns a Registry with the named tools enabled.
+func testRegistry(t *testing.T, names ...string) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	if err := r.Enable(names); err != nil {
+		t.Fatalf("Enable(%v): %v", names, err)
+	}
+	return r
+}
+
+func TestRegistry_RegisterAndExecute(t *testing.T) {
+	r := NewRegistry()
+	def := provider.Tool{Name: "echo", Description: "Echo", InputSchema: json.RawMessage(`{"type": "object"}`)}
+	err := r.Register(def, func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+		return provider.ToolResult{CallID: call.ID, Content: opts.Workdir + ":" + string(call.Arguments)}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	result := r.Execute(context.Background(), provider.ToolCall{ID: "e1", Name: "echo", Arguments: json.RawMessage(`{}`)}, ExecuteOptions{Workdir: "/w", Verbose: true, Stderr: &stderr})
+	if result.IsError || result.Content != "/w:{}" || result.CallID != "e1" {
+		t.Errorf("result = %+v", result)
+	}
+	if stderr.String() != "[tool] echo {}\n" {
+		t.Errorf("stderr = %q, want verbose log line", stderr.String())
+	}
+
+	if err := r.Register(def, nil); err == nil || err.Error() != `tool "echo" is already registered` {
+		t.Errorf("duplicate Register err = %v", err)
+	}
+}
+
+func TestRegistry_NilHasNoTools(t *testing.T) {
+	var r *Registry
+	if r.Has("echo") || r.Definitions() != nil {
+		t.Error("nil Registry should have no tools")
+	}
+	result := r.Execute(context.Background(), provider.ToolCall{ID: "x", Name: "echo"}, ExecuteOptions{})
+	if !result.IsError || result.Content != `Unknown tool: "echo"` {
+		t.Errorf("result = %+v, want unknown tool error", result)
+	}
+}
+
+func TestForAgent(t *testing.T) {
+	cfg := &agent.AgentConfig{Name: "lead", SubAgents: []string{"helper"}, Tools: []string{ReadFileToolName, ListDirToolName}}
+
+	callAgent := func(ctx context.Context, call provider.ToolCall, opts ExecuteOptions) provider.ToolResult {
+		return provider.ToolResult{CallID: call.ID, Content: "delegated"}
+	}
+
+	r, err := ForAgent(cfg, callAgent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, def := range r.Definitions() {
+		names = append(names, def.Name)
+	}
+	if got := strings.Join(names, ","); got != "call_agent,read_file,list_dir" {
+		t.Errorf("tools = %s, want call_agent,read_file,list_dir", got)
+	}
+	if result := r.Execute(context.Background(), provider.ToolCall{ID: "c1", Name: CallAgentToolName}, ExecuteOptions{}); result.Content != "delegated" {
+		t.Errorf("call_agent result = %+v, want the given executor's", result)
+	}
+
+	r, err = ForAgent(cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Has(CallAgentToolName) {
+		t.Error("call_agent should not be registered without an executor")
+	}
+
+	if _, err := ForAgent(&agent.AgentConfig{Tools: []string{"nope"}}, nil); err == nil {
+		t.Error("expected error for unknown tool")
+	}
+}
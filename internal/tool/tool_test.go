@@ -1,43 +1,11 @@
 package tool
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"net/http/httptest"
-	"os"
-	"path/filepath"
 	"strings"
 	"testing"
-	"time"
-
-	"github.com/jrswab/axe/internal/config"
-	"github.com/jrswab/axe/internal/memory"
-	"github.com/jrswab/axe/internal/provider"
 )
 
-// helper: set up a temp XDG config dir with an agents/ subdirectory
-func setupToolTestAgentsDir(t *testing.T) string {
-	t.Helper()
-	tmpDir := t.TempDir()
-	t.Setenv("XDG_CONFIG_HOME", tmpDir)
-	agentsDir := filepath.Join(tmpDir, "axe", "agents")
-	if err := os.MkdirAll(agentsDir, 0755); err != nil {
-		t.Fatalf("failed to create agents dir: %v", err)
-	}
-	return agentsDir
-}
-
-func writeToolTestAgent(t *testing.T, agentsDir, name, content string) {
-	t.Helper()
-	path := filepath.Join(agentsDir, name+".toml")
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
-		t.Fatalf("failed to write agent file: %v", err)
-	}
-}
-
 func TestCallAgentTool_Definition(t *testing.T) {
 	tool := CallAgentTool([]string{"helper", "runner"})
 
@@ -56,781 +24,87 @@ func TestCallAgentTool_Definition(t *testing.T) {
 		t.Errorf("Description missing agent name 'runner': %q", tool.Description)
 	}
 
-	// Must have exactly three parameters
-	if len(tool.Parameters) != 3 {
-		t.Fatalf("Parameters count = %d, want 3", len(tool.Parameters))
+	schema := decodeSchema(t, tool.InputSchema)
+	if schema.Type != "object" {
+		t.Errorf("schema type = %q, want %q", schema.Type, "object")
 	}
 
-	// Check "agent" parameter
-	agentParam, ok := tool.Parameters["agent"]
+	// Must have exactly three properties
+	if len(schema.Properties) != 3 {
+		t.Fatalf("properties count = %d, want 3", len(schema.Properties))
+	}
+
+	// Check "agent" property
+	agentParam, ok := schema.Properties["agent"]
 	if !ok {
-		t.Fatal("missing 'agent' parameter")
+		t.Fatal("missing 'agent' property")
 	}
 	if agentParam.Type != "string" {
-		t.Errorf("agent.Type = %q, want %q", agentParam.Type, "string")
-	}
-	if !agentParam.Required {
-		t.Error("agent.Required = false, want true")
+		t.Errorf("agent.type = %q, want %q", agentParam.Type, "string")
 	}
 	if !strings.Contains(agentParam.Description, "helper") {
-		t.Errorf("agent.Description missing agent name 'helper': %q", agentParam.Description)
+		t.Errorf("agent.description missing agent name 'helper': %q", agentParam.Description)
 	}
 
-	// Check "task" parameter
-	taskParam, ok := tool.Parameters["task"]
+	// Check "task" property
+	taskParam, ok := schema.Properties["task"]
 	if !ok {
-		t.Fatal("missing 'task' parameter")
+		t.Fatal("missing 'task' property")
 	}
 	if taskParam.Type != "string" {
-		t.Errorf("task.Type = %q, want %q", taskParam.Type, "string")
-	}
-	if !taskParam.Required {
-		t.Error("task.Required = false, want true")
+		t.Errorf("task.type = %q, want %q", taskParam.Type, "string")
 	}
 
-	// Check "context" parameter
-	contextParam, ok := tool.Parameters["context"]
+	// Check "context" property
+	contextParam, ok := schema.Properties["context"]
 	if !ok {
-		t.Fatal("missing 'context' parameter")
+		t.Fatal("missing 'context' property")
 	}
 	if contextParam.Type != "string" {
-		t.Errorf("context.Type = %q, want %q", contextParam.Type, "string")
-	}
-	if contextParam.Required {
-		t.Error("context.Required = true, want false")
-	}
-}
-
-func TestCallAgentTool_EmptyAgents(t *testing.T) {
-	tool := CallAgentTool([]string{})
-
-	if tool.Name != CallAgentToolName {
-		t.Errorf("Name = %q, want %q", tool.Name, CallAgentToolName)
-	}
-
-	// Must still have valid structure with 3 parameters
-	if len(tool.Parameters) != 3 {
-		t.Fatalf("Parameters count = %d, want 3", len(tool.Parameters))
-	}
-
-	if _, ok := tool.Parameters["agent"]; !ok {
-		t.Error("missing 'agent' parameter")
-	}
-	if _, ok := tool.Parameters["task"]; !ok {
-		t.Error("missing 'task' parameter")
-	}
-	if _, ok := tool.Parameters["context"]; !ok {
-		t.Error("missing 'context' parameter")
-	}
-}
-
-// --- Phase 7a: ExecuteCallAgent argument validation tests ---
-
-func TestExecuteCallAgent_EmptyAgentName(t *testing.T) {
-	call := provider.ToolCall{
-		ID:        "test-1",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for empty agent name")
-	}
-	want := `call_agent error: "agent" argument is required`
-	if result.Content != want {
-		t.Errorf("Content = %q, want %q", result.Content, want)
-	}
-	if result.CallID != "test-1" {
-		t.Errorf("CallID = %q, want %q", result.CallID, "test-1")
-	}
-}
-
-func TestExecuteCallAgent_EmptyTask(t *testing.T) {
-	call := provider.ToolCall{
-		ID:        "test-2",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": ""},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for empty task")
-	}
-	want := `call_agent error: "task" argument is required`
-	if result.Content != want {
-		t.Errorf("Content = %q, want %q", result.Content, want)
-	}
-}
-
-func TestExecuteCallAgent_AgentNotAllowed(t *testing.T) {
-	call := provider.ToolCall{
-		ID:        "test-3",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "unknown", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper", "runner"},
-		MaxDepth:      3,
-		Depth:         0,
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for unknown agent")
-	}
-	want := `call_agent error: agent "unknown" is not in this agent's sub_agents list`
-	if result.Content != want {
-		t.Errorf("Content = %q, want %q", result.Content, want)
-	}
-}
-
-func TestExecuteCallAgent_DepthLimitReached(t *testing.T) {
-	call := provider.ToolCall{
-		ID:        "test-4",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         3,
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for depth limit")
-	}
-	want := fmt.Sprintf("call_agent error: maximum sub-agent depth (%d) reached", 3)
-	if result.Content != want {
-		t.Errorf("Content = %q, want %q", result.Content, want)
-	}
-}
-
-// --- Phase 7b: Sub-Agent Loading and Execution tests ---
-
-func TestExecuteCallAgent_AgentNotFound(t *testing.T) {
-	_ = setupToolTestAgentsDir(t)
-
-	call := provider.ToolCall{
-		ID:        "test-5",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "nonexistent", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"nonexistent"},
-		MaxDepth:      3,
-		Depth:         0,
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for missing agent")
-	}
-	if !strings.Contains(result.Content, "failed to load agent") {
-		t.Errorf("Content = %q, want to contain 'failed to load agent'", result.Content)
-	}
-	if result.CallID != "test-5" {
-		t.Errorf("CallID = %q, want %q", result.CallID, "test-5")
-	}
-}
-
-func TestExecuteCallAgent_Success(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	// Start mock provider server that returns a simple text response
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resp := map[string]interface{}{
-			"id":    "msg_123",
-			"type":  "message",
-			"model": "claude-sonnet-4-20250514",
-			"role":  "assistant",
-			"content": []map[string]interface{}{
-				{"type": "text", "text": "Sub-agent result here"},
-			},
-			"stop_reason": "end_turn",
-			"usage": map[string]int{
-				"input_tokens":  10,
-				"output_tokens": 5,
-			},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	// Write sub-agent TOML pointing at our test server
-	toml := fmt.Sprintf(`name = "helper"
-model = "anthropic/claude-sonnet-4-20250514"
-system_prompt = "You are a helper."
-`)
-	writeToolTestAgent(t, agentsDir, "helper", toml)
-
-	// Set API key env var for anthropic
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	// Set base URL to our test server
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-6",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "say hello"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if result.IsError {
-		t.Fatalf("expected IsError=false, got error: %s", result.Content)
-	}
-	if result.Content != "Sub-agent result here" {
-		t.Errorf("Content = %q, want %q", result.Content, "Sub-agent result here")
-	}
-	if result.CallID != "test-6" {
-		t.Errorf("CallID = %q, want %q", result.CallID, "test-6")
-	}
-}
-
-func TestExecuteCallAgent_WithContext(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	var receivedBody map[string]interface{}
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewDecoder(r.Body).Decode(&receivedBody)
-		resp := map[string]interface{}{
-			"id":    "msg_123",
-			"type":  "message",
-			"model": "claude-sonnet-4-20250514",
-			"role":  "assistant",
-			"content": []map[string]interface{}{
-				{"type": "text", "text": "done"},
-			},
-			"stop_reason": "end_turn",
-			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:   "test-7",
-		Name: CallAgentToolName,
-		Arguments: map[string]string{
-			"agent":   "helper",
-			"task":    "analyze code",
-			"context": "The code is in main.go",
-		},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if result.IsError {
-		t.Fatalf("expected IsError=false, got error: %s", result.Content)
-	}
-
-	// Verify the user message contains both task and context
-	messages, ok := receivedBody["messages"].([]interface{})
-	if !ok || len(messages) == 0 {
-		t.Fatal("no messages in request body")
-	}
-	firstMsg, ok := messages[0].(map[string]interface{})
-	if !ok {
-		t.Fatal("first message is not a map")
-	}
-	content, _ := firstMsg["content"].(string)
-	if !strings.Contains(content, "Task: analyze code") {
-		t.Errorf("user message missing task: %q", content)
-	}
-	if !strings.Contains(content, "Context:\nThe code is in main.go") {
-		t.Errorf("user message missing context: %q", content)
-	}
-}
-
-func TestExecuteCallAgent_WithoutContext(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	var receivedBody map[string]interface{}
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewDecoder(r.Body).Decode(&receivedBody)
-		resp := map[string]interface{}{
-			"id":    "msg_123",
-			"type":  "message",
-			"model": "claude-sonnet-4-20250514",
-			"role":  "assistant",
-			"content": []map[string]interface{}{
-				{"type": "text", "text": "done"},
-			},
-			"stop_reason": "end_turn",
-			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-8",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "analyze code"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if result.IsError {
-		t.Fatalf("expected IsError=false, got error: %s", result.Content)
-	}
-
-	// Verify the user message contains only task (no context section)
-	messages, ok := receivedBody["messages"].([]interface{})
-	if !ok || len(messages) == 0 {
-		t.Fatal("no messages in request body")
-	}
-	firstMsg, ok := messages[0].(map[string]interface{})
-	if !ok {
-		t.Fatal("first message is not a map")
-	}
-	content, _ := firstMsg["content"].(string)
-	if !strings.Contains(content, "Task: analyze code") {
-		t.Errorf("user message missing task: %q", content)
-	}
-	if strings.Contains(content, "Context:") {
-		t.Errorf("user message should not contain Context section: %q", content)
-	}
-}
-
-// --- Phase 7c: Error Handling tests ---
-
-func TestExecuteCallAgent_APIError(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"type":"error","error":{"type":"server_error","message":"Internal server error"}}`))
-	}))
-	defer server.Close()
-
-	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-9",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for API error")
-	}
-	if !strings.Contains(result.Content, "sub-agent") {
-		t.Errorf("Content = %q, want to contain 'sub-agent'", result.Content)
-	}
-	if !strings.Contains(result.Content, "You may retry or proceed without this result") {
-		t.Errorf("Content missing retry suggestion: %q", result.Content)
-	}
-}
-
-// TestExecuteCallAgent_DepthLimitNoTools verifies that a sub-agent at the depth limit
-// runs without tools injected, even when the sub-agent has sub_agents configured.
-// This tests Req 10.3: tools are only injected when newDepth < MaxDepth.
-func TestExecuteCallAgent_DepthLimitNoTools(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	var receivedBody string
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		bodyBytes, _ := io.ReadAll(r.Body)
-		receivedBody = string(bodyBytes)
-		resp := map[string]interface{}{
-			"id":    "msg_depth",
-			"type":  "message",
-			"model": "claude-sonnet-4-20250514",
-			"role":  "assistant",
-			"content": []map[string]interface{}{
-				{"type": "text", "text": "depth-limited result"},
-			},
-			"stop_reason": "end_turn",
-			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	// Sub-agent has sub_agents configured, but depth should prevent tool injection
-	writeToolTestAgent(t, agentsDir, "deep-helper", `name = "deep-helper"
-model = "anthropic/claude-sonnet-4-20250514"
-sub_agents = ["another-agent"]
-`)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-depth-tools",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "deep-helper", "task": "do something"},
-	}
-
-	// Depth=2, MaxDepth=3: newDepth will be 3, which is NOT < 3, so no tools should be injected
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"deep-helper"},
-		MaxDepth:      3,
-		Depth:         2,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if result.IsError {
-		t.Fatalf("expected IsError=false, got error: %s", result.Content)
-	}
-	if result.Content != "depth-limited result" {
-		t.Errorf("Content = %q, want %q", result.Content, "depth-limited result")
+		t.Errorf("context.type = %q, want %q", contextParam.Type, "string")
 	}
 
-	// The request sent to the mock server should NOT contain tools
-	if strings.Contains(receivedBody, `"tools"`) {
-		t.Errorf("expected no 'tools' in request body when at depth limit, but found tools in: %s", receivedBody)
+	// agent and task are required, context is optional
+	if strings.Join(schema.Required, ",") != "agent,task" {
+		t.Errorf("required = %v, want [agent task]", schema.Required)
 	}
 }
 
-func TestExecuteCallAgent_Timeout(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(3 * time.Second)
-		resp := map[string]interface{}{
-			"id":          "msg_123",
-			"type":        "message",
-			"model":       "claude-sonnet-4-20250514",
-			"role":        "assistant",
-			"content":     []map[string]interface{}{{"type": "text", "text": "done"}},
-			"stop_reason": "end_turn",
-			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	writeToolTestAgent(t, agentsDir, "helper", `name = "helper"`+"\n"+`model = "anthropic/claude-sonnet-4-20250514"`)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-10",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		Timeout:       1, // 1 second timeout
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for timeout")
-	}
-	if !strings.Contains(result.Content, "sub-agent") {
-		t.Errorf("Content = %q, want to contain 'sub-agent'", result.Content)
-	}
+// testSchema is the subset of a JSON Schema the tool definition tests inspect.
+type testSchema struct {
+	Type       string `json:"type"`
+	Properties map[string]struct {
+		Type        string `json:"type"`
+		Description string `json:"description"`
+	} `json:"properties"`
+	Required []string `json:"required"`
 }
 
-// --- Phase 5a: Sub-Agent Memory Integration tests ---
-
-func TestExecuteCallAgent_MemoryEnabled_LoadsIntoPrompt(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	// Set up XDG_DATA_HOME for memory file
-	dataDir := t.TempDir()
-	t.Setenv("XDG_DATA_HOME", dataDir)
-
-	// Pre-populate the sub-agent's memory file
-	memDir := filepath.Join(dataDir, "axe", "memory")
-	if err := os.MkdirAll(memDir, 0755); err != nil {
-		t.Fatalf("failed to create memory dir: %v", err)
-	}
-	memContent := "## 2026-02-28T10:00:00Z\n**Task:** previous task\n**Result:** previous result\n\n"
-	memPath := filepath.Join(memDir, "helper.md")
-	if err := os.WriteFile(memPath, []byte(memContent), 0644); err != nil {
-		t.Fatalf("failed to write memory file: %v", err)
-	}
-
-	// Mock provider that captures the system prompt from the request body
-	var receivedBody map[string]interface{}
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewDecoder(r.Body).Decode(&receivedBody)
-		resp := map[string]interface{}{
-			"id":    "msg_mem",
-			"type":  "message",
-			"model": "claude-sonnet-4-20250514",
-			"role":  "assistant",
-			"content": []map[string]interface{}{
-				{"type": "text", "text": "done"},
-			},
-			"stop_reason": "end_turn",
-			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	// Write sub-agent config with memory enabled
-	toml := `name = "helper"
-model = "anthropic/claude-sonnet-4-20250514"
-system_prompt = "You are a helper."
-
-[memory]
-enabled = true
-`
-	writeToolTestAgent(t, agentsDir, "helper", toml)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-mem-load",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if result.IsError {
-		t.Fatalf("expected IsError=false, got error: %s", result.Content)
-	}
-
-	// Verify system prompt contains the memory section
-	system, ok := receivedBody["system"].(string)
-	if !ok {
-		t.Fatal("no 'system' field in request body")
-	}
-	if !strings.Contains(system, "## Memory") {
-		t.Errorf("system prompt missing '## Memory' section: %q", system)
-	}
-	if !strings.Contains(system, "previous task") {
-		t.Errorf("system prompt missing memory entry content: %q", system)
-	}
-	if !strings.Contains(system, "previous result") {
-		t.Errorf("system prompt missing memory entry result: %q", system)
+func decodeSchema(t *testing.T, raw json.RawMessage) testSchema {
+	t.Helper()
+	var s testSchema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		t.Fatalf("InputSchema is not valid JSON: %v", err)
 	}
+	return s
 }
 
-func TestExecuteCallAgent_MemoryEnabled_AppendsEntry(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	// Set up XDG_DATA_HOME for memory file
-	dataDir := t.TempDir()
-	t.Setenv("XDG_DATA_HOME", dataDir)
-
-	// Override Now for deterministic timestamps
-	origNow := memory.Now
-	memory.Now = func() time.Time {
-		return time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC)
-	}
-	defer func() { memory.Now = origNow }()
-
-	// Mock provider returning a known response
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resp := map[string]interface{}{
-			"id":    "msg_append",
-			"type":  "message",
-			"model": "claude-sonnet-4-20250514",
-			"role":  "assistant",
-			"content": []map[string]interface{}{
-				{"type": "text", "text": "Sub-agent completed the task"},
-			},
-			"stop_reason": "end_turn",
-			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	// Write sub-agent config with memory enabled
-	toml := `name = "helper"
-model = "anthropic/claude-sonnet-4-20250514"
-system_prompt = "You are a helper."
-
-[memory]
-enabled = true
-`
-	writeToolTestAgent(t, agentsDir, "helper", toml)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-mem-append",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "do the thing"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if result.IsError {
-		t.Fatalf("expected IsError=false, got error: %s", result.Content)
-	}
+func TestCallAgentTool_EmptyAgents(t *testing.T) {
+	tool := CallAgentTool([]string{})
 
-	// Verify memory file was created with the correct entry
-	memPath := filepath.Join(dataDir, "axe", "memory", "helper.md")
-	data, err := os.ReadFile(memPath)
-	if err != nil {
-		t.Fatalf("failed to read memory file: %v", err)
+	if tool.Name != CallAgentToolName {
+		t.Errorf("Name = %q, want %q", tool.Name, CallAgentToolName)
 	}
-	content := string(data)
 
-	if !strings.Contains(content, "## 2026-02-28T12:00:00Z") {
-		t.Errorf("memory file missing timestamp: %q", content)
-	}
-	if !strings.Contains(content, "**Task:** Task: do the thing") {
-		t.Errorf("memory file missing task: %q", content)
-	}
-	if !strings.Contains(content, "**Result:** Sub-agent completed the task") {
-		t.Errorf("memory file missing result: %q", content)
+	// Must still have valid structure with 3 properties
+	schema := decodeSchema(t, tool.InputSchema)
+	if len(schema.Properties) != 3 {
+		t.Fatalf("properties count = %d, want 3", len(schema.Properties))
 	}
-}
-
-func TestExecuteCallAgent_MemoryDisabled_NoFileCreated(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	// Set up XDG_DATA_HOME for memory file
-	dataDir := t.TempDir()
-	t.Setenv("XDG_DATA_HOME", dataDir)
 
-	// Mock provider
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resp := map[string]interface{}{
-			"id":    "msg_nomem",
-			"type":  "message",
-			"model": "claude-sonnet-4-20250514",
-			"role":  "assistant",
-			"content": []map[string]interface{}{
-				{"type": "text", "text": "done"},
-			},
-			"stop_reason": "end_turn",
-			"usage":       map[string]int{"input_tokens": 10, "output_tokens": 5},
+	for _, name := range []string{"agent", "task", "context"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("missing %q property", name)
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
-	defer server.Close()
-
-	// Write sub-agent config with memory DISABLED (default)
-	toml := `name = "helper"
-model = "anthropic/claude-sonnet-4-20250514"
-system_prompt = "You are a helper."
-`
-	writeToolTestAgent(t, agentsDir, "helper", toml)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-mem-disabled",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if result.IsError {
-		t.Fatalf("expected IsError=false, got error: %s", result.Content)
-	}
-
-	// Verify no memory file was created
-	memPath := filepath.Join(dataDir, "axe", "memory", "helper.md")
-	if _, err := os.Stat(memPath); err == nil {
-		t.Errorf("expected no memory file at %s, but it exists", memPath)
-	}
-}
-
-func TestExecuteCallAgent_MemoryEnabled_Error_NoEntryAppended(t *testing.T) {
-	agentsDir := setupToolTestAgentsDir(t)
-
-	// Set up XDG_DATA_HOME for memory file
-	dataDir := t.TempDir()
-	t.Setenv("XDG_DATA_HOME", dataDir)
-
-	// Mock provider that returns an error
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"type":"error","error":{"type":"server_error","message":"Internal server error"}}`))
-	}))
-	defer server.Close()
-
-	// Write sub-agent config with memory enabled
-	toml := `name = "helper"
-model = "anthropic/claude-sonnet-4-20250514"
-system_prompt = "You are a helper."
-
-[memory]
-enabled = true
-`
-	writeToolTestAgent(t, agentsDir, "helper", toml)
-	t.Setenv("ANTHROPIC_API_KEY", "test-key")
-	t.Setenv("AXE_ANTHROPIC_BASE_URL", server.URL)
-
-	call := provider.ToolCall{
-		ID:        "test-mem-error",
-		Name:      CallAgentToolName,
-		Arguments: map[string]string{"agent": "helper", "task": "do something"},
-	}
-	opts := ExecuteOptions{
-		AllowedAgents: []string{"helper"},
-		MaxDepth:      3,
-		Depth:         0,
-		GlobalConfig:  &config.GlobalConfig{},
-	}
-	result := ExecuteCallAgent(context.Background(), call, opts)
-	if !result.IsError {
-		t.Fatal("expected IsError=true for API error")
-	}
-
-	// Verify no memory file was created (error should prevent memory append)
-	memPath := filepath.Join(dataDir, "axe", "memory", "helper.md")
-	if _, err := os.Stat(memPath); err == nil {
-		t.Errorf("expected no memory file at %s after error, but it exists", memPath)
 	}
 }
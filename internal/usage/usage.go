@@ -0,0 +1,175 @@
+// Package usage accumulates token usage across a run, including nested
+// sub-agents, and converts it to cost using the configured pricing table.
+package usage
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+// Counts holds token counts for one or more provider responses.
+type Counts struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	CacheCreationTokens int `json:"cache_creation_tokens"`
+	CacheReadTokens     int `json:"cache_read_tokens"`
+}
+
+// Add adds o to c.
+func (c *Counts) Add(o Counts) {
+	c.InputTokens += o.InputTokens
+	c.OutputTokens += o.OutputTokens
+	c.CacheCreationTokens += o.CacheCreationTokens
+	c.CacheReadTokens += o.CacheReadTokens
+}
+
+// FromResponse returns the token counts reported in resp.
+func FromResponse(resp *provider.Response) Counts {
+	return Counts{
+		InputTokens:         resp.InputTokens,
+		OutputTokens:        resp.OutputTokens,
+		CacheCreationTokens: resp.CacheCreationTokens,
+		CacheReadTokens:     resp.CacheReadTokens,
+	}
+}
+
+// Cost is the price of a run in USD.
+type Cost struct {
+	Total   float64            `json:"total"`
+	ByAgent map[string]float64 `json:"by_agent"`
+	ByModel map[string]float64 `json:"by_model"`
+	// Unpriced lists models with no known price. Their tokens are not
+	// included in the totals above.
+	Unpriced []string `json:"unpriced_models,omitempty"`
+}
+
+// key identifies the usage of one model by one agent.
+type key struct {
+	agent string
+	model string
+}
+
+// Tracker accumulates token usage by agent and model. It is safe for
+// concurrent use, so parallel sub-agents can share one Tracker. A nil
+// *Tracker ignores all records.
+type Tracker struct {
+	mu     sync.Mutex
+	counts map[key]Counts
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{counts: make(map[key]Counts)}
+}
+
+// Record adds the token usage of resp, answered by model ("provider/model")
+// on behalf of agent.
+func (t *Tracker) Record(agent, model string, resp *provider.Response) {
+	if t == nil || resp == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := key{agent: agent, model: model}
+	c := t.counts[k]
+	c.Add(FromResponse(resp))
+	t.counts[k] = c
+}
+
+// Total returns the usage of all agents and models combined.
+func (t *Tracker) Total() Counts {
+	var total Counts
+	if t == nil {
+		return total
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range t.counts {
+		total.Add(c)
+	}
+	return total
+}
+
+// ByAgent returns usage grouped by agent name.
+func (t *Tracker) ByAgent() map[string]Counts {
+	return t.group(func(k key) string { return k.agent })
+}
+
+// ByModel returns usage grouped by "provider/model".
+func (t *Tracker) ByModel() map[string]Counts {
+	return t.group(func(k key) string { return k.model })
+}
+
+func (t *Tracker) group(by func(key) string) map[string]Counts {
+	result := make(map[string]Counts)
+	if t == nil {
+		return result
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for k, c := range t.counts {
+		g := result[by(k)]
+		g.Add(c)
+		result[by(k)] = g
+	}
+	return result
+}
+
+// Cost prices the recorded usage with globalCfg's pricing table.
+func (t *Tracker) Cost(globalCfg *config.GlobalConfig) Cost {
+	cost := Cost{
+		ByAgent: make(map[string]float64),
+		ByModel: make(map[string]float64),
+	}
+	if t == nil {
+		return cost
+	}
+	if globalCfg == nil {
+		globalCfg = &config.GlobalConfig{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	unpriced := make(map[string]bool)
+	for k, c := range t.counts {
+		price, ok := globalCfg.ResolvePricing(k.model)
+		if !ok {
+			unpriced[k.model] = true
+			continue
+		}
+		usd := price.Cost(c.InputTokens, c.OutputTokens, c.CacheCreationTokens, c.CacheReadTokens)
+		cost.Total += usd
+		cost.ByAgent[k.agent] += usd
+		cost.ByModel[k.model] += usd
+	}
+
+	cost.Total = round(cost.Total)
+	for name, usd := range cost.ByAgent {
+		cost.ByAgent[name] = round(usd)
+	}
+	for name, usd := range cost.ByModel {
+		cost.ByModel[name] = round(usd)
+	}
+	for model := range unpriced {
+		cost.Unpriced = append(cost.Unpriced, model)
+	}
+	sort.Strings(cost.Unpriced)
+
+	return cost
+}
+
+// round rounds usd to a millionth of a dollar so sums print cleanly.
+func round(usd float64) float64 {
+	return math.Round(usd*1e6) / 1e6
+}
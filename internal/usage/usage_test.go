@@ -0,0 +1,87 @@
+package usage
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/jrswab/axe/internal/config"
+	"github.com/jrswab/axe/internal/provider"
+)
+
+func TestTracker_TotalsAndGroups(t *testing.T) {
+	tr := NewTracker()
+	tr.Record("parent", "anthropic/claude-sonnet-4", &provider.Response{InputTokens: 100, OutputTokens: 10, CacheReadTokens: 50})
+	tr.Record("parent", "anthropic/claude-sonnet-4", &provider.Response{InputTokens: 200, OutputTokens: 20})
+	tr.Record("helper", "openai/gpt-4o-mini", &provider.Response{InputTokens: 30, OutputTokens: 3, CacheCreationTokens: 7})
+
+	want := Counts{InputTokens: 330, OutputTokens: 33, CacheCreationTokens: 7, CacheReadTokens: 50}
+	if got := tr.Total(); got != want {
+		t.Errorf("Total = %+v, want %+v", got, want)
+	}
+
+	byAgent := tr.ByAgent()
+	if byAgent["parent"].InputTokens != 300 || byAgent["helper"].OutputTokens != 3 {
+		t.Errorf("ByAgent = %+v", byAgent)
+	}
+	byModel := tr.ByModel()
+	if byModel["openai/gpt-4o-mini"].CacheCreationTokens != 7 || len(byModel) != 2 {
+		t.Errorf("ByModel = %+v", byModel)
+	}
+}
+
+func TestTracker_Cost(t *testing.T) {
+	cfg := &config.GlobalConfig{Pricing: map[string]config.ModelPricing{
+		"test/big":   {Input: 10, Output: 30},
+		"test/small": {Input: 1, Output: 2},
+	}}
+
+	tr := NewTracker()
+	tr.Record("parent", "test/big", &provider.Response{InputTokens: 1_000_000, OutputTokens: 100_000})
+	tr.Record("helper", "test/small", &provider.Response{InputTokens: 500_000, OutputTokens: 500_000})
+	tr.Record("helper", "test/big", &provider.Response{InputTokens: 100_000})
+	tr.Record("helper", "mystery/model", &provider.Response{InputTokens: 999})
+
+	cost := tr.Cost(cfg)
+	if cost.Total != 15.5 {
+		t.Errorf("Total = %v, want 15.5", cost.Total)
+	}
+	if cost.ByAgent["parent"] != 13 || cost.ByAgent["helper"] != 2.5 {
+		t.Errorf("ByAgent = %v, want parent 13, helper 2.5", cost.ByAgent)
+	}
+	if cost.ByModel["test/big"] != 14 || cost.ByModel["test/small"] != 1.5 {
+		t.Errorf("ByModel = %v, want test/big 14, test/small 1.5", cost.ByModel)
+	}
+	if len(cost.Unpriced) != 1 || cost.Unpriced[0] != "mystery/model" {
+		t.Errorf("Unpriced = %v, want [mystery/model]", cost.Unpriced)
+	}
+}
+
+func TestTracker_ConcurrentRecord(t *testing.T) {
+	tr := NewTracker()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tr.Record("agent", "test/model", &provider.Response{InputTokens: 1, OutputTokens: 2})
+		}()
+	}
+	wg.Wait()
+
+	if got := tr.Total(); got.InputTokens != 50 || got.OutputTokens != 100 {
+		t.Errorf("Total = %+v, want 50 input, 100 output", got)
+	}
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tr *Tracker
+	tr.Record("agent", "test/model", &provider.Response{InputTokens: 1})
+
+	if got := tr.Total(); got != (Counts{}) {
+		t.Errorf("Total = %+v, want zero", got)
+	}
+	if cost := tr.Cost(nil); cost.Total != 0 {
+		t.Errorf("Cost.Total = %v, want 0", cost.Total)
+	}
+}